@@ -4,14 +4,18 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"google.golang.org/grpc"
+
 	"github.com/mashhkensss/PR-service/internal/app"
 	"github.com/mashhkensss/PR-service/internal/config"
+	"github.com/mashhkensss/PR-service/internal/stream"
 )
 
 const startupTimeout = 10 * time.Second
@@ -30,12 +34,13 @@ func run() int {
 		"read_timeout", cfg.HTTP.ReadTimeout,
 		"write_timeout", cfg.HTTP.WriteTimeout,
 		"shutdown_timeout", cfg.HTTP.ShutdownTimeout,
+		"grpc_addr", cfg.GRPC.Addr,
 	)
 
 	startCtx, cancel := context.WithTimeout(context.Background(), startupTimeout)
 	defer cancel()
 
-	handler, closeApp, err := app.Build(startCtx, cfg, rootLogger)
+	servers, closeApp, err := app.Build(startCtx, cfg, rootLogger)
 	if err != nil {
 		logger.Error("bootstrap failed", "error", err)
 		return 1
@@ -48,13 +53,18 @@ func run() int {
 
 	server := &http.Server{
 		Addr:              cfg.HTTP.Addr,
-		Handler:           handler,
+		Handler:           servers.HTTP,
 		ReadHeaderTimeout: cfg.HTTP.ReadTimeout,
 		ReadTimeout:       cfg.HTTP.ReadTimeout,
 		WriteTimeout:      cfg.HTTP.WriteTimeout,
 	}
+	draining := make(chan struct{})
+	server.BaseContext = func(net.Listener) context.Context {
+		return stream.WithDrain(context.Background(), draining)
+	}
+	server.RegisterOnShutdown(func() { close(draining) })
 
-	serverErr := make(chan error, 1)
+	serverErr := make(chan error, 2)
 	go func() {
 		logger.Info("http listen start", "addr", cfg.HTTP.Addr)
 		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -64,6 +74,23 @@ func run() int {
 		serverErr <- nil
 	}()
 
+	grpcErr := make(chan error, 1)
+	if servers.GRPC != nil {
+		lis, err := net.Listen("tcp", cfg.GRPC.Addr)
+		if err != nil {
+			logger.Error("grpc listen failed", "addr", cfg.GRPC.Addr, "error", err)
+			_ = server.Close()
+			return 1
+		}
+		go func() {
+			logger.Info("grpc listen start", "addr", cfg.GRPC.Addr)
+			// после GracefulStop Serve возвращает nil
+			grpcErr <- servers.GRPC.Serve(lis)
+		}()
+		// на ранних выходах сервер останавливается до закрытия базы в closeApp
+		defer servers.GRPC.Stop()
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
@@ -77,12 +104,27 @@ func run() int {
 		}
 		logger.Info("server stopped")
 		return 0
+	case err := <-grpcErr:
+		logger.Error("grpc server failed", "error", err)
+		_ = server.Close()
+		return 1
 	}
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
 	defer cancel()
 
-	if err := server.Shutdown(shutdownCtx); err != nil {
+	// Serve возвращается сразу после закрытия слушателя, поэтому вызовы gRPC дожидаются
+	// отдельно: иначе база закроется под ещё работающими обработчиками
+	grpcStopped := make(chan struct{})
+	go func() {
+		defer close(grpcStopped)
+		if servers.GRPC != nil {
+			stopGRPC(shutdownCtx, servers.GRPC)
+		}
+	}()
+	err = server.Shutdown(shutdownCtx)
+	<-grpcStopped
+	if err != nil {
 		logger.Error("server shutdown failed", "error", err)
 		return 1
 	}
@@ -91,11 +133,39 @@ func run() int {
 		logger.Error("server failed", "error", err)
 		return 1
 	}
+	if servers.GRPC != nil {
+		if err := <-grpcErr; err != nil {
+			logger.Error("grpc server failed", "error", err)
+			return 1
+		}
+	}
 
 	logger.Info("server shutdown complete")
 	return 0
 }
 
+// stopGRPC дожидается текущих вызовов, но не дольше ctx, после чего рвёт соединения
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "token":
+			os.Exit(runToken(os.Args[2:], os.Stdout, os.Stderr))
+		case "migrate":
+			os.Exit(runMigrate(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
 	os.Exit(run())
 }
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/mashhkensss/PR-service/internal/app"
+	"github.com/mashhkensss/PR-service/internal/config"
+)
+
+const migrateUsage = "usage: reviewer-service migrate up | down [N] | status | version"
+
+// runMigrate управляет схемой встроенными миграциями:
+//
+//	reviewer-service migrate up
+//	reviewer-service migrate down 1
+func runMigrate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprintln(stderr, migrateUsage) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	command, rest := fs.Arg(0), fs.Args()[1:]
+	switch command {
+	case "up", "down", "status", "version":
+	default:
+		fs.Usage()
+		return 2
+	}
+	steps := 1
+	switch {
+	case command == "down" && len(rest) == 1:
+		n, err := strconv.Atoi(rest[0])
+		if err != nil || n <= 0 {
+			fmt.Fprintf(stderr, "invalid number of steps %q\n", rest[0])
+			return 2
+		}
+		steps = n
+	case len(rest) > 0:
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := config.LoadDatabase()
+	if err != nil {
+		fmt.Fprintf(stderr, "config: %v\n", err)
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := app.OpenDB(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return 1
+	}
+	defer db.Close()
+	migrator, err := app.NewMigrator(db)
+	if err != nil {
+		fmt.Fprintf(stderr, "load migrations: %v\n", err)
+		return 1
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Fprintf(stdout, "applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "migrate up: %v\n", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(stdout, "no change")
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			fmt.Fprintf(stdout, "reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "migrate down: %v\n", err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Fprintln(stdout, "no change")
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Fprintf(stderr, "migrate status: %v\n", err)
+			return 1
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Fprintf(stdout, "%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+	case "version":
+		version, dirty, err := migrator.Version(ctx)
+		if err != nil {
+			fmt.Fprintf(stderr, "migrate version: %v\n", err)
+			return 1
+		}
+		if dirty {
+			fmt.Fprintf(stdout, "%d (dirty)\n", version)
+		} else {
+			fmt.Fprintln(stdout, version)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/app"
+	"github.com/mashhkensss/PR-service/internal/auth"
+	"github.com/mashhkensss/PR-service/internal/config"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	tokenservice "github.com/mashhkensss/PR-service/internal/service/token"
+)
+
+// runToken выпускает токен тем же сервисом, что и POST /auth/token. Доступ к секретам
+// подписи уже даёт право выпускать токены, поэтому CLI действует как системный субъект:
+//
+//	reviewer-service token -sub u1 -role admin -ttl 1h
+func runToken(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("token", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	subject := fs.String("sub", "", "subject (user_id)")
+	role := fs.String("role", auth.RoleUser, "role: admin, team_lead or user")
+	ttl := fs.Duration("ttl", time.Hour, "token lifetime")
+	scope := fs.String("scope", "", "space-separated scopes narrowing the role")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	cfg, err := config.LoadAuth()
+	if err != nil {
+		fmt.Fprintf(stderr, "config: %v\n", err)
+		return 1
+	}
+	signer := app.NewSigner(cfg)
+	if signer == nil {
+		fmt.Fprintln(stderr, "ADMIN_SECRET and USER_SECRET are not set; tokens for JWKS keys are issued by the identity provider")
+		return 1
+	}
+	token, _, err := tokenservice.New(signer).Issue(context.Background(), requester.System(), auth.TokenRequest{Subject: *subject, Role: *role, Scope: *scope, TTL: *ttl})
+	if err != nil {
+		fmt.Fprintf(stderr, "issue token: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(stdout, token)
+	return 0
+}
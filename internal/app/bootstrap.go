@@ -6,90 +6,467 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
+	"sync"
 	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
+	"google.golang.org/grpc"
 
+	"github.com/mashhkensss/PR-service/internal/auth"
 	"github.com/mashhkensss/PR-service/internal/config"
+	domainsla "github.com/mashhkensss/PR-service/internal/domain/sla"
+	"github.com/mashhkensss/PR-service/internal/grpcapi"
 	apphttp "github.com/mashhkensss/PR-service/internal/http"
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	apikeyhandler "github.com/mashhkensss/PR-service/internal/http/handlers/apikey"
+	authhandler "github.com/mashhkensss/PR-service/internal/http/handlers/auth"
+	batchhandler "github.com/mashhkensss/PR-service/internal/http/handlers/batch"
 	healthhandler "github.com/mashhkensss/PR-service/internal/http/handlers/health"
+	idempotencyhandler "github.com/mashhkensss/PR-service/internal/http/handlers/idempotency"
 	prhandler "github.com/mashhkensss/PR-service/internal/http/handlers/pullrequest"
+	revocationhandler "github.com/mashhkensss/PR-service/internal/http/handlers/revocation"
+	slahandler "github.com/mashhkensss/PR-service/internal/http/handlers/sla"
 	statshandler "github.com/mashhkensss/PR-service/internal/http/handlers/stats"
+	streamhandler "github.com/mashhkensss/PR-service/internal/http/handlers/stream"
 	teamhandler "github.com/mashhkensss/PR-service/internal/http/handlers/team"
+	transferhandler "github.com/mashhkensss/PR-service/internal/http/handlers/transfer"
 	userhandler "github.com/mashhkensss/PR-service/internal/http/handlers/user"
 	"github.com/mashhkensss/PR-service/internal/http/middleware"
+	"github.com/mashhkensss/PR-service/internal/metrics"
+	"github.com/mashhkensss/PR-service/internal/outbox"
 	"github.com/mashhkensss/PR-service/internal/persistence/postgres"
+	apikeyrepo "github.com/mashhkensss/PR-service/internal/persistence/postgres/apikey"
 	idempotencyrepo "github.com/mashhkensss/PR-service/internal/persistence/postgres/idempotency"
+	"github.com/mashhkensss/PR-service/internal/persistence/postgres/migrate"
+	outboxrepo "github.com/mashhkensss/PR-service/internal/persistence/postgres/outbox"
 	prrepo "github.com/mashhkensss/PR-service/internal/persistence/postgres/pullrequest"
+	ratelimitrepo "github.com/mashhkensss/PR-service/internal/persistence/postgres/ratelimit"
+	revocationrepo "github.com/mashhkensss/PR-service/internal/persistence/postgres/revocation"
+	slarepo "github.com/mashhkensss/PR-service/internal/persistence/postgres/sla"
 	statsrepo "github.com/mashhkensss/PR-service/internal/persistence/postgres/stats"
+	streamrepo "github.com/mashhkensss/PR-service/internal/persistence/postgres/stream"
 	teamrepo "github.com/mashhkensss/PR-service/internal/persistence/postgres/team"
+	transferrepo "github.com/mashhkensss/PR-service/internal/persistence/postgres/transfer"
 	userrepo "github.com/mashhkensss/PR-service/internal/persistence/postgres/user"
+	"github.com/mashhkensss/PR-service/internal/ratelimit"
+	"github.com/mashhkensss/PR-service/internal/service"
+	apikeyservice "github.com/mashhkensss/PR-service/internal/service/apikey"
 	"github.com/mashhkensss/PR-service/internal/service/assignment"
+	idempotencyservice "github.com/mashhkensss/PR-service/internal/service/idempotency"
 	pullrequestservice "github.com/mashhkensss/PR-service/internal/service/pullrequest"
+	revocationservice "github.com/mashhkensss/PR-service/internal/service/revocation"
+	slaservice "github.com/mashhkensss/PR-service/internal/service/sla"
 	statsservice "github.com/mashhkensss/PR-service/internal/service/stats"
+	streamservice "github.com/mashhkensss/PR-service/internal/service/stream"
 	teamservice "github.com/mashhkensss/PR-service/internal/service/team"
+	tokenservice "github.com/mashhkensss/PR-service/internal/service/token"
+	transferservice "github.com/mashhkensss/PR-service/internal/service/transfer"
 	userservice "github.com/mashhkensss/PR-service/internal/service/user"
+	"github.com/mashhkensss/PR-service/internal/stream"
+	"github.com/mashhkensss/PR-service/internal/tracing"
+	"github.com/mashhkensss/PR-service/migrations"
 )
 
-func Build(ctx context.Context, cfg config.Config, logger *slog.Logger) (http.Handler, func() error, error) {
-	db, err := sql.Open("pgx", cfg.Database.DSN)
-	if err != nil {
-		return nil, nil, fmt.Errorf("open db: %w", err)
-	}
+// Servers — транспорты поверх одних и тех же сервисов
+type Servers struct {
+	HTTP http.Handler
+	// GRPC nil, если GRPC_ADDR не задан
+	GRPC *grpc.Server
+}
+
+func Build(ctx context.Context, cfg config.Config, logger *slog.Logger) (servers Servers, cleanup func() error, err error) {
 	pingCtx := ctx
 	if pingCtx == nil {
 		pingCtx = context.Background()
 	}
-	pingErr := attemptPing(pingCtx, db, cfg.Database.ConnectRetries, cfg.Database.ConnectRetryInterval)
-	if pingErr != nil {
-		_ = db.Close()
-		return nil, nil, fmt.Errorf("ping db: %w", pingErr)
+	db, err := OpenDB(pingCtx, cfg)
+	if err != nil {
+		return Servers{}, nil, err
+	}
+	// при ошибке сборки всё уже открытое закрывается в обратном порядке
+	closers := []func() error{db.Close}
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(closers) - 1; i >= 0; i-- {
+			_ = closers[i]()
+		}
+	}()
+	if cfg.Database.AutoMigrate {
+		if err := autoMigrate(pingCtx, db, logger.With("component", "migrate")); err != nil {
+			return Servers{}, nil, err
+		}
 	}
 
-	txManager := postgres.NewTxManager(db)
+	shutdownTracing, err := tracing.Setup(pingCtx, tracing.Options{
+		Exporter:     cfg.Tracing.Exporter,
+		ServiceName:  cfg.Tracing.ServiceName,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		OTLPInsecure: cfg.Tracing.OTLPInsecure,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return Servers{}, nil, fmt.Errorf("tracing: %w", err)
+	}
+	closers = append(closers, func() error { return shutdownTracing(context.Background()) })
+
+	txManager := postgres.NewTxManager(db).WithRetry(postgres.RetryPolicy{
+		MaxAttempts: cfg.Database.TxMaxAttempts,
+		BaseDelay:   cfg.Database.TxRetryDelay,
+		MaxDelay:    postgres.DefaultRetryPolicy.MaxDelay,
+	})
+
+	var (
+		m          *metrics.Metrics
+		prObserver pullrequestservice.Observer
+		idObserver middleware.IdempotencyObserver
+	)
+	if cfg.Metrics.Enabled {
+		m = metrics.New()
+		m.RegisterDB(db, "postgres")
+		prObserver = m
+		idObserver = m
+	}
 
 	teamRepo := teamrepo.New(db)
 	userRepo := userrepo.New(db)
 	prRepo := prrepo.New(db)
 	statsRepo := statsrepo.New(db)
+	slaRepo := slarepo.New(db)
+	apiKeyRepo := apikeyrepo.New(db)
+	revocationRepo := revocationrepo.New(db)
+
+	streamRepo := streamrepo.New(db)
+	streamBroker := stream.NewBroker(streamRepo, cfg.Stream.PollInterval, cfg.Stream.Retention, logger.With("component", "stream"))
+	eventStores := service.EventStores{stream.NewRecorder(streamRepo)}
 
-	teamSvc := teamservice.New(teamRepo, txManager)
-	userSvc := userservice.New(userRepo, prRepo)
-	assigner := assignment.NewStrategy(nil)
-	prSvc := pullrequestservice.New(teamRepo, userRepo, prRepo, txManager, assigner)
-	statsSvc := statsservice.New(statsRepo)
+	// без приёмника событий outbox не пишется: иначе таблица росла бы без релея
+	var outboxRelay *outbox.Relay
+	if sink := outboxSink(cfg); sink != nil {
+		outboxRepo := outboxrepo.New(db)
+		eventStores = append(eventStores, outboxRepo)
+		outboxRelay = outbox.NewRelay(outboxRepo, sink, outbox.RelayOptions{
+			Interval:    cfg.Outbox.PollInterval,
+			Batch:       cfg.Outbox.Batch,
+			Lease:       2 * cfg.Outbox.HTTPTimeout,
+			Retention:   cfg.Outbox.Retention,
+			MaxAttempts: cfg.Outbox.MaxAttempts,
+		}, logger.With("component", "outbox"))
+	}
+
+	teamSvc := teamservice.WithTracing(teamservice.New(teamRepo, userRepo, txManager, eventStores))
+	userSvc := userservice.WithTracing(userservice.New(userRepo, teamRepo, prRepo, txManager, eventStores))
+	assigner := assignment.WithTracing(assignment.NewStrategy(nil))
+	prSvc := pullrequestservice.WithTracing(pullrequestservice.New(teamRepo, userRepo, prRepo, txManager, assigner, prObserver, eventStores))
+	streamSvc := streamservice.WithTracing(streamservice.New(streamRepo, streamBroker, teamRepo, 100))
+	statsSvc := statsservice.WithTracing(statsservice.New(statsRepo, teamRepo, txManager))
+	transferSvc := transferservice.WithTracing(transferservice.New(transferrepo.New(db), txManager))
+
+	var slaDefaults domainsla.Policy
+	if cfg.SLA.DefaultReviewWithin > 0 {
+		slaDefaults, err = domainsla.NewDefault(cfg.SLA.DefaultReviewWithin, cfg.SLA.DefaultBusinessDays, domainsla.Escalation(cfg.SLA.DefaultEscalation))
+		if err != nil {
+			return Servers{}, nil, fmt.Errorf("sla defaults: %w", err)
+		}
+	}
+	slaSvc := slaservice.WithTracing(slaservice.New(slaRepo, teamRepo, prSvc, txManager, slaservice.NewLogNotifier(logger.With("component", "sla")), slaDefaults))
+
+	apiKeySvc := apikeyservice.WithTracing(apikeyservice.New(apiKeyRepo))
+
+	// отзывы загружаются до приёма запросов, иначе отозванные токены прошли бы до первого обновления
+	denylist := revocationservice.NewDenylist(revocationRepo, logger.With("component", "revocation"))
+	if err := denylist.Refresh(pingCtx); err != nil {
+		return Servers{}, nil, fmt.Errorf("load revocations: %w", err)
+	}
+	revocationSvc := revocationservice.WithTracing(revocationservice.New(revocationRepo, denylist))
 
 	teamHandler := teamhandler.New(teamSvc, logger.With("handler", "team"))
 	userHandler := userhandler.New(userSvc, logger.With("handler", "user"))
 	prHandler := prhandler.New(prSvc, logger.With("handler", "pullrequest"))
 	statsHandler := statshandler.New(statsSvc, logger.With("handler", "stats"))
+	slaHandler := slahandler.New(slaSvc, logger.With("handler", "sla"))
+	apiKeyHandler := apikeyhandler.New(apiKeySvc, logger.With("handler", "apikey"))
+	revocationHandler := revocationhandler.New(revocationSvc, logger.With("handler", "revocation"))
 	healthHandler := healthhandler.New(db)
 
-	auth := middleware.NewAuthorization([]byte(cfg.Auth.AdminSecret), []byte(cfg.Auth.UserSecret))
+	verifier, jwks, err := buildVerifier(pingCtx, cfg, denylist, logger.With("component", "auth"))
+	if err != nil {
+		return Servers{}, nil, fmt.Errorf("auth: %w", err)
+	}
+	authz := middleware.NewAuthorization(verifier, apiKeySvc)
 	l := middleware.NewLogger(logger.With("component", "http"))
-	rateLimiter := middleware.NewRateLimiter(cfg.RateLimit.Requests, cfg.RateLimit.Interval, cfg.RateLimit.TrustForwardHeader)
+	routeLimits, err := middleware.ParseRouteLimits(cfg.RateLimit.Routes)
+	if err != nil {
+		return Servers{}, nil, fmt.Errorf("rate limit: %w", err)
+	}
+	rateLimiter := middleware.NewRateLimiter(cfg.RateLimit.Requests, cfg.RateLimit.Interval, cfg.RateLimit.TrustForwardHeader).
+		WithRoutes(routeLimits...)
+	var ipRateLimiter *middleware.RateLimiter
+	if cfg.RateLimit.IPRequests > 0 {
+		ipRateLimiter = middleware.NewRateLimiter(cfg.RateLimit.IPRequests, cfg.RateLimit.IPInterval, cfg.RateLimit.TrustForwardHeader).ByIP()
+	}
+	// gRPC получает то же хранилище: в памяти процесса бакеты тоже общие для обоих транспортов
+	var buckets ratelimit.Storage = ratelimit.NewMemory(cfg.RateLimit.Interval * 10)
+	if cfg.RateLimit.Backend == "postgres" {
+		buckets = ratelimitrepo.New(db, time.Minute)
+	}
+	rateLimiter.WithStore(buckets)
+	if ipRateLimiter != nil {
+		ipRateLimiter.WithStore(buckets)
+	}
+	rateLimitLogger := logger.With("component", "rate_limit")
+	onRateLimitError := func(r *http.Request, err error) {
+		rateLimitLogger.Error("rate limit store failed", "path", r.URL.Path, "error", err)
+	}
+	rateLimiter.OnError(onRateLimitError)
+	if ipRateLimiter != nil {
+		ipRateLimiter.OnError(onRateLimitError)
+	}
+	cacheStatuses, err := middleware.ParseCacheStatuses(cfg.Idempotency.CacheStatuses)
+	if err != nil {
+		return Servers{}, nil, fmt.Errorf("idempotency: %w", err)
+	}
 	idempotencyRepo := idempotencyrepo.New(db)
-	idempotency := middleware.NewIdempotencyMiddleware(idempotencyRepo, cfg.Idempotency.TTL, logger.With("component", "idempotency"))
-	validator := middleware.NewValidatorMiddleware(middleware.NewTagValidator())
-
-	router := apphttp.NewRouter(apphttp.RouterConfig{
-		TeamHandler:   teamHandler,
-		UserHandler:   userHandler,
-		PRHandler:     prHandler,
-		StatsHandler:  statsHandler,
-		HealthHandler: healthHandler,
-		Auth:          auth,
-		Logger:        l.Middleware,
-		RateLimiter:   rateLimiter.Middleware,
-		Idempotency:   idempotency,
-		Validator:     validator,
-	})
+	idempotency := middleware.NewIdempotencyMiddleware(idempotencyRepo, middleware.IdempotencyConfig{
+		TTL:           cfg.Idempotency.TTL,
+		LockTTL:       cfg.Idempotency.LockTTL,
+		Wait:          cfg.Idempotency.Wait,
+		CacheStatuses: cacheStatuses,
+	}, logger.With("component", "idempotency"), idObserver)
+	idempotencySvc := idempotencyservice.WithTracing(idempotencyservice.New(idempotencyRepo))
+	tagValidator := middleware.NewTagValidator()
+	validator := middleware.NewValidatorMiddleware(tagValidator)
+
+	routerCfg := apphttp.RouterConfig{
+		TeamHandler:        teamHandler,
+		UserHandler:        userHandler,
+		PRHandler:          prHandler,
+		StatsHandler:       statsHandler,
+		SLAHandler:         slaHandler,
+		APIKeyHandler:      apiKeyHandler,
+		RevocationHandler:  revocationHandler,
+		IdempotencyHandler: idempotencyhandler.New(idempotencySvc, logger.With("handler", "idempotency")),
+		StreamHandler:      streamhandler.New(streamSvc, cfg.Stream.Heartbeat, logger.With("handler", "stream")),
+		TransferHandler:    transferhandler.New(transferSvc, cfg.Transfer.ImportMaxBytes, logger.With("handler", "transfer")),
+		HealthHandler:      healthHandler,
+		Auth:               authz,
+		Logger:             l.Middleware,
+		RateLimiter:        rateLimiter.Middleware,
+		Idempotency:        idempotency,
+		Validator:          validator,
+		Tracing:            tracing.Middleware,
+	}
+	// операции batch проходят тот же middleware идемпотентности, что и одиночные маршруты;
+	// аутентификация, валидатор и лимит уже применены к самому POST /batch
+	routerCfg.BatchHandler = batchhandler.New(map[string]batchhandler.Operation{
+		dto.BatchOpCreate:      {Path: "/pullRequest/create", Handler: idempotency(http.HandlerFunc(prHandler.CreatePullRequest))},
+		dto.BatchOpMerge:       {Path: "/pullRequest/merge", Handler: idempotency(http.HandlerFunc(prHandler.MergePullRequest))},
+		dto.BatchOpReassign:    {Path: "/pullRequest/reassign", Handler: idempotency(http.HandlerFunc(prHandler.ReassignReviewer))},
+		dto.BatchOpSetIsActive: {Path: "/users/setIsActive", Handler: idempotency(http.HandlerFunc(userHandler.SetIsActive))},
+	}, txManager, cfg.Batch.MaxOperations, logger.With("handler", "batch"))
+	if ipRateLimiter != nil {
+		routerCfg.IPRateLimiter = ipRateLimiter.Middleware
+	}
+	if signer := NewSigner(cfg); signer != nil {
+		routerCfg.TokenHandler = authhandler.New(tokenservice.WithTracing(tokenservice.New(signer)), logger.With("handler", "auth"))
+	}
+	if m != nil {
+		rateLimiter.OnReject(m.RateLimited)
+		if ipRateLimiter != nil {
+			ipRateLimiter.OnReject(m.RateLimited)
+		}
+		routerCfg.Metrics = m.Middleware
+		routerCfg.MetricsHandler = m.Handler()
+	}
+	servers = Servers{HTTP: apphttp.NewRouter(routerCfg)}
+	if cfg.GRPC.Addr != "" {
+		grpcLimit := &grpcapi.RateLimit{
+			Store:      buckets,
+			Limit:      cfg.RateLimit.Requests,
+			Interval:   cfg.RateLimit.Interval,
+			IPLimit:    cfg.RateLimit.IPRequests,
+			IPInterval: cfg.RateLimit.IPInterval,
+			OnError: func(method string, err error) {
+				rateLimitLogger.Error("rate limit store failed", "method", method, "error", err)
+			},
+		}
+		if m != nil {
+			grpcLimit.OnReject = func(string) { m.RateLimited(nil) }
+		}
+		servers.GRPC = grpcapi.NewServer(grpcapi.ServerConfig{
+			Teams:        teamSvc,
+			Users:        userSvc,
+			PullRequests: prSvc,
+			Stats:        statsSvc,
+			Auth:         authz,
+			RateLimit:    grpcLimit,
+			Validator:    tagValidator,
+			Logger:       logger.With("component", "grpc"),
+		})
+	}
 
-	cleanup := func() error {
+	background, stopBackground := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	if jwks != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			jwks.Run(background, cfg.Auth.JWKSRefreshInterval)
+		}()
+	}
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		denylist.Run(background, cfg.Auth.RevocationRefreshInterval)
+	}()
+	purger := idempotencyservice.NewPurger(idempotencySvc, cfg.Idempotency.PurgeInterval, cfg.Idempotency.PurgeBatch, logger.With("component", "idempotency"))
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		purger.Run(background)
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		streamBroker.Run(background)
+	}()
+	if outboxRelay != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			outboxRelay.Run(background)
+		}()
+	}
+	if cfg.SLA.CheckInterval > 0 {
+		scheduler := slaservice.NewScheduler(slaSvc, cfg.SLA.CheckInterval, logger.With("component", "sla"))
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			scheduler.Run(background)
+		}()
+	}
+
+	cleanup = func() error {
+		stopBackground()
+		workers.Wait()
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			logger.Error("tracing shutdown failed", "error", err)
+		}
 		return db.Close()
 	}
 
-	return router, cleanup, nil
+	return servers, cleanup, nil
+}
+
+func outboxSink(cfg config.Config) outbox.Sink {
+	switch cfg.Outbox.Sink {
+	case "stdout":
+		return outbox.NewWriterSink(os.Stdout)
+	case "http":
+		return outbox.NewHTTPSink(cfg.Outbox.HTTPURL, cfg.Outbox.HTTPTimeout)
+	default:
+		return nil
+	}
+}
+
+// buildVerifier собирает источники ключей: JWKS с привязкой ролей к kid и, если заданы,
+// старые HMAC-секреты, каждый из которых подписывает только свою роль
+func buildVerifier(ctx context.Context, cfg config.Config, denylist auth.Denylist, logger *slog.Logger) (*auth.Verifier, *auth.JWKS, error) {
+	var (
+		sources []auth.KeySource
+		jwks    *auth.JWKS
+	)
+	if cfg.Auth.JWKSURL != "" || cfg.Auth.JWKSFile != "" {
+		binding, err := auth.ParseRoleBinding(cfg.Auth.KeyRoles)
+		if err != nil {
+			return nil, nil, err
+		}
+		jwks, err = auth.NewJWKS(auth.JWKSOptions{
+			URL:     cfg.Auth.JWKSURL,
+			File:    cfg.Auth.JWKSFile,
+			Binding: binding,
+			Logger:  logger,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := jwks.Refresh(ctx); err != nil {
+			return nil, nil, fmt.Errorf("load jwks: %w", err)
+		}
+		sources = append(sources, jwks)
+	}
+	if keys := secretKeys(cfg); len(keys) > 0 {
+		sources = append(sources, keys)
+	}
+	verifier := auth.NewVerifier(auth.VerifierOptions{
+		Issuer:          cfg.Auth.Issuer,
+		Audience:        cfg.Auth.Audience,
+		ClockSkew:       cfg.Auth.ClockSkew,
+		AllowMissingExp: !cfg.Auth.RequireExp,
+		Denylist:        denylist,
+	}, sources...)
+	return verifier, jwks, nil
+}
+
+// NewSigner выпускает токены теми же HMAC-секретами, которыми их проверяет Verifier.
+// Возвращает nil, если секреты не заданы и сервис работает только с JWKS
+func NewSigner(cfg config.Config) *auth.Signer {
+	keys := secretKeys(cfg)
+	if len(keys) == 0 {
+		return nil
+	}
+	return auth.NewSigner(auth.SignerOptions{
+		Issuer:   cfg.Auth.Issuer,
+		Audience: cfg.Auth.Audience,
+		MaxTTL:   cfg.Auth.TokenMaxTTL,
+	}, keys...)
+}
+
+func secretKeys(cfg config.Config) auth.StaticKeys {
+	if cfg.Auth.AdminSecret == "" {
+		return nil
+	}
+	return auth.StaticKeys{
+		auth.HMACKey("", []byte(cfg.Auth.AdminSecret), auth.RoleAdmin, auth.RoleTeamLead),
+		auth.HMACKey("", []byte(cfg.Auth.UserSecret), auth.RoleUser),
+	}
+}
+
+// OpenDB открывает пул и ждёт доступности базы с повторами из конфигурации
+func OpenDB(ctx context.Context, cfg config.Config) (*sql.DB, error) {
+	db, err := sql.Open("pgx", cfg.Database.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("open db: %w", err)
+	}
+	if err := attemptPing(ctx, db, cfg.Database.ConnectRetries, cfg.Database.ConnectRetryInterval); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("ping db: %w", err)
+	}
+	return db, nil
+}
+
+// NewMigrator собирает мигратор над встроенными в бинарник миграциями
+func NewMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	return migrate.New(db, migrations.FS)
+}
+
+func autoMigrate(ctx context.Context, db *sql.DB, logger *slog.Logger) error {
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return fmt.Errorf("load migrations: %w", err)
+	}
+	applied, err := migrator.Up(ctx)
+	for _, m := range applied {
+		logger.Info("migration applied", "version", m.Version, "name", m.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	return nil
 }
 
 func attemptPing(ctx context.Context, db *sql.DB, retries int, interval time.Duration) error {
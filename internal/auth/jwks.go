@@ -0,0 +1,288 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS разбирает JWK Set. Ключи без kid, не для подписи, с неподдерживаемым алгоритмом
+// или некорректные пропускаются, чтобы чужой ключ в общем JWKS не ломал загрузку остальных;
+// некорректные попадают в logger, если он задан
+func ParseJWKS(data []byte, binding RoleBinding, logger *slog.Logger) ([]Key, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+	keys := make([]Key, 0, len(set.Keys))
+	for _, raw := range set.Keys {
+		if raw.Kid == "" || (raw.Use != "" && raw.Use != "sig") {
+			continue
+		}
+		key, err := parseJWK(raw)
+		if err != nil {
+			if logger != nil {
+				logger.Warn("jwks key skipped", "kid", raw.Kid, "error", err)
+			}
+			continue
+		}
+		if key.Algorithm == "" {
+			continue
+		}
+		key.Roles = binding.Roles(raw.Kid)
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func parseJWK(raw jwk) (Key, error) {
+	key := Key{ID: raw.Kid}
+	switch raw.Kty {
+	case "RSA":
+		if raw.Alg != "" && raw.Alg != AlgRS256 {
+			return Key{}, nil
+		}
+		n, err := decodeBigInt(raw.N)
+		if err != nil {
+			return Key{}, fmt.Errorf("modulus: %w", err)
+		}
+		e, err := decodeBigInt(raw.E)
+		if err != nil {
+			return Key{}, fmt.Errorf("exponent: %w", err)
+		}
+		if n.BitLen() < 2048 {
+			return Key{}, errors.New("rsa key shorter than 2048 bits")
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return Key{}, errors.New("invalid rsa exponent")
+		}
+		key.Algorithm = AlgRS256
+		key.public = &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "EC":
+		if raw.Crv != "P-256" || (raw.Alg != "" && raw.Alg != AlgES256) {
+			return Key{}, nil
+		}
+		x, err := decodeBigInt(raw.X)
+		if err != nil {
+			return Key{}, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeBigInt(raw.Y)
+		if err != nil {
+			return Key{}, fmt.Errorf("y: %w", err)
+		}
+		curve := elliptic.P256()
+		if !curve.IsOnCurve(x, y) {
+			return Key{}, errors.New("point is not on P-256")
+		}
+		key.Algorithm = AlgES256
+		key.public = &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	}
+	return key, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("empty value")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+type JWKSOptions struct {
+	// URL или File — откуда читать JWK Set; задаётся ровно одно
+	URL     string
+	File    string
+	Binding RoleBinding
+	Client  *http.Client
+	// MinRefreshInterval ограничивает внеплановые перечитывания на неизвестный kid
+	MinRefreshInterval time.Duration
+	Logger             *slog.Logger
+}
+
+// JWKS держит ключи из JWK Set и перечитывает их по таймеру (Run) и при встрече
+// неизвестного kid — так новый ключ подхватывается сразу после ротации
+type JWKS struct {
+	opts JWKSOptions
+
+	mu   sync.RWMutex
+	keys map[string]Key
+	// lastAttempt — начало последнего перечитывания, в том числе неудачного
+	lastAttempt time.Time
+	// inflight закрывается, когда завершится перечитывание по неизвестному kid
+	inflight chan struct{}
+
+	refreshMu sync.Mutex
+}
+
+// lookupRefreshTimeout ограничивает перечитывание по неизвестному kid, если у клиента нет своего таймаута
+const lookupRefreshTimeout = 10 * time.Second
+
+func NewJWKS(opts JWKSOptions) (*JWKS, error) {
+	if (opts.URL == "") == (opts.File == "") {
+		return nil, errors.New("jwks: exactly one of url or file must be set")
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 5 * time.Second}
+	}
+	if opts.MinRefreshInterval <= 0 {
+		opts.MinRefreshInterval = 30 * time.Second
+	}
+	return &JWKS{opts: opts, keys: make(map[string]Key)}, nil
+}
+
+// Refresh перечитывает набор ключей. При ошибке остаются прежние ключи
+func (j *JWKS) Refresh(ctx context.Context) error {
+	j.refreshMu.Lock()
+	defer j.refreshMu.Unlock()
+	return j.refresh(ctx)
+}
+
+func (j *JWKS) refresh(ctx context.Context) error {
+	j.mu.Lock()
+	j.lastAttempt = time.Now()
+	j.mu.Unlock()
+	data, err := j.load(ctx)
+	if err != nil {
+		return err
+	}
+	keys, err := ParseJWKS(data, j.opts.Binding, j.opts.Logger)
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]Key, len(keys))
+	for _, k := range keys {
+		byID[k.ID] = k
+	}
+	j.mu.Lock()
+	j.keys = byID
+	j.mu.Unlock()
+	return nil
+}
+
+func (j *JWKS) load(ctx context.Context) ([]byte, error) {
+	if j.opts.File != "" {
+		return os.ReadFile(j.opts.File)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.opts.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.opts.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// Lookup ищет ключ по kid. Неизвестный kid вызывает перечитывание, но не чаще MinRefreshInterval,
+// считая и неудачные попытки: недоступный JWKS не должен дёргаться на каждый запрос
+func (j *JWKS) Lookup(ctx context.Context, kid string) []Key {
+	if kid == "" {
+		return nil
+	}
+	if key, ok := j.key(kid); ok {
+		return []Key{key}
+	}
+
+	if done := j.refreshOnMiss(ctx, kid); done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	if key, ok := j.key(kid); ok {
+		return []Key{key}
+	}
+	return nil
+}
+
+// refreshOnMiss возвращает канал текущего перечитывания или nil, если перечитывать рано.
+// Одновременные запросы ждут одно перечитывание; оно идёт на контексте без отмены,
+// чтобы завершение запроса, который его начал, не обрывало загрузку для остальных
+func (j *JWKS) refreshOnMiss(ctx context.Context, kid string) <-chan struct{} {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.inflight != nil {
+		return j.inflight
+	}
+	if time.Since(j.lastAttempt) < j.opts.MinRefreshInterval {
+		return nil
+	}
+	j.lastAttempt = time.Now()
+	done := make(chan struct{})
+	j.inflight = done
+
+	go func() {
+		defer close(done)
+		refreshCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), lookupRefreshTimeout)
+		defer cancel()
+		if err := j.Refresh(refreshCtx); err != nil && j.opts.Logger != nil {
+			j.opts.Logger.Error("jwks refresh failed", "error", err, "kid", kid)
+		}
+		j.mu.Lock()
+		j.inflight = nil
+		j.mu.Unlock()
+	}()
+	return done
+}
+
+func (j *JWKS) key(kid string) (Key, bool) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	key, ok := j.keys[kid]
+	return key, ok
+}
+
+// Run перечитывает ключи каждые interval и блокируется до отмены ctx
+func (j *JWKS) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := j.Refresh(ctx); err != nil && ctx.Err() == nil && j.opts.Logger != nil {
+				j.opts.Logger.Error("jwks refresh failed", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+)
+
+// Key — ключ проверки подписи. Roles — роли, токены которых этот ключ вправе подписывать:
+// токен с role вне списка отклоняется даже при корректной подписи
+type Key struct {
+	ID        string
+	Algorithm string
+	Roles     []string
+
+	public crypto.PublicKey
+	secret []byte
+}
+
+func (k Key) allows(role string) bool {
+	for _, r := range k.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// HMACKey — симметричный ключ HS256 для старых токенов на ADMIN_SECRET/USER_SECRET
+func HMACKey(id string, secret []byte, roles ...string) Key {
+	return Key{ID: id, Algorithm: AlgHS256, Roles: roles, secret: secret}
+}
+
+// KeySource отдаёт ключи-кандидаты для kid из заголовка токена
+type KeySource interface {
+	Lookup(ctx context.Context, kid string) []Key
+}
+
+// StaticKeys — неизменяемый набор ключей. Токен без kid проверяется всеми ключами набора
+type StaticKeys []Key
+
+func (s StaticKeys) Lookup(_ context.Context, kid string) []Key {
+	if kid == "" {
+		return s
+	}
+	var out []Key
+	for _, k := range s {
+		if k.ID == kid {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// RoleBinding сопоставляет роли шаблоны kid (синтаксис path.Match)
+type RoleBinding map[string][]string
+
+// ParseRoleBinding разбирает строку вида "admin=admin-*;user=user-*,admin-*"
+func ParseRoleBinding(raw string) (RoleBinding, error) {
+	binding := make(RoleBinding)
+	for _, part := range strings.Split(raw, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		role, patterns, ok := strings.Cut(part, "=")
+		role = strings.TrimSpace(role)
+		if !ok || role == "" {
+			return nil, fmt.Errorf("invalid role binding %q", part)
+		}
+		for _, pattern := range strings.Split(patterns, ",") {
+			pattern = strings.TrimSpace(pattern)
+			if pattern == "" {
+				continue
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid kid pattern %q: %w", pattern, err)
+			}
+			binding[role] = append(binding[role], pattern)
+		}
+	}
+	if len(binding) == 0 {
+		return nil, fmt.Errorf("empty role binding")
+	}
+	return binding, nil
+}
+
+// Roles возвращает роли, которые может подписывать ключ с данным kid
+func (b RoleBinding) Roles(kid string) []string {
+	var roles []string
+	for role, patterns := range b {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, kid); ok {
+				roles = append(roles, role)
+				break
+			}
+		}
+	}
+	return roles
+}
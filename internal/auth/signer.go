@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidTokenRequest = errors.New("invalid token request")
+
+type SignerOptions struct {
+	// Issuer и Audience проставляются в токен, чтобы его принял Verifier с теми же настройками
+	Issuer   string
+	Audience string
+	// MaxTTL ограничивает срок жизни выпускаемых токенов; 0 — без ограничения
+	MaxTTL time.Duration
+	Now    func() time.Time
+}
+
+// Signer выпускает HS256-токены ключами, которыми их потом проверяет Verifier.
+// Ключ выбирается по роли, поэтому USER_SECRET не подпишет admin-токен и здесь
+type Signer struct {
+	opts SignerOptions
+	keys []Key
+}
+
+func NewSigner(opts SignerOptions, keys ...Key) *Signer {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Signer{opts: opts, keys: keys}
+}
+
+type TokenRequest struct {
+	Subject string
+	Role    string
+	Scope   string
+	TTL     time.Duration
+}
+
+// Issue заполняет стандартные claims и подписывает токен
+func (s *Signer) Issue(req TokenRequest) (string, Claims, error) {
+	if req.Subject == "" {
+		return "", Claims{}, fmt.Errorf("%w: subject is required", ErrInvalidTokenRequest)
+	}
+	if req.TTL <= 0 {
+		return "", Claims{}, fmt.Errorf("%w: ttl must be positive", ErrInvalidTokenRequest)
+	}
+	if s.opts.MaxTTL > 0 && req.TTL > s.opts.MaxTTL {
+		return "", Claims{}, fmt.Errorf("%w: ttl exceeds %s", ErrInvalidTokenRequest, s.opts.MaxTTL)
+	}
+	id, err := newTokenID()
+	if err != nil {
+		return "", Claims{}, err
+	}
+	now := s.opts.Now()
+	claims := Claims{
+		ID:        id,
+		Subject:   req.Subject,
+		Role:      req.Role,
+		Scope:     req.Scope,
+		Issuer:    s.opts.Issuer,
+		IssuedAt:  now.Unix(),
+		NotBefore: now.Unix(),
+		ExpiresAt: now.Add(req.TTL).Unix(),
+	}
+	if s.opts.Audience != "" {
+		claims.Audience = Audience{s.opts.Audience}
+	}
+	token, err := s.Sign(claims)
+	if err != nil {
+		return "", Claims{}, err
+	}
+	return token, claims, nil
+}
+
+// Sign подписывает claims как есть первым ключом, которому разрешена роль
+func (s *Signer) Sign(claims Claims) (string, error) {
+	key, ok := s.keyFor(claims.Role)
+	if !ok {
+		return "", fmt.Errorf("%w: no signing key for role %q", ErrInvalidTokenRequest, claims.Role)
+	}
+	h, err := encodeSegment(header{Alg: key.Algorithm, Kid: key.ID, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	p, err := encodeSegment(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := h + "." + p
+	mac := hmac.New(sha256.New, key.secret)
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (s *Signer) keyFor(role string) (Key, bool) {
+	for _, k := range s.keys {
+		// асимметричных закрытых ключей у сервиса нет, подписываем только HS256
+		if k.Algorithm == AlgHS256 && len(k.secret) > 0 && k.allows(role) {
+			return k, true
+		}
+	}
+	return Key{}, false
+}
+
+func newTokenID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate jti: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func encodeSegment(v any) (string, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
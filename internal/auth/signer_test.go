@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSigner_RoundTrip(t *testing.T) {
+	keys := StaticKeys{
+		HMACKey("", []byte("admin-secret"), RoleAdmin, RoleTeamLead),
+		HMACKey("", []byte("user-secret"), RoleUser),
+	}
+	now := time.Unix(1_700_000_000, 0)
+	clock := func() time.Time { return now }
+	signer := NewSigner(SignerOptions{Issuer: "reviewer-service", Audience: "api", MaxTTL: time.Hour, Now: clock}, keys...)
+	verifier := NewVerifier(VerifierOptions{Issuer: "reviewer-service", Audience: "api", Now: clock}, keys)
+
+	ids := make(map[string]bool)
+	for _, role := range []string{RoleAdmin, RoleTeamLead, RoleUser} {
+		token, issued, err := signer.Issue(TokenRequest{Subject: "u1", Role: role, Scope: "teams:read", TTL: time.Hour})
+		if err != nil {
+			t.Fatalf("issue %s: %v", role, err)
+		}
+		claims, err := verifier.Verify(context.Background(), token)
+		if err != nil {
+			t.Fatalf("verify %s: %v", role, err)
+		}
+		if claims.Subject != "u1" || claims.Role != role || claims.Scope != "teams:read" || claims.ExpiresAt != issued.ExpiresAt {
+			t.Fatalf("claims mismatch: %+v", claims)
+		}
+		// jti нужен, чтобы отозвать конкретный токен
+		if claims.ID == "" || ids[claims.ID] {
+			t.Fatalf("expected unique jti, got %q", claims.ID)
+		}
+		ids[claims.ID] = true
+	}
+}
+
+func TestSigner_Rejects(t *testing.T) {
+	signer := NewSigner(SignerOptions{MaxTTL: time.Hour}, HMACKey("", []byte("user-secret"), RoleUser))
+
+	cases := map[string]TokenRequest{
+		"missing subject":  {Role: RoleUser, TTL: time.Minute},
+		"zero ttl":         {Subject: "u1", Role: RoleUser},
+		"ttl over max":     {Subject: "u1", Role: RoleUser, TTL: 2 * time.Hour},
+		"role without key": {Subject: "u1", Role: RoleAdmin, TTL: time.Minute},
+	}
+	for name, req := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := signer.Issue(req); !errors.Is(err, ErrInvalidTokenRequest) {
+				t.Fatalf("expected ErrInvalidTokenRequest, got %v", err)
+			}
+		})
+	}
+
+	// асимметричные ключи из JWKS подписывать нельзя
+	jwksOnly := NewSigner(SignerOptions{}, Key{ID: "admin-1", Algorithm: AlgRS256, Roles: []string{RoleAdmin}})
+	if _, _, err := jwksOnly.Issue(TokenRequest{Subject: "u1", Role: RoleAdmin, TTL: time.Minute}); !errors.Is(err, ErrInvalidTokenRequest) {
+		t.Fatalf("expected public-only key to be unusable, got %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+type VerifierOptions struct {
+	// Issuer и Audience проверяются, только если заданы
+	Issuer   string
+	Audience string
+	// ClockSkew — допустимое расхождение часов для exp, nbf и iat
+	ClockSkew time.Duration
+	// AllowMissingExp принимает токены без exp; такие токены живут, пока их не отзовут
+	AllowMissingExp bool
+	// Denylist, если задан, отклоняет отозванные токены
+	Denylist Denylist
+	Now      func() time.Time
+}
+
+// Denylist вызывается на каждый запрос, поэтому должен отвечать из памяти
+type Denylist interface {
+	Revoked(c Claims) bool
+}
+
+// Verifier проверяет JWT по ключам из нескольких источников
+type Verifier struct {
+	opts    VerifierOptions
+	sources []KeySource
+}
+
+func NewVerifier(opts VerifierOptions, sources ...KeySource) *Verifier {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Verifier{opts: opts, sources: sources}
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return Claims{}, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+
+	key, ok := v.findKey(ctx, h, parts[0]+"."+parts[1], sig)
+	if !ok {
+		return Claims{}, fmt.Errorf("%w: signature", ErrInvalidToken)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, fmt.Errorf("%w: payload: %v", ErrInvalidToken, err)
+	}
+	if !key.allows(claims.Role) {
+		return Claims{}, fmt.Errorf("%w: key %q may not sign role %q", ErrInvalidToken, key.ID, claims.Role)
+	}
+	if err := v.validate(claims); err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if v.opts.Denylist != nil && v.opts.Denylist.Revoked(claims) {
+		return Claims{}, fmt.Errorf("%w: token revoked", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+func (v *Verifier) findKey(ctx context.Context, h header, signed string, sig []byte) (Key, bool) {
+	for _, src := range v.sources {
+		for _, key := range src.Lookup(ctx, h.Kid) {
+			// alg из заголовка должен совпадать с ключом, иначе возможна подмена алгоритма
+			if key.Algorithm != h.Alg {
+				continue
+			}
+			if verifySignature(key, signed, sig) {
+				return key, true
+			}
+		}
+	}
+	return Key{}, false
+}
+
+func (v *Verifier) validate(c Claims) error {
+	now := v.opts.Now()
+	skew := v.opts.ClockSkew
+
+	if c.ExpiresAt == 0 && !v.opts.AllowMissingExp {
+		return errors.New("missing exp")
+	}
+	if c.ExpiresAt != 0 && now.After(time.Unix(c.ExpiresAt, 0).Add(skew)) {
+		return errors.New("token expired")
+	}
+	if c.NotBefore != 0 && now.Add(skew).Before(time.Unix(c.NotBefore, 0)) {
+		return errors.New("token not valid yet")
+	}
+	if c.IssuedAt != 0 && now.Add(skew).Before(time.Unix(c.IssuedAt, 0)) {
+		return errors.New("token issued in the future")
+	}
+	if v.opts.Issuer != "" && c.Issuer != v.opts.Issuer {
+		return fmt.Errorf("unexpected issuer %q", c.Issuer)
+	}
+	if v.opts.Audience != "" && !c.Audience.Contains(v.opts.Audience) {
+		return errors.New("unexpected audience")
+	}
+	return nil
+}
+
+func verifySignature(key Key, signed string, sig []byte) bool {
+	digest := sha256.Sum256([]byte(signed))
+	switch key.Algorithm {
+	case AlgHS256:
+		if len(key.secret) == 0 {
+			return false
+		}
+		mac := hmac.New(sha256.New, key.secret)
+		mac.Write([]byte(signed))
+		return hmac.Equal(mac.Sum(nil), sig)
+	case AlgRS256:
+		pub, ok := key.public.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	case AlgES256:
+		pub, ok := key.public.(*ecdsa.PublicKey)
+		// JWS хранит подпись ES256 как r||s по 32 байта, а не в DER
+		if !ok || len(sig) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	}
+	return false
+}
+
+func decodeSegment(segment string, dst any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, dst)
+}
@@ -0,0 +1,379 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	rsaOnce sync.Once
+	rsaKey  *rsa.PrivateKey
+)
+
+func testRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	rsaOnce.Do(func() {
+		var err error
+		if rsaKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			t.Fatalf("generate rsa: %v", err)
+		}
+	})
+	return rsaKey
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA", "kid": kid, "use": "sig", "alg": AlgRS256,
+		"n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "EC", "kid": kid, "crv": "P-256",
+		"x": b64(key.X.FillBytes(make([]byte, 32))), "y": b64(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+func jwksJSON(t *testing.T, keys ...map[string]string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatalf("marshal jwks: %v", err)
+	}
+	return data
+}
+
+func sign(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	t.Helper()
+	h, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	p, _ := json.Marshal(claims)
+	data := b64(h) + "." + b64(p)
+	digest := sha256.Sum256([]byte(data))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		s, err := rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("sign rsa: %v", err)
+		}
+		sig = s
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatalf("sign ec: %v", err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(data))
+		sig = mac.Sum(nil)
+	}
+	return data + "." + b64(sig)
+}
+
+func validClaims(role string, now time.Time) map[string]any {
+	return map[string]any{
+		"sub": "u1", "role": role, "iss": "https://idp.example.com", "aud": "reviewer-service",
+		"iat": now.Unix(), "nbf": now.Unix(), "exp": now.Add(time.Hour).Unix(),
+	}
+}
+
+type jwksServer struct {
+	mu       sync.Mutex
+	body     []byte
+	requests int
+}
+
+func (s *jwksServer) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	_, _ = w.Write(s.body)
+}
+
+func (s *jwksServer) set(body []byte) {
+	s.mu.Lock()
+	s.body = body
+	s.mu.Unlock()
+}
+
+func newTestJWKS(t *testing.T, body []byte) (*JWKS, *jwksServer) {
+	t.Helper()
+	srv := &jwksServer{body: body}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	binding, err := ParseRoleBinding("admin=admin-*;user=user-*,admin-*")
+	if err != nil {
+		t.Fatalf("binding: %v", err)
+	}
+	jwks, err := NewJWKS(JWKSOptions{URL: ts.URL, Binding: binding, MinRefreshInterval: time.Nanosecond})
+	if err != nil {
+		t.Fatalf("new jwks: %v", err)
+	}
+	if err := jwks.Refresh(context.Background()); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	return jwks, srv
+}
+
+func TestVerifier_AsymmetricKeys(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	rsaPriv := testRSAKey(t)
+	ecPriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ec: %v", err)
+	}
+	jwks, _ := newTestJWKS(t, jwksJSON(t, rsaJWK("admin-1", rsaPriv), ecJWK("user-1", ecPriv)))
+	v := NewVerifier(VerifierOptions{
+		Issuer:    "https://idp.example.com",
+		Audience:  "reviewer-service",
+		ClockSkew: time.Minute,
+		Now:       func() time.Time { return now },
+	}, jwks)
+
+	claims, err := v.Verify(context.Background(), sign(t, AlgRS256, "admin-1", rsaPriv, validClaims(RoleAdmin, now)))
+	if err != nil || claims.Role != RoleAdmin || claims.Subject != "u1" {
+		t.Fatalf("rs256 admin token rejected: %v %+v", err, claims)
+	}
+	if _, err := v.Verify(context.Background(), sign(t, AlgES256, "user-1", ecPriv, validClaims(RoleUser, now))); err != nil {
+		t.Fatalf("es256 user token rejected: %v", err)
+	}
+
+	// ключ user-* не может выпускать admin-токены
+	if _, err := v.Verify(context.Background(), sign(t, AlgES256, "user-1", ecPriv, validClaims(RoleAdmin, now))); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected role/key mismatch to be rejected, got %v", err)
+	}
+	// alg в заголовке должен соответствовать ключу
+	if _, err := v.Verify(context.Background(), sign(t, AlgHS256, "admin-1", []byte("x"), validClaims(RoleAdmin, now))); err == nil {
+		t.Fatalf("expected alg mismatch to be rejected")
+	}
+}
+
+func TestVerifier_ClaimsValidation(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	rsaPriv := testRSAKey(t)
+	jwks, _ := newTestJWKS(t, jwksJSON(t, rsaJWK("admin-1", rsaPriv)))
+	v := NewVerifier(VerifierOptions{
+		Issuer:    "https://idp.example.com",
+		Audience:  "reviewer-service",
+		ClockSkew: 30 * time.Second,
+		Now:       func() time.Time { return now },
+	}, jwks)
+
+	cases := map[string]struct {
+		mutate func(map[string]any)
+		ok     bool
+	}{
+		"valid":                {func(map[string]any) {}, true},
+		"expired within skew":  {func(c map[string]any) { c["exp"] = now.Add(-10 * time.Second).Unix() }, true},
+		"expired":              {func(c map[string]any) { c["exp"] = now.Add(-time.Minute).Unix() }, false},
+		"missing exp":          {func(c map[string]any) { delete(c, "exp") }, false},
+		"nbf in future":        {func(c map[string]any) { c["nbf"] = now.Add(time.Minute).Unix() }, false},
+		"nbf within skew":      {func(c map[string]any) { c["nbf"] = now.Add(10 * time.Second).Unix() }, true},
+		"iat in future":        {func(c map[string]any) { c["iat"] = now.Add(time.Hour).Unix() }, false},
+		"wrong issuer":         {func(c map[string]any) { c["iss"] = "https://evil.example.com" }, false},
+		"wrong audience":       {func(c map[string]any) { c["aud"] = "other" }, false},
+		"audience in array":    {func(c map[string]any) { c["aud"] = []string{"other", "reviewer-service"} }, true},
+		"missing audience":     {func(c map[string]any) { delete(c, "aud") }, false},
+		"unknown role for key": {func(c map[string]any) { c["role"] = "root" }, false},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			claims := validClaims(RoleAdmin, now)
+			tc.mutate(claims)
+			_, err := v.Verify(context.Background(), sign(t, AlgRS256, "admin-1", rsaPriv, claims))
+			if tc.ok && err != nil {
+				t.Fatalf("expected token to pass, got %v", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected token to be rejected")
+			}
+		})
+	}
+}
+
+func TestJWKS_RefreshesOnUnknownKid(t *testing.T) {
+	now := time.Now()
+	oldKey := testRSAKey(t)
+	newKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ec: %v", err)
+	}
+	jwks, srv := newTestJWKS(t, jwksJSON(t, rsaJWK("admin-1", oldKey)))
+	v := NewVerifier(VerifierOptions{}, jwks)
+
+	token := sign(t, AlgES256, "admin-2", newKey, validClaims(RoleAdmin, now))
+	srv.set(jwksJSON(t, rsaJWK("admin-1", oldKey), ecJWK("admin-2", newKey)))
+
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Fatalf("rotated key must be picked up, got %v", err)
+	}
+	requests := srv.requests
+	if _, err := v.Verify(context.Background(), token); err != nil {
+		t.Fatalf("second verify: %v", err)
+	}
+	if srv.requests != requests {
+		t.Fatalf("known kid must not trigger refresh")
+	}
+
+	// отозванный из JWKS ключ перестаёт приниматься после перечитывания
+	srv.set(jwksJSON(t, ecJWK("admin-2", newKey)))
+	if err := jwks.Refresh(context.Background()); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if _, err := v.Verify(context.Background(), sign(t, AlgRS256, "admin-1", oldKey, validClaims(RoleAdmin, now))); err == nil {
+		t.Fatalf("removed key must be rejected")
+	}
+}
+
+func TestParseJWKS_SkipsInvalidKeys(t *testing.T) {
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("generate rsa: %v", err)
+	}
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ec: %v", err)
+	}
+	offCurve := ecJWK("broken-ec", ec)
+	offCurve["y"] = b64([]byte{1})
+
+	keys, err := ParseJWKS(jwksJSON(t, rsaJWK("weak", weak), offCurve, rsaJWK("admin-1", testRSAKey(t))), nil, nil)
+	if err != nil {
+		t.Fatalf("invalid keys must not fail the whole set: %v", err)
+	}
+	if len(keys) != 1 || keys[0].ID != "admin-1" {
+		t.Fatalf("expected only the valid key, got %+v", keys)
+	}
+}
+
+func TestJWKS_ThrottlesFailedRefresh(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(ts.Close)
+	jwks, err := NewJWKS(JWKSOptions{URL: ts.URL, MinRefreshInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("new jwks: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			jwks.Lookup(context.Background(), "unknown")
+		}()
+	}
+	wg.Wait()
+	if keys := jwks.Lookup(context.Background(), "unknown"); keys != nil || requests.Load() != 1 {
+		t.Fatalf("failed refresh must be throttled, got %d requests", requests.Load())
+	}
+}
+
+func TestJWKS_RefreshOutlivesCanceledRequest(t *testing.T) {
+	key := testRSAKey(t)
+	srv := &jwksServer{body: jwksJSON(t, rsaJWK("admin-1", key))}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	jwks, err := NewJWKS(JWKSOptions{URL: ts.URL, MinRefreshInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("new jwks: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	jwks.Lookup(ctx, "admin-1")
+	deadline := time.Now().Add(5 * time.Second)
+	for jwks.Lookup(context.Background(), "admin-1") == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("refresh started by a canceled request must complete")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStaticHMACKeys_BindRoles(t *testing.T) {
+	now := time.Now()
+	v := NewVerifier(VerifierOptions{}, StaticKeys{
+		HMACKey("", []byte("admin-secret"), RoleAdmin),
+		HMACKey("", []byte("user-secret"), RoleUser),
+	})
+
+	if _, err := v.Verify(context.Background(), sign(t, AlgHS256, "", []byte("admin-secret"), validClaims(RoleAdmin, now))); err != nil {
+		t.Fatalf("admin token rejected: %v", err)
+	}
+	if _, err := v.Verify(context.Background(), sign(t, AlgHS256, "", []byte("user-secret"), validClaims(RoleUser, now))); err != nil {
+		t.Fatalf("user token rejected: %v", err)
+	}
+	if _, err := v.Verify(context.Background(), sign(t, AlgHS256, "", []byte("user-secret"), validClaims(RoleAdmin, now))); err == nil {
+		t.Fatalf("user secret must not mint admin tokens")
+	}
+}
+
+func TestParseRoleBinding(t *testing.T) {
+	b, err := ParseRoleBinding("admin=admin-*; user=user-*,admin-*")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if roles := b.Roles("admin-7"); len(roles) != 2 {
+		t.Fatalf("expected admin key to sign both roles, got %v", roles)
+	}
+	if roles := b.Roles("other"); len(roles) != 0 {
+		t.Fatalf("unexpected roles %v", roles)
+	}
+	if _, err := ParseRoleBinding("admin"); err == nil {
+		t.Fatalf("expected error for malformed binding")
+	}
+}
+
+type denyJTI string
+
+func (d denyJTI) Revoked(c Claims) bool { return c.ID == string(d) }
+
+func TestVerifier_MissingExpAndDenylist(t *testing.T) {
+	now := time.Now()
+	secret := []byte("user-secret")
+	keys := StaticKeys{HMACKey("", secret, RoleUser)}
+	claims := validClaims(RoleUser, now)
+	delete(claims, "exp")
+	eternal := sign(t, AlgHS256, "", secret, claims)
+
+	if _, err := NewVerifier(VerifierOptions{}, keys).Verify(context.Background(), eternal); err == nil {
+		t.Fatalf("token without exp must be rejected by default")
+	}
+	lenient := NewVerifier(VerifierOptions{AllowMissingExp: true, Denylist: denyJTI("revoked")}, keys)
+	if _, err := lenient.Verify(context.Background(), eternal); err != nil {
+		t.Fatalf("token without exp must pass when allowed, got %v", err)
+	}
+
+	claims["jti"] = "revoked"
+	if _, err := lenient.Verify(context.Background(), sign(t, AlgHS256, "", secret, claims)); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("revoked token must be rejected, got %v", err)
+	}
+}
@@ -12,6 +12,11 @@ type Config struct {
 		DSN                string
 		ConnectRetries     int
 		ConnectRetryInterval time.Duration
+		// AutoMigrate — накатывать встроенные миграции при старте; реплики ждут друг друга на advisory lock
+		AutoMigrate bool
+		// TxMaxAttempts и TxRetryDelay — повтор транзакций, прерванных конфликтом сериализации или deadlock
+		TxMaxAttempts int
+		TxRetryDelay  time.Duration
 	}
 	HTTP struct {
 		Addr            string
@@ -19,17 +24,89 @@ type Config struct {
 		WriteTimeout    time.Duration
 		ShutdownTimeout time.Duration
 	}
+	GRPC struct {
+		// Addr пустой — gRPC-сервер не запускается; остановка укладывается в HTTP_SHUTDOWN_TIMEOUT
+		Addr string
+	}
 	Auth struct {
-		AdminSecret string
-		UserSecret  string
+		AdminSecret         string
+		UserSecret          string
+		JWKSURL             string
+		JWKSFile            string
+		JWKSRefreshInterval time.Duration
+		KeyRoles            string
+		Issuer              string
+		Audience            string
+		ClockSkew           time.Duration
+		TokenMaxTTL         time.Duration
+		// RequireExp отклоняет токены без exp; выключается только на время миграции старых клиентов
+		RequireExp                bool
+		RevocationRefreshInterval time.Duration
 	}
 	RateLimit struct {
 		Requests           int
 		Interval           time.Duration
 		TrustForwardHeader bool
+		// Routes — лимиты по шаблонам маршрутов, формат см. middleware.ParseRouteLimits
+		Routes string
+		// Backend — где хранить бакеты: memory (на каждую реплику свой лимит) или postgres (общий)
+		Backend string
+		// IPRequests за IPInterval — общий лимит на IP до аутентификации; 0 — выключен
+		IPRequests int
+		IPInterval time.Duration
 	}
 	Idempotency struct {
-		TTL time.Duration
+		TTL     time.Duration
+		LockTTL time.Duration
+		Wait    time.Duration
+		// PurgeInterval и PurgeBatch — как часто и какими пачками удалять истёкшие ключи
+		PurgeInterval time.Duration
+		PurgeBatch    int
+		// CacheStatuses — какие 4xx сохраняются для повторов, формат см. middleware.ParseCacheStatuses
+		CacheStatuses string
+	}
+	Metrics struct {
+		Enabled bool
+	}
+	Tracing struct {
+		Exporter     string
+		ServiceName  string
+		OTLPEndpoint string
+		OTLPInsecure bool
+		SampleRatio  float64
+	}
+	SLA struct {
+		CheckInterval       time.Duration
+		DefaultReviewWithin time.Duration
+		DefaultBusinessDays bool
+		DefaultEscalation   string
+	}
+	Stream struct {
+		// PollInterval — как часто проверять журнал назначений на новые записи
+		PollInterval time.Duration
+		Heartbeat    time.Duration
+		// Retention — сколько хранить журнал; продолжить поток можно только в его пределах
+		Retention time.Duration
+	}
+	Batch struct {
+		// MaxOperations — предел операций в одном POST /batch; атомарный пакет держит транзакцию всё это время
+		MaxOperations int
+	}
+	Transfer struct {
+		// ImportMaxBytes — предел тела POST /admin/import; запрос разбирается в память целиком
+		ImportMaxBytes int
+	}
+	Outbox struct {
+		// Sink — куда релей доставляет события: none (outbox не пишется), stdout или http
+		Sink         string
+		HTTPURL      string
+		HTTPTimeout  time.Duration
+		PollInterval time.Duration
+		Batch        int
+		// Retention — сколько хранить опубликованные события
+		Retention time.Duration
+		// MaxAttempts — после стольких неудачных доставок событие откладывается как мёртвое
+		MaxAttempts int
 	}
 }
 
@@ -37,13 +114,7 @@ func Load() (Config, error) {
 	var cfg Config
 	var err error
 
-	if cfg.Database.DSN, err = requiredEnv("DB_DSN"); err != nil {
-		return cfg, err
-	}
-	if cfg.Database.ConnectRetries, err = intOrDefault("DB_CONNECT_RETRIES", 5); err != nil {
-		return cfg, err
-	}
-	if cfg.Database.ConnectRetryInterval, err = durationOrDefault("DB_CONNECT_RETRY_INTERVAL", time.Second); err != nil {
+	if err = loadDatabase(&cfg); err != nil {
 		return cfg, err
 	}
 
@@ -58,10 +129,9 @@ func Load() (Config, error) {
 		return cfg, err
 	}
 
-	if cfg.Auth.AdminSecret, err = requiredEnv("ADMIN_SECRET"); err != nil {
-		return cfg, err
-	}
-	if cfg.Auth.UserSecret, err = requiredEnv("USER_SECRET"); err != nil {
+	cfg.GRPC.Addr = envOrDefault("GRPC_ADDR", "")
+
+	if err = loadAuth(&cfg); err != nil {
 		return cfg, err
 	}
 
@@ -74,14 +144,221 @@ func Load() (Config, error) {
 	if cfg.RateLimit.TrustForwardHeader, err = boolOrDefault("RATE_LIMIT_TRUST_FORWARD", false); err != nil {
 		return cfg, err
 	}
+	if cfg.RateLimit.IPRequests, err = intOrDefault("RATE_LIMIT_IP_REQUESTS", 100); err != nil {
+		return cfg, err
+	}
+	if cfg.RateLimit.IPInterval, err = durationOrDefault("RATE_LIMIT_IP_INTERVAL", time.Second); err != nil {
+		return cfg, err
+	}
+	cfg.RateLimit.Routes = envOrDefault("RATE_LIMIT_ROUTES", "")
+	cfg.RateLimit.Backend = envOrDefault("RATE_LIMIT_BACKEND", "memory")
+	if cfg.RateLimit.Backend != "memory" && cfg.RateLimit.Backend != "postgres" {
+		return cfg, fmt.Errorf("RATE_LIMIT_BACKEND must be memory or postgres, got %q", cfg.RateLimit.Backend)
+	}
 
 	if cfg.Idempotency.TTL, err = durationOrDefault("IDEMPOTENCY_TTL", time.Minute); err != nil {
 		return cfg, err
 	}
+	if cfg.Idempotency.LockTTL, err = durationOrDefault("IDEMPOTENCY_LOCK_TTL", 30*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.Idempotency.Wait, err = durationOrDefault("IDEMPOTENCY_WAIT", 2*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.Idempotency.PurgeInterval, err = durationOrDefault("IDEMPOTENCY_PURGE_INTERVAL", time.Minute); err != nil {
+		return cfg, err
+	}
+	if cfg.Idempotency.PurgeBatch, err = intOrDefault("IDEMPOTENCY_PURGE_BATCH", 1000); err != nil {
+		return cfg, err
+	}
+	cfg.Idempotency.CacheStatuses = envOrDefault("IDEMPOTENCY_CACHE_STATUSES", "400,404,409")
+	if cfg.Idempotency.PurgeBatch <= 0 {
+		return cfg, fmt.Errorf("IDEMPOTENCY_PURGE_BATCH must be positive, got %d", cfg.Idempotency.PurgeBatch)
+	}
+
+	if cfg.Metrics.Enabled, err = boolOrDefault("METRICS_ENABLED", true); err != nil {
+		return cfg, err
+	}
+
+	cfg.Tracing.Exporter = envOrDefault("TRACING_EXPORTER", "none")
+	cfg.Tracing.ServiceName = envOrDefault("TRACING_SERVICE_NAME", "reviewer-service")
+	cfg.Tracing.OTLPEndpoint = envOrDefault("TRACING_OTLP_ENDPOINT", "")
+	if cfg.Tracing.OTLPInsecure, err = boolOrDefault("TRACING_OTLP_INSECURE", false); err != nil {
+		return cfg, err
+	}
+	if cfg.Tracing.SampleRatio, err = floatOrDefault("TRACING_SAMPLE_RATIO", 1); err != nil {
+		return cfg, err
+	}
+
+	if cfg.SLA.CheckInterval, err = durationOrDefault("SLA_CHECK_INTERVAL", time.Minute); err != nil {
+		return cfg, err
+	}
+	if cfg.SLA.DefaultReviewWithin, err = durationOrDefault("SLA_DEFAULT_REVIEW_WITHIN", 24*time.Hour); err != nil {
+		return cfg, err
+	}
+	if cfg.SLA.DefaultBusinessDays, err = boolOrDefault("SLA_DEFAULT_BUSINESS_DAYS", true); err != nil {
+		return cfg, err
+	}
+	cfg.SLA.DefaultEscalation = envOrDefault("SLA_DEFAULT_ESCALATION", "EVENT")
+
+	if cfg.Stream.PollInterval, err = durationOrDefault("STREAM_POLL_INTERVAL", time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.Stream.Heartbeat, err = durationOrDefault("STREAM_HEARTBEAT", 15*time.Second); err != nil {
+		return cfg, err
+	}
+	if cfg.Stream.Retention, err = durationOrDefault("STREAM_RETENTION", 72*time.Hour); err != nil {
+		return cfg, err
+	}
+
+	if cfg.Batch.MaxOperations, err = intOrDefault("BATCH_MAX_OPERATIONS", 500); err != nil {
+		return cfg, err
+	}
+	if cfg.Batch.MaxOperations <= 0 {
+		return cfg, fmt.Errorf("BATCH_MAX_OPERATIONS must be positive, got %d", cfg.Batch.MaxOperations)
+	}
+
+	if cfg.Transfer.ImportMaxBytes, err = intOrDefault("IMPORT_MAX_BYTES", 64<<20); err != nil {
+		return cfg, err
+	}
+	if cfg.Transfer.ImportMaxBytes <= 0 {
+		return cfg, fmt.Errorf("IMPORT_MAX_BYTES must be positive, got %d", cfg.Transfer.ImportMaxBytes)
+	}
+
+	if err = loadOutbox(&cfg); err != nil {
+		return cfg, err
+	}
 
 	return cfg, nil
 }
 
+func loadOutbox(cfg *Config) error {
+	var err error
+	cfg.Outbox.Sink = envOrDefault("OUTBOX_SINK", "none")
+	switch cfg.Outbox.Sink {
+	case "none", "stdout":
+	case "http":
+		if cfg.Outbox.HTTPURL, err = requiredEnv("OUTBOX_HTTP_URL"); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("OUTBOX_SINK must be none, stdout or http, got %q", cfg.Outbox.Sink)
+	}
+	if cfg.Outbox.HTTPTimeout, err = durationOrDefault("OUTBOX_HTTP_TIMEOUT", 5*time.Second); err != nil {
+		return err
+	}
+	if cfg.Outbox.PollInterval, err = durationOrDefault("OUTBOX_POLL_INTERVAL", time.Second); err != nil {
+		return err
+	}
+	if cfg.Outbox.Batch, err = intOrDefault("OUTBOX_BATCH", 100); err != nil {
+		return err
+	}
+	if cfg.Outbox.Batch <= 0 {
+		return fmt.Errorf("OUTBOX_BATCH must be positive, got %d", cfg.Outbox.Batch)
+	}
+	if cfg.Outbox.Retention, err = durationOrDefault("OUTBOX_RETENTION", 24*time.Hour); err != nil {
+		return err
+	}
+	if cfg.Outbox.MaxAttempts, err = intOrDefault("OUTBOX_MAX_ATTEMPTS", 20); err != nil {
+		return err
+	}
+	if cfg.Outbox.MaxAttempts <= 0 {
+		return fmt.Errorf("OUTBOX_MAX_ATTEMPTS must be positive, got %d", cfg.Outbox.MaxAttempts)
+	}
+	return nil
+}
+
+// LoadDatabase читает только настройки базы — этого достаточно для миграций из CLI
+func LoadDatabase() (Config, error) {
+	var cfg Config
+	err := loadDatabase(&cfg)
+	return cfg, err
+}
+
+func loadDatabase(cfg *Config) error {
+	var err error
+	if cfg.Database.DSN, err = requiredEnv("DB_DSN"); err != nil {
+		return err
+	}
+	if cfg.Database.ConnectRetries, err = intOrDefault("DB_CONNECT_RETRIES", 5); err != nil {
+		return err
+	}
+	if cfg.Database.ConnectRetryInterval, err = durationOrDefault("DB_CONNECT_RETRY_INTERVAL", time.Second); err != nil {
+		return err
+	}
+	if cfg.Database.AutoMigrate, err = boolOrDefault("DB_AUTO_MIGRATE", false); err != nil {
+		return err
+	}
+	if cfg.Database.TxMaxAttempts, err = intOrDefault("DB_TX_MAX_ATTEMPTS", 3); err != nil {
+		return err
+	}
+	if cfg.Database.TxMaxAttempts <= 0 {
+		return fmt.Errorf("DB_TX_MAX_ATTEMPTS must be positive, got %d", cfg.Database.TxMaxAttempts)
+	}
+	if cfg.Database.TxRetryDelay, err = durationOrDefault("DB_TX_RETRY_DELAY", 20*time.Millisecond); err != nil {
+		return err
+	}
+	return nil
+}
+
+// LoadAuth читает только настройки аутентификации — этого достаточно для выпуска токенов из CLI
+func LoadAuth() (Config, error) {
+	var cfg Config
+	err := loadAuth(&cfg)
+	return cfg, err
+}
+
+func loadAuth(cfg *Config) error {
+	var err error
+	cfg.Auth.JWKSURL = envOrDefault("AUTH_JWKS_URL", "")
+	cfg.Auth.JWKSFile = envOrDefault("AUTH_JWKS_FILE", "")
+	if cfg.Auth.JWKSURL != "" && cfg.Auth.JWKSFile != "" {
+		return fmt.Errorf("only one of AUTH_JWKS_URL and AUTH_JWKS_FILE may be set")
+	}
+	if cfg.Auth.JWKSRefreshInterval, err = durationOrDefault("AUTH_JWKS_REFRESH_INTERVAL", 5*time.Minute); err != nil {
+		return err
+	}
+	cfg.Auth.KeyRoles = envOrDefault("AUTH_KEY_ROLES", "")
+	cfg.Auth.Issuer = envOrDefault("AUTH_ISSUER", "")
+	cfg.Auth.Audience = envOrDefault("AUTH_AUDIENCE", "")
+	if cfg.Auth.ClockSkew, err = durationOrDefault("AUTH_CLOCK_SKEW", 30*time.Second); err != nil {
+		return err
+	}
+	if cfg.Auth.TokenMaxTTL, err = durationOrDefault("AUTH_TOKEN_MAX_TTL", 24*time.Hour); err != nil {
+		return err
+	}
+	if cfg.Auth.RequireExp, err = boolOrDefault("AUTH_REQUIRE_EXP", true); err != nil {
+		return err
+	}
+	if cfg.Auth.RevocationRefreshInterval, err = durationOrDefault("AUTH_REVOCATION_REFRESH_INTERVAL", 10*time.Second); err != nil {
+		return err
+	}
+
+	cfg.Auth.AdminSecret = envOrDefault("ADMIN_SECRET", "")
+	cfg.Auth.UserSecret = envOrDefault("USER_SECRET", "")
+	jwks := cfg.Auth.JWKSURL != "" || cfg.Auth.JWKSFile != ""
+	if jwks {
+		// без явной привязки любой ключ из JWKS мог бы подписать admin-токен
+		if cfg.Auth.KeyRoles == "" {
+			return fmt.Errorf("missing required env AUTH_KEY_ROLES")
+		}
+	} else {
+		if cfg.Auth.AdminSecret, err = requiredEnv("ADMIN_SECRET"); err != nil {
+			return err
+		}
+		if cfg.Auth.UserSecret, err = requiredEnv("USER_SECRET"); err != nil {
+			return err
+		}
+	}
+	if (cfg.Auth.AdminSecret == "") != (cfg.Auth.UserSecret == "") {
+		return fmt.Errorf("ADMIN_SECRET and USER_SECRET must be set together")
+	}
+	if cfg.Auth.AdminSecret != "" && cfg.Auth.AdminSecret == cfg.Auth.UserSecret {
+		return fmt.Errorf("ADMIN_SECRET and USER_SECRET must differ")
+	}
+	return nil
+}
+
 func requiredEnv(key string) (string, error) {
 	if val, ok := os.LookupEnv(key); ok && val != "" {
 		return val, nil
@@ -128,3 +405,14 @@ func boolOrDefault(key string, fallback bool) (bool, error) {
 	}
 	return fallback, nil
 }
+
+func floatOrDefault(key string, fallback float64) (float64, error) {
+	if val, ok := os.LookupEnv(key); ok && val != "" {
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return 0, fmt.Errorf("env %s has invalid float %q: %w", key, val, err)
+		}
+		return f, nil
+	}
+	return fallback, nil
+}
@@ -0,0 +1,140 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+)
+
+// Prefix отличает API-ключи сервиса от прочих секретов (удобно для secret-сканеров)
+const Prefix = "prs_"
+
+// Key — долгоживущий ключ для сервисных клиентов. Сам секрет не хранится, только его SHA-256
+type Key struct {
+	id         string
+	name       string
+	role       requester.Role
+	scopes     []requester.Scope
+	createdAt  time.Time
+	expiresAt  *time.Time
+	lastUsedAt *time.Time
+	revokedAt  *time.Time
+}
+
+func New(id, name string, role requester.Role, scopes []requester.Scope, createdAt time.Time, expiresAt *time.Time) (Key, error) {
+	if strings.TrimSpace(id) == "" {
+		return Key{}, domain.ErrInvalidIdentifier
+	}
+	if strings.TrimSpace(name) == "" {
+		return Key{}, domain.ErrInvalidName
+	}
+	if !role.Valid() {
+		return Key{}, fmt.Errorf("%w: unknown role %q", domain.ErrInvalidAPIKey, role)
+	}
+	// ключ без scopes получил бы все права роли; для ботов права перечисляются явно
+	if len(scopes) == 0 {
+		return Key{}, fmt.Errorf("%w: at least one scope is required", domain.ErrInvalidAPIKey)
+	}
+	for _, s := range scopes {
+		if !role.Allows(s) {
+			return Key{}, fmt.Errorf("%w: scope %q is not allowed for role %s", domain.ErrInvalidAPIKey, s, role)
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(createdAt) {
+		return Key{}, fmt.Errorf("%w: expires_at must be in the future", domain.ErrInvalidAPIKey)
+	}
+	return Key{
+		id:        id,
+		name:      name,
+		role:      role,
+		scopes:    append([]requester.Scope(nil), scopes...),
+		createdAt: createdAt.UTC(),
+		expiresAt: utc(expiresAt),
+	}, nil
+}
+
+// WithUsage восстанавливает отметки использования и отзыва, прочитанные из хранилища
+func (k Key) WithUsage(lastUsedAt, revokedAt *time.Time) Key {
+	k.lastUsedAt = utc(lastUsedAt)
+	k.revokedAt = utc(revokedAt)
+	return k
+}
+
+func (k Key) ID() string                { return k.id }
+func (k Key) Name() string              { return k.name }
+func (k Key) Role() requester.Role      { return k.role }
+func (k Key) Scopes() []requester.Scope { return append([]requester.Scope(nil), k.scopes...) }
+func (k Key) CreatedAt() time.Time      { return k.createdAt }
+func (k Key) ExpiresAt() *time.Time     { return utc(k.expiresAt) }
+func (k Key) LastUsedAt() *time.Time    { return utc(k.lastUsedAt) }
+func (k Key) RevokedAt() *time.Time     { return utc(k.revokedAt) }
+
+// Active — ключ не отозван и не истёк
+func (k Key) Active(now time.Time) bool {
+	if k.revokedAt != nil {
+		return false
+	}
+	return k.expiresAt == nil || now.Before(*k.expiresAt)
+}
+
+// Secret — только что выпущенный ключ. Plain показывается клиенту один раз
+type Secret struct {
+	ID    string
+	Plain string
+	Hash  []byte
+}
+
+// Generate выпускает ключ вида prs_<id>_<secret>. id открыт и служит для поиска записи,
+// secret — 256 бит случайных данных
+func Generate() (Secret, error) {
+	idBytes := make([]byte, 8)
+	if _, err := rand.Read(idBytes); err != nil {
+		return Secret{}, fmt.Errorf("generate key id: %w", err)
+	}
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return Secret{}, fmt.Errorf("generate key secret: %w", err)
+	}
+	id := hex.EncodeToString(idBytes)
+	plain := Prefix + id + "_" + base64.RawURLEncoding.EncodeToString(secretBytes)
+	return Secret{ID: id, Plain: plain, Hash: Hash(plain)}, nil
+}
+
+// Parse достаёт id из предъявленного ключа
+func Parse(plain string) (string, bool) {
+	rest, ok := strings.CutPrefix(plain, Prefix)
+	if !ok {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	if !ok || id == "" || secret == "" {
+		return "", false
+	}
+	return id, true
+}
+
+func Hash(plain string) []byte {
+	sum := sha256.Sum256([]byte(plain))
+	return sum[:]
+}
+
+// Matches сравнивает предъявленный ключ с сохранённым хешем за постоянное время
+func Matches(plain string, hash []byte) bool {
+	return subtle.ConstantTimeCompare(Hash(plain), hash) == 1
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := t.UTC()
+	return &v
+}
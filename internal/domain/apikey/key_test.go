@@ -0,0 +1,73 @@
+package apikey
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+)
+
+func TestNewKeyValidation(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	scopes := []requester.Scope{requester.ScopePullRequestsWrite}
+
+	if _, err := New("k1", "", requester.RoleAdmin, scopes, now, nil); !errors.Is(err, domain.ErrInvalidName) {
+		t.Fatalf("expected ErrInvalidName, got %v", err)
+	}
+	if _, err := New("k1", "ci", "root", scopes, now, nil); !errors.Is(err, domain.ErrInvalidAPIKey) {
+		t.Fatalf("expected unknown role to be rejected, got %v", err)
+	}
+	if _, err := New("k1", "ci", requester.RoleAdmin, nil, now, nil); !errors.Is(err, domain.ErrInvalidAPIKey) {
+		t.Fatalf("expected empty scopes to be rejected, got %v", err)
+	}
+	if _, err := New("k1", "ci", requester.RoleUser, []requester.Scope{requester.ScopeStatsRead}, now, nil); !errors.Is(err, domain.ErrInvalidAPIKey) {
+		t.Fatalf("expected scope outside role to be rejected, got %v", err)
+	}
+	if _, err := New("k1", "ci", requester.RoleAdmin, scopes, now, &past); !errors.Is(err, domain.ErrInvalidAPIKey) {
+		t.Fatalf("expected past expiry to be rejected, got %v", err)
+	}
+}
+
+func TestKeyActive(t *testing.T) {
+	now := time.Now()
+	expires := now.Add(time.Hour)
+	k, err := New("k1", "ci", requester.RoleAdmin, []requester.Scope{requester.ScopeStatsRead}, now, &expires)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !k.Active(now) {
+		t.Fatalf("fresh key must be active")
+	}
+	if k.Active(expires) {
+		t.Fatalf("expired key must be inactive")
+	}
+	if k.WithUsage(nil, &now).Active(now) {
+		t.Fatalf("revoked key must be inactive")
+	}
+}
+
+func TestGenerateAndParse(t *testing.T) {
+	s, err := Generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if !strings.HasPrefix(s.Plain, Prefix) {
+		t.Fatalf("unexpected key format %q", s.Plain)
+	}
+	id, ok := Parse(s.Plain)
+	if !ok || id != s.ID {
+		t.Fatalf("parse mismatch: %q %v", id, ok)
+	}
+	if !Matches(s.Plain, s.Hash) || Matches(s.Plain+"x", s.Hash) {
+		t.Fatalf("hash comparison mismatch")
+	}
+	for _, bad := range []string{"", "prs_", "prs_abc", "prs__secret", "token"} {
+		if _, ok := Parse(bad); ok {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
@@ -6,7 +6,10 @@ var (
 	ErrTeamExists               = errors.New("team already exists")
 	ErrTeamMismatch             = errors.New("user belongs to another team")
 	ErrTeamAccessDenied         = errors.New("team access denied")
+	ErrPermissionDenied         = errors.New("permission denied")
 	ErrUserExists               = errors.New("user already exists")
+	ErrUserNotFound             = errors.New("user not found")
+	ErrMemberHasHistory         = errors.New("member has pull requests or reviews")
 	ErrPullRequestExists        = errors.New("pull request already exists")
 	ErrPullRequestAlreadyMerged = errors.New("pull request already merged")
 	ErrReviewerLimitExceeded    = errors.New("maximum number of reviewers reached")
@@ -16,4 +19,13 @@ var (
 	ErrNoActiveCandidate        = errors.New("no active replacement candidate in team")
 	ErrInvalidIdentifier        = errors.New("identifier must not be empty")
 	ErrInvalidName              = errors.New("name must not be empty")
+	ErrInvalidSLAPolicy         = errors.New("invalid review sla policy")
+	ErrInvalidStatsFilter       = errors.New("invalid stats filter")
+	ErrInvalidReviewVerdict     = errors.New("invalid review verdict")
+	ErrInvalidAPIKey            = errors.New("invalid api key")
+	ErrAPIKeyRejected           = errors.New("api key is unknown, revoked or expired")
+	ErrInvalidRevocation        = errors.New("invalid token revocation")
+	ErrVersionMismatch          = errors.New("resource version does not match")
+	ErrInvalidPullRequestStatus = errors.New("invalid pull request status")
+	ErrInvalidImportMode        = errors.New("invalid import mode")
 )
@@ -0,0 +1,74 @@
+package event
+
+import (
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+)
+
+type Type string
+
+const (
+	TypePullRequestCreated  Type = "PullRequestCreated"
+	TypeReviewerAssigned    Type = "ReviewerAssigned"
+	TypeReviewerReplaced    Type = "ReviewerReplaced"
+	TypePullRequestMerged   Type = "PullRequestMerged"
+	TypeUserActivityChanged Type = "UserActivityChanged"
+)
+
+// AggregateType вместе с AggregateID задаёт поток событий: порядок доставки гарантируется внутри потока
+type AggregateType string
+
+const (
+	AggregatePullRequest AggregateType = "pull_request"
+	AggregateUser        AggregateType = "user"
+)
+
+// Event — факт изменения агрегата. Агрегаты копят события при изменении,
+// сервис сохраняет их в outbox в той же транзакции, что и сам агрегат
+type Event struct {
+	Type          Type
+	AggregateType AggregateType
+	AggregateID   string
+	OccurredAt    time.Time
+	// Payload — одна из структур ниже, сериализуется в JSON
+	Payload any
+}
+
+type PullRequestCreated struct {
+	PullRequestID   domain.PullRequestID `json:"pull_request_id"`
+	PullRequestName string               `json:"pull_request_name"`
+	AuthorID        domain.UserID        `json:"author_id"`
+}
+
+type ReviewerAssigned struct {
+	PullRequestID domain.PullRequestID `json:"pull_request_id"`
+	ReviewerID    domain.UserID        `json:"reviewer_id"`
+}
+
+type ReviewerReplaced struct {
+	PullRequestID domain.PullRequestID `json:"pull_request_id"`
+	OldReviewerID domain.UserID        `json:"old_reviewer_id"`
+	NewReviewerID domain.UserID        `json:"new_reviewer_id"`
+}
+
+type PullRequestMerged struct {
+	PullRequestID domain.PullRequestID `json:"pull_request_id"`
+	MergedAt      time.Time            `json:"merged_at"`
+	// Reviewers — кто был назначен на момент merge: их назначения закрываются
+	Reviewers []domain.UserID `json:"reviewers"`
+}
+
+type UserActivityChanged struct {
+	UserID   domain.UserID   `json:"user_id"`
+	TeamName domain.TeamName `json:"team_name"`
+	IsActive bool            `json:"is_active"`
+}
+
+func ForPullRequest(id domain.PullRequestID, t Type, at time.Time, payload any) Event {
+	return Event{Type: t, AggregateType: AggregatePullRequest, AggregateID: string(id), OccurredAt: at.UTC(), Payload: payload}
+}
+
+func ForUser(id domain.UserID, t Type, at time.Time, payload any) Event {
+	return Event{Type: t, AggregateType: AggregateUser, AggregateID: string(id), OccurredAt: at.UTC(), Payload: payload}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/event"
 )
 
 const MaxReviewersPerPullRequest = 2
@@ -19,6 +20,8 @@ type PullRequest struct {
 	createdAt       time.Time
 	mergedAt        *time.Time
 	lastUpdate      time.Time
+	version         domain.Version
+	events          []event.Event
 }
 
 func New(id domain.PullRequestID, name string, author domain.UserID, createdAt time.Time) (PullRequest, error) {
@@ -35,7 +38,7 @@ func New(id domain.PullRequestID, name string, author domain.UserID, createdAt t
 		createdAt = time.Now().UTC()
 	}
 	ts := createdAt.UTC()
-	return PullRequest{
+	pr := PullRequest{
 		pullRequestID:   id,
 		pullRequestName: strings.TrimSpace(name),
 		authorID:        author,
@@ -43,7 +46,14 @@ func New(id domain.PullRequestID, name string, author domain.UserID, createdAt t
 		assigned:        make([]domain.UserID, 0, MaxReviewersPerPullRequest),
 		createdAt:       ts,
 		lastUpdate:      ts,
-	}, nil
+		version:         domain.InitialVersion,
+	}
+	pr.record(event.TypePullRequestCreated, ts, event.PullRequestCreated{
+		PullRequestID:   id,
+		PullRequestName: pr.pullRequestName,
+		AuthorID:        author,
+	})
+	return pr, nil
 }
 
 func (pr *PullRequest) PullRequestID() domain.PullRequestID {
@@ -102,6 +112,42 @@ func (pr *PullRequest) LastUpdate() time.Time {
 	return pr.lastUpdate
 }
 
+func (pr *PullRequest) Version() domain.Version {
+	if pr == nil {
+		return 0
+	}
+	return pr.version
+}
+
+// WithVersion возвращает копию с версией из хранилища; сам агрегат версию не меняет
+func (pr PullRequest) WithVersion(v domain.Version) PullRequest {
+	pr.assigned = slices.Clone(pr.assigned)
+	pr.events = slices.Clone(pr.events)
+	pr.version = v
+	return pr
+}
+
+// Events возвращает события, накопленные с момента создания или последнего ClearEvents
+func (pr *PullRequest) Events() []event.Event {
+	if pr == nil {
+		return nil
+	}
+	return slices.Clone(pr.events)
+}
+
+// ClearEvents вызывается после восстановления агрегата из хранилища: восстановление проходит
+// через те же методы и не должно порождать новых событий
+func (pr *PullRequest) ClearEvents() {
+	if pr == nil {
+		return
+	}
+	pr.events = nil
+}
+
+func (pr *PullRequest) record(t event.Type, at time.Time, payload any) {
+	pr.events = append(pr.events, event.ForPullRequest(pr.pullRequestID, t, at, payload))
+}
+
 func (pr *PullRequest) touch() {
 	if pr == nil {
 		return
@@ -124,8 +170,14 @@ func (pr *PullRequest) AssignReviewers(reviewers []domain.UserID) error {
 		return err
 	}
 
+	previous := pr.assigned
 	pr.assigned = clean
 	pr.touch()
+	for _, reviewer := range clean {
+		if !slices.Contains(previous, reviewer) {
+			pr.record(event.TypeReviewerAssigned, pr.lastUpdate, event.ReviewerAssigned{PullRequestID: pr.pullRequestID, ReviewerID: reviewer})
+		}
+	}
 
 	return nil
 }
@@ -153,6 +205,7 @@ func (pr *PullRequest) AppendReviewer(candidate domain.UserID) error {
 
 	pr.assigned = append(pr.assigned, candidate)
 	pr.touch()
+	pr.record(event.TypeReviewerAssigned, pr.lastUpdate, event.ReviewerAssigned{PullRequestID: pr.pullRequestID, ReviewerID: candidate})
 
 	return nil
 }
@@ -182,6 +235,11 @@ func (pr *PullRequest) ReplaceReviewer(oldReviewer, newReviewer domain.UserID) e
 
 	pr.assigned[idx] = newReviewer
 	pr.touch()
+	pr.record(event.TypeReviewerReplaced, pr.lastUpdate, event.ReviewerReplaced{
+		PullRequestID: pr.pullRequestID,
+		OldReviewerID: oldReviewer,
+		NewReviewerID: newReviewer,
+	})
 
 	return nil
 }
@@ -199,6 +257,11 @@ func (pr *PullRequest) Merge(ts time.Time) (changed bool) {
 	merged := ts.UTC()
 	pr.mergedAt = &merged
 	pr.touch()
+	pr.record(event.TypePullRequestMerged, merged, event.PullRequestMerged{
+		PullRequestID: pr.pullRequestID,
+		MergedAt:      merged,
+		Reviewers:     slices.Clone(pr.assigned),
+	})
 
 	return true
 }
@@ -1,10 +1,12 @@
 package pullrequest
 
 import (
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/event"
 )
 
 func TestNewPullRequestDefaults(t *testing.T) {
@@ -85,3 +87,35 @@ func TestMergeIdempotent(t *testing.T) {
 		t.Fatalf("second merge must be idempotent")
 	}
 }
+
+func TestPullRequestRecordsEvents(t *testing.T) {
+	pr, _ := New("pr-1", "Feature", "author", time.Time{})
+	_ = pr.AssignReviewers([]domain.UserID{"rev1"})
+	_ = pr.AssignReviewers([]domain.UserID{"rev1", "rev2"})
+	_ = pr.ReplaceReviewer("rev2", "rev3")
+	pr.Merge(time.Time{})
+	pr.Merge(time.Time{})
+
+	var types []event.Type
+	for _, e := range pr.Events() {
+		if e.AggregateType != event.AggregatePullRequest || e.AggregateID != "pr-1" {
+			t.Fatalf("unexpected stream %s/%s", e.AggregateType, e.AggregateID)
+		}
+		types = append(types, e.Type)
+	}
+	want := []event.Type{
+		event.TypePullRequestCreated,
+		event.TypeReviewerAssigned,
+		event.TypeReviewerAssigned,
+		event.TypeReviewerReplaced,
+		event.TypePullRequestMerged,
+	}
+	if !slices.Equal(types, want) {
+		t.Fatalf("unexpected events %v", types)
+	}
+
+	pr.ClearEvents()
+	if len(pr.Events()) != 0 {
+		t.Fatalf("events must be cleared")
+	}
+}
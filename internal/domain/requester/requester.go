@@ -1,36 +1,251 @@
 package requester
 
 import (
+	"strings"
+
 	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/pullrequest"
 	"github.com/mashhkensss/PR-service/internal/domain/team"
 )
 
+type Role string
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleTeamLead Role = "team_lead"
+	RoleUser     Role = "user"
+)
+
+// Scope — право на группу операций. Токен может сузить набор прав роли, но не расширить
+type Scope string
+
+const (
+	ScopeTeamsRead         Scope = "teams:read"
+	ScopeTeamsWrite        Scope = "teams:write"
+	ScopePullRequestsWrite Scope = "pull_requests:write"
+	ScopeReviewsWrite      Scope = "reviews:write"
+	ScopeAssignmentsRead   Scope = "assignments:read"
+	ScopeSLARead           Scope = "sla:read"
+	ScopeSLAWrite          Scope = "sla:write"
+	ScopeStatsRead         Scope = "stats:read"
+	ScopeAPIKeysWrite      Scope = "api_keys:write"
+	ScopeTokensWrite       Scope = "tokens:write"
+	ScopeIdempotencyWrite  Scope = "idempotency:write"
+	ScopeDataExport        Scope = "data:export"
+	ScopeDataImport        Scope = "data:import"
+)
+
+var roleScopes = map[Role][]Scope{
+	RoleAdmin: {
+		ScopeTeamsRead, ScopeTeamsWrite, ScopePullRequestsWrite, ScopeReviewsWrite,
+		ScopeAssignmentsRead, ScopeSLARead, ScopeSLAWrite, ScopeStatsRead, ScopeAPIKeysWrite, ScopeTokensWrite,
+		ScopeIdempotencyWrite, ScopeDataExport, ScopeDataImport,
+	},
+	RoleTeamLead: {
+		ScopeTeamsRead, ScopeTeamsWrite, ScopePullRequestsWrite, ScopeReviewsWrite,
+		ScopeAssignmentsRead, ScopeSLARead, ScopeSLAWrite,
+	},
+	RoleUser: {
+		ScopeTeamsRead, ScopePullRequestsWrite, ScopeReviewsWrite, ScopeAssignmentsRead, ScopeSLARead,
+	},
+}
+
+// Valid сообщает, что роль известна
+func (r Role) Valid() bool {
+	_, ok := roleScopes[r]
+	return ok
+}
+
+// Allows сообщает, что scope входит в набор прав роли
+func (r Role) Allows(scope Scope) bool {
+	for _, s := range roleScopes[r] {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseScopes разбирает claim scope (RFC 8693: значения через пробел)
+func ParseScopes(raw string) []Scope {
+	fields := strings.Fields(raw)
+	if len(fields) == 0 {
+		return nil
+	}
+	scopes := make([]Scope, 0, len(fields))
+	for _, f := range fields {
+		scopes = append(scopes, Scope(f))
+	}
+	return scopes
+}
+
 // Requester описывает субъекта, выполняющего запрос
 type Requester struct {
-	id      domain.UserID
-	isAdmin bool
+	id     domain.UserID
+	role   Role
+	scopes map[Scope]struct{}
+	system bool
 }
 
-func New(id domain.UserID, isAdmin bool) Requester {
-	return Requester{id: id, isAdmin: isAdmin}
+// New строит субъекта с правами роли. Если переданы scopes, права сужаются до их пересечения с ролью
+func New(id domain.UserID, role Role, scopes ...Scope) Requester {
+	allowed := make(map[Scope]struct{})
+	for _, s := range roleScopes[role] {
+		allowed[s] = struct{}{}
+	}
+	if len(scopes) > 0 {
+		narrowed := make(map[Scope]struct{}, len(scopes))
+		for _, s := range scopes {
+			if _, ok := allowed[s]; ok {
+				narrowed[s] = struct{}{}
+			}
+		}
+		allowed = narrowed
+	}
+	return Requester{id: id, role: role, scopes: allowed}
 }
 
+// Anonymous — вызов без аутентификации: ни одна проверка прав не проходит
 func Anonymous() Requester {
 	return Requester{}
 }
 
+// System — внутренний вызов сервиса (шедулеры, фоновые задачи): проверки прав не применяются.
+// Из запроса клиента не строится никогда
+func System() Requester {
+	return Requester{system: true}
+}
+
 func (r Requester) UserID() domain.UserID {
 	return r.id
 }
 
+func (r Requester) Role() Role {
+	return r.role
+}
+
 func (r Requester) IsAdmin() bool {
-	return r.isAdmin
+	return r.system || r.role == RoleAdmin
 }
 
-func (r Requester) CanViewTeam(t team.Team) bool {
-	if r.isAdmin || r.id == "" {
+func (r Requester) Has(scope Scope) bool {
+	if r.system {
 		return true
 	}
-	_, ok := t.Member(r.id)
+	_, ok := r.scopes[scope]
 	return ok
 }
+
+func (r Requester) is(id domain.UserID) bool {
+	return r.id != "" && r.id == id
+}
+
+func (r Requester) isMember(t team.Team) bool {
+	_, ok := t.Member(r.id)
+	return r.id != "" && ok
+}
+
+// leads — субъект тимлид этой команды
+func (r Requester) leads(t team.Team) bool {
+	return r.role == RoleTeamLead && r.isMember(t)
+}
+
+func (r Requester) CanViewTeam(t team.Team) bool {
+	if !r.Has(ScopeTeamsRead) {
+		return false
+	}
+	return r.IsAdmin() || r.isMember(t)
+}
+
+// CanCreateTeam — заводить новые команды может только администратор
+func (r Requester) CanCreateTeam() bool {
+	return r.Has(ScopeTeamsWrite) && r.IsAdmin()
+}
+
+// CanManageTeam — управлять участниками и настройками команды может администратор или её тимлид
+func (r Requester) CanManageTeam(t team.Team) bool {
+	if !r.Has(ScopeTeamsWrite) {
+		return false
+	}
+	return r.IsAdmin() || r.leads(t)
+}
+
+func (r Requester) CanViewSLA(t team.Team) bool {
+	if !r.Has(ScopeSLARead) {
+		return false
+	}
+	return r.IsAdmin() || r.isMember(t)
+}
+
+func (r Requester) CanManageSLA(t team.Team) bool {
+	if !r.Has(ScopeSLAWrite) {
+		return false
+	}
+	return r.IsAdmin() || r.leads(t)
+}
+
+// CanViewAssignments — свои назначения видит каждый, тимлид — назначения своей команды
+func (r Requester) CanViewAssignments(reviewer domain.UserID, reviewerTeam team.Team) bool {
+	if !r.Has(ScopeAssignmentsRead) {
+		return false
+	}
+	return r.IsAdmin() || r.is(reviewer) || r.leads(reviewerTeam)
+}
+
+// CanCreatePullRequest — автор заводит PR от своего имени, администратор — от любого
+func (r Requester) CanCreatePullRequest(author domain.UserID) bool {
+	if !r.Has(ScopePullRequestsWrite) {
+		return false
+	}
+	return r.IsAdmin() || r.is(author)
+}
+
+// CanManagePullRequest — merge и переназначение: администратор, автор PR или тимлид команды автора
+func (r Requester) CanManagePullRequest(pr pullrequest.PullRequest, authorTeam team.Team) bool {
+	if !r.Has(ScopePullRequestsWrite) {
+		return false
+	}
+	return r.IsAdmin() || r.is(pr.AuthorID()) || r.leads(authorTeam)
+}
+
+// CanReview — вердикт выносит сам назначенный ревьювер; администратор может сделать это за него
+func (r Requester) CanReview(reviewer domain.UserID) bool {
+	if !r.Has(ScopeReviewsWrite) {
+		return false
+	}
+	return r.IsAdmin() || r.is(reviewer)
+}
+
+func (r Requester) CanViewStats() bool {
+	return r.Has(ScopeStatsRead)
+}
+
+// CanManageAPIKeys — выпускать и отзывать API-ключи может только администратор
+func (r Requester) CanManageAPIKeys() bool {
+	return r.Has(ScopeAPIKeysWrite) && r.IsAdmin()
+}
+
+// CanIssueTokens — выпускать токены от имени любого субъекта может только администратор
+func (r Requester) CanIssueTokens() bool {
+	return r.Has(ScopeTokensWrite) && r.IsAdmin()
+}
+
+// CanRevokeTokens — отзывать токены может только администратор
+func (r Requester) CanRevokeTokens() bool {
+	return r.Has(ScopeTokensWrite) && r.IsAdmin()
+}
+
+// CanManageIdempotency — смотреть и удалять чужие ключи идемпотентности может только администратор
+func (r Requester) CanManageIdempotency() bool {
+	return r.Has(ScopeIdempotencyWrite) && r.IsAdmin()
+}
+
+// CanExportData — выгрузка всех команд и PR доступна только администратору
+func (r Requester) CanExportData() bool {
+	return r.Has(ScopeDataExport) && r.IsAdmin()
+}
+
+// CanImportData — загрузка данных в обход прав на отдельные команды и PR доступна только администратору
+func (r Requester) CanImportData() bool {
+	return r.Has(ScopeDataImport) && r.IsAdmin()
+}
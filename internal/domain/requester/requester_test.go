@@ -23,10 +23,11 @@ func TestRequester_CanViewTeam(t *testing.T) {
 		requester Requester
 		want      bool
 	}{
-		{"admin", New("someone", true), true},
-		{"member", New(domain.UserID("u1"), false), true},
-		{"anonymous", Anonymous(), true},
-		{"outsider", New(domain.UserID("u2"), false), false},
+		{"admin", New("someone", RoleAdmin), true},
+		{"member", New(domain.UserID("u1"), RoleUser), true},
+		{"system", System(), true},
+		{"anonymous", Anonymous(), false},
+		{"outsider", New(domain.UserID("u2"), RoleUser), false},
 	}
 
 	for _, tt := range tests {
@@ -38,3 +39,46 @@ func TestRequester_CanViewTeam(t *testing.T) {
 		})
 	}
 }
+
+func TestRequester_TeamLead(t *testing.T) {
+	lead, _ := domainuser.New("lead", "Lena", "backend", true)
+	dev, _ := domainuser.New("dev", "Dan", "backend", true)
+	backend, err := domainteam.New("backend", []domainuser.User{lead, dev})
+	if err != nil {
+		t.Fatalf("unexpected team error: %v", err)
+	}
+	other, err := domainteam.New("frontend", []domainuser.User{})
+	if err != nil {
+		t.Fatalf("unexpected team error: %v", err)
+	}
+
+	r := New("lead", RoleTeamLead)
+	if !r.CanManageTeam(backend) || !r.CanManageSLA(backend) {
+		t.Fatalf("team lead must manage own team")
+	}
+	if r.CanManageTeam(other) || r.CanManageSLA(other) {
+		t.Fatalf("team lead must not manage another team")
+	}
+	if !r.CanViewAssignments("dev", backend) {
+		t.Fatalf("team lead must see assignments of own team")
+	}
+	if r.CanCreateTeam() || r.CanViewStats() {
+		t.Fatalf("team lead must not create teams or read stats")
+	}
+	if New("dev", RoleUser).CanManageTeam(backend) {
+		t.Fatalf("regular member must not manage team")
+	}
+}
+
+func TestRequester_ScopesNarrowRole(t *testing.T) {
+	r := New("root", RoleAdmin, ParseScopes("teams:read stats:read")...)
+	if !r.Has(ScopeStatsRead) || r.Has(ScopeTeamsWrite) {
+		t.Fatalf("scopes must narrow admin rights")
+	}
+	if New("dev", RoleUser, ScopeStatsRead).CanViewStats() {
+		t.Fatalf("scope must not widen role rights")
+	}
+	if New("ghost", Role("root")).Has(ScopeTeamsRead) {
+		t.Fatalf("unknown role must have no scopes")
+	}
+}
@@ -0,0 +1,78 @@
+package revocation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+)
+
+// Kind — по какому признаку отзываются токены
+type Kind string
+
+const (
+	// KindToken отзывает один токен по jti
+	KindToken Kind = "jti"
+	// KindSubject отзывает все токены субъекта, выпущенные не позже момента отзыва
+	KindSubject Kind = "subject"
+)
+
+func (k Kind) Valid() bool {
+	return k == KindToken || k == KindSubject
+}
+
+// Revocation — запись denylist. Отзыв по субъекту отсекается по iat, и токены, выпущенные
+// после него, снова принимаются. Отзыв по jti с известным exp токена удаляется, когда токен истёк
+type Revocation struct {
+	kind      Kind
+	value     string
+	revokedAt time.Time
+	expiresAt time.Time
+}
+
+func New(kind Kind, value string, revokedAt time.Time) (Revocation, error) {
+	if !kind.Valid() {
+		return Revocation{}, fmt.Errorf("%w: unknown kind %q", domain.ErrInvalidRevocation, kind)
+	}
+	if strings.TrimSpace(value) == "" {
+		return Revocation{}, fmt.Errorf("%w: %s must not be empty", domain.ErrInvalidRevocation, kind)
+	}
+	return Revocation{kind: kind, value: value, revokedAt: revokedAt.UTC()}, nil
+}
+
+func (r Revocation) Kind() Kind           { return r.kind }
+func (r Revocation) Value() string        { return r.value }
+func (r Revocation) RevokedAt() time.Time { return r.revokedAt }
+
+// ExpiresAt — exp отозванного токена; нулевое значение — срок неизвестен, запись хранится бессрочно
+func (r Revocation) ExpiresAt() time.Time { return r.expiresAt }
+
+// WithExpiry задаёт exp отозванного токена. Срок есть только у отзыва по jti
+func (r Revocation) WithExpiry(expiresAt time.Time) (Revocation, error) {
+	if expiresAt.IsZero() {
+		return r, nil
+	}
+	if r.kind != KindToken {
+		return Revocation{}, fmt.Errorf("%w: expires_at applies only to %s", domain.ErrInvalidRevocation, KindToken)
+	}
+	r.expiresAt = expiresAt.UTC()
+	return r, nil
+}
+
+// Expired сообщает, что отозванный токен истёк к моменту now и запись больше не нужна
+func (r Revocation) Expired(now time.Time) bool {
+	return !r.expiresAt.IsZero() && r.expiresAt.Before(now)
+}
+
+// Covers сообщает, что токен с такими jti, sub и iat отозван этой записью.
+// Токен без iat при отзыве по субъекту считается выпущенным раньше отзыва
+func (r Revocation) Covers(jti, subject string, issuedAt int64) bool {
+	switch r.kind {
+	case KindToken:
+		return jti != "" && jti == r.value
+	case KindSubject:
+		return subject == r.value && issuedAt <= r.revokedAt.Unix()
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package revocation
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+)
+
+func TestNewRevocationValidation(t *testing.T) {
+	now := time.Now()
+	if _, err := New("token", "abc", now); !errors.Is(err, domain.ErrInvalidRevocation) {
+		t.Fatalf("expected unknown kind to be rejected, got %v", err)
+	}
+	if _, err := New(KindSubject, " ", now); !errors.Is(err, domain.ErrInvalidRevocation) {
+		t.Fatalf("expected empty value to be rejected, got %v", err)
+	}
+}
+
+func TestRevocationCovers(t *testing.T) {
+	at := time.Unix(1_700_000_000, 0)
+	byJTI, _ := New(KindToken, "abc", at)
+	bySubject, _ := New(KindSubject, "u1", at)
+
+	cases := map[string]struct {
+		r        Revocation
+		jti, sub string
+		iat      int64
+		want     bool
+	}{
+		"same jti":              {byJTI, "abc", "u1", at.Unix() + 60, true},
+		"other jti":             {byJTI, "def", "u1", 0, false},
+		"token without jti":     {byJTI, "", "u1", 0, false},
+		"issued before":         {bySubject, "", "u1", at.Unix() - 60, true},
+		"issued in same second": {bySubject, "", "u1", at.Unix(), true},
+		"issued after":          {bySubject, "", "u1", at.Unix() + 1, false},
+		"without iat":           {bySubject, "", "u1", 0, true},
+		"other subject":         {bySubject, "", "u2", at.Unix() - 60, false},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.r.Covers(tc.jti, tc.sub, tc.iat); got != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package sla
+
+import (
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+)
+
+// PendingReview — назначение ревьювера на открытый PR, по которому ещё не было ревью
+type PendingReview struct {
+	PullRequestID domain.PullRequestID
+	ReviewerID    domain.UserID
+	TeamName      domain.TeamName
+	AssignedAt    time.Time
+	EscalatedAt   *time.Time
+	// Policy пустая, если у команды автора нет собственной SLA
+	Policy Policy
+}
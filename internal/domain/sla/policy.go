@@ -0,0 +1,115 @@
+package sla
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+)
+
+type Escalation string
+
+const (
+	EscalationReassign    Escalation = "REASSIGN"
+	EscalationAddReviewer Escalation = "ADD_REVIEWER"
+	EscalationEvent       Escalation = "EVENT"
+)
+
+// Policy описывает SLA команды на первое ревью: за какое время назначенный ревьювер должен
+// отреагировать и что делать при нарушении
+type Policy struct {
+	teamName         domain.TeamName
+	reviewWithin     time.Duration
+	businessDaysOnly bool
+	escalation       Escalation
+}
+
+func New(teamName domain.TeamName, reviewWithin time.Duration, businessDaysOnly bool, escalation Escalation) (Policy, error) {
+	if err := domain.ValidateTeamName(teamName); err != nil {
+		return Policy{}, err
+	}
+	if reviewWithin <= 0 {
+		return Policy{}, fmt.Errorf("%w: review window must be positive", domain.ErrInvalidSLAPolicy)
+	}
+	esc, err := ParseEscalation(string(escalation))
+	if err != nil {
+		return Policy{}, err
+	}
+	return Policy{
+		teamName:         teamName,
+		reviewWithin:     reviewWithin,
+		businessDaysOnly: businessDaysOnly,
+		escalation:       esc,
+	}, nil
+}
+
+// NewDefault строит политику без привязки к команде; применяется к командам без собственных настроек
+func NewDefault(reviewWithin time.Duration, businessDaysOnly bool, escalation Escalation) (Policy, error) {
+	if reviewWithin <= 0 {
+		return Policy{}, fmt.Errorf("%w: review window must be positive", domain.ErrInvalidSLAPolicy)
+	}
+	esc, err := ParseEscalation(string(escalation))
+	if err != nil {
+		return Policy{}, err
+	}
+	return Policy{reviewWithin: reviewWithin, businessDaysOnly: businessDaysOnly, escalation: esc}, nil
+}
+
+func ParseEscalation(raw string) (Escalation, error) {
+	switch esc := Escalation(strings.ToUpper(strings.TrimSpace(raw))); esc {
+	case EscalationReassign, EscalationAddReviewer, EscalationEvent:
+		return esc, nil
+	default:
+		return "", fmt.Errorf("%w: unknown escalation %q", domain.ErrInvalidSLAPolicy, raw)
+	}
+}
+
+// IsZero сообщает, что политика не задана
+func (p Policy) IsZero() bool { return p.reviewWithin <= 0 }
+
+func (p Policy) TeamName() domain.TeamName   { return p.teamName }
+func (p Policy) ReviewWithin() time.Duration { return p.reviewWithin }
+func (p Policy) BusinessDaysOnly() bool      { return p.businessDaysOnly }
+func (p Policy) Escalation() Escalation      { return p.escalation }
+
+// WithTeam возвращает копию политики для другой команды (используется для дефолтной политики)
+func (p Policy) WithTeam(teamName domain.TeamName) Policy {
+	p.teamName = teamName
+	return p
+}
+
+// Deadline считает момент, к которому ревьювер должен отреагировать. В режиме рабочих дней
+// суббота и воскресенье (UTC) не учитываются
+func (p Policy) Deadline(assignedAt time.Time) time.Time {
+	start := assignedAt.UTC()
+	if !p.businessDaysOnly {
+		return start.Add(p.reviewWithin)
+	}
+	return addBusinessTime(start, p.reviewWithin)
+}
+
+func (p Policy) Breached(assignedAt, now time.Time) bool {
+	return now.UTC().After(p.Deadline(assignedAt))
+}
+
+func addBusinessTime(start time.Time, d time.Duration) time.Time {
+	t := skipWeekend(start)
+	remaining := d
+	for {
+		midnight := time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		available := midnight.Sub(t)
+		if remaining <= available {
+			return t.Add(remaining)
+		}
+		remaining -= available
+		t = skipWeekend(midnight)
+	}
+}
+
+func skipWeekend(t time.Time) time.Time {
+	for t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+	}
+	return t
+}
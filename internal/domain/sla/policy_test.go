@@ -0,0 +1,62 @@
+package sla
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+)
+
+func TestNewPolicyValidation(t *testing.T) {
+	if _, err := New("", time.Hour, false, EscalationEvent); err == nil {
+		t.Fatalf("expected error for empty team")
+	}
+	if _, err := New("backend", 0, false, EscalationEvent); !errors.Is(err, domain.ErrInvalidSLAPolicy) {
+		t.Fatalf("expected ErrInvalidSLAPolicy for zero window, got %v", err)
+	}
+	if _, err := New("backend", time.Hour, false, "PAGE_EVERYONE"); !errors.Is(err, domain.ErrInvalidSLAPolicy) {
+		t.Fatalf("expected ErrInvalidSLAPolicy for unknown escalation, got %v", err)
+	}
+	p, err := New("backend", time.Hour, false, "reassign")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Escalation() != EscalationReassign {
+		t.Fatalf("escalation must be normalized, got %s", p.Escalation())
+	}
+}
+
+func TestDeadlineCalendarTime(t *testing.T) {
+	p, _ := New("backend", 24*time.Hour, false, EscalationEvent)
+	friday := time.Date(2025, 1, 3, 15, 0, 0, 0, time.UTC)
+	if got := p.Deadline(friday); !got.Equal(friday.Add(24 * time.Hour)) {
+		t.Fatalf("unexpected deadline %v", got)
+	}
+}
+
+func TestDeadlineSkipsWeekend(t *testing.T) {
+	p, _ := New("backend", 24*time.Hour, true, EscalationEvent)
+	friday := time.Date(2025, 1, 3, 15, 0, 0, 0, time.UTC)
+	want := time.Date(2025, 1, 6, 15, 0, 0, 0, time.UTC)
+	if got := p.Deadline(friday); !got.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	saturday := time.Date(2025, 1, 4, 10, 0, 0, 0, time.UTC)
+	want = time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC)
+	if got := p.Deadline(saturday); !got.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestBreached(t *testing.T) {
+	p, _ := New("backend", time.Hour, false, EscalationEvent)
+	assigned := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	if p.Breached(assigned, assigned.Add(30*time.Minute)) {
+		t.Fatalf("must not be breached before deadline")
+	}
+	if !p.Breached(assigned, assigned.Add(2*time.Hour)) {
+		t.Fatalf("must be breached after deadline")
+	}
+}
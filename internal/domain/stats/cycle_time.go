@@ -0,0 +1,52 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+)
+
+// Dimension — разрез, в котором посчитана строка отчёта
+type Dimension string
+
+const (
+	DimensionTotal    Dimension = "total"
+	DimensionTeam     Dimension = "team"
+	DimensionWeek     Dimension = "week"
+	DimensionReviewer Dimension = "reviewer"
+)
+
+type Percentiles struct {
+	Count int
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+}
+
+// DurationGroup — перцентили длительности в одном разрезе. Заполнено только поле,
+// соответствующее Dimension; Week — понедельник недели в UTC
+type DurationGroup struct {
+	Dimension  Dimension
+	Team       domain.TeamName
+	Week       time.Time
+	ReviewerID domain.UserID
+	Percentiles
+}
+
+// ReassignmentGroup — переназначения по PR, созданным в окне
+type ReassignmentGroup struct {
+	Dimension     Dimension
+	Team          domain.TeamName
+	Week          time.Time
+	PullRequests  int
+	Reassigned    int
+	Reassignments int
+}
+
+// Rate — доля PR, у которых ревьювер менялся хотя бы раз
+func (g ReassignmentGroup) Rate() float64 {
+	if g.PullRequests == 0 {
+		return 0
+	}
+	return float64(g.Reassigned) / float64(g.PullRequests)
+}
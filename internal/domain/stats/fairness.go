@@ -0,0 +1,52 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// CoefficientOfVariation — отношение стандартного отклонения (по генеральной совокупности)
+// к среднему. Для пустой выборки и нулевого среднего возвращает 0
+func CoefficientOfVariation(values []int) float64 {
+	mean := Mean(values)
+	if mean == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		d := float64(v) - mean
+		sum += d * d
+	}
+	return math.Sqrt(sum/float64(len(values))) / mean
+}
+
+// Gini — коэффициент Джини: 0 при идеально ровной нагрузке, стремится к 1, когда всё
+// досталось одному участнику
+func Gini(values []int) float64 {
+	n := len(values)
+	if n == 0 {
+		return 0
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	var total, weighted float64
+	for i, v := range sorted {
+		total += float64(v)
+		weighted += float64(i+1) * float64(v)
+	}
+	if total == 0 {
+		return 0
+	}
+	return (2*weighted)/(float64(n)*total) - float64(n+1)/float64(n)
+}
+
+func Mean(values []int) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var total int
+	for _, v := range values {
+		total += v
+	}
+	return float64(total) / float64(len(values))
+}
@@ -0,0 +1,41 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestGini(t *testing.T) {
+	cases := []struct {
+		name   string
+		values []int
+		want   float64
+	}{
+		{name: "empty", values: nil, want: 0},
+		{name: "all zero", values: []int{0, 0, 0}, want: 0},
+		{name: "even", values: []int{3, 3, 3}, want: 0},
+		{name: "single holder", values: []int{0, 0, 0, 4}, want: 0.75},
+		{name: "skewed", values: []int{1, 2, 3}, want: 2.0 / 9.0},
+	}
+	for _, tc := range cases {
+		if got := Gini(tc.values); !almostEqual(got, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestCoefficientOfVariation(t *testing.T) {
+	if got := CoefficientOfVariation([]int{2, 2, 2}); got != 0 {
+		t.Fatalf("expected 0 for even load, got %v", got)
+	}
+	if got := CoefficientOfVariation([]int{0, 0}); got != 0 {
+		t.Fatalf("expected 0 for zero mean, got %v", got)
+	}
+	if got := CoefficientOfVariation([]int{1, 3}); !almostEqual(got, 0.5) {
+		t.Fatalf("expected 0.5, got %v", got)
+	}
+}
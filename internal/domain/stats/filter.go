@@ -0,0 +1,42 @@
+package stats
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+)
+
+// Filter ограничивает выборку статистики: окно [From, To) по времени назначения ревьювера,
+// команда ревьювера и статус PR. Пустые поля не фильтруют
+type Filter struct {
+	From   *time.Time
+	To     *time.Time
+	Team   domain.TeamName
+	Status domain.PullRequestStatus
+}
+
+func (f Filter) Validate() error {
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return fmt.Errorf("%w: from must be before to", domain.ErrInvalidStatsFilter)
+	}
+	switch f.Status {
+	case "", domain.PullRequestStatusOpen, domain.PullRequestStatusMerged:
+	default:
+		return fmt.Errorf("%w: status must be OPEN or MERGED", domain.ErrInvalidStatsFilter)
+	}
+	return nil
+}
+
+// ReviewerLoad — нагрузка одного ревьювера в рамках фильтра
+type ReviewerLoad struct {
+	UserID   domain.UserID
+	TeamName domain.TeamName
+	IsActive bool
+	Open     int
+	Merged   int
+}
+
+func (l ReviewerLoad) Total() int {
+	return l.Open + l.Merged
+}
@@ -11,6 +11,7 @@ import (
 type Team struct {
 	teamName domain.TeamName
 	members  map[domain.UserID]domainuser.User
+	version  domain.Version
 }
 
 func New(teamName domain.TeamName, members []domainuser.User) (Team, error) {
@@ -21,6 +22,7 @@ func New(teamName domain.TeamName, members []domainuser.User) (Team, error) {
 	t := Team{
 		teamName: teamName,
 		members:  make(map[domain.UserID]domainuser.User, len(members)),
+		version:  domain.InitialVersion,
 	}
 
 	for _, m := range members {
@@ -39,6 +41,19 @@ func (t *Team) TeamName() domain.TeamName {
 	return t.teamName
 }
 
+// Version растёт при любом изменении состава или активности участников
+func (t *Team) Version() domain.Version {
+	if t == nil {
+		return 0
+	}
+	return t.version
+}
+
+func (t Team) WithVersion(v domain.Version) Team {
+	t.version = v
+	return t
+}
+
 func (t *Team) UpsertMember(u domainuser.User) error {
 	if err := domain.ValidateTeamName(u.TeamName()); err != nil {
 		return err
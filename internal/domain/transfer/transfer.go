@@ -0,0 +1,136 @@
+package transfer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/pullrequest"
+	"github.com/mashhkensss/PR-service/internal/domain/team"
+)
+
+// Mode — что делать с записью импорта, ключ которой уже есть в базе
+type Mode string
+
+const (
+	// ModeFail отклоняет весь импорт, если хотя бы одна запись уже существует
+	ModeFail Mode = "fail"
+	// ModeSkip оставляет существующую запись как есть
+	ModeSkip Mode = "skip"
+	// ModeUpsert перезаписывает существующую запись
+	ModeUpsert Mode = "upsert"
+)
+
+func (m Mode) Valid() bool {
+	switch m {
+	case ModeFail, ModeSkip, ModeUpsert:
+		return true
+	}
+	return false
+}
+
+// Assignment — назначение ревьювера с отметками ревью, которых нет в агрегате PR
+type Assignment struct {
+	ReviewerID domain.UserID
+	AssignedAt time.Time
+	ReviewedAt *time.Time
+	Verdict    domain.ReviewVerdict
+	VerdictAt  *time.Time
+}
+
+// PullRequest — PR вместе с назначениями в порядке слотов
+type PullRequest struct {
+	PullRequest pullrequest.PullRequest
+	Assignments []Assignment
+}
+
+// NewPullRequest собирает PR через конструктор и методы агрегата, поэтому к записи импорта
+// применяются те же правила, что и к созданию через API. Событий восстановленный PR не несёт
+func NewPullRequest(id domain.PullRequestID, name string, author domain.UserID, status domain.PullRequestStatus,
+	createdAt time.Time, mergedAt *time.Time, assignments []Assignment) (PullRequest, error) {
+	pr, err := pullrequest.New(id, name, author, createdAt)
+	if err != nil {
+		return PullRequest{}, err
+	}
+
+	// AssignReviewers молча отбрасывает лишних, а импорт не должен терять назначения
+	if len(assignments) > pullrequest.MaxReviewersPerPullRequest {
+		return PullRequest{}, domain.ErrReviewerLimitExceeded
+	}
+	reviewers := make([]domain.UserID, 0, len(assignments))
+	result := make([]Assignment, 0, len(assignments))
+	for _, a := range assignments {
+		if err := domain.ValidateUserID(a.ReviewerID); err != nil {
+			return PullRequest{}, err
+		}
+		for _, r := range reviewers {
+			if r == a.ReviewerID {
+				return PullRequest{}, fmt.Errorf("%w: %s", domain.ErrReviewerAlreadyAssigned, a.ReviewerID)
+			}
+		}
+		if a.Verdict != "" {
+			if err := domain.ValidateReviewVerdict(a.Verdict); err != nil {
+				return PullRequest{}, err
+			}
+		}
+		if a.AssignedAt.IsZero() {
+			a.AssignedAt = pr.CreatedAt()
+		}
+		reviewers = append(reviewers, a.ReviewerID)
+		result = append(result, a)
+	}
+	if err := pr.AssignReviewers(reviewers); err != nil {
+		return PullRequest{}, err
+	}
+
+	switch status {
+	case domain.PullRequestStatusOpen:
+		if mergedAt != nil {
+			return PullRequest{}, fmt.Errorf("%w: open pull request has merged_at", domain.ErrInvalidPullRequestStatus)
+		}
+	case domain.PullRequestStatusMerged:
+		if mergedAt == nil {
+			return PullRequest{}, fmt.Errorf("%w: merged pull request without merged_at", domain.ErrInvalidPullRequestStatus)
+		}
+		pr.Merge(*mergedAt)
+	default:
+		return PullRequest{}, fmt.Errorf("%w: %q", domain.ErrInvalidPullRequestStatus, status)
+	}
+	pr.ClearEvents()
+	return PullRequest{PullRequest: pr, Assignments: result}, nil
+}
+
+// Dataset — содержимое импорта: команды идут раньше PR, потому что PR ссылаются на их участников
+type Dataset struct {
+	Teams        []team.Team
+	PullRequests []PullRequest
+}
+
+// Kind — тип записи в отчёте импорта
+type Kind string
+
+const (
+	KindTeam        Kind = "team"
+	KindPullRequest Kind = "pull_request"
+)
+
+// Counts — исход записей одного типа
+type Counts struct {
+	Created int
+	Updated int
+	Skipped int
+}
+
+// RecordError — ошибка записи; Index — позиция записи среди записей своего типа во входных данных
+type RecordError struct {
+	Kind  Kind
+	Index int
+	ID    string
+	Err   error
+}
+
+type Report struct {
+	Teams        Counts
+	PullRequests Counts
+	Errors       []RecordError
+}
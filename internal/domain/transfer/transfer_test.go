@@ -0,0 +1,54 @@
+package transfer
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+)
+
+func TestNewPullRequest_RestoresMergedWithoutEvents(t *testing.T) {
+	created := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	merged := created.Add(time.Hour)
+	pr, err := NewPullRequest("pr-1", "Add search", "u1", domain.PullRequestStatusMerged, created, &merged, []Assignment{
+		{ReviewerID: "u2", Verdict: domain.ReviewVerdictApproved},
+		{ReviewerID: "u3", AssignedAt: created.Add(time.Minute)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pr.PullRequest.Status() != domain.PullRequestStatusMerged || !pr.PullRequest.MergedAt().Equal(merged) {
+		t.Fatalf("unexpected pull request %+v", pr.PullRequest)
+	}
+	if len(pr.PullRequest.Events()) != 0 {
+		t.Fatalf("restored pull request must not carry events, got %d", len(pr.PullRequest.Events()))
+	}
+	if !pr.Assignments[0].AssignedAt.Equal(created) || len(pr.PullRequest.AssignedReviewers()) != 2 {
+		t.Fatalf("unexpected assignments %+v", pr.Assignments)
+	}
+}
+
+func TestNewPullRequest_Rejects(t *testing.T) {
+	created := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name        string
+		status      domain.PullRequestStatus
+		mergedAt    *time.Time
+		assignments []Assignment
+		want        error
+	}{
+		{"unknown status", "CLOSED", nil, nil, domain.ErrInvalidPullRequestStatus},
+		{"open with merged_at", domain.PullRequestStatusOpen, &created, nil, domain.ErrInvalidPullRequestStatus},
+		{"merged without merged_at", domain.PullRequestStatusMerged, nil, nil, domain.ErrInvalidPullRequestStatus},
+		{"author reviews", domain.PullRequestStatusOpen, nil, []Assignment{{ReviewerID: "u1"}}, domain.ErrAuthorIsReviewer},
+		{"duplicate reviewer", domain.PullRequestStatusOpen, nil, []Assignment{{ReviewerID: "u2"}, {ReviewerID: "u2"}}, domain.ErrReviewerAlreadyAssigned},
+		{"too many reviewers", domain.PullRequestStatusOpen, nil, []Assignment{{ReviewerID: "u2"}, {ReviewerID: "u3"}, {ReviewerID: "u4"}}, domain.ErrReviewerLimitExceeded},
+		{"bad verdict", domain.PullRequestStatusOpen, nil, []Assignment{{ReviewerID: "u2", Verdict: "LGTM"}}, domain.ErrInvalidReviewVerdict},
+	}
+	for _, tc := range cases {
+		if _, err := NewPullRequest("pr-1", "Add search", "u1", tc.status, created, tc.mergedAt, tc.assignments); !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
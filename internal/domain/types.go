@@ -7,9 +7,38 @@ type (
 	IdempotencyID string
 )
 
+// Version — номер версии агрегата для оптимистичной блокировки, растёт на каждой записи
+type Version int64
+
+const (
+	// AnyVersion — предусловие не задано, запись проходит при любой версии
+	AnyVersion Version = 0
+	// InitialVersion получает только что созданный агрегат
+	InitialVersion Version = 1
+)
+
+// Check сверяет ожидаемую клиентом версию с текущей
+func (v Version) Check(actual Version) error {
+	if v != AnyVersion && v != actual {
+		return ErrVersionMismatch
+	}
+	return nil
+}
+
+// Next — версия после очередной записи
+func (v Version) Next() Version { return v + 1 }
+
 type PullRequestStatus string
 
 const (
 	PullRequestStatusOpen   PullRequestStatus = "OPEN"
 	PullRequestStatusMerged PullRequestStatus = "MERGED"
 )
+
+type ReviewVerdict string
+
+const (
+	ReviewVerdictApproved         ReviewVerdict = "APPROVED"
+	ReviewVerdictChangesRequested ReviewVerdict = "CHANGES_REQUESTED"
+	ReviewVerdictCommented        ReviewVerdict = "COMMENTED"
+)
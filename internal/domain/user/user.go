@@ -1,9 +1,12 @@
 package user
 
 import (
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/event"
 )
 
 type User struct {
@@ -11,6 +14,8 @@ type User struct {
 	username string
 	teamName domain.TeamName
 	isActive bool
+	version  domain.Version
+	events   []event.Event
 }
 
 func New(userID domain.UserID, username string, teamName domain.TeamName, isActive bool) (User, error) {
@@ -28,6 +33,7 @@ func New(userID domain.UserID, username string, teamName domain.TeamName, isActi
 		username: strings.TrimSpace(username),
 		teamName: teamName,
 		isActive: isActive,
+		version:  domain.InitialVersion,
 	}, nil
 }
 
@@ -35,8 +41,25 @@ func (u User) UserID() domain.UserID     { return u.userID }
 func (u User) Username() string          { return u.username }
 func (u User) TeamName() domain.TeamName { return u.teamName }
 func (u User) IsActive() bool            { return u.isActive }
+func (u User) Version() domain.Version   { return u.version }
 
+// WithActivity записывает UserActivityChanged, только если активность действительно меняется
 func (u User) WithActivity(active bool) User {
+	if u.isActive == active {
+		return u
+	}
 	u.isActive = active
+	u.events = append(slices.Clone(u.events), event.ForUser(u.userID, event.TypeUserActivityChanged, time.Now(), event.UserActivityChanged{
+		UserID:   u.userID,
+		TeamName: u.teamName,
+		IsActive: active,
+	}))
+	return u
+}
+
+func (u User) Events() []event.Event { return slices.Clone(u.events) }
+
+func (u User) WithVersion(v domain.Version) User {
+	u.version = v
 	return u
 }
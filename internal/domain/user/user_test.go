@@ -2,6 +2,8 @@ package user
 
 import (
 	"testing"
+
+	"github.com/mashhkensss/PR-service/internal/domain/event"
 )
 
 func TestNewUserSuccess(t *testing.T) {
@@ -40,3 +42,21 @@ func TestWithActivityReturnsCopy(t *testing.T) {
 		t.Fatalf("original struct must remain unchanged")
 	}
 }
+
+func TestWithActivityRecordsOnlyChanges(t *testing.T) {
+	u, _ := New("u1", "Alice", "backend", true)
+	if events := u.WithActivity(true).Events(); len(events) != 0 {
+		t.Fatalf("unchanged activity must not record events, got %v", events)
+	}
+	events := u.WithActivity(false).Events()
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %v", events)
+	}
+	payload, ok := events[0].Payload.(event.UserActivityChanged)
+	if !ok || payload.IsActive || payload.TeamName != "backend" {
+		t.Fatalf("unexpected payload %+v", events[0].Payload)
+	}
+	if len(u.Events()) != 0 {
+		t.Fatalf("original user must not be modified")
+	}
+}
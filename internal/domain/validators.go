@@ -39,3 +39,11 @@ func ValidatePullRequestName(name string) error {
 	}
 	return nil
 }
+
+func ValidateReviewVerdict(v ReviewVerdict) error {
+	switch v {
+	case ReviewVerdictApproved, ReviewVerdictChangesRequested, ReviewVerdictCommented:
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidReviewVerdict, v)
+}
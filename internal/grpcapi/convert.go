@@ -0,0 +1,117 @@
+package grpcapi
+
+import (
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	reviewerv1 "github.com/mashhkensss/PR-service/api/reviewer/v1"
+	"github.com/mashhkensss/PR-service/internal/domain"
+	domainpr "github.com/mashhkensss/PR-service/internal/domain/pullrequest"
+	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
+	domainuser "github.com/mashhkensss/PR-service/internal/domain/user"
+)
+
+// UNSPECIFIED в фильтре — любой статус, в вердикте — COMMENTED, как пустые поля в HTTP
+var (
+	statusToDomain = map[reviewerv1.PullRequestStatus]domain.PullRequestStatus{
+		reviewerv1.PullRequestStatus_PULL_REQUEST_STATUS_UNSPECIFIED: "",
+		reviewerv1.PullRequestStatus_PULL_REQUEST_STATUS_OPEN:        domain.PullRequestStatusOpen,
+		reviewerv1.PullRequestStatus_PULL_REQUEST_STATUS_MERGED:      domain.PullRequestStatusMerged,
+	}
+	verdictToDomain = map[reviewerv1.ReviewVerdict]domain.ReviewVerdict{
+		reviewerv1.ReviewVerdict_REVIEW_VERDICT_UNSPECIFIED:       domain.ReviewVerdictCommented,
+		reviewerv1.ReviewVerdict_REVIEW_VERDICT_APPROVED:          domain.ReviewVerdictApproved,
+		reviewerv1.ReviewVerdict_REVIEW_VERDICT_CHANGES_REQUESTED: domain.ReviewVerdictChangesRequested,
+		reviewerv1.ReviewVerdict_REVIEW_VERDICT_COMMENTED:         domain.ReviewVerdictCommented,
+	}
+)
+
+// expectedVersion — аналог response.IfMatch: 0 отключает проверку, отрицательная версия не совпадёт ни с чем
+func expectedVersion(v int64) (domain.Version, error) {
+	if v < 0 {
+		return 0, toStatus(domain.ErrVersionMismatch)
+	}
+	return domain.Version(v), nil
+}
+
+func teamFromDomain(src domainteam.Team) *reviewerv1.Team {
+	members := src.Members()
+	dst := &reviewerv1.Team{
+		TeamName: string(src.TeamName()),
+		Members:  make([]*reviewerv1.TeamMember, 0, len(members)),
+		Version:  int64(src.Version()),
+	}
+	for _, m := range members {
+		dst.Members = append(dst.Members, &reviewerv1.TeamMember{
+			UserId:   string(m.UserID()),
+			Username: m.Username(),
+			IsActive: m.IsActive(),
+		})
+	}
+	return dst
+}
+
+func userFromDomain(src domainuser.User) *reviewerv1.User {
+	return &reviewerv1.User{
+		UserId:   string(src.UserID()),
+		Username: src.Username(),
+		TeamName: string(src.TeamName()),
+		IsActive: src.IsActive(),
+		Version:  int64(src.Version()),
+	}
+}
+
+func pullRequestFromDomain(src domainpr.PullRequest) *reviewerv1.PullRequest {
+	reviewers := src.AssignedReviewers()
+	dst := &reviewerv1.PullRequest{
+		PullRequestId:     string(src.PullRequestID()),
+		PullRequestName:   src.PullRequestName(),
+		AuthorId:          string(src.AuthorID()),
+		Status:            statusFromDomain(src.Status()),
+		AssignedReviewers: make([]string, 0, len(reviewers)),
+		CreatedAt:         timestamppb.New(src.CreatedAt()),
+		Version:           int64(src.Version()),
+	}
+	for _, id := range reviewers {
+		dst.AssignedReviewers = append(dst.AssignedReviewers, string(id))
+	}
+	if mergedAt := src.MergedAt(); mergedAt != nil {
+		dst.MergedAt = timestamppb.New(*mergedAt)
+	}
+	return dst
+}
+
+func pullRequestShortFromDomain(src domainpr.PullRequest) *reviewerv1.PullRequestShort {
+	return &reviewerv1.PullRequestShort{
+		PullRequestId:   string(src.PullRequestID()),
+		PullRequestName: src.PullRequestName(),
+		AuthorId:        string(src.AuthorID()),
+		Status:          statusFromDomain(src.Status()),
+		Version:         int64(src.Version()),
+	}
+}
+
+func statusFromDomain(s domain.PullRequestStatus) reviewerv1.PullRequestStatus {
+	for k, v := range statusToDomain {
+		if v == s && k != reviewerv1.PullRequestStatus_PULL_REQUEST_STATUS_UNSPECIFIED {
+			return k
+		}
+	}
+	return reviewerv1.PullRequestStatus_PULL_REQUEST_STATUS_UNSPECIFIED
+}
+
+func verdictFromDomain(v domain.ReviewVerdict) reviewerv1.ReviewVerdict {
+	for k, d := range verdictToDomain {
+		if d == v && k != reviewerv1.ReviewVerdict_REVIEW_VERDICT_UNSPECIFIED {
+			return k
+		}
+	}
+	return reviewerv1.ReviewVerdict_REVIEW_VERDICT_UNSPECIFIED
+}
+
+func mapSlice[S, D any](src []S, fn func(S) D) []D {
+	dst := make([]D, 0, len(src))
+	for _, v := range src {
+		dst = append(dst, fn(v))
+	}
+	return dst
+}
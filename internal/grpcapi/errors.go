@@ -0,0 +1,68 @@
+package grpcapi
+
+import (
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	"github.com/mashhkensss/PR-service/internal/http/httperror"
+)
+
+// ErrorDomain — ErrorInfo.domain в деталях статуса
+const ErrorDomain = "reviewer-service"
+
+// StatusFromError разбирает ошибку тем же httperror.FromError, что и HTTP: код ErrorResponse
+// уходит в ErrorInfo.reason, а код gRPC выбирается по нему и по HTTP-статусу
+func StatusFromError(err error) *status.Status {
+	return newStatus(httperror.FromError(err))
+}
+
+func toStatus(err error) error {
+	return StatusFromError(err).Err()
+}
+
+func invalid(message string) error {
+	return newStatus(httperror.InvalidRequest(message)).Err()
+}
+
+func unauthenticated() error {
+	return newStatus(httperror.Unauthorized()).Err()
+}
+
+func newStatus(httpStatus int, resp dto.ErrorResponse) *status.Status {
+	st := status.New(codeFor(httpStatus, resp.Error.Code), resp.Error.Message)
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: resp.Error.Code, Domain: ErrorDomain})
+	if err != nil {
+		return st
+	}
+	return detailed
+}
+
+func codeFor(httpStatus int, code string) codes.Code {
+	switch code {
+	case httperror.CodeTeamExists, httperror.CodeUserExists, httperror.CodePRExists, httperror.CodeReviewerExists:
+		return codes.AlreadyExists
+	case httperror.CodePrecondition:
+		// версия устарела: клиент перечитывает ресурс и повторяет, как после 412
+		return codes.Aborted
+	}
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
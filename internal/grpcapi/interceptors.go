@@ -0,0 +1,75 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	"github.com/mashhkensss/PR-service/internal/tracing"
+)
+
+var apiKeyMetadata = strings.ToLower(mw.APIKeyHeader)
+
+// authInterceptor — аналог RequireAuthenticated: те же Bearer-токены и API-ключи, но из метаданных
+func authInterceptor(authz *mw.Authorization) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx, err := authz.Authenticate(ctx, first(md, "authorization"), first(md, apiKeyMetadata))
+		if err != nil {
+			return nil, unauthenticated()
+		}
+		return handler(ctx, req)
+	}
+}
+
+func loggingInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		st := status.Convert(err)
+		fields := []any{
+			"method", info.FullMethod,
+			"code", st.Code().String(),
+			"duration", time.Since(start),
+		}
+		if p, ok := peer.FromContext(ctx); ok {
+			fields = append(fields, "peer", p.Addr.String())
+		}
+		if traceID := tracing.TraceID(ctx); traceID != "" {
+			fields = append(fields, "trace_id", traceID)
+		}
+		if err != nil {
+			fields = append(fields, "message", st.Message())
+		}
+		logger.Info("grpc_request", fields...)
+		return resp, err
+	}
+}
+
+// recoveryInterceptor отвечает Internal вместо падения процесса, как chi Recoverer
+func recoveryInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				logger.Error("grpc_panic", "method", info.FullMethod, "panic", rec, "stack", string(debug.Stack()))
+				err = toStatus(nil)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+func first(md metadata.MD, key string) string {
+	if vals := md.Get(key); len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package grpcapi
+
+import (
+	"context"
+	"time"
+
+	reviewerv1 "github.com/mashhkensss/PR-service/api/reviewer/v1"
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	pullrequestservice "github.com/mashhkensss/PR-service/internal/service/pullrequest"
+)
+
+type pullRequestServer struct {
+	reviewerv1.UnimplementedPullRequestServiceServer
+	service   pullrequestservice.Service
+	validator mw.Validator
+}
+
+func (s *pullRequestServer) CreatePullRequest(ctx context.Context, req *reviewerv1.CreatePullRequestRequest) (*reviewerv1.CreatePullRequestResponse, error) {
+	payload := dto.CreatePullRequestRequest{
+		PullRequestID:   req.GetPullRequestId(),
+		PullRequestName: req.GetPullRequestName(),
+		AuthorID:        req.GetAuthorId(),
+	}
+	if err := s.validator.ValidateStruct(payload); err != nil {
+		return nil, invalid(err.Error())
+	}
+	pr, err := payload.ToDomain(time.Now())
+	if err != nil {
+		return nil, invalid(err.Error())
+	}
+	created, err := s.service.Create(ctx, mw.RequesterFromContext(ctx), pr)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerv1.CreatePullRequestResponse{Pr: pullRequestFromDomain(created)}, nil
+}
+
+func (s *pullRequestServer) MergePullRequest(ctx context.Context, req *reviewerv1.MergePullRequestRequest) (*reviewerv1.MergePullRequestResponse, error) {
+	payload := dto.MergePullRequestRequest{PullRequestID: req.GetPullRequestId()}
+	if err := s.validator.ValidateStruct(payload); err != nil {
+		return nil, invalid(err.Error())
+	}
+	expected, err := expectedVersion(req.GetExpectedVersion())
+	if err != nil {
+		return nil, err
+	}
+	merged, err := s.service.Merge(ctx, mw.RequesterFromContext(ctx), domain.PullRequestID(payload.PullRequestID), time.Now(), expected)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerv1.MergePullRequestResponse{Pr: pullRequestFromDomain(merged)}, nil
+}
+
+func (s *pullRequestServer) ReassignReviewer(ctx context.Context, req *reviewerv1.ReassignReviewerRequest) (*reviewerv1.ReassignReviewerResponse, error) {
+	payload := dto.ReassignReviewerRequest{PullRequestID: req.GetPullRequestId(), OldUserID: req.GetOldUserId()}
+	if err := s.validator.ValidateStruct(payload); err != nil {
+		return nil, invalid(err.Error())
+	}
+	expected, err := expectedVersion(req.GetExpectedVersion())
+	if err != nil {
+		return nil, err
+	}
+	pr, replacement, err := s.service.Reassign(ctx, mw.RequesterFromContext(ctx), domain.PullRequestID(payload.PullRequestID), domain.UserID(payload.OldUserID), expected)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerv1.ReassignReviewerResponse{Pr: pullRequestFromDomain(pr), ReplacedBy: string(replacement)}, nil
+}
+
+func (s *pullRequestServer) SubmitReview(ctx context.Context, req *reviewerv1.SubmitReviewRequest) (*reviewerv1.SubmitReviewResponse, error) {
+	verdict, ok := verdictToDomain[req.GetVerdict()]
+	if !ok {
+		return nil, invalid("unknown verdict")
+	}
+	payload := dto.SubmitReviewRequest{
+		PullRequestID: req.GetPullRequestId(),
+		ReviewerID:    req.GetReviewerId(),
+		Verdict:       string(verdict),
+	}
+	if err := s.validator.ValidateStruct(payload); err != nil {
+		return nil, invalid(err.Error())
+	}
+	expected, err := expectedVersion(req.GetExpectedVersion())
+	if err != nil {
+		return nil, err
+	}
+	pr, err := s.service.SubmitReview(ctx, mw.RequesterFromContext(ctx), domain.PullRequestID(payload.PullRequestID), domain.UserID(payload.ReviewerID), verdict, time.Now(), expected)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerv1.SubmitReviewResponse{Pr: pullRequestFromDomain(pr), Verdict: verdictFromDomain(verdict)}, nil
+}
@@ -0,0 +1,97 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/mashhkensss/PR-service/internal/http/httperror"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	"github.com/mashhkensss/PR-service/internal/ratelimit"
+)
+
+// RateLimit — те же лимиты, что у HTTP. Ключи бакетов совпадают с ключами RateLimiter,
+// поэтому при общем Store subject расходует один бюджет через оба транспорта
+type RateLimit struct {
+	Store    ratelimit.Storage
+	Limit    int
+	Interval time.Duration
+	// IPLimit > 0 включает лимит по адресу клиента до аутентификации, как RATE_LIMIT_IP_REQUESTS
+	IPLimit    int
+	IPInterval time.Duration
+
+	OnReject func(method string)
+	// OnError — ошибка хранилища; вызов при этом пропускается, как в HTTP
+	OnError func(method string, err error)
+}
+
+// ipRateLimitInterceptor стоит до аутентификации и ограничивает перебор токенов и API-ключей
+func ipRateLimitInterceptor(rl RateLimit) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := rl.take(ctx, info.FullMethod, "net:"+peerIP(ctx), rl.IPLimit, rl.IPInterval); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// rateLimitInterceptor стоит после аутентификации: ключ — subject, без него — адрес клиента
+func rateLimitInterceptor(rl RateLimit) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		key := "ip:" + peerIP(ctx)
+		if claims, ok := mw.ClaimsFromContext(ctx); ok && claims.Subject != "" {
+			key = "sub:" + claims.Subject
+		}
+		if err := rl.take(ctx, info.FullMethod, key, rl.Limit, rl.Interval); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (rl RateLimit) take(ctx context.Context, method, key string, limit int, interval time.Duration) error {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	res, err := rl.Store.Take(ctx, key, limit, interval, time.Now())
+	if err != nil {
+		if rl.OnError != nil {
+			rl.OnError(method, err)
+		}
+		return nil
+	}
+	if res.Allowed {
+		return nil
+	}
+	if rl.OnReject != nil {
+		rl.OnReject(method)
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfter(res.Reset))))
+	return newStatus(httperror.RateLimited()).Err()
+}
+
+// peerIP — адрес соединения: метаданным вроде x-forwarded-for клиент gRPC может написать что угодно
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func retryAfter(d time.Duration) int {
+	secs := int((d + time.Second - 1) / time.Second)
+	if secs <= 0 {
+		secs = 1
+	}
+	return secs
+}
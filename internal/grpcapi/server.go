@@ -0,0 +1,65 @@
+package grpcapi
+
+import (
+	"log/slog"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+
+	reviewerv1 "github.com/mashhkensss/PR-service/api/reviewer/v1"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	pullrequestservice "github.com/mashhkensss/PR-service/internal/service/pullrequest"
+	statsservice "github.com/mashhkensss/PR-service/internal/service/stats"
+	teamservice "github.com/mashhkensss/PR-service/internal/service/team"
+	userservice "github.com/mashhkensss/PR-service/internal/service/user"
+)
+
+// ServerConfig — те же сервисы, что у HTTP-хендлеров; транспорт только переводит сообщения.
+// Ключ идемпотентности gRPC не поддерживает: кэш Idempotency-Key хранит HTTP-ответы.
+// Повтор изменяющего вызова клиент защищает expected_version, как If-Match; повтор создания
+// получает AlreadyExists
+type ServerConfig struct {
+	Teams        teamservice.Service
+	Users        userservice.Service
+	PullRequests pullrequestservice.Service
+	Stats        statsservice.Service
+
+	// Auth nil отключает авторизацию, как RouterConfig.Auth
+	Auth *mw.Authorization
+	// RateLimit nil отключает ограничение частоты
+	RateLimit *RateLimit
+	// Validator проверяет запросы по тегам тех же DTO, что и HTTP
+	Validator mw.Validator
+	Logger    *slog.Logger
+}
+
+func NewServer(cfg ServerConfig, opts ...grpc.ServerOption) *grpc.Server {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	validator := cfg.Validator
+	if validator == nil {
+		validator = mw.NewTagValidator()
+	}
+
+	interceptors := []grpc.UnaryServerInterceptor{recoveryInterceptor(logger), loggingInterceptor(logger)}
+	if cfg.RateLimit != nil && cfg.RateLimit.IPLimit > 0 {
+		interceptors = append(interceptors, ipRateLimitInterceptor(*cfg.RateLimit))
+	}
+	if cfg.Auth != nil {
+		interceptors = append(interceptors, authInterceptor(cfg.Auth))
+	}
+	if cfg.RateLimit != nil {
+		interceptors = append(interceptors, rateLimitInterceptor(*cfg.RateLimit))
+	}
+	// stats handler продолжает трассу из метаданных и открывает span вызова, как tracing.Middleware в HTTP
+	opts = append(opts, grpc.StatsHandler(otelgrpc.NewServerHandler()), grpc.ChainUnaryInterceptor(interceptors...))
+	server := grpc.NewServer(opts...)
+
+	reviewerv1.RegisterTeamServiceServer(server, &teamServer{service: cfg.Teams, validator: validator})
+	reviewerv1.RegisterUserServiceServer(server, &userServer{service: cfg.Users, validator: validator})
+	reviewerv1.RegisterPullRequestServiceServer(server, &pullRequestServer{service: cfg.PullRequests, validator: validator})
+	reviewerv1.RegisterStatsServiceServer(server, &statsServer{service: cfg.Stats})
+	return server
+}
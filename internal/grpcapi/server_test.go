@@ -0,0 +1,243 @@
+package grpcapi
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	reviewerv1 "github.com/mashhkensss/PR-service/api/reviewer/v1"
+	"github.com/mashhkensss/PR-service/internal/auth"
+	"github.com/mashhkensss/PR-service/internal/domain"
+	domainpr "github.com/mashhkensss/PR-service/internal/domain/pullrequest"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
+	"github.com/mashhkensss/PR-service/internal/http/httperror"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	"github.com/mashhkensss/PR-service/internal/ratelimit"
+	pullrequestservice "github.com/mashhkensss/PR-service/internal/service/pullrequest"
+	teamservice "github.com/mashhkensss/PR-service/internal/service/team"
+)
+
+type tokenVerifier map[string]auth.Claims
+
+func (v tokenVerifier) Verify(_ context.Context, token string) (auth.Claims, error) {
+	claims, ok := v[token]
+	if !ok {
+		return auth.Claims{}, auth.ErrInvalidToken
+	}
+	return claims, nil
+}
+
+type teamServiceStub struct {
+	teamservice.Service
+	getFn func(actor requester.Requester, name domain.TeamName) (domainteam.Team, error)
+}
+
+func (s teamServiceStub) GetTeamForUser(_ context.Context, actor requester.Requester, name domain.TeamName) (domainteam.Team, error) {
+	return s.getFn(actor, name)
+}
+
+type prServiceStub struct {
+	pullrequestservice.Service
+	mergeFn func(id domain.PullRequestID, expected domain.Version) (domainpr.PullRequest, error)
+}
+
+func (s prServiceStub) Merge(_ context.Context, _ requester.Requester, id domain.PullRequestID, _ time.Time, expected domain.Version) (domainpr.PullRequest, error) {
+	return s.mergeFn(id, expected)
+}
+
+func dial(t *testing.T, cfg ServerConfig) *grpc.ClientConn {
+	t.Helper()
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := NewServer(cfg)
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func withToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func reason(t *testing.T, err error) string {
+	t.Helper()
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info.GetReason()
+		}
+	}
+	t.Fatalf("no ErrorInfo in %v", err)
+	return ""
+}
+
+func TestStatusFromError(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		err    error
+		code   codes.Code
+		reason string
+	}{
+		{domain.ErrTeamExists, codes.AlreadyExists, httperror.CodeTeamExists},
+		{domain.ErrPullRequestExists, codes.AlreadyExists, httperror.CodePRExists},
+		{domain.ErrPullRequestAlreadyMerged, codes.FailedPrecondition, httperror.CodePRMerged},
+		{domain.ErrNoActiveCandidate, codes.FailedPrecondition, httperror.CodeNoCandidate},
+		{domain.ErrTeamAccessDenied, codes.PermissionDenied, httperror.CodeTeamForbidden},
+		{domain.ErrPermissionDenied, codes.PermissionDenied, httperror.CodeForbidden},
+		{domain.ErrAuthorIsReviewer, codes.InvalidArgument, httperror.CodeAuthorConflict},
+		{domain.ErrInvalidStatsFilter, codes.InvalidArgument, httperror.CodeInvalidInput},
+		{domain.ErrVersionMismatch, codes.Aborted, httperror.CodePrecondition},
+		{fmt.Errorf("get team: %w", sql.ErrNoRows), codes.NotFound, httperror.CodeNotFound},
+		{errors.New("boom"), codes.Internal, httperror.CodeInternalError},
+	}
+	for _, tc := range cases {
+		st := StatusFromError(tc.err)
+		if st.Code() != tc.code {
+			t.Errorf("%v: code = %s, want %s", tc.err, st.Code(), tc.code)
+		}
+		if got := reason(t, st.Err()); got != tc.reason {
+			t.Errorf("%v: reason = %s, want %s", tc.err, got, tc.reason)
+		}
+	}
+}
+
+func TestServer_RequiresAuthentication(t *testing.T) {
+	t.Parallel()
+
+	var seen requester.Requester
+	conn := dial(t, ServerConfig{
+		Teams: teamServiceStub{getFn: func(actor requester.Requester, name domain.TeamName) (domainteam.Team, error) {
+			seen = actor
+			return domainteam.New(name, nil)
+		}},
+		Auth: mw.NewAuthorization(tokenVerifier{"lead": {Subject: "u1", Role: auth.RoleTeamLead}}, nil),
+	})
+	client := reviewerv1.NewTeamServiceClient(conn)
+
+	_, err := client.GetTeam(context.Background(), &reviewerv1.GetTeamRequest{TeamName: "backend"})
+	if status.Code(err) != codes.Unauthenticated || reason(t, err) != httperror.CodeUnauthorized {
+		t.Fatalf("no token: err = %v", err)
+	}
+	_, err = client.GetTeam(withToken(context.Background(), "forged"), &reviewerv1.GetTeamRequest{TeamName: "backend"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("bad token: err = %v", err)
+	}
+
+	resp, err := client.GetTeam(withToken(context.Background(), "lead"), &reviewerv1.GetTeamRequest{TeamName: "backend"})
+	if err != nil {
+		t.Fatalf("GetTeam: %v", err)
+	}
+	if resp.GetTeam().GetTeamName() != "backend" {
+		t.Fatalf("team = %+v", resp.GetTeam())
+	}
+	if seen.UserID() != "u1" || seen.Role() != requester.Role(auth.RoleTeamLead) {
+		t.Fatalf("requester = %+v", seen)
+	}
+}
+
+func TestServer_MapsServiceErrorsAndValidation(t *testing.T) {
+	t.Parallel()
+
+	var expected domain.Version
+	conn := dial(t, ServerConfig{
+		PullRequests: prServiceStub{mergeFn: func(id domain.PullRequestID, v domain.Version) (domainpr.PullRequest, error) {
+			expected = v
+			return domainpr.PullRequest{}, domain.ErrVersionMismatch
+		}},
+	})
+	client := reviewerv1.NewPullRequestServiceClient(conn)
+
+	_, err := client.MergePullRequest(context.Background(), &reviewerv1.MergePullRequestRequest{})
+	if status.Code(err) != codes.InvalidArgument || reason(t, err) != httperror.CodeInvalidInput {
+		t.Fatalf("empty id: err = %v", err)
+	}
+
+	_, err = client.MergePullRequest(context.Background(), &reviewerv1.MergePullRequestRequest{PullRequestId: "pr-1", ExpectedVersion: 3})
+	if status.Code(err) != codes.Aborted || reason(t, err) != httperror.CodePrecondition {
+		t.Fatalf("stale version: err = %v", err)
+	}
+	if expected != 3 {
+		t.Fatalf("expected version = %d, want 3", expected)
+	}
+}
+
+func TestServer_RecoversFromPanic(t *testing.T) {
+	t.Parallel()
+
+	conn := dial(t, ServerConfig{
+		Teams: teamServiceStub{getFn: func(requester.Requester, domain.TeamName) (domainteam.Team, error) {
+			panic("boom")
+		}},
+	})
+	_, err := reviewerv1.NewTeamServiceClient(conn).GetTeam(context.Background(), &reviewerv1.GetTeamRequest{TeamName: "backend"})
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("err = %v, want Internal", err)
+	}
+}
+
+func TestServer_RateLimitsBySubject(t *testing.T) {
+	t.Parallel()
+
+	store := ratelimit.NewMemory(time.Minute)
+	var rejected []string
+	conn := dial(t, ServerConfig{
+		Teams: teamServiceStub{getFn: func(_ requester.Requester, name domain.TeamName) (domainteam.Team, error) {
+			return domainteam.New(name, nil)
+		}},
+		Auth: mw.NewAuthorization(tokenVerifier{
+			"alice": {Subject: "u1", Role: auth.RoleUser},
+			"bob":   {Subject: "u2", Role: auth.RoleUser},
+		}, nil),
+		RateLimit: &RateLimit{Store: store, Limit: 1, Interval: time.Minute, OnReject: func(method string) {
+			rejected = append(rejected, method)
+		}},
+	})
+	client := reviewerv1.NewTeamServiceClient(conn)
+	req := &reviewerv1.GetTeamRequest{TeamName: "backend"}
+
+	if _, err := client.GetTeam(withToken(context.Background(), "alice"), req); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	var header metadata.MD
+	_, err := client.GetTeam(withToken(context.Background(), "alice"), req, grpc.Header(&header))
+	if status.Code(err) != codes.ResourceExhausted || reason(t, err) != httperror.CodeRateLimited {
+		t.Fatalf("second call: err = %v", err)
+	}
+	if got := header.Get("retry-after"); len(got) != 1 || got[0] == "" {
+		t.Fatalf("retry-after = %v", got)
+	}
+	if _, err := client.GetTeam(withToken(context.Background(), "bob"), req); err != nil {
+		t.Fatalf("other subject: %v", err)
+	}
+	if len(rejected) != 1 || rejected[0] != reviewerv1.TeamService_GetTeam_FullMethodName {
+		t.Fatalf("rejected = %v", rejected)
+	}
+	// ключ совпадает с HTTP-лимитером: бюджет subject общий для обоих транспортов
+	if res, _ := store.Take(context.Background(), "sub:u2", 1, time.Minute, time.Now()); res.Allowed {
+		t.Fatal("gRPC call must spend the subject's shared bucket")
+	}
+}
@@ -0,0 +1,182 @@
+package grpcapi
+
+import (
+	"context"
+
+	reviewerv1 "github.com/mashhkensss/PR-service/api/reviewer/v1"
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/stats"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	statsservice "github.com/mashhkensss/PR-service/internal/service/stats"
+)
+
+type statsServer struct {
+	reviewerv1.UnimplementedStatsServiceServer
+	service statsservice.Service
+}
+
+func (s *statsServer) GetAssignments(ctx context.Context, req *reviewerv1.StatsFilter) (*reviewerv1.AssignmentsStats, error) {
+	filter, err := statsFilter(req)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.service.GetAssignments(ctx, mw.RequesterFromContext(ctx), filter)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &reviewerv1.AssignmentsStats{
+		ByUser:        counts(result.ByUser),
+		ByPullRequest: counts(result.ByPullRequest),
+		Reviewers:     make([]*reviewerv1.ReviewerStats, 0, len(result.Reviewers)),
+	}
+	for _, r := range result.Reviewers {
+		resp.Reviewers = append(resp.Reviewers, &reviewerv1.ReviewerStats{
+			UserId:   r.UserID,
+			TeamName: r.TeamName,
+			IsActive: r.IsActive,
+			Open:     int64(r.Open),
+			Merged:   int64(r.Merged),
+			Total:    int64(r.Total),
+		})
+	}
+	return resp, nil
+}
+
+func (s *statsServer) GetSummary(ctx context.Context, req *reviewerv1.StatsFilter) (*reviewerv1.Summary, error) {
+	filter, err := statsFilter(req)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := s.service.GetSummary(ctx, mw.RequesterFromContext(ctx), filter)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerv1.Summary{
+		UsersCount:                    int64(summary.UsersCount),
+		ActiveUsersWithoutAssignments: int64(summary.ActiveUsersWithoutAssignments),
+		PullRequestsCount:             int64(summary.PullRequestsCount),
+		AssignmentsTotal:              int64(summary.AssignmentsTotal),
+		OpenAssignments:               int64(summary.OpenAssignments),
+		MergedAssignments:             int64(summary.MergedAssignments),
+	}, nil
+}
+
+func (s *statsServer) GetCycleTime(ctx context.Context, req *reviewerv1.StatsFilter) (*reviewerv1.CycleTimeReport, error) {
+	filter, err := statsFilter(req)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.service.GetCycleTime(ctx, mw.RequesterFromContext(ctx), filter)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerv1.CycleTimeReport{
+		CreateToMerge:     durationBreakdown(report.CreateToMerge),
+		AssignmentToMerge: durationBreakdown(report.AssignmentToMerge),
+		Reassignment: &reviewerv1.ReassignmentBreakdown{
+			Overall: reassignmentStats(report.Reassignment.Overall),
+			ByTeam:  mapSlice(report.Reassignment.ByTeam, reassignmentStats),
+			ByWeek:  mapSlice(report.Reassignment.ByWeek, reassignmentStats),
+		},
+	}, nil
+}
+
+func (s *statsServer) GetFairness(ctx context.Context, req *reviewerv1.StatsFilter) (*reviewerv1.FairnessReport, error) {
+	filter, err := statsFilter(req)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.service.GetFairness(ctx, mw.RequesterFromContext(ctx), filter)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerv1.FairnessReport{Teams: mapSlice(report.Teams, teamFairness)}, nil
+}
+
+func statsFilter(req *reviewerv1.StatsFilter) (stats.Filter, error) {
+	status, ok := statusToDomain[req.GetStatus()]
+	if !ok {
+		return stats.Filter{}, invalid("unknown status")
+	}
+	filter := stats.Filter{
+		Team:   domain.TeamName(req.GetTeam()),
+		Status: status,
+	}
+	if req.GetFrom() != nil {
+		from := req.GetFrom().AsTime()
+		filter.From = &from
+	}
+	if req.GetTo() != nil {
+		to := req.GetTo().AsTime()
+		filter.To = &to
+	}
+	return filter, nil
+}
+
+func counts(src map[string]int) map[string]int64 {
+	dst := make(map[string]int64, len(src))
+	for k, v := range src {
+		dst[k] = int64(v)
+	}
+	return dst
+}
+
+func durationBreakdown(src statsservice.DurationBreakdown) *reviewerv1.DurationBreakdown {
+	return &reviewerv1.DurationBreakdown{
+		Overall:    durationStats(src.Overall),
+		ByTeam:     mapSlice(src.ByTeam, durationStats),
+		ByWeek:     mapSlice(src.ByWeek, durationStats),
+		ByReviewer: mapSlice(src.ByReviewer, durationStats),
+	}
+}
+
+func durationStats(src statsservice.DurationStats) *reviewerv1.DurationStats {
+	return &reviewerv1.DurationStats{
+		TeamName:   src.TeamName,
+		Week:       src.Week,
+		ReviewerId: src.ReviewerID,
+		Count:      int64(src.Count),
+		P50Seconds: src.P50Seconds,
+		P90Seconds: src.P90Seconds,
+		P99Seconds: src.P99Seconds,
+	}
+}
+
+func reassignmentStats(src statsservice.ReassignmentStats) *reviewerv1.ReassignmentStats {
+	return &reviewerv1.ReassignmentStats{
+		TeamName:               src.TeamName,
+		Week:                   src.Week,
+		PullRequests:           int64(src.PullRequests),
+		ReassignedPullRequests: int64(src.ReassignedPullRequests),
+		Reassignments:          int64(src.Reassignments),
+		Rate:                   src.Rate,
+	}
+}
+
+func teamFairness(src statsservice.TeamFairness) *reviewerv1.TeamFairness {
+	return &reviewerv1.TeamFairness{
+		TeamName:               src.TeamName,
+		ActiveMembers:          int64(src.ActiveMembers),
+		AssignmentsTotal:       int64(src.AssignmentsTotal),
+		Mean:                   src.Mean,
+		CoefficientOfVariation: src.CoefficientOfVariation,
+		Gini:                   src.Gini,
+		MostLoaded:             loadExtreme(src.MostLoaded),
+		LeastLoaded:            loadExtreme(src.LeastLoaded),
+		Members:                mapSlice(src.Members, memberLoad),
+	}
+}
+
+func loadExtreme(src statsservice.LoadExtreme) *reviewerv1.LoadExtreme {
+	return &reviewerv1.LoadExtreme{UserIds: src.UserIDs, Assignments: int64(src.Assignments)}
+}
+
+func memberLoad(src statsservice.MemberLoad) *reviewerv1.MemberLoad {
+	return &reviewerv1.MemberLoad{
+		UserId:      src.UserID,
+		Assignments: int64(src.Assignments),
+		Share:       src.Share,
+		EvenShare:   src.EvenShare,
+		ShareVsEven: src.ShareVsEven,
+	}
+}
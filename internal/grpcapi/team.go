@@ -0,0 +1,54 @@
+package grpcapi
+
+import (
+	"context"
+
+	reviewerv1 "github.com/mashhkensss/PR-service/api/reviewer/v1"
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	teamservice "github.com/mashhkensss/PR-service/internal/service/team"
+)
+
+type teamServer struct {
+	reviewerv1.UnimplementedTeamServiceServer
+	service   teamservice.Service
+	validator mw.Validator
+}
+
+func (s *teamServer) AddTeam(ctx context.Context, req *reviewerv1.AddTeamRequest) (*reviewerv1.AddTeamResponse, error) {
+	payload := dto.Team{
+		TeamName: req.GetTeam().GetTeamName(),
+		Members:  make([]dto.TeamMember, 0, len(req.GetTeam().GetMembers())),
+	}
+	for _, m := range req.GetTeam().GetMembers() {
+		payload.Members = append(payload.Members, dto.TeamMember{
+			UserID:   m.GetUserId(),
+			Username: m.GetUsername(),
+			IsActive: m.GetIsActive(),
+		})
+	}
+	if err := s.validator.ValidateStruct(payload); err != nil {
+		return nil, invalid(err.Error())
+	}
+	aggregate, err := payload.ToDomain()
+	if err != nil {
+		return nil, invalid(err.Error())
+	}
+	created, err := s.service.AddTeam(ctx, mw.RequesterFromContext(ctx), aggregate)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerv1.AddTeamResponse{Team: teamFromDomain(created)}, nil
+}
+
+func (s *teamServer) GetTeam(ctx context.Context, req *reviewerv1.GetTeamRequest) (*reviewerv1.GetTeamResponse, error) {
+	if req.GetTeamName() == "" {
+		return nil, invalid("team_name is required")
+	}
+	t, err := s.service.GetTeamForUser(ctx, mw.RequesterFromContext(ctx), domain.TeamName(req.GetTeamName()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerv1.GetTeamResponse{Team: teamFromDomain(t)}, nil
+}
@@ -0,0 +1,52 @@
+package grpcapi
+
+import (
+	"context"
+
+	reviewerv1 "github.com/mashhkensss/PR-service/api/reviewer/v1"
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	userservice "github.com/mashhkensss/PR-service/internal/service/user"
+)
+
+type userServer struct {
+	reviewerv1.UnimplementedUserServiceServer
+	service   userservice.Service
+	validator mw.Validator
+}
+
+func (s *userServer) SetIsActive(ctx context.Context, req *reviewerv1.SetIsActiveRequest) (*reviewerv1.SetIsActiveResponse, error) {
+	isActive := req.GetIsActive()
+	payload := dto.SetUserActiveRequest{UserID: req.GetUserId(), IsActive: &isActive}
+	if err := s.validator.ValidateStruct(payload); err != nil {
+		return nil, invalid(err.Error())
+	}
+	expected, err := expectedVersion(req.GetExpectedVersion())
+	if err != nil {
+		return nil, err
+	}
+	updated, err := s.service.SetIsActive(ctx, mw.RequesterFromContext(ctx), domain.UserID(payload.UserID), isActive, expected)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &reviewerv1.SetIsActiveResponse{User: userFromDomain(updated)}, nil
+}
+
+func (s *userServer) GetReview(ctx context.Context, req *reviewerv1.GetReviewRequest) (*reviewerv1.GetReviewResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, invalid("user_id is required")
+	}
+	prs, err := s.service.GetReviewAssignments(ctx, mw.RequesterFromContext(ctx), domain.UserID(req.GetUserId()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &reviewerv1.GetReviewResponse{
+		UserId:       req.GetUserId(),
+		PullRequests: make([]*reviewerv1.PullRequestShort, 0, len(prs)),
+	}
+	for _, pr := range prs {
+		resp.PullRequests = append(resp.PullRequests, pullRequestShortFromDomain(pr))
+	}
+	return resp, nil
+}
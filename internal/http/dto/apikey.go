@@ -0,0 +1,46 @@
+package dto
+
+import (
+	"time"
+
+	domainapikey "github.com/mashhkensss/PR-service/internal/domain/apikey"
+)
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required"`
+	Role      string     `json:"role" validate:"required,oneof=admin team_lead user"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,required"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type RevokeAPIKeyRequest struct {
+	ID string `json:"id" validate:"required"`
+}
+
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func APIKeyFromDomain(k domainapikey.Key) APIKey {
+	scopes := make([]string, 0, len(k.Scopes()))
+	for _, s := range k.Scopes() {
+		scopes = append(scopes, string(s))
+	}
+	return APIKey{
+		ID:         k.ID(),
+		Name:       k.Name(),
+		Role:       string(k.Role()),
+		Scopes:     scopes,
+		CreatedAt:  k.CreatedAt(),
+		ExpiresAt:  k.ExpiresAt(),
+		LastUsedAt: k.LastUsedAt(),
+		RevokedAt:  k.RevokedAt(),
+	}
+}
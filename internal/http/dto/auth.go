@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain/revocation"
+)
+
+type IssueTokenRequest struct {
+	Subject string `json:"subject" validate:"required"`
+	Role    string `json:"role" validate:"required,oneof=admin team_lead user"`
+	// TTL — Go duration, например 1h
+	TTL   string `json:"ttl" validate:"required"`
+	Scope string `json:"scope,omitempty"`
+}
+
+type IssuedToken struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RevokeTokenRequest — ровно одно из полей: jti отзывает один токен, subject — все выпущенные ранее токены субъекта.
+// ExpiresAt — exp отзываемого по jti токена, после него запись удаляется
+type RevokeTokenRequest struct {
+	JTI       string     `json:"jti,omitempty" validate:"required_without=Subject,excluded_with=Subject"`
+	Subject   string     `json:"subject,omitempty" validate:"required_without=JTI"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" validate:"excluded_without=JTI"`
+}
+
+type TokenRevocation struct {
+	Kind      string     `json:"kind"`
+	Value     string     `json:"value"`
+	RevokedAt time.Time  `json:"revoked_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+func TokenRevocationFromDomain(r revocation.Revocation) TokenRevocation {
+	out := TokenRevocation{
+		Kind:      string(r.Kind()),
+		Value:     r.Value(),
+		RevokedAt: r.RevokedAt(),
+	}
+	if exp := r.ExpiresAt(); !exp.IsZero() {
+		out.ExpiresAt = &exp
+	}
+	return out
+}
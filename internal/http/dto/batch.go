@@ -0,0 +1,46 @@
+package dto
+
+import "encoding/json"
+
+const (
+	BatchModeAtomic      = "atomic"
+	BatchModeIndependent = "independent"
+)
+
+// Операции batch совпадают с одиночными эндпоинтами
+const (
+	BatchOpCreate      = "create"
+	BatchOpMerge       = "merge"
+	BatchOpReassign    = "reassign"
+	BatchOpSetIsActive = "setIsActive"
+)
+
+type BatchRequest struct {
+	Mode       string           `json:"mode" validate:"required,oneof=atomic independent"`
+	Operations []BatchOperation `json:"operations" validate:"required,min=1,dive"`
+}
+
+// BatchOperation — тело и заголовки одиночного запроса; Body передаётся обработчику как есть
+type BatchOperation struct {
+	Op             string          `json:"op" validate:"required,oneof=create merge reassign setIsActive"`
+	IdempotencyKey string          `json:"idempotency_key,omitempty"`
+	IfMatch        string          `json:"if_match,omitempty"`
+	Body           json.RawMessage `json:"body" validate:"required"`
+}
+
+// BatchResult — ответ одиночного эндпоинта на операцию: статус, ETag и тело без изменений
+type BatchResult struct {
+	Index    int             `json:"index"`
+	Op       string          `json:"op"`
+	Status   int             `json:"status"`
+	ETag     string          `json:"etag,omitempty"`
+	Replayed bool            `json:"replayed,omitempty"`
+	Body     json.RawMessage `json:"body"`
+}
+
+type BatchResponse struct {
+	Mode string `json:"mode"`
+	// Committed в атомарном режиме — транзакция зафиксирована; в независимом — все операции успешны
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
@@ -1,7 +1,11 @@
 package dto
 
+import "strings"
+
 type ErrorResponse struct {
 	Error APIError `json:"error"`
+	// Violations нет в прежнем формате: они отдаются только в problem+json
+	Violations []FieldViolation `json:"-"`
 }
 
 type APIError struct {
@@ -9,6 +13,29 @@ type APIError struct {
 	Message string `json:"message" validate:"required"`
 }
 
+// FieldViolation — нарушенное правило валидации одного поля; Field — путь в JSON запроса
+type FieldViolation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// Problem — тело application/problem+json по RFC 9457
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	// Instance — request_id, под которым запрос записан в лог
+	Instance string           `json:"instance,omitempty"`
+	Code     string           `json:"code"`
+	Errors   []FieldViolation `json:"errors,omitempty"`
+}
+
+// ProblemTypePrefix — type проблемы строится из кода, поэтому стабилен вместе с ним
+const ProblemTypePrefix = "urn:pr-service:problem:"
+
 // NewErrorResponse для хендлеров
 func NewErrorResponse(code, message string) ErrorResponse {
 	return ErrorResponse{
@@ -18,3 +45,16 @@ func NewErrorResponse(code, message string) ErrorResponse {
 		},
 	}
 }
+
+// Problem переводит ошибку в RFC 9457; title — стандартный текст статуса
+func (e ErrorResponse) Problem(status int, title, instance string) Problem {
+	return Problem{
+		Type:     ProblemTypePrefix + strings.ToLower(strings.ReplaceAll(e.Error.Code, "_", "-")),
+		Title:    title,
+		Status:   status,
+		Detail:   e.Error.Message,
+		Instance: instance,
+		Code:     e.Error.Code,
+		Errors:   e.Violations,
+	}
+}
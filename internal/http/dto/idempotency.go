@@ -0,0 +1,39 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/idempotency"
+)
+
+// DeleteIdempotencyKeyRequest — пустой subject соответствует запросам без аутентификации
+type DeleteIdempotencyKeyRequest struct {
+	Subject string `json:"subject"`
+	Key     string `json:"key" validate:"required"`
+}
+
+type IdempotencyRecord struct {
+	Subject   string    `json:"subject"`
+	Key       string    `json:"key"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	State     string    `json:"state"`
+	Status    int       `json:"status,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func IdempotencyRecordFromDomain(r idempotency.Record) IdempotencyRecord {
+	state := "completed"
+	if r.Pending {
+		state = "pending"
+	}
+	return IdempotencyRecord{
+		Subject:   r.Key.Subject,
+		Key:       r.Key.Value,
+		Method:    r.Key.Method,
+		Path:      r.Key.Path,
+		State:     state,
+		Status:    r.Status,
+		ExpiresAt: r.ExpiresAt,
+	}
+}
@@ -15,6 +15,7 @@ type PullRequest struct {
 	Assigned        []string   `json:"assigned_reviewers" validate:"max=2,dive,required"`
 	CreatedAt       *time.Time `json:"createdAt,omitempty"`
 	MergedAt        *time.Time `json:"mergedAt,omitempty"`
+	Version         int64      `json:"version,omitempty"`
 }
 
 type PullRequestShort struct {
@@ -22,6 +23,7 @@ type PullRequestShort struct {
 	PullRequestName string `json:"pull_request_name" validate:"required"`
 	AuthorID        string `json:"author_id" validate:"required"`
 	Status          string `json:"status" validate:"required,oneof=OPEN MERGED"`
+	Version         int64  `json:"version,omitempty"`
 }
 
 type CreatePullRequestRequest struct {
@@ -56,6 +58,7 @@ func PullRequestFromDomain(src domainpr.PullRequest) PullRequest {
 		Assigned:        dtoReviewers,
 		CreatedAt:       &createdAt,
 		MergedAt:        src.MergedAt(),
+		Version:         int64(src.Version()),
 	}
 }
 
@@ -65,6 +68,7 @@ func PullRequestShortFromDomain(src domainpr.PullRequest) PullRequestShort {
 		PullRequestName: src.PullRequestName(),
 		AuthorID:        string(src.AuthorID()),
 		Status:          string(src.Status()),
+		Version:         int64(src.Version()),
 	}
 }
 
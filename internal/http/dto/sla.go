@@ -0,0 +1,56 @@
+package dto
+
+import (
+	"errors"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	domainsla "github.com/mashhkensss/PR-service/internal/domain/sla"
+)
+
+type SLAPolicy struct {
+	TeamName         string `json:"team_name" validate:"required"`
+	ReviewWithin     string `json:"review_within" validate:"required"`
+	BusinessDaysOnly *bool  `json:"business_days_only,omitempty"`
+	Escalation       string `json:"escalation" validate:"required,oneof=REASSIGN ADD_REVIEWER EVENT"`
+}
+
+type SLABreach struct {
+	PullRequestID string     `json:"pull_request_id"`
+	ReviewerID    string     `json:"reviewer_id"`
+	TeamName      string     `json:"team_name"`
+	AssignedAt    time.Time  `json:"assigned_at"`
+	Deadline      time.Time  `json:"deadline"`
+	Escalation    string     `json:"escalation"`
+	EscalatedAt   *time.Time `json:"escalated_at,omitempty"`
+}
+
+type SubmitReviewRequest struct {
+	PullRequestID string `json:"pull_request_id" validate:"required"`
+	ReviewerID    string `json:"reviewer_id" validate:"required"`
+	// Verdict по умолчанию COMMENTED
+	Verdict string `json:"verdict,omitempty" validate:"omitempty,oneof=APPROVED CHANGES_REQUESTED COMMENTED"`
+}
+
+func SLAPolicyFromDomain(p domainsla.Policy) SLAPolicy {
+	business := p.BusinessDaysOnly()
+	return SLAPolicy{
+		TeamName:         string(p.TeamName()),
+		ReviewWithin:     p.ReviewWithin().String(),
+		BusinessDaysOnly: &business,
+		Escalation:       string(p.Escalation()),
+	}
+}
+
+// ToDomain по умолчанию считает SLA в рабочих днях
+func (p SLAPolicy) ToDomain() (domainsla.Policy, error) {
+	within, err := time.ParseDuration(p.ReviewWithin)
+	if err != nil {
+		return domainsla.Policy{}, errors.New("review_within must be a duration like 24h")
+	}
+	business := true
+	if p.BusinessDaysOnly != nil {
+		business = *p.BusinessDaysOnly
+	}
+	return domainsla.New(domain.TeamName(p.TeamName), within, business, domainsla.Escalation(p.Escalation))
+}
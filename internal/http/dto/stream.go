@@ -0,0 +1,26 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/stream"
+)
+
+// AssignmentEvent — данные события SSE из /events/stream
+type AssignmentEvent struct {
+	Kind          string    `json:"kind"`
+	PullRequestID string    `json:"pull_request_id"`
+	ReviewerID    string    `json:"reviewer_id"`
+	TeamName      string    `json:"team_name,omitempty"`
+	OccurredAt    time.Time `json:"occurred_at"`
+}
+
+func AssignmentEventFromEntry(e stream.Entry) AssignmentEvent {
+	return AssignmentEvent{
+		Kind:          string(e.Kind),
+		PullRequestID: string(e.PullRequestID),
+		ReviewerID:    string(e.ReviewerID),
+		TeamName:      string(e.TeamName),
+		OccurredAt:    e.OccurredAt,
+	}
+}
@@ -19,6 +19,25 @@ type Team struct {
 	Members  []TeamMember `json:"members" validate:"required,dive"`
 }
 
+type AddTeamMemberRequest struct {
+	TeamName string     `json:"team_name" validate:"required"`
+	Member   TeamMember `json:"member"`
+}
+
+type RemoveTeamMemberRequest struct {
+	TeamName string `json:"team_name" validate:"required"`
+	UserID   string `json:"user_id" validate:"required"`
+}
+
+func (r AddTeamMemberRequest) ToDomain() (domainuser.User, error) {
+	return domainuser.New(
+		domain.UserID(r.Member.UserID),
+		r.Member.Username,
+		domain.TeamName(r.TeamName),
+		r.Member.IsActive,
+	)
+}
+
 func TeamFromDomain(src domainteam.Team) Team {
 	members := src.Members()
 	dtoMembers := make([]TeamMember, 0, len(members))
@@ -0,0 +1,114 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/transfer"
+)
+
+// NDJSONContentType — по строке JSON на запись, с полем type
+const NDJSONContentType = "application/x-ndjson"
+
+type TransferAssignment struct {
+	UserID     string     `json:"user_id" validate:"required"`
+	AssignedAt *time.Time `json:"assigned_at,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	Verdict    string     `json:"verdict,omitempty" validate:"omitempty,oneof=APPROVED CHANGES_REQUESTED COMMENTED"`
+	VerdictAt  *time.Time `json:"verdict_at,omitempty"`
+}
+
+// TransferPullRequest — PR для выгрузки и загрузки; assigned_at без значения при импорте равен created_at
+type TransferPullRequest struct {
+	PullRequestID   string               `json:"pull_request_id" validate:"required"`
+	PullRequestName string               `json:"pull_request_name" validate:"required"`
+	AuthorID        string               `json:"author_id" validate:"required"`
+	Status          string               `json:"status" validate:"required,oneof=OPEN MERGED"`
+	CreatedAt       time.Time            `json:"created_at" validate:"required"`
+	MergedAt        *time.Time           `json:"merged_at,omitempty"`
+	Reviewers       []TransferAssignment `json:"reviewers" validate:"max=2,dive"`
+}
+
+// TransferDocument — выгрузка одним JSON-документом
+type TransferDocument struct {
+	Teams        []Team                `json:"teams"`
+	PullRequests []TransferPullRequest `json:"pull_requests"`
+}
+
+// Записи NDJSON: type совпадает с kind в отчёте импорта
+type TransferTeamRecord struct {
+	Type string `json:"type"`
+	Team
+}
+
+type TransferPullRequestRecord struct {
+	Type string `json:"type"`
+	TransferPullRequest
+}
+
+type ImportCounts struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+}
+
+// ImportError — отклонённая запись. Index — позиция среди записей того же типа, Line — строка NDJSON
+type ImportError struct {
+	Kind    string           `json:"kind"`
+	Index   int              `json:"index"`
+	Line    int              `json:"line,omitempty"`
+	ID      string           `json:"id,omitempty"`
+	Code    string           `json:"code"`
+	Message string           `json:"message"`
+	Errors  []FieldViolation `json:"errors,omitempty"`
+}
+
+type ImportResponse struct {
+	Mode         string        `json:"mode"`
+	Committed    bool          `json:"committed"`
+	Teams        ImportCounts  `json:"teams"`
+	PullRequests ImportCounts  `json:"pull_requests"`
+	Errors       []ImportError `json:"errors"`
+}
+
+func TransferPullRequestFromDomain(src transfer.PullRequest) TransferPullRequest {
+	reviewers := make([]TransferAssignment, 0, len(src.Assignments))
+	for _, a := range src.Assignments {
+		assignedAt := a.AssignedAt
+		reviewers = append(reviewers, TransferAssignment{
+			UserID:     string(a.ReviewerID),
+			AssignedAt: &assignedAt,
+			ReviewedAt: a.ReviewedAt,
+			Verdict:    string(a.Verdict),
+			VerdictAt:  a.VerdictAt,
+		})
+	}
+	pr := src.PullRequest
+	return TransferPullRequest{
+		PullRequestID:   string(pr.PullRequestID()),
+		PullRequestName: pr.PullRequestName(),
+		AuthorID:        string(pr.AuthorID()),
+		Status:          string(pr.Status()),
+		CreatedAt:       pr.CreatedAt(),
+		MergedAt:        pr.MergedAt(),
+		Reviewers:       reviewers,
+	}
+}
+
+func (p TransferPullRequest) ToDomain() (transfer.PullRequest, error) {
+	assignments := make([]transfer.Assignment, 0, len(p.Reviewers))
+	for _, r := range p.Reviewers {
+		a := transfer.Assignment{
+			ReviewerID: domain.UserID(r.UserID),
+			ReviewedAt: r.ReviewedAt,
+			Verdict:    domain.ReviewVerdict(r.Verdict),
+			VerdictAt:  r.VerdictAt,
+		}
+		if r.AssignedAt != nil {
+			a.AssignedAt = *r.AssignedAt
+		}
+		assignments = append(assignments, a)
+	}
+	return transfer.NewPullRequest(domain.PullRequestID(p.PullRequestID), p.PullRequestName, domain.UserID(p.AuthorID),
+		domain.PullRequestStatus(p.Status), p.CreatedAt, p.MergedAt, assignments)
+}
@@ -0,0 +1,118 @@
+package apikeyhandler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	"github.com/mashhkensss/PR-service/internal/http/httperror"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	"github.com/mashhkensss/PR-service/internal/http/response"
+	apikeyservice "github.com/mashhkensss/PR-service/internal/service/apikey"
+)
+
+type Handler interface {
+	Create(w http.ResponseWriter, r *http.Request)
+	List(w http.ResponseWriter, r *http.Request)
+	Revoke(w http.ResponseWriter, r *http.Request)
+}
+
+type handler struct {
+	service apikeyservice.Service
+	logger  *slog.Logger
+}
+
+func New(service apikeyservice.Service, logger *slog.Logger) Handler {
+	return &handler{service: service, logger: logger}
+}
+
+func (h *handler) Create(w http.ResponseWriter, r *http.Request) {
+	var payload dto.CreateAPIKeyRequest
+	if !h.decode(w, r, &payload) {
+		return
+	}
+	scopes := make([]requester.Scope, 0, len(payload.Scopes))
+	for _, s := range payload.Scopes {
+		scopes = append(scopes, requester.Scope(s))
+	}
+	key, plain, err := h.service.Create(r.Context(), mw.RequesterFromContext(r.Context()), apikeyservice.CreateParams{
+		Name:      payload.Name,
+		Role:      requester.Role(payload.Role),
+		Scopes:    scopes,
+		ExpiresAt: payload.ExpiresAt,
+	}, time.Now())
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "name", payload.Name)...)
+		return
+	}
+	resp := struct {
+		APIKey dto.APIKey `json:"api_key"`
+		Key    string     `json:"key"`
+	}{
+		APIKey: dto.APIKeyFromDomain(key),
+		Key:    plain,
+	}
+	response.JSON(w, http.StatusCreated, resp)
+}
+
+func (h *handler) List(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.List(r.Context(), mw.RequesterFromContext(r.Context()))
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r)...)
+		return
+	}
+	resp := struct {
+		APIKeys []dto.APIKey `json:"api_keys"`
+	}{
+		APIKeys: make([]dto.APIKey, 0, len(keys)),
+	}
+	for _, k := range keys {
+		resp.APIKeys = append(resp.APIKeys, dto.APIKeyFromDomain(k))
+	}
+	response.JSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	var payload dto.RevokeAPIKeyRequest
+	if !h.decode(w, r, &payload) {
+		return
+	}
+	key, err := h.service.Revoke(r.Context(), mw.RequesterFromContext(r.Context()), payload.ID, time.Now())
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "key_id", payload.ID)...)
+		return
+	}
+	resp := struct {
+		APIKey dto.APIKey `json:"api_key"`
+	}{
+		APIKey: dto.APIKeyFromDomain(key),
+	}
+	response.JSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) decode(w http.ResponseWriter, r *http.Request, payload any) bool {
+	if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+		status, resp := httperror.InvalidRequest("invalid JSON payload")
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+		return false
+	}
+	if validator, ok := mw.ValidatorFromContext(r.Context()); ok {
+		if err := validator.ValidateStruct(payload); err != nil {
+			status, resp := httperror.Validation(err)
+			httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+			return false
+		}
+	}
+	return true
+}
+
+func logFields(r *http.Request, extra ...any) []any {
+	fields := []any{"method", r.Method, "path", r.URL.Path}
+	if claims, ok := mw.ClaimsFromContext(r.Context()); ok && claims.Subject != "" {
+		fields = append(fields, "user_id", claims.Subject)
+	}
+	return append(fields, extra...)
+}
@@ -0,0 +1,82 @@
+package authhandler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/auth"
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	"github.com/mashhkensss/PR-service/internal/http/httperror"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	"github.com/mashhkensss/PR-service/internal/http/response"
+	tokenservice "github.com/mashhkensss/PR-service/internal/service/token"
+)
+
+type Handler interface {
+	IssueToken(w http.ResponseWriter, r *http.Request)
+}
+
+type handler struct {
+	service tokenservice.Service
+	logger  *slog.Logger
+}
+
+func New(service tokenservice.Service, logger *slog.Logger) Handler {
+	return &handler{service: service, logger: logger}
+}
+
+func (h *handler) IssueToken(w http.ResponseWriter, r *http.Request) {
+	var payload dto.IssueTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		status, resp := httperror.InvalidRequest("invalid JSON payload")
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+		return
+	}
+	if validator, ok := mw.ValidatorFromContext(r.Context()); ok {
+		if err := validator.ValidateStruct(payload); err != nil {
+			status, resp := httperror.Validation(err)
+			httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+			return
+		}
+	}
+	ttl, err := time.ParseDuration(payload.TTL)
+	if err != nil {
+		status, resp := httperror.InvalidRequest("ttl must be a duration like 1h")
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+		return
+	}
+
+	token, claims, err := h.service.Issue(r.Context(), mw.RequesterFromContext(r.Context()), auth.TokenRequest{
+		Subject: payload.Subject,
+		Role:    payload.Role,
+		Scope:   payload.Scope,
+		TTL:     ttl,
+	})
+	if errors.Is(err, auth.ErrInvalidTokenRequest) {
+		status, resp := httperror.InvalidRequest(err.Error())
+		httperror.Write(w, r, status, resp, h.logger, logFields(r, "subject", payload.Subject)...)
+		return
+	}
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "subject", payload.Subject)...)
+		return
+	}
+	h.logger.Info("token issued", logFields(r, "subject", claims.Subject, "role", claims.Role, "expires_at", claims.ExpiresAt)...)
+
+	response.JSON(w, http.StatusCreated, dto.IssuedToken{
+		Token:     token,
+		TokenType: "Bearer",
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0).UTC(),
+	})
+}
+
+func logFields(r *http.Request, extra ...any) []any {
+	fields := []any{"method", r.Method, "path", r.URL.Path}
+	if claims, ok := mw.ClaimsFromContext(r.Context()); ok && claims.Subject != "" {
+		fields = append(fields, "user_id", claims.Subject)
+	}
+	return append(fields, extra...)
+}
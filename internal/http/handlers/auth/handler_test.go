@@ -0,0 +1,85 @@
+package authhandler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/auth"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	tokenservice "github.com/mashhkensss/PR-service/internal/service/token"
+)
+
+var testKeys = auth.StaticKeys{
+	auth.HMACKey("", []byte("admin-secret"), auth.RoleAdmin, auth.RoleTeamLead),
+	auth.HMACKey("", []byte("user-secret"), auth.RoleUser),
+}
+
+func newTestHandler() (*handler, *mw.Authorization, *auth.Signer) {
+	signer := auth.NewSigner(auth.SignerOptions{MaxTTL: time.Hour}, testKeys...)
+	authz := mw.NewAuthorization(auth.NewVerifier(auth.VerifierOptions{}, testKeys), nil)
+	return &handler{service: tokenservice.New(signer), logger: slog.New(slog.NewTextHandler(io.Discard, nil))}, authz, signer
+}
+
+func issue(h *handler, authz *mw.Authorization, bearer, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/auth/token", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+bearer)
+	rr := httptest.NewRecorder()
+	authz.RequireAuthenticated(mw.NewValidatorMiddleware(mw.NewTagValidator())(http.HandlerFunc(h.IssueToken))).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestIssueToken_RoundTrip(t *testing.T) {
+	h, authz, signer := newTestHandler()
+	admin, _, err := signer.Issue(auth.TokenRequest{Subject: "root", Role: auth.RoleAdmin, TTL: time.Minute})
+	if err != nil {
+		t.Fatalf("issue admin token: %v", err)
+	}
+
+	rr := issue(h, authz, admin, `{"subject":"u1","role":"team_lead","ttl":"30m","scope":"teams:read"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	claims, err := auth.NewVerifier(auth.VerifierOptions{}, testKeys).Verify(context.Background(), resp.Token)
+	if err != nil {
+		t.Fatalf("issued token rejected: %v", err)
+	}
+	if claims.Subject != "u1" || claims.Role != auth.RoleTeamLead || claims.Scope != "teams:read" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+}
+
+func TestIssueToken_Rejects(t *testing.T) {
+	h, authz, signer := newTestHandler()
+	admin, _, _ := signer.Issue(auth.TokenRequest{Subject: "root", Role: auth.RoleAdmin, TTL: time.Minute})
+	user, _, _ := signer.Issue(auth.TokenRequest{Subject: "u1", Role: auth.RoleUser, TTL: time.Minute})
+
+	cases := map[string]struct {
+		bearer, body string
+		status       int
+	}{
+		"non-admin caller": {user, `{"subject":"u1","role":"admin","ttl":"1h"}`, http.StatusForbidden},
+		"ttl over max":     {admin, `{"subject":"u1","role":"user","ttl":"2h"}`, http.StatusBadRequest},
+		"bad ttl":          {admin, `{"subject":"u1","role":"user","ttl":"soon"}`, http.StatusBadRequest},
+		"unknown role":     {admin, `{"subject":"u1","role":"root","ttl":"1h"}`, http.StatusBadRequest},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if rr := issue(h, authz, tc.bearer, tc.body); rr.Code != tc.status {
+				t.Fatalf("expected %d, got %d", tc.status, rr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package batchhandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	"github.com/mashhkensss/PR-service/internal/http/httperror"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	"github.com/mashhkensss/PR-service/internal/http/response"
+	"github.com/mashhkensss/PR-service/internal/service"
+)
+
+type Handler interface {
+	Execute(w http.ResponseWriter, r *http.Request)
+}
+
+// Operation — одиночный эндпоинт, который выполняет операцию batch. Handler оборачивается тем же
+// middleware идемпотентности, что и маршрут Path, поэтому ключ операции совместим с одиночным запросом
+type Operation struct {
+	Path    string
+	Handler http.Handler
+}
+
+type handler struct {
+	operations    map[string]Operation
+	tx            service.TxRunner
+	maxOperations int
+	logger        *slog.Logger
+}
+
+// atomicTx — вложенные транзакции сервисов присоединяются к ней, а конфликт сериализации
+// приводит к повтору всего пакета. Метрики операций уходят только после её фиксации
+var atomicTx = service.TxOptions{Isolation: service.IsolationSerializable}
+
+func New(operations map[string]Operation, tx service.TxRunner, maxOperations int, logger *slog.Logger) Handler {
+	return &handler{operations: operations, tx: tx, maxOperations: maxOperations, logger: logger}
+}
+
+func (h *handler) Execute(w http.ResponseWriter, r *http.Request) {
+	var payload dto.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		status, resp := httperror.InvalidRequest("invalid JSON payload")
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+		return
+	}
+	if validator, ok := mw.ValidatorFromContext(r.Context()); ok {
+		if err := validator.ValidateStruct(payload); err != nil {
+			status, resp := httperror.Validation(err)
+			httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+			return
+		}
+	}
+	if h.maxOperations > 0 && len(payload.Operations) > h.maxOperations {
+		status, resp := httperror.InvalidRequest(fmt.Sprintf("batch must contain at most %d operations", h.maxOperations))
+		httperror.Write(w, r, status, resp, h.logger, logFields(r, "operations", len(payload.Operations))...)
+		return
+	}
+
+	var resp dto.BatchResponse
+	if payload.Mode == dto.BatchModeAtomic {
+		var err error
+		if resp, err = h.atomic(r, payload.Operations); err != nil {
+			httperror.Respond(w, r, err, h.logger, logFields(r, "operations", len(payload.Operations))...)
+			return
+		}
+	} else {
+		resp = h.independent(r, payload.Operations)
+	}
+
+	// ответ с ошибкой сервера в операции не кешируется по ключу пакета, чтобы пакет можно было повторить
+	status := http.StatusOK
+	for _, result := range resp.Results {
+		if result.Status >= http.StatusInternalServerError {
+			status = http.StatusInternalServerError
+			break
+		}
+	}
+	response.JSON(w, status, resp)
+}
+
+func (h *handler) atomic(r *http.Request, ops []dto.BatchOperation) (dto.BatchResponse, error) {
+	results := make([]dto.BatchResult, len(ops))
+	failed := -1
+	err := service.ExecInTxWith(r.Context(), h.tx, atomicTx, func(ctx context.Context) error {
+		failed = -1
+		for i, op := range ops {
+			// своя точка сохранения: после ошибки операции транзакция остаётся пригодной,
+			// и middleware идемпотентности успевает снять ключ до отката пакета
+			err := service.ExecInTx(ctx, h.tx, func(ctx context.Context) error {
+				result, err := h.dispatch(ctx, r, i, op)
+				results[i] = result
+				return err
+			})
+			if err != nil {
+				failed = i
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil && failed < 0 {
+		return dto.BatchResponse{}, err
+	}
+	if failed >= 0 {
+		message := fmt.Sprintf("operation %d failed, batch rolled back", failed)
+		for i := range results {
+			switch {
+			case i < failed:
+				results[i] = h.skipped(r, i, ops[i], httperror.CodeRolledBack, message)
+			case i > failed:
+				results[i] = h.skipped(r, i, ops[i], httperror.CodeNotExecuted, message)
+			}
+		}
+	}
+	return dto.BatchResponse{Mode: dto.BatchModeAtomic, Committed: err == nil, Results: results}, nil
+}
+
+func (h *handler) independent(r *http.Request, ops []dto.BatchOperation) dto.BatchResponse {
+	resp := dto.BatchResponse{Mode: dto.BatchModeIndependent, Committed: true, Results: make([]dto.BatchResult, len(ops))}
+	for i, op := range ops {
+		result, err := h.dispatch(r.Context(), r, i, op)
+		resp.Results[i] = result
+		if err != nil {
+			resp.Committed = false
+		}
+	}
+	return resp
+}
+
+// dispatch выполняет операцию как отдельный POST на одиночный эндпоинт. Ошибка возвращается
+// для любого неуспешного ответа и оборачивает исходную причину, если она известна
+func (h *handler) dispatch(ctx context.Context, r *http.Request, index int, op dto.BatchOperation) (dto.BatchResult, error) {
+	target, ok := h.operations[op.Op]
+	if !ok {
+		result := h.reject(r, index, op, http.StatusBadRequest, dto.NewErrorResponse(httperror.CodeInvalidInput, "unsupported operation"))
+		return result, &operationError{index: index, status: result.Status}
+	}
+	ctx, cause := httperror.WithCause(ctx)
+	sub, err := http.NewRequestWithContext(ctx, http.MethodPost, target.Path, bytes.NewReader(op.Body))
+	if err != nil {
+		status, resp := httperror.FromError(err)
+		return h.reject(r, index, op, status, resp), &operationError{index: index, status: status, cause: err}
+	}
+	sub.RemoteAddr = r.RemoteAddr
+	sub.Header.Set("Content-Type", "application/json")
+	if accept := r.Header.Get("Accept"); accept != "" {
+		sub.Header.Set("Accept", accept)
+	}
+	if op.IdempotencyKey != "" {
+		sub.Header.Set("Idempotency-Key", op.IdempotencyKey)
+	}
+	if op.IfMatch != "" {
+		sub.Header.Set("If-Match", op.IfMatch)
+	}
+
+	rec := newRecorder()
+	target.Handler.ServeHTTP(rec, sub)
+	result := rec.result(index, op.Op)
+	if result.Status >= http.StatusBadRequest {
+		return result, &operationError{index: index, status: result.Status, cause: *cause}
+	}
+	return result, nil
+}
+
+// reject — ответ операции, не дошедшей до эндпоинта, в том же формате ошибок
+func (h *handler) reject(r *http.Request, index int, op dto.BatchOperation, status int, resp dto.ErrorResponse) dto.BatchResult {
+	rec := newRecorder()
+	response.ErrorResponse(rec, r, status, resp)
+	return rec.result(index, op.Op)
+}
+
+func (h *handler) skipped(r *http.Request, index int, op dto.BatchOperation, code, message string) dto.BatchResult {
+	return h.reject(r, index, op, http.StatusFailedDependency, dto.NewErrorResponse(code, message))
+}
+
+// operationError откатывает атомарный пакет; через Unwrap менеджер транзакций видит
+// конфликт сериализации и повторяет пакет
+type operationError struct {
+	index  int
+	status int
+	cause  error
+}
+
+func (e *operationError) Error() string {
+	if e.cause != nil {
+		return fmt.Sprintf("batch operation %d: status %d: %v", e.index, e.status, e.cause)
+	}
+	return fmt.Sprintf("batch operation %d: status %d", e.index, e.status)
+}
+
+func (e *operationError) Unwrap() error {
+	return e.cause
+}
+
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newRecorder() *recorder {
+	return &recorder{header: make(http.Header)}
+}
+
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *recorder) Write(p []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(p)
+}
+
+func (rec *recorder) result(index int, op string) dto.BatchResult {
+	result := dto.BatchResult{
+		Index:    index,
+		Op:       op,
+		Status:   rec.status,
+		ETag:     rec.header.Get("ETag"),
+		Replayed: rec.header.Get(mw.IdempotentReplayedHeader) == "true",
+	}
+	if body := bytes.TrimSpace(rec.body.Bytes()); len(body) > 0 {
+		result.Body = json.RawMessage(body)
+	}
+	return result
+}
+
+func logFields(r *http.Request, extra ...any) []any {
+	fields := []any{"method", r.Method, "path", r.URL.Path}
+	if claims, ok := mw.ClaimsFromContext(r.Context()); ok && claims.Subject != "" {
+		fields = append(fields, "user_id", claims.Subject)
+	}
+	return append(fields, extra...)
+}
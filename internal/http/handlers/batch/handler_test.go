@@ -0,0 +1,194 @@
+package batchhandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	"github.com/mashhkensss/PR-service/internal/http/httperror"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	"github.com/mashhkensss/PR-service/internal/http/response"
+	"github.com/mashhkensss/PR-service/internal/service"
+)
+
+var errConflict = errors.New("serialization failure")
+
+// txStub повторяет fn один раз при errConflict, как TxManager при конфликте сериализации;
+// вложенный вызов присоединяется к внешнему и считается как точка сохранения
+type txStub struct {
+	opts       service.TxOptions
+	attempts   int
+	savepoints int
+}
+
+type inTxKey struct{}
+
+func (tx *txStub) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return tx.WithinTxOptions(ctx, service.TxOptions{}, fn)
+}
+
+func (tx *txStub) WithinTxOptions(ctx context.Context, opts service.TxOptions, fn func(ctx context.Context) error) error {
+	if ctx.Value(inTxKey{}) != nil {
+		tx.savepoints++
+		return fn(ctx)
+	}
+	tx.opts = opts
+	ctx = context.WithValue(ctx, inTxKey{}, true)
+	for {
+		tx.attempts++
+		err := fn(ctx)
+		if errors.Is(err, errConflict) && tx.attempts == 1 {
+			continue
+		}
+		return err
+	}
+}
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func created(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("ETag", `"1"`)
+	response.JSON(w, http.StatusCreated, json.RawMessage(body))
+}
+
+func failing(err error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		httperror.Respond(w, r, err, nil)
+	}
+}
+
+func execute(h Handler, body string) (*httptest.ResponseRecorder, dto.BatchResponse) {
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", "batch-key")
+	rr := httptest.NewRecorder()
+	mw.NewValidatorMiddleware(mw.NewTagValidator())(http.HandlerFunc(h.Execute)).ServeHTTP(rr, req)
+	var resp dto.BatchResponse
+	_ = json.NewDecoder(rr.Body).Decode(&resp)
+	return rr, resp
+}
+
+func TestExecute_IndependentForwardsHeadersAndKeepsGoing(t *testing.T) {
+	var seen http.Header
+	h := New(map[string]Operation{
+		dto.BatchOpCreate: {Path: "/pullRequest/create", Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = r.Header.Clone()
+			created(w, r)
+		})},
+		dto.BatchOpMerge: {Path: "/pullRequest/merge", Handler: failing(domain.ErrPullRequestAlreadyMerged)},
+	}, &txStub{}, 10, newTestLogger())
+
+	rr, resp := execute(h, `{"mode":"independent","operations":[
+		{"op":"merge","body":{"pull_request_id":"pr-1"}},
+		{"op":"create","idempotency_key":"item-1","if_match":"\"3\"","body":{"pull_request_id":"pr-2"}}]}`)
+
+	if rr.Code != http.StatusOK || resp.Committed || len(resp.Results) != 2 {
+		t.Fatalf("unexpected batch %d %+v", rr.Code, resp)
+	}
+	if r := resp.Results[0]; r.Status != http.StatusConflict || !strings.Contains(string(r.Body), httperror.CodePRMerged) {
+		t.Fatalf("unexpected merge result %+v", r)
+	}
+	if r := resp.Results[1]; r.Status != http.StatusCreated || r.ETag != `"1"` || string(r.Body) != `{"pull_request_id":"pr-2"}` {
+		t.Fatalf("unexpected create result %+v", r)
+	}
+	if seen.Get("Idempotency-Key") != "item-1" || seen.Get("If-Match") != `"3"` {
+		t.Fatalf("item headers not forwarded: %v", seen)
+	}
+}
+
+func TestExecute_AtomicRollsBackOnFirstFailure(t *testing.T) {
+	tx := &txStub{}
+	calls := 0
+	h := New(map[string]Operation{
+		dto.BatchOpCreate: {Path: "/pullRequest/create", Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			created(w, r)
+		})},
+		dto.BatchOpSetIsActive: {Path: "/users/setIsActive", Handler: failing(domain.ErrPermissionDenied)},
+	}, tx, 10, newTestLogger())
+
+	rr, resp := execute(h, `{"mode":"atomic","operations":[
+		{"op":"create","body":{}},
+		{"op":"setIsActive","body":{}},
+		{"op":"create","body":{}}]}`)
+
+	if rr.Code != http.StatusOK || resp.Committed || calls != 1 {
+		t.Fatalf("unexpected batch %d %+v, calls %d", rr.Code, resp, calls)
+	}
+	if tx.opts.Isolation != service.IsolationSerializable {
+		t.Fatalf("atomic batch must run serializable, got %+v", tx.opts)
+	}
+	if tx.savepoints != 2 {
+		t.Fatalf("each executed operation must run under its own savepoint, got %d", tx.savepoints)
+	}
+	want := []struct {
+		status int
+		code   string
+	}{
+		{http.StatusFailedDependency, httperror.CodeRolledBack},
+		{http.StatusForbidden, httperror.CodeForbidden},
+		{http.StatusFailedDependency, httperror.CodeNotExecuted},
+	}
+	for i, w := range want {
+		var body dto.ErrorResponse
+		_ = json.Unmarshal(resp.Results[i].Body, &body)
+		if resp.Results[i].Status != w.status || body.Error.Code != w.code {
+			t.Fatalf("result %d: got %d %s, want %d %s", i, resp.Results[i].Status, body.Error.Code, w.status, w.code)
+		}
+	}
+}
+
+func TestExecute_AtomicRetriesSerializationFailure(t *testing.T) {
+	tx := &txStub{}
+	h := New(map[string]Operation{
+		dto.BatchOpReassign: {Path: "/pullRequest/reassign", Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tx.attempts == 1 {
+				failing(errConflict)(w, r)
+				return
+			}
+			created(w, r)
+		})},
+	}, tx, 10, newTestLogger())
+
+	rr, resp := execute(h, `{"mode":"atomic","operations":[{"op":"reassign","body":{}}]}`)
+	if rr.Code != http.StatusOK || !resp.Committed || tx.attempts != 2 || resp.Results[0].Status != http.StatusCreated {
+		t.Fatalf("expected committed retry, got %d %+v after %d attempts", rr.Code, resp, tx.attempts)
+	}
+}
+
+func TestExecute_ServerErrorIsNotCacheable(t *testing.T) {
+	h := New(map[string]Operation{
+		dto.BatchOpMerge: {Path: "/pullRequest/merge", Handler: failing(errors.New("boom"))},
+	}, &txStub{}, 10, newTestLogger())
+
+	rr, resp := execute(h, `{"mode":"independent","operations":[{"op":"merge","body":{}}]}`)
+	if rr.Code != http.StatusInternalServerError || len(resp.Results) != 1 || resp.Results[0].Status != http.StatusInternalServerError {
+		t.Fatalf("expected 500 with results, got %d %+v", rr.Code, resp)
+	}
+}
+
+func TestExecute_RejectsInvalidBatch(t *testing.T) {
+	h := New(map[string]Operation{}, &txStub{}, 1, newTestLogger())
+	cases := []string{
+		`{`,
+		`{"mode":"parallel","operations":[{"op":"create","body":{}}]}`,
+		`{"mode":"atomic","operations":[]}`,
+		`{"mode":"atomic","operations":[{"op":"delete","body":{}}]}`,
+		`{"mode":"atomic","operations":[{"op":"create","body":{}},{"op":"create","body":{}}]}`,
+	}
+	for _, body := range cases {
+		if rr, _ := execute(h, body); rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package idempotencyhandler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	"github.com/mashhkensss/PR-service/internal/http/httperror"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	"github.com/mashhkensss/PR-service/internal/http/response"
+	idempotencyservice "github.com/mashhkensss/PR-service/internal/service/idempotency"
+)
+
+type Handler interface {
+	Get(w http.ResponseWriter, r *http.Request)
+	Delete(w http.ResponseWriter, r *http.Request)
+}
+
+type handler struct {
+	service idempotencyservice.Service
+	logger  *slog.Logger
+}
+
+func New(service idempotencyservice.Service, logger *slog.Logger) Handler {
+	return &handler{service: service, logger: logger}
+}
+
+func (h *handler) Get(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	subject, key := q.Get("subject"), q.Get("key")
+	if key == "" {
+		status, resp := httperror.InvalidRequest("key is required")
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+		return
+	}
+	records, err := h.service.Inspect(r.Context(), mw.RequesterFromContext(r.Context()), subject, key)
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "subject", subject)...)
+		return
+	}
+	resp := struct {
+		Records []dto.IdempotencyRecord `json:"records"`
+	}{
+		Records: make([]dto.IdempotencyRecord, 0, len(records)),
+	}
+	for _, rec := range records {
+		resp.Records = append(resp.Records, dto.IdempotencyRecordFromDomain(rec))
+	}
+	response.JSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) Delete(w http.ResponseWriter, r *http.Request) {
+	var payload dto.DeleteIdempotencyKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		status, resp := httperror.InvalidRequest("invalid JSON payload")
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+		return
+	}
+	if validator, ok := mw.ValidatorFromContext(r.Context()); ok {
+		if err := validator.ValidateStruct(payload); err != nil {
+			status, resp := httperror.Validation(err)
+			httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+			return
+		}
+	}
+
+	deleted, err := h.service.Delete(r.Context(), mw.RequesterFromContext(r.Context()), payload.Subject, payload.Key)
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "subject", payload.Subject)...)
+		return
+	}
+	h.logger.Info("idempotency key deleted", logFields(r, "subject", payload.Subject, "deleted", deleted)...)
+
+	resp := struct {
+		Deleted int64 `json:"deleted"`
+	}{
+		Deleted: deleted,
+	}
+	response.JSON(w, http.StatusOK, resp)
+}
+
+func logFields(r *http.Request, extra ...any) []any {
+	fields := []any{"method", r.Method, "path", r.URL.Path}
+	if claims, ok := mw.ClaimsFromContext(r.Context()); ok && claims.Subject != "" {
+		fields = append(fields, "user_id", claims.Subject)
+	}
+	return append(fields, extra...)
+}
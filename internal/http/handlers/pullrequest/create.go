@@ -15,25 +15,25 @@ func (h *handler) CreatePullRequest(w http.ResponseWriter, r *http.Request) {
 	var payload dto.CreatePullRequestRequest
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		status, resp := httperror.InvalidRequest("invalid JSON payload")
-		httperror.Write(w, status, resp, h.logger, logFields(r)...)
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
 		return
 	}
 	if validator, ok := mw.ValidatorFromContext(r.Context()); ok {
 		if err := validator.ValidateStruct(payload); err != nil {
-			status, resp := httperror.InvalidRequest(err.Error())
-			httperror.Write(w, status, resp, h.logger, logFields(r)...)
+			status, resp := httperror.Validation(err)
+			httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
 			return
 		}
 	}
 	pr, err := payload.ToDomain(time.Now())
 	if err != nil {
 		status, resp := httperror.InvalidRequest(err.Error())
-		httperror.Write(w, status, resp, h.logger, logFields(r)...)
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
 		return
 	}
-	created, err := h.service.Create(r.Context(), pr)
+	created, err := h.service.Create(r.Context(), mw.RequesterFromContext(r.Context()), pr)
 	if err != nil {
-		httperror.Respond(w, err, h.logger, logFields(r, "pull_request_id", payload.PullRequestID)...)
+		httperror.Respond(w, r, err, h.logger, logFields(r, "pull_request_id", payload.PullRequestID)...)
 		return
 	}
 	resp := struct {
@@ -41,5 +41,6 @@ func (h *handler) CreatePullRequest(w http.ResponseWriter, r *http.Request) {
 	}{
 		PR: dto.PullRequestFromDomain(created),
 	}
+	w.Header().Set("ETag", response.ETag(created.Version()))
 	response.JSON(w, http.StatusCreated, resp)
 }
@@ -12,6 +12,7 @@ type Handler interface {
 	CreatePullRequest(w http.ResponseWriter, r *http.Request)
 	MergePullRequest(w http.ResponseWriter, r *http.Request)
 	ReassignReviewer(w http.ResponseWriter, r *http.Request)
+	SubmitReview(w http.ResponseWriter, r *http.Request)
 }
 
 type handler struct {
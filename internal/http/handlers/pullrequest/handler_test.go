@@ -10,8 +10,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mashhkensss/PR-service/internal/auth"
 	"github.com/mashhkensss/PR-service/internal/domain"
 	domainpr "github.com/mashhkensss/PR-service/internal/domain/pullrequest"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
 	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
 )
 
@@ -19,23 +21,24 @@ type prServiceMock struct {
 	createFn   func(ctx context.Context, pr domainpr.PullRequest) (domainpr.PullRequest, error)
 	mergeFn    func(ctx context.Context, id domain.PullRequestID, ts time.Time) (domainpr.PullRequest, error)
 	reassignFn func(ctx context.Context, id domain.PullRequestID, old domain.UserID) (domainpr.PullRequest, domain.UserID, error)
+	reviewFn   func(ctx context.Context, actor requester.Requester, id domain.PullRequestID, reviewer domain.UserID, verdict domain.ReviewVerdict, ts time.Time) (domainpr.PullRequest, error)
 }
 
-func (m prServiceMock) Create(ctx context.Context, pr domainpr.PullRequest) (domainpr.PullRequest, error) {
+func (m prServiceMock) Create(ctx context.Context, actor requester.Requester, pr domainpr.PullRequest) (domainpr.PullRequest, error) {
 	if m.createFn != nil {
 		return m.createFn(ctx, pr)
 	}
 	return pr, nil
 }
 
-func (m prServiceMock) Merge(ctx context.Context, id domain.PullRequestID, ts time.Time) (domainpr.PullRequest, error) {
+func (m prServiceMock) Merge(ctx context.Context, actor requester.Requester, id domain.PullRequestID, ts time.Time, _ domain.Version) (domainpr.PullRequest, error) {
 	if m.mergeFn != nil {
 		return m.mergeFn(ctx, id, ts)
 	}
 	return domainpr.New(id, "name", "author", ts)
 }
 
-func (m prServiceMock) Reassign(ctx context.Context, id domain.PullRequestID, old domain.UserID) (domainpr.PullRequest, domain.UserID, error) {
+func (m prServiceMock) Reassign(ctx context.Context, actor requester.Requester, id domain.PullRequestID, old domain.UserID, _ domain.Version) (domainpr.PullRequest, domain.UserID, error) {
 	if m.reassignFn != nil {
 		return m.reassignFn(ctx, id, old)
 	}
@@ -43,6 +46,18 @@ func (m prServiceMock) Reassign(ctx context.Context, id domain.PullRequestID, ol
 	return pr, "new", nil
 }
 
+func (m prServiceMock) AddReviewer(ctx context.Context, id domain.PullRequestID) (domainpr.PullRequest, domain.UserID, error) {
+	pr, _ := domainpr.New(id, "name", "author", time.Now())
+	return pr, "new", nil
+}
+
+func (m prServiceMock) SubmitReview(ctx context.Context, actor requester.Requester, id domain.PullRequestID, reviewer domain.UserID, verdict domain.ReviewVerdict, ts time.Time, _ domain.Version) (domainpr.PullRequest, error) {
+	if m.reviewFn != nil {
+		return m.reviewFn(ctx, actor, id, reviewer, verdict, ts)
+	}
+	return domainpr.New(id, "name", "author", ts)
+}
+
 func prTestLogger() *slog.Logger {
 	return slog.New(slog.NewTextHandler(io.Discard, nil))
 }
@@ -111,6 +126,29 @@ func TestMergePullRequest_Success(t *testing.T) {
 	if rr.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", rr.Code)
 	}
+	if etag := rr.Header().Get("ETag"); etag != `"1"` {
+		t.Fatalf("unexpected ETag %q", etag)
+	}
+}
+
+func TestMergePullRequest_WeakIfMatch(t *testing.T) {
+	called := false
+	h := &handler{
+		service: prServiceMock{
+			mergeFn: func(ctx context.Context, id domain.PullRequestID, ts time.Time) (domainpr.PullRequest, error) {
+				called = true
+				return domainpr.New(id, "name", "author", ts)
+			},
+		},
+		logger: prTestLogger(),
+	}
+	req := httptest.NewRequest(http.MethodPost, "/pullRequest/merge", strings.NewReader(`{"pull_request_id":"pr-1"}`))
+	req.Header.Set("If-Match", `W/"1"`)
+	rr := httptest.NewRecorder()
+	mw.NewValidatorMiddleware(mw.NewTagValidator())(http.HandlerFunc(h.MergePullRequest)).ServeHTTP(rr, req)
+	if rr.Code != http.StatusPreconditionFailed || called {
+		t.Fatalf("weak tag must fail the precondition without a write, got %d called=%v", rr.Code, called)
+	}
 }
 
 func TestMergePullRequest_InvalidJSON(t *testing.T) {
@@ -159,3 +197,58 @@ func TestReassignReviewer_ServiceError(t *testing.T) {
 		t.Fatalf("expected 409, got %d", rr.Code)
 	}
 }
+
+func TestSubmitReview_ForbiddenForOtherUser(t *testing.T) {
+	h := &handler{
+		service: prServiceMock{
+			reviewFn: func(ctx context.Context, actor requester.Requester, id domain.PullRequestID, reviewer domain.UserID, verdict domain.ReviewVerdict, ts time.Time) (domainpr.PullRequest, error) {
+				if !actor.CanReview(reviewer) {
+					return domainpr.PullRequest{}, domain.ErrPermissionDenied
+				}
+				return domainpr.PullRequest{}, nil
+			},
+		},
+		logger: prTestLogger(),
+	}
+	body := `{"pull_request_id":"pr-1","reviewer_id":"rev1"}`
+	req := httptest.NewRequest(http.MethodPost, "/pullRequest/review", strings.NewReader(body))
+	authz := newAuthorization()
+	req.Header.Set("Authorization", "Bearer "+newToken("rev2", "user"))
+	rr := httptest.NewRecorder()
+	authz.RequireAuthenticated(mw.NewValidatorMiddleware(mw.NewTagValidator())(http.HandlerFunc(h.SubmitReview))).ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+}
+
+func TestSubmitReview_NotAssigned(t *testing.T) {
+	h := &handler{
+		service: prServiceMock{
+			reviewFn: func(ctx context.Context, actor requester.Requester, id domain.PullRequestID, reviewer domain.UserID, verdict domain.ReviewVerdict, ts time.Time) (domainpr.PullRequest, error) {
+				return domainpr.PullRequest{}, domain.ErrReviewerNotAssigned
+			},
+		},
+		logger: prTestLogger(),
+	}
+	body := `{"pull_request_id":"pr-1","reviewer_id":"rev1"}`
+	req := httptest.NewRequest(http.MethodPost, "/pullRequest/review", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	mw.NewValidatorMiddleware(mw.NewTagValidator())(http.HandlerFunc(h.SubmitReview)).ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+}
+
+var testKeys = auth.StaticKeys{
+	auth.HMACKey("", []byte("admin-secret"), auth.RoleAdmin),
+	auth.HMACKey("", []byte("user-secret"), auth.RoleUser),
+}
+
+func newAuthorization() *mw.Authorization {
+	return mw.NewAuthorization(auth.NewVerifier(auth.VerifierOptions{}, testKeys), nil)
+}
+
+func newToken(subject, role string) string {
+	token, _, _ := auth.NewSigner(auth.SignerOptions{}, testKeys...).Issue(auth.TokenRequest{Subject: subject, Role: role, TTL: time.Hour})
+	return token
+}
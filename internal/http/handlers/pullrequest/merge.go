@@ -16,19 +16,24 @@ func (h *handler) MergePullRequest(w http.ResponseWriter, r *http.Request) {
 	var payload dto.MergePullRequestRequest
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		status, resp := httperror.InvalidRequest("invalid JSON payload")
-		httperror.Write(w, status, resp, h.logger, logFields(r)...)
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
 		return
 	}
 	if validator, ok := mw.ValidatorFromContext(r.Context()); ok {
 		if err := validator.ValidateStruct(payload); err != nil {
-			status, resp := httperror.InvalidRequest(err.Error())
-			httperror.Write(w, status, resp, h.logger, logFields(r)...)
+			status, resp := httperror.Validation(err)
+			httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
 			return
 		}
 	}
-	merged, err := h.service.Merge(r.Context(), domain.PullRequestID(payload.PullRequestID), time.Now())
+	expected, err := response.IfMatch(r)
 	if err != nil {
-		httperror.Respond(w, err, h.logger, logFields(r, "pull_request_id", payload.PullRequestID)...)
+		httperror.Respond(w, r, err, h.logger, logFields(r, "pull_request_id", payload.PullRequestID)...)
+		return
+	}
+	merged, err := h.service.Merge(r.Context(), mw.RequesterFromContext(r.Context()), domain.PullRequestID(payload.PullRequestID), time.Now(), expected)
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "pull_request_id", payload.PullRequestID)...)
 		return
 	}
 	resp := struct {
@@ -36,5 +41,6 @@ func (h *handler) MergePullRequest(w http.ResponseWriter, r *http.Request) {
 	}{
 		PR: dto.PullRequestFromDomain(merged),
 	}
+	w.Header().Set("ETag", response.ETag(merged.Version()))
 	response.JSON(w, http.StatusOK, resp)
 }
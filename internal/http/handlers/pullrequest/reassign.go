@@ -15,19 +15,24 @@ func (h *handler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 	var payload dto.ReassignReviewerRequest
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		status, resp := httperror.InvalidRequest("invalid JSON payload")
-		httperror.Write(w, status, resp, h.logger, logFields(r)...)
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
 		return
 	}
 	if validator, ok := mw.ValidatorFromContext(r.Context()); ok {
 		if err := validator.ValidateStruct(payload); err != nil {
-			status, resp := httperror.InvalidRequest(err.Error())
-			httperror.Write(w, status, resp, h.logger, logFields(r)...)
+			status, resp := httperror.Validation(err)
+			httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
 			return
 		}
 	}
-	pr, replacement, err := h.service.Reassign(r.Context(), domain.PullRequestID(payload.PullRequestID), domain.UserID(payload.OldUserID))
+	expected, err := response.IfMatch(r)
 	if err != nil {
-		httperror.Respond(w, err, h.logger, logFields(r, "pull_request_id", payload.PullRequestID, "old_reviewer_id", payload.OldUserID)...)
+		httperror.Respond(w, r, err, h.logger, logFields(r, "pull_request_id", payload.PullRequestID)...)
+		return
+	}
+	pr, replacement, err := h.service.Reassign(r.Context(), mw.RequesterFromContext(r.Context()), domain.PullRequestID(payload.PullRequestID), domain.UserID(payload.OldUserID), expected)
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "pull_request_id", payload.PullRequestID, "old_reviewer_id", payload.OldUserID)...)
 		return
 	}
 	resp := struct {
@@ -37,5 +42,6 @@ func (h *handler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 		PullRequest: dto.PullRequestFromDomain(pr),
 		ReplacedBy:  string(replacement),
 	}
+	w.Header().Set("ETag", response.ETag(pr.Version()))
 	response.JSON(w, http.StatusOK, resp)
 }
@@ -0,0 +1,52 @@
+package pullrequesthandler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	"github.com/mashhkensss/PR-service/internal/http/httperror"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	"github.com/mashhkensss/PR-service/internal/http/response"
+)
+
+func (h *handler) SubmitReview(w http.ResponseWriter, r *http.Request) {
+	var payload dto.SubmitReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		status, resp := httperror.InvalidRequest("invalid JSON payload")
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+		return
+	}
+	if validator, ok := mw.ValidatorFromContext(r.Context()); ok {
+		if err := validator.ValidateStruct(payload); err != nil {
+			status, resp := httperror.Validation(err)
+			httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+			return
+		}
+	}
+	verdict := domain.ReviewVerdict(payload.Verdict)
+	if verdict == "" {
+		verdict = domain.ReviewVerdictCommented
+	}
+	expected, err := response.IfMatch(r)
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "pull_request_id", payload.PullRequestID)...)
+		return
+	}
+	pr, err := h.service.SubmitReview(r.Context(), mw.RequesterFromContext(r.Context()), domain.PullRequestID(payload.PullRequestID), domain.UserID(payload.ReviewerID), verdict, time.Now(), expected)
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "pull_request_id", payload.PullRequestID, "reviewer_id", payload.ReviewerID)...)
+		return
+	}
+	resp := struct {
+		PR      dto.PullRequest `json:"pr"`
+		Verdict string          `json:"verdict"`
+	}{
+		PR:      dto.PullRequestFromDomain(pr),
+		Verdict: string(verdict),
+	}
+	w.Header().Set("ETag", response.ETag(pr.Version()))
+	response.JSON(w, http.StatusOK, resp)
+}
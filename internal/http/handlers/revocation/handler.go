@@ -0,0 +1,92 @@
+package revocationhandler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain/revocation"
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	"github.com/mashhkensss/PR-service/internal/http/httperror"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	"github.com/mashhkensss/PR-service/internal/http/response"
+	revocationservice "github.com/mashhkensss/PR-service/internal/service/revocation"
+)
+
+type Handler interface {
+	Revoke(w http.ResponseWriter, r *http.Request)
+	List(w http.ResponseWriter, r *http.Request)
+}
+
+type handler struct {
+	service revocationservice.Service
+	logger  *slog.Logger
+}
+
+func New(service revocationservice.Service, logger *slog.Logger) Handler {
+	return &handler{service: service, logger: logger}
+}
+
+func (h *handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	var payload dto.RevokeTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		status, resp := httperror.InvalidRequest("invalid JSON payload")
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+		return
+	}
+	if validator, ok := mw.ValidatorFromContext(r.Context()); ok {
+		if err := validator.ValidateStruct(payload); err != nil {
+			status, resp := httperror.Validation(err)
+			httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+			return
+		}
+	}
+	kind, value := revocation.KindSubject, payload.Subject
+	if payload.JTI != "" {
+		kind, value = revocation.KindToken, payload.JTI
+	}
+	var expiresAt time.Time
+	if payload.ExpiresAt != nil {
+		expiresAt = *payload.ExpiresAt
+	}
+
+	rev, err := h.service.Revoke(r.Context(), mw.RequesterFromContext(r.Context()), kind, value, expiresAt, time.Now())
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "kind", kind, "value", value)...)
+		return
+	}
+	h.logger.Info("tokens revoked", logFields(r, "kind", kind, "value", value)...)
+
+	resp := struct {
+		Revocation dto.TokenRevocation `json:"revocation"`
+	}{
+		Revocation: dto.TokenRevocationFromDomain(rev),
+	}
+	response.JSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) List(w http.ResponseWriter, r *http.Request) {
+	revs, err := h.service.List(r.Context(), mw.RequesterFromContext(r.Context()))
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r)...)
+		return
+	}
+	resp := struct {
+		Revocations []dto.TokenRevocation `json:"revocations"`
+	}{
+		Revocations: make([]dto.TokenRevocation, 0, len(revs)),
+	}
+	for _, rev := range revs {
+		resp.Revocations = append(resp.Revocations, dto.TokenRevocationFromDomain(rev))
+	}
+	response.JSON(w, http.StatusOK, resp)
+}
+
+func logFields(r *http.Request, extra ...any) []any {
+	fields := []any{"method", r.Method, "path", r.URL.Path}
+	if claims, ok := mw.ClaimsFromContext(r.Context()); ok && claims.Subject != "" {
+		fields = append(fields, "user_id", claims.Subject)
+	}
+	return append(fields, extra...)
+}
@@ -0,0 +1,117 @@
+package slahandler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	"github.com/mashhkensss/PR-service/internal/http/httperror"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	"github.com/mashhkensss/PR-service/internal/http/response"
+	slaservice "github.com/mashhkensss/PR-service/internal/service/sla"
+)
+
+type Handler interface {
+	SetPolicy(w http.ResponseWriter, r *http.Request)
+	GetPolicy(w http.ResponseWriter, r *http.Request)
+	ListBreaches(w http.ResponseWriter, r *http.Request)
+}
+
+type handler struct {
+	service slaservice.Service
+	logger  *slog.Logger
+}
+
+func New(service slaservice.Service, logger *slog.Logger) Handler {
+	return &handler{service: service, logger: logger}
+}
+
+func (h *handler) SetPolicy(w http.ResponseWriter, r *http.Request) {
+	var payload dto.SLAPolicy
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		status, resp := httperror.InvalidRequest("invalid JSON payload")
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+		return
+	}
+	if validator, ok := mw.ValidatorFromContext(r.Context()); ok {
+		if err := validator.ValidateStruct(payload); err != nil {
+			status, resp := httperror.Validation(err)
+			httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+			return
+		}
+	}
+	policy, err := payload.ToDomain()
+	if err != nil {
+		status, resp := httperror.InvalidRequest(err.Error())
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+		return
+	}
+	saved, err := h.service.SetPolicy(r.Context(), mw.RequesterFromContext(r.Context()), policy)
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "team_name", payload.TeamName)...)
+		return
+	}
+	resp := struct {
+		Policy dto.SLAPolicy `json:"policy"`
+	}{
+		Policy: dto.SLAPolicyFromDomain(saved),
+	}
+	response.JSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("team_name")
+	if name == "" {
+		status, resp := httperror.InvalidRequest("team_name is required")
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+		return
+	}
+	policy, err := h.service.GetPolicy(r.Context(), mw.RequesterFromContext(r.Context()), domain.TeamName(name))
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "team_name", name)...)
+		return
+	}
+	resp := struct {
+		Policy dto.SLAPolicy `json:"policy"`
+	}{
+		Policy: dto.SLAPolicyFromDomain(policy),
+	}
+	response.JSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) ListBreaches(w http.ResponseWriter, r *http.Request) {
+	team := r.URL.Query().Get("team_name")
+	breaches, err := h.service.ListBreaches(r.Context(), mw.RequesterFromContext(r.Context()), domain.TeamName(team), time.Now().UTC())
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r)...)
+		return
+	}
+	resp := struct {
+		Breaches []dto.SLABreach `json:"breaches"`
+	}{
+		Breaches: make([]dto.SLABreach, 0, len(breaches)),
+	}
+	for _, b := range breaches {
+		resp.Breaches = append(resp.Breaches, dto.SLABreach{
+			PullRequestID: string(b.PullRequestID),
+			ReviewerID:    string(b.ReviewerID),
+			TeamName:      string(b.TeamName),
+			AssignedAt:    b.AssignedAt,
+			Deadline:      b.Deadline,
+			Escalation:    string(b.Escalation),
+			EscalatedAt:   b.EscalatedAt,
+		})
+	}
+	response.JSON(w, http.StatusOK, resp)
+}
+
+func logFields(r *http.Request, extra ...any) []any {
+	fields := []any{"method", r.Method, "path", r.URL.Path}
+	if claims, ok := mw.ClaimsFromContext(r.Context()); ok && claims.Subject != "" {
+		fields = append(fields, "user_id", claims.Subject)
+	}
+	return append(fields, extra...)
+}
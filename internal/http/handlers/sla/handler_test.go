@@ -0,0 +1,137 @@
+package slahandler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	domainsla "github.com/mashhkensss/PR-service/internal/domain/sla"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	slaservice "github.com/mashhkensss/PR-service/internal/service/sla"
+)
+
+type slaServiceMock struct {
+	setFn      func(ctx context.Context, p domainsla.Policy) (domainsla.Policy, error)
+	getFn      func(ctx context.Context, team domain.TeamName) (domainsla.Policy, error)
+	breachesFn func(ctx context.Context, team domain.TeamName, now time.Time) ([]slaservice.Breach, error)
+}
+
+func (m slaServiceMock) SetPolicy(ctx context.Context, actor requester.Requester, p domainsla.Policy) (domainsla.Policy, error) {
+	if m.setFn != nil {
+		return m.setFn(ctx, p)
+	}
+	return p, nil
+}
+
+func (m slaServiceMock) GetPolicy(ctx context.Context, actor requester.Requester, team domain.TeamName) (domainsla.Policy, error) {
+	if m.getFn != nil {
+		return m.getFn(ctx, team)
+	}
+	return domainsla.New(team, time.Hour, true, domainsla.EscalationEvent)
+}
+
+func (m slaServiceMock) ListBreaches(ctx context.Context, actor requester.Requester, team domain.TeamName, now time.Time) ([]slaservice.Breach, error) {
+	if m.breachesFn != nil {
+		return m.breachesFn(ctx, team, now)
+	}
+	return nil, nil
+}
+
+func (m slaServiceMock) EscalateBreaches(ctx context.Context, now time.Time) ([]slaservice.Escalation, error) {
+	return nil, nil
+}
+
+func slaTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestSetPolicy_Success(t *testing.T) {
+	var saved domainsla.Policy
+	h := &handler{
+		service: slaServiceMock{
+			setFn: func(ctx context.Context, p domainsla.Policy) (domainsla.Policy, error) {
+				saved = p
+				return p, nil
+			},
+		},
+		logger: slaTestLogger(),
+	}
+	body := `{"team_name":"backend","review_within":"8h","escalation":"REASSIGN"}`
+	req := httptest.NewRequest(http.MethodPost, "/sla/policy", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	mw.NewValidatorMiddleware(mw.NewTagValidator())(http.HandlerFunc(h.SetPolicy)).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if saved.ReviewWithin() != 8*time.Hour || !saved.BusinessDaysOnly() {
+		t.Fatalf("unexpected saved policy %+v", saved)
+	}
+}
+
+func TestSetPolicy_InvalidDuration(t *testing.T) {
+	h := &handler{service: slaServiceMock{}, logger: slaTestLogger()}
+	body := `{"team_name":"backend","review_within":"tomorrow","escalation":"EVENT"}`
+	req := httptest.NewRequest(http.MethodPost, "/sla/policy", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	mw.NewValidatorMiddleware(mw.NewTagValidator())(http.HandlerFunc(h.SetPolicy)).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestGetPolicy_NotFound(t *testing.T) {
+	h := &handler{
+		service: slaServiceMock{
+			getFn: func(ctx context.Context, team domain.TeamName) (domainsla.Policy, error) {
+				return domainsla.Policy{}, sql.ErrNoRows
+			},
+		},
+		logger: slaTestLogger(),
+	}
+	req := httptest.NewRequest(http.MethodGet, "/sla/policy?team_name=ghost", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.GetPolicy).ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
+
+func TestListBreaches(t *testing.T) {
+	h := &handler{
+		service: slaServiceMock{
+			breachesFn: func(ctx context.Context, team domain.TeamName, now time.Time) ([]slaservice.Breach, error) {
+				if team != "backend" {
+					t.Fatalf("expected team filter, got %q", team)
+				}
+				return []slaservice.Breach{{PullRequestID: "pr-1", ReviewerID: "rev1", TeamName: "backend", Escalation: domainsla.EscalationEvent}}, nil
+			},
+		},
+		logger: slaTestLogger(),
+	}
+	req := httptest.NewRequest(http.MethodGet, "/sla/breaches?team_name=backend", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.ListBreaches).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var resp struct {
+		Breaches []struct {
+			PullRequestID string `json:"pull_request_id"`
+		} `json:"breaches"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Breaches) != 1 || resp.Breaches[0].PullRequestID != "pr-1" {
+		t.Fatalf("unexpected breaches %+v", resp.Breaches)
+	}
+}
@@ -3,7 +3,11 @@ package statshandler
 import (
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/stats"
 	"github.com/mashhkensss/PR-service/internal/http/httperror"
 	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
 	"github.com/mashhkensss/PR-service/internal/http/response"
@@ -13,6 +17,8 @@ import (
 type Handler interface {
 	GetAssignmentStats(w http.ResponseWriter, r *http.Request)
 	GetSummary(w http.ResponseWriter, r *http.Request)
+	GetCycleTime(w http.ResponseWriter, r *http.Request)
+	GetFairness(w http.ResponseWriter, r *http.Request)
 }
 
 type handler struct {
@@ -25,34 +31,93 @@ func New(service statsservice.Service, logger *slog.Logger) Handler {
 }
 
 func (h *handler) GetAssignmentStats(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.service.GetAssignments(r.Context())
+	filter, ok := h.parseFilter(w, r)
+	if !ok {
+		return
+	}
+	result, err := h.service.GetAssignments(r.Context(), mw.RequesterFromContext(r.Context()), filter)
 	if err != nil {
-		httperror.Respond(w, err, h.logger, logFields(r)...)
+		httperror.Respond(w, r, err, h.logger, logFields(r)...)
 		return
 	}
-	response.JSON(w, http.StatusOK, stats)
+	response.JSON(w, http.StatusOK, result)
 }
 
 func (h *handler) GetSummary(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.service.GetAssignments(r.Context())
+	filter, ok := h.parseFilter(w, r)
+	if !ok {
+		return
+	}
+	summary, err := h.service.GetSummary(r.Context(), mw.RequesterFromContext(r.Context()), filter)
 	if err != nil {
-		httperror.Respond(w, err, h.logger, logFields(r)...)
+		httperror.Respond(w, r, err, h.logger, logFields(r)...)
 		return
 	}
-	totalAssignments := 0
-	for _, count := range stats.ByUser {
-		totalAssignments += count
+	response.JSON(w, http.StatusOK, summary)
+}
+
+func (h *handler) GetCycleTime(w http.ResponseWriter, r *http.Request) {
+	filter, ok := h.parseFilter(w, r)
+	if !ok {
+		return
 	}
-	summary := struct {
-		UsersCount        int `json:"users_count"`
-		PullRequestsCount int `json:"pull_requests_count"`
-		AssignmentsTotal  int `json:"assignments_total"`
-	}{
-		UsersCount:        len(stats.ByUser),
-		PullRequestsCount: len(stats.ByPullRequest),
-		AssignmentsTotal:  totalAssignments,
+	report, err := h.service.GetCycleTime(r.Context(), mw.RequesterFromContext(r.Context()), filter)
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r)...)
+		return
 	}
-	response.JSON(w, http.StatusOK, summary)
+	response.JSON(w, http.StatusOK, report)
+}
+
+func (h *handler) GetFairness(w http.ResponseWriter, r *http.Request) {
+	filter, ok := h.parseFilter(w, r)
+	if !ok {
+		return
+	}
+	report, err := h.service.GetFairness(r.Context(), mw.RequesterFromContext(r.Context()), filter)
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "team", filter.Team)...)
+		return
+	}
+	response.JSON(w, http.StatusOK, report)
+}
+
+func (h *handler) parseFilter(w http.ResponseWriter, r *http.Request) (stats.Filter, bool) {
+	q := r.URL.Query()
+	filter := stats.Filter{
+		Team:   domain.TeamName(q.Get("team")),
+		Status: domain.PullRequestStatus(strings.ToUpper(q.Get("status"))),
+	}
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		raw := q.Get(p.name)
+		if raw == "" {
+			continue
+		}
+		ts, dateOnly, err := parseTime(raw)
+		if err != nil {
+			status, resp := httperror.InvalidRequest(p.name + " must be RFC3339 or YYYY-MM-DD")
+			httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+			return stats.Filter{}, false
+		}
+		// окно не включает to; дата без времени означает весь день, поэтому граница сдвигается на следующий
+		if dateOnly && p.name == "to" {
+			ts = ts.AddDate(0, 0, 1)
+		}
+		*p.dst = &ts
+	}
+	return filter, true
+}
+
+// parseTime сообщает, задана ли только дата
+func parseTime(raw string) (time.Time, bool, error) {
+	if ts, err := time.Parse(time.RFC3339, raw); err == nil {
+		return ts.UTC(), false, nil
+	}
+	ts, err := time.Parse(time.DateOnly, raw)
+	return ts, true, err
 }
 
 func logFields(r *http.Request, extra ...any) []any {
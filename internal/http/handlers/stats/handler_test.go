@@ -2,34 +2,63 @@ package statshandler
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/stats"
 	statsservice "github.com/mashhkensss/PR-service/internal/service/stats"
 )
 
 type statsServiceMock struct {
-	getFn func(ctx context.Context) (statsservice.AssignmentsStats, error)
+	getFn     func(ctx context.Context, f stats.Filter) (statsservice.AssignmentsStats, error)
+	summaryFn func(ctx context.Context, f stats.Filter) (statsservice.Summary, error)
+	cycleFn   func(ctx context.Context, f stats.Filter) (statsservice.CycleTimeReport, error)
+	fairFn    func(ctx context.Context, f stats.Filter) (statsservice.FairnessReport, error)
 }
 
-func (m statsServiceMock) GetAssignments(ctx context.Context) (statsservice.AssignmentsStats, error) {
+func (m statsServiceMock) GetAssignments(ctx context.Context, actor requester.Requester, f stats.Filter) (statsservice.AssignmentsStats, error) {
 	if m.getFn != nil {
-		return m.getFn(ctx)
+		return m.getFn(ctx, f)
 	}
 	return statsservice.AssignmentsStats{}, nil
 }
 
+func (m statsServiceMock) GetSummary(ctx context.Context, actor requester.Requester, f stats.Filter) (statsservice.Summary, error) {
+	if m.summaryFn != nil {
+		return m.summaryFn(ctx, f)
+	}
+	return statsservice.Summary{}, nil
+}
+
+func (m statsServiceMock) GetCycleTime(ctx context.Context, actor requester.Requester, f stats.Filter) (statsservice.CycleTimeReport, error) {
+	if m.cycleFn != nil {
+		return m.cycleFn(ctx, f)
+	}
+	return statsservice.CycleTimeReport{}, nil
+}
+
+func (m statsServiceMock) GetFairness(ctx context.Context, actor requester.Requester, f stats.Filter) (statsservice.FairnessReport, error) {
+	if m.fairFn != nil {
+		return m.fairFn(ctx, f)
+	}
+	return statsservice.FairnessReport{}, nil
+}
+
 func statsTestLogger() *slog.Logger {
 	return slog.New(slog.NewTextHandler(io.Discard, nil))
 }
 
 func TestGetAssignmentStats_Success(t *testing.T) {
 	svc := statsServiceMock{
-		getFn: func(ctx context.Context) (statsservice.AssignmentsStats, error) {
+		getFn: func(ctx context.Context, f stats.Filter) (statsservice.AssignmentsStats, error) {
 			return statsservice.AssignmentsStats{ByUser: map[string]int{"u1": 1}}, nil
 		},
 	}
@@ -42,9 +71,69 @@ func TestGetAssignmentStats_Success(t *testing.T) {
 	}
 }
 
+func TestGetAssignmentStats_Filters(t *testing.T) {
+	var got stats.Filter
+	svc := statsServiceMock{
+		getFn: func(ctx context.Context, f stats.Filter) (statsservice.AssignmentsStats, error) {
+			got = f
+			return statsservice.AssignmentsStats{}, nil
+		},
+	}
+	h := &handler{service: svc, logger: statsTestLogger()}
+	req := httptest.NewRequest(http.MethodGet, "/stats/assignments?from=2025-01-01&to=2025-02-01T00:00:00Z&team=backend&status=open", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.GetAssignmentStats).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if got.From == nil || !got.From.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected from %v", got.From)
+	}
+	if got.To == nil || !got.To.Equal(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected to %v", got.To)
+	}
+	if got.Team != "backend" || got.Status != domain.PullRequestStatusOpen {
+		t.Fatalf("unexpected filter %+v", got)
+	}
+}
+
+func TestGetAssignmentStats_DateOnlyToIncludesDay(t *testing.T) {
+	var got stats.Filter
+	svc := statsServiceMock{
+		getFn: func(ctx context.Context, f stats.Filter) (statsservice.AssignmentsStats, error) {
+			got = f
+			return statsservice.AssignmentsStats{}, nil
+		},
+	}
+	h := &handler{service: svc, logger: statsTestLogger()}
+	req := httptest.NewRequest(http.MethodGet, "/stats/assignments?from=2025-03-01&to=2025-03-31", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.GetAssignmentStats).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if got.From == nil || !got.From.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected from %v", got.From)
+	}
+	// 31 марта входит в окно: граница — начало 1 апреля
+	if got.To == nil || !got.To.Equal(time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected to %v", got.To)
+	}
+}
+
+func TestGetAssignmentStats_InvalidFrom(t *testing.T) {
+	h := &handler{service: statsServiceMock{}, logger: statsTestLogger()}
+	req := httptest.NewRequest(http.MethodGet, "/stats/assignments?from=yesterday", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.GetAssignmentStats).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
 func TestGetAssignmentStats_Error(t *testing.T) {
 	svc := statsServiceMock{
-		getFn: func(ctx context.Context) (statsservice.AssignmentsStats, error) {
+		getFn: func(ctx context.Context, f stats.Filter) (statsservice.AssignmentsStats, error) {
 			return statsservice.AssignmentsStats{}, errors.New("boom")
 		},
 	}
@@ -59,11 +148,8 @@ func TestGetAssignmentStats_Error(t *testing.T) {
 
 func TestGetSummary(t *testing.T) {
 	svc := statsServiceMock{
-		getFn: func(ctx context.Context) (statsservice.AssignmentsStats, error) {
-			return statsservice.AssignmentsStats{
-				ByUser:        map[string]int{"u1": 2, "u2": 1},
-				ByPullRequest: map[string]int{"pr-1": 2},
-			}, nil
+		summaryFn: func(ctx context.Context, f stats.Filter) (statsservice.Summary, error) {
+			return statsservice.Summary{UsersCount: 2, PullRequestsCount: 1, AssignmentsTotal: 2}, nil
 		},
 	}
 	h := &handler{service: svc, logger: statsTestLogger()}
@@ -74,3 +160,53 @@ func TestGetSummary(t *testing.T) {
 		t.Fatalf("expected 200, got %d", rr.Code)
 	}
 }
+
+func TestGetSummary_InvalidFilter(t *testing.T) {
+	svc := statsServiceMock{
+		summaryFn: func(ctx context.Context, f stats.Filter) (statsservice.Summary, error) {
+			return statsservice.Summary{}, f.Validate()
+		},
+	}
+	h := &handler{service: svc, logger: statsTestLogger()}
+	req := httptest.NewRequest(http.MethodGet, "/stats/summary?status=CLOSED", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.GetSummary).ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestGetCycleTime(t *testing.T) {
+	svc := statsServiceMock{
+		cycleFn: func(ctx context.Context, f stats.Filter) (statsservice.CycleTimeReport, error) {
+			if f.Team != "backend" {
+				t.Fatalf("expected team filter, got %+v", f)
+			}
+			return statsservice.CycleTimeReport{
+				CreateToMerge: statsservice.DurationBreakdown{Overall: statsservice.DurationStats{Count: 1, P50Seconds: 60}},
+			}, nil
+		},
+	}
+	h := &handler{service: svc, logger: statsTestLogger()}
+	req := httptest.NewRequest(http.MethodGet, "/stats/cycle-time?team=backend", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.GetCycleTime).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+}
+
+func TestGetFairness_TeamNotFound(t *testing.T) {
+	svc := statsServiceMock{
+		fairFn: func(ctx context.Context, f stats.Filter) (statsservice.FairnessReport, error) {
+			return statsservice.FairnessReport{}, sql.ErrNoRows
+		},
+	}
+	h := &handler{service: svc, logger: statsTestLogger()}
+	req := httptest.NewRequest(http.MethodGet, "/stats/fairness?team=ghost", nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.GetFairness).ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,135 @@
+package streamhandler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	"github.com/mashhkensss/PR-service/internal/http/httperror"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	streamservice "github.com/mashhkensss/PR-service/internal/service/stream"
+	"github.com/mashhkensss/PR-service/internal/stream"
+)
+
+// retryMillis — через сколько EventSource переподключается после обрыва
+const retryMillis = 3000
+
+type Handler interface {
+	Stream(w http.ResponseWriter, r *http.Request)
+}
+
+type handler struct {
+	service   streamservice.Service
+	heartbeat time.Duration
+	logger    *slog.Logger
+}
+
+func New(service streamservice.Service, heartbeat time.Duration, logger *slog.Logger) Handler {
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+	return &handler{service: service, heartbeat: heartbeat, logger: logger}
+}
+
+// Stream отдаёт назначения как Server-Sent Events. Комментарий-пинг раз в heartbeat не даёт
+// прокси закрыть простаивающее соединение; после обрыва клиент продолжает с Last-Event-ID
+func (h *handler) Stream(w http.ResponseWriter, r *http.Request) {
+	lastID, err := lastEventID(r)
+	if err != nil {
+		status, resp := httperror.InvalidRequest("Last-Event-ID must be a non-negative integer")
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+		return
+	}
+	team := domain.TeamName(r.URL.Query().Get("team_name"))
+	sub, err := h.service.Subscribe(r.Context(), mw.RequesterFromContext(r.Context()), team, lastID)
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "team_name", team)...)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// таймауты сервера рассчитаны на обычные запросы и оборвали бы поток
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", retryMillis)
+	if err := rc.Flush(); err != nil {
+		h.logger.Error("stream flush is not supported", logFields(r, "error", err)...)
+		return
+	}
+
+	// при остановке сервера поток закрывается, клиент переподключится к другой реплике
+	streamCtx, stop := stream.Draining(r.Context())
+	defer stop()
+	for {
+		ctx, cancel := context.WithTimeout(streamCtx, h.heartbeat)
+		entries, err := sub.Next(ctx)
+		cancel()
+		switch {
+		case streamCtx.Err() != nil:
+			return
+		case errors.Is(err, context.DeadlineExceeded):
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		case err != nil:
+			// клиент переподключится и продолжит с последнего полученного id
+			h.logger.Error("stream read failed", logFields(r, "error", err, "last_event_id", sub.Cursor())...)
+			return
+		default:
+			err = writeEntries(w, entries)
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func writeEntries(w http.ResponseWriter, entries []stream.Entry) error {
+	for _, e := range entries {
+		data, err := json.Marshal(dto.AssignmentEventFromEntry(e))
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: assignment\ndata: %s\n\n", e.ID, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lastEventID берётся из заголовка, который EventSource шлёт при переподключении,
+// или из query для клиентов, которые не умеют ставить заголовки
+func lastEventID(r *http.Request) (int64, error) {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	if raw == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id < 0 {
+		return 0, errors.New("invalid last event id")
+	}
+	return id, nil
+}
+
+func logFields(r *http.Request, extra ...any) []any {
+	fields := []any{"method", r.Method, "path", r.URL.Path}
+	if claims, ok := mw.ClaimsFromContext(r.Context()); ok && claims.Subject != "" {
+		fields = append(fields, "user_id", claims.Subject)
+	}
+	return append(fields, extra...)
+}
@@ -0,0 +1,140 @@
+package streamhandler
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/stream"
+)
+
+type memoryLog struct {
+	entries []stream.Entry
+}
+
+func (l *memoryLog) Append(context.Context, ...stream.Entry) error { return nil }
+
+func (l *memoryLog) After(_ context.Context, _ stream.Filter, after stream.Position, limit int) ([]stream.Entry, error) {
+	var res []stream.Entry
+	for _, e := range l.entries {
+		if e.ID > after.ID && len(res) < limit {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+func (l *memoryLog) Head(context.Context) (stream.Position, error) {
+	n := int64(len(l.entries))
+	return stream.Position{XID: n, ID: n}, nil
+}
+
+func (l *memoryLog) Locate(_ context.Context, id int64) (stream.Position, error) {
+	return stream.Position{XID: id, ID: id}, nil
+}
+
+func (l *memoryLog) Purge(context.Context, time.Time, int) (int64, error) { return 0, nil }
+
+type streamServiceMock struct {
+	broker *stream.Broker
+	err    error
+	gotID  int64
+}
+
+func (m *streamServiceMock) Subscribe(_ context.Context, _ requester.Requester, _ domain.TeamName, lastEventID int64) (*stream.Subscription, error) {
+	m.gotID = lastEventID
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.broker.Subscribe(stream.Filter{}, stream.Position{XID: lastEventID, ID: lastEventID}, 10), nil
+}
+
+func streamTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestStreamResumesFromLastEventID(t *testing.T) {
+	at := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	log := &memoryLog{entries: []stream.Entry{
+		{ID: 1, Kind: stream.KindAssigned, PullRequestID: "pr-1", ReviewerID: "u1", OccurredAt: at},
+		{ID: 2, Kind: stream.KindMerged, PullRequestID: "pr-1", ReviewerID: "u1", OccurredAt: at},
+	}}
+	svc := &streamServiceMock{broker: stream.NewBroker(log, time.Hour, 0, nil)}
+	srv := httptest.NewServer(http.HandlerFunc(New(svc, 20*time.Millisecond, streamTestLogger()).Stream))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if scanner.Text() == ": ping" {
+			break
+		}
+	}
+	out := strings.Join(lines, "\n")
+	if svc.gotID != 1 || !strings.Contains(out, "id: 2\nevent: assignment\ndata: {\"kind\":\"MERGED\"") {
+		t.Fatalf("unexpected stream:\n%s", out)
+	}
+	if strings.Contains(out, "id: 1\n") {
+		t.Fatalf("already delivered entry must not be repeated:\n%s", out)
+	}
+}
+
+func TestStreamRejectsInvalidLastEventID(t *testing.T) {
+	h := New(&streamServiceMock{}, time.Second, streamTestLogger())
+	req := httptest.NewRequest(http.MethodGet, "/events/stream?last_event_id=abc", nil)
+	rr := httptest.NewRecorder()
+	h.Stream(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestStreamMapsPermissionError(t *testing.T) {
+	h := New(&streamServiceMock{err: domain.ErrPermissionDenied}, time.Second, streamTestLogger())
+	req := httptest.NewRequest(http.MethodGet, "/events/stream?team_name=backend", nil)
+	rr := httptest.NewRecorder()
+	h.Stream(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+}
+
+func TestStreamClosesOnDrain(t *testing.T) {
+	svc := &streamServiceMock{broker: stream.NewBroker(&memoryLog{}, time.Hour, 0, nil)}
+	drain := make(chan struct{})
+	req := httptest.NewRequest(http.MethodGet, "/events/stream", nil)
+	req = req.WithContext(stream.WithDrain(req.Context(), drain))
+	done := make(chan struct{})
+	go func() {
+		New(svc, time.Hour, streamTestLogger()).Stream(httptest.NewRecorder(), req)
+		close(done)
+	}()
+	close(drain)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("stream must stop when the server drains")
+	}
+}
@@ -14,25 +14,25 @@ func (h *handler) AddTeam(w http.ResponseWriter, r *http.Request) {
 	var payload dto.Team
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		status, resp := httperror.InvalidRequest("invalid JSON payload")
-		httperror.Write(w, status, resp, h.logger, logFields(r)...)
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
 		return
 	}
 	if validator, ok := mw.ValidatorFromContext(r.Context()); ok {
 		if err := validator.ValidateStruct(payload); err != nil {
-			status, resp := httperror.InvalidRequest(err.Error())
-			httperror.Write(w, status, resp, h.logger, logFields(r)...)
+			status, resp := httperror.Validation(err)
+			httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
 			return
 		}
 	}
 	teamAggregate, err := payload.ToDomain()
 	if err != nil {
 		status, resp := httperror.InvalidRequest(err.Error())
-		httperror.Write(w, status, resp, h.logger, logFields(r)...)
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
 		return
 	}
-	created, err := h.service.AddTeam(r.Context(), teamAggregate)
+	created, err := h.service.AddTeam(r.Context(), mw.RequesterFromContext(r.Context()), teamAggregate)
 	if err != nil {
-		httperror.Respond(w, err, h.logger, logFields(r)...)
+		httperror.Respond(w, r, err, h.logger, logFields(r)...)
 		return
 	}
 	resp := struct {
@@ -40,5 +40,6 @@ func (h *handler) AddTeam(w http.ResponseWriter, r *http.Request) {
 	}{
 		Team: dto.TeamFromDomain(created),
 	}
+	w.Header().Set("ETag", response.ETag(created.Version()))
 	response.JSON(w, http.StatusCreated, resp)
 }
@@ -4,7 +4,6 @@ import (
 	"net/http"
 
 	"github.com/mashhkensss/PR-service/internal/domain"
-	"github.com/mashhkensss/PR-service/internal/domain/requester"
 	"github.com/mashhkensss/PR-service/internal/http/dto"
 	"github.com/mashhkensss/PR-service/internal/http/httperror"
 	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
@@ -15,20 +14,18 @@ func (h *handler) GetTeam(w http.ResponseWriter, r *http.Request) {
 	name := r.URL.Query().Get("team_name")
 	if name == "" {
 		status, resp := httperror.InvalidRequest("team_name is required")
-		httperror.Write(w, status, resp, h.logger, logFields(r)...)
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
 		return
 	}
 
-	actor := requester.Anonymous()
-	if claims, ok := mw.ClaimsFromContext(r.Context()); ok {
-		actor = requester.New(domain.UserID(claims.Subject), claims.Role == "admin")
-	}
-
-	teamAggregate, err := h.service.GetTeamForUser(r.Context(), actor, domain.TeamName(name))
+	teamAggregate, err := h.service.GetTeamForUser(r.Context(), mw.RequesterFromContext(r.Context()), domain.TeamName(name))
 	if err != nil {
-		httperror.Respond(w, err, h.logger, logFields(r)...)
+		httperror.Respond(w, r, err, h.logger, logFields(r)...)
 		return
 	}
 
+	if response.NotModified(w, r, response.ETag(teamAggregate.Version())) {
+		return
+	}
 	response.JSON(w, http.StatusOK, dto.TeamFromDomain(teamAggregate))
 }
@@ -11,6 +11,8 @@ import (
 type Handler interface {
 	AddTeam(w http.ResponseWriter, r *http.Request)
 	GetTeam(w http.ResponseWriter, r *http.Request)
+	AddMember(w http.ResponseWriter, r *http.Request)
+	RemoveMember(w http.ResponseWriter, r *http.Request)
 }
 
 type handler struct {
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -22,9 +23,25 @@ type teamServiceMock struct {
 	addFn func(ctx context.Context, t domainteam.Team) (domainteam.Team, error)
 	getFn func(ctx context.Context, name domain.TeamName) (domainteam.Team, error)
 	forFn func(ctx context.Context, actor requester.Requester, name domain.TeamName) (domainteam.Team, error)
+	memFn func(ctx context.Context, member domainuser.User, expected domain.Version) (domainteam.Team, error)
+	remFn func(ctx context.Context, name domain.TeamName, id domain.UserID, expected domain.Version) (domainteam.Team, error)
 }
 
-func (m teamServiceMock) AddTeam(ctx context.Context, t domainteam.Team) (domainteam.Team, error) {
+func (m teamServiceMock) AddMember(ctx context.Context, actor requester.Requester, member domainuser.User, expected domain.Version) (domainteam.Team, error) {
+	if m.memFn != nil {
+		return m.memFn(ctx, member, expected)
+	}
+	return domainteam.New(member.TeamName(), []domainuser.User{member})
+}
+
+func (m teamServiceMock) RemoveMember(ctx context.Context, actor requester.Requester, name domain.TeamName, id domain.UserID, expected domain.Version) (domainteam.Team, error) {
+	if m.remFn != nil {
+		return m.remFn(ctx, name, id, expected)
+	}
+	return domainteam.New(name, nil)
+}
+
+func (m teamServiceMock) AddTeam(ctx context.Context, actor requester.Requester, t domainteam.Team) (domainteam.Team, error) {
 	if m.addFn != nil {
 		return m.addFn(ctx, t)
 	}
@@ -92,6 +109,27 @@ func TestAddTeam_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestAddTeam_ValidationProblem(t *testing.T) {
+	h := &handler{service: teamServiceMock{}, logger: newTestLogger()}
+	body := `{"team_name":"backend","members":[{"user_id":"u1","username":"Alice"},{"username":"Bob"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/team/add", strings.NewReader(body))
+	req.Header.Set("Accept", "application/problem+json")
+	rr := httptest.NewRecorder()
+	mw.NewValidatorMiddleware(mw.NewTagValidator())(http.HandlerFunc(h.AddTeam)).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest || rr.Header().Get("Content-Type") != "application/problem+json" {
+		t.Fatalf("expected 400 problem+json, got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	var problem dto.Problem
+	if err := json.NewDecoder(rr.Body).Decode(&problem); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	want := []dto.FieldViolation{{Field: "members[1].user_id", Rule: "required", Message: "is required"}}
+	if problem.Code != "INVALID_REQUEST" || problem.Status != http.StatusBadRequest || !reflect.DeepEqual(problem.Errors, want) {
+		t.Fatalf("unexpected problem %+v", problem)
+	}
+}
+
 func TestAddTeam_ServiceError(t *testing.T) {
 	h := &handler{
 		service: teamServiceMock{
@@ -128,6 +166,33 @@ func TestGetTeam_Success(t *testing.T) {
 	}
 }
 
+func TestGetTeam_NotModified(t *testing.T) {
+	teamAgg, _ := domainteam.New("backend", nil)
+	teamAgg = teamAgg.WithVersion(5)
+	h := &handler{
+		service: teamServiceMock{
+			forFn: func(ctx context.Context, actor requester.Requester, name domain.TeamName) (domainteam.Team, error) {
+				return teamAgg, nil
+			},
+		},
+		logger: newTestLogger(),
+	}
+	send := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/team/get?team_name=backend", nil)
+		req.Header.Set("If-None-Match", ifNoneMatch)
+		rr := httptest.NewRecorder()
+		http.HandlerFunc(h.GetTeam).ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := send(`"5"`); rr.Code != http.StatusNotModified || rr.Body.Len() != 0 || rr.Header().Get("ETag") != `"5"` {
+		t.Fatalf("expected empty 304 with ETag, got %d %q", rr.Code, rr.Body.String())
+	}
+	if rr := send(`"4"`); rr.Code != http.StatusOK || rr.Header().Get("ETag") != `"5"` {
+		t.Fatalf("stale tag must get a fresh body, got %d", rr.Code)
+	}
+}
+
 func TestGetTeam_MissingName(t *testing.T) {
 	h := &handler{service: teamServiceMock{}, logger: newTestLogger()}
 	req := httptest.NewRequest(http.MethodGet, "/team/get", nil)
@@ -154,3 +219,45 @@ func TestGetTeam_AccessDenied(t *testing.T) {
 		t.Fatalf("expected 403, got %d", rr.Code)
 	}
 }
+
+func TestAddMember_PassesIfMatch(t *testing.T) {
+	var gotVersion domain.Version
+	h := &handler{
+		service: teamServiceMock{
+			memFn: func(ctx context.Context, member domainuser.User, expected domain.Version) (domainteam.Team, error) {
+				gotVersion = expected
+				aggregate, err := domainteam.New(member.TeamName(), []domainuser.User{member})
+				return aggregate.WithVersion(expected.Next()), err
+			},
+		},
+		logger: newTestLogger(),
+	}
+	body := `{"team_name":"backend","member":{"user_id":"u4","username":"Dave","is_active":true}}`
+	req := httptest.NewRequest(http.MethodPost, "/team/addMember", strings.NewReader(body))
+	req.Header.Set("If-Match", `"3"`)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.AddMember).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if gotVersion != 3 || rr.Header().Get("ETag") != `"4"` {
+		t.Fatalf("expected version 3 and ETag \"4\", got %d %q", gotVersion, rr.Header().Get("ETag"))
+	}
+}
+
+func TestRemoveMember_HasHistory(t *testing.T) {
+	h := &handler{
+		service: teamServiceMock{
+			remFn: func(ctx context.Context, name domain.TeamName, id domain.UserID, expected domain.Version) (domainteam.Team, error) {
+				return domainteam.Team{}, domain.ErrMemberHasHistory
+			},
+		},
+		logger: newTestLogger(),
+	}
+	req := httptest.NewRequest(http.MethodPost, "/team/removeMember", strings.NewReader(`{"team_name":"backend","user_id":"u2"}`))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(h.RemoveMember).ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", rr.Code)
+	}
+}
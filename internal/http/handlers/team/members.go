@@ -0,0 +1,82 @@
+package teamhandler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	"github.com/mashhkensss/PR-service/internal/http/httperror"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	"github.com/mashhkensss/PR-service/internal/http/response"
+)
+
+func (h *handler) AddMember(w http.ResponseWriter, r *http.Request) {
+	var payload dto.AddTeamMemberRequest
+	if !h.decode(w, r, &payload) {
+		return
+	}
+	member, err := payload.ToDomain()
+	if err != nil {
+		status, resp := httperror.InvalidRequest(err.Error())
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+		return
+	}
+	expected, err := response.IfMatch(r)
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r)...)
+		return
+	}
+	updated, err := h.service.AddMember(r.Context(), mw.RequesterFromContext(r.Context()), member, expected)
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "team_name", payload.TeamName)...)
+		return
+	}
+	writeTeam(w, updated)
+}
+
+func (h *handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	var payload dto.RemoveTeamMemberRequest
+	if !h.decode(w, r, &payload) {
+		return
+	}
+	expected, err := response.IfMatch(r)
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r)...)
+		return
+	}
+	updated, err := h.service.RemoveMember(r.Context(), mw.RequesterFromContext(r.Context()),
+		domain.TeamName(payload.TeamName), domain.UserID(payload.UserID), expected)
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "team_name", payload.TeamName)...)
+		return
+	}
+	writeTeam(w, updated)
+}
+
+func (h *handler) decode(w http.ResponseWriter, r *http.Request, payload any) bool {
+	if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+		status, resp := httperror.InvalidRequest("invalid JSON payload")
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+		return false
+	}
+	if validator, ok := mw.ValidatorFromContext(r.Context()); ok {
+		if err := validator.ValidateStruct(payload); err != nil {
+			status, resp := httperror.Validation(err)
+			httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+			return false
+		}
+	}
+	return true
+}
+
+func writeTeam(w http.ResponseWriter, t domainteam.Team) {
+	resp := struct {
+		Team dto.Team `json:"team"`
+	}{
+		Team: dto.TeamFromDomain(t),
+	}
+	w.Header().Set("ETag", response.ETag(t.Version()))
+	response.JSON(w, http.StatusOK, resp)
+}
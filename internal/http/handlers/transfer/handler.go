@@ -0,0 +1,425 @@
+package transferhandler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
+	"github.com/mashhkensss/PR-service/internal/domain/transfer"
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	"github.com/mashhkensss/PR-service/internal/http/httperror"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	"github.com/mashhkensss/PR-service/internal/http/response"
+	transferservice "github.com/mashhkensss/PR-service/internal/service/transfer"
+)
+
+// flushEvery — сколько записей экспорта копится в буфере ответа между сбросами клиенту
+const flushEvery = 100
+
+type Handler interface {
+	Export(w http.ResponseWriter, r *http.Request)
+	Import(w http.ResponseWriter, r *http.Request)
+}
+
+type handler struct {
+	service  transferservice.Service
+	maxBytes int64
+	logger   *slog.Logger
+}
+
+func New(service transferservice.Service, maxBytes int, logger *slog.Logger) Handler {
+	return &handler{service: service, maxBytes: int64(maxBytes), logger: logger}
+}
+
+// Export отдаёт данные потоком по мере чтения из базы. Ошибка после начала ответа
+// обрывает соединение, чтобы клиент не принял неполную выгрузку за целую
+func (h *handler) Export(w http.ResponseWriter, r *http.Request) {
+	ndjson, err := exportFormat(r)
+	if err != nil {
+		status, resp := httperror.InvalidRequest(err.Error())
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// выгрузка большой базы не укладывается в таймаут записи обычного запроса
+	_ = rc.SetWriteDeadline(time.Time{})
+	sink := &exportSink{w: w, rc: rc, ndjson: ndjson}
+	err = h.service.Export(r.Context(), mw.RequesterFromContext(r.Context()), sink)
+	if err == nil {
+		err = sink.close()
+	}
+	if err == nil {
+		return
+	}
+	if !sink.started {
+		httperror.Respond(w, r, err, h.logger, logFields(r)...)
+		return
+	}
+	h.logger.Error("export aborted", logFields(r, "teams", sink.teams, "pull_requests", sink.pullRequests, "error", err)...)
+	panic(http.ErrAbortHandler)
+}
+
+func exportFormat(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get("format") {
+	case "ndjson":
+		return true, nil
+	case "json":
+		return false, nil
+	case "":
+		return strings.Contains(r.Header.Get("Accept"), dto.NDJSONContentType), nil
+	default:
+		return false, errors.New("format must be json or ndjson")
+	}
+}
+
+// exportSink пишет заголовки ответа только с первой записью: до неё ошибку сервиса
+// ещё можно отдать обычным ответом
+type exportSink struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	ndjson  bool
+	started bool
+
+	teams        int
+	pullRequests int
+}
+
+func (s *exportSink) Team(t domainteam.Team) error {
+	team := dto.TeamFromDomain(t)
+	if err := s.write(transfer.KindTeam, dto.TransferTeamRecord{Type: string(transfer.KindTeam), Team: team}, team); err != nil {
+		return err
+	}
+	s.teams++
+	return nil
+}
+
+func (s *exportSink) PullRequest(pr transfer.PullRequest) error {
+	item := dto.TransferPullRequestFromDomain(pr)
+	record := dto.TransferPullRequestRecord{Type: string(transfer.KindPullRequest), TransferPullRequest: item}
+	if err := s.write(transfer.KindPullRequest, record, item); err != nil {
+		return err
+	}
+	s.pullRequests++
+	return nil
+}
+
+func (s *exportSink) start() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+	if s.ndjson {
+		s.w.Header().Set("Content-Type", dto.NDJSONContentType)
+	} else {
+		s.w.Header().Set("Content-Type", "application/json")
+	}
+	s.w.WriteHeader(http.StatusOK)
+	if s.ndjson {
+		return nil
+	}
+	_, err := io.WriteString(s.w, `{"teams":[`)
+	return err
+}
+
+// write для JSON кладёт item в массив своего раздела: команды приходят раньше PR,
+// поэтому массив teams закрывается на первом PR
+func (s *exportSink) write(kind transfer.Kind, record, item any) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if s.ndjson {
+		if err := json.NewEncoder(&buf).Encode(record); err != nil {
+			return err
+		}
+	} else {
+		if kind == transfer.KindPullRequest && s.pullRequests == 0 {
+			buf.WriteString(`],"pull_requests":[`)
+		} else if s.teams+s.pullRequests > 0 {
+			buf.WriteByte(',')
+		}
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+	if _, err := s.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if (s.teams+s.pullRequests+1)%flushEvery == 0 {
+		// без Flush ответ всё равно уйдёт целиком, только позже
+		if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *exportSink) close() error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	if !s.ndjson {
+		tail := "]}\n"
+		if s.pullRequests == 0 {
+			tail = `],"pull_requests":[]}` + "\n"
+		}
+		if _, err := io.WriteString(s.w, tail); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import разбирает все записи до обращения к сервису. Если хотя бы одна запись не прошла
+// проверку, отвечает отчётом об ошибках и ничего не записывает
+func (h *handler) Import(w http.ResponseWriter, r *http.Request) {
+	mode := transfer.Mode(r.URL.Query().Get("mode"))
+	if mode == "" {
+		mode = transfer.ModeFail
+	}
+	if !mode.Valid() {
+		status, resp := httperror.InvalidRequest("mode must be fail, skip or upsert")
+		httperror.Write(w, r, status, resp, h.logger, logFields(r, "mode", mode)...)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// загрузка и транзакция импорта дольше таймаутов обычного запроса, размер ограничен maxBytes
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+	body := http.MaxBytesReader(w, r.Body, h.maxBytes)
+
+	p := newParser(r)
+	var err error
+	if isNDJSON(r.Header.Get("Content-Type")) {
+		err = p.ndjson(body)
+	} else {
+		err = p.document(body)
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		status, resp := httperror.InvalidRequest(err.Error())
+		if errors.As(err, &tooLarge) {
+			status, resp = http.StatusRequestEntityTooLarge, dto.NewErrorResponse(httperror.CodeInvalidInput, "request body too large")
+		}
+		httperror.Write(w, r, status, resp, h.logger, logFields(r, "mode", mode)...)
+		return
+	}
+
+	resp := dto.ImportResponse{Mode: string(mode), Errors: p.errors}
+	if len(p.errors) > 0 {
+		h.logger.Warn("import rejected", logFields(r, "mode", mode, "errors", len(p.errors))...)
+		response.JSON(w, http.StatusUnprocessableEntity, resp)
+		return
+	}
+
+	report, err := h.service.Import(r.Context(), mw.RequesterFromContext(r.Context()), p.data, mode)
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r, "mode", mode)...)
+		return
+	}
+	resp.Teams = importCounts(report.Teams)
+	resp.PullRequests = importCounts(report.PullRequests)
+	for _, e := range report.Errors {
+		resp.Errors = append(resp.Errors, p.reportError(e))
+	}
+	if len(resp.Errors) > 0 {
+		h.logger.Warn("import rejected", logFields(r, "mode", mode, "errors", len(resp.Errors))...)
+		response.JSON(w, http.StatusUnprocessableEntity, resp)
+		return
+	}
+	resp.Committed = true
+	response.JSON(w, http.StatusOK, resp)
+}
+
+func isNDJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == dto.NDJSONContentType
+}
+
+func importCounts(c transfer.Counts) dto.ImportCounts {
+	return dto.ImportCounts{Created: c.Created, Updated: c.Updated, Skipped: c.Skipped}
+}
+
+// parser собирает Dataset и ошибки записей. Сервис вызывается, только если ошибок разбора нет,
+// и тогда индекс записи в Dataset совпадает с её Index во входных данных
+type parser struct {
+	validator mw.Validator
+	data      transfer.Dataset
+	errors    []dto.ImportError
+	// seen — сколько записей каждого типа уже прочитано
+	seen map[string]int
+	// lines — строка NDJSON каждой записи Dataset, чтобы ошибки сервиса указывали на неё
+	lines map[transfer.Kind][]int
+}
+
+func newParser(r *http.Request) *parser {
+	validator, _ := mw.ValidatorFromContext(r.Context())
+	return &parser{
+		validator: validator,
+		errors:    []dto.ImportError{},
+		seen:      make(map[string]int),
+		lines:     make(map[transfer.Kind][]int),
+	}
+}
+
+func (p *parser) document(body io.Reader) error {
+	var doc dto.TransferDocument
+	if err := json.NewDecoder(body).Decode(&doc); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return err
+		}
+		return errors.New("invalid JSON payload")
+	}
+	for _, t := range doc.Teams {
+		p.team(t, 0)
+	}
+	for _, pr := range doc.PullRequests {
+		p.pullRequest(pr, 0)
+	}
+	return nil
+}
+
+func (p *parser) ndjson(body io.Reader) error {
+	reader := bufio.NewReader(body)
+	for line := 1; ; line++ {
+		raw, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if raw = bytes.TrimSpace(raw); len(raw) > 0 {
+			p.record(raw, line)
+		}
+		if err != nil {
+			return nil
+		}
+	}
+}
+
+func (p *parser) record(raw []byte, line int) {
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &head); err != nil {
+		p.reject(head.Type, p.next(head.Type), line, "", invalid("invalid JSON record"))
+		return
+	}
+	switch transfer.Kind(head.Type) {
+	case transfer.KindTeam:
+		var rec dto.TransferTeamRecord
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			p.reject(head.Type, p.next(head.Type), line, "", invalid("invalid team record"))
+			return
+		}
+		p.team(rec.Team, line)
+	case transfer.KindPullRequest:
+		var rec dto.TransferPullRequestRecord
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			p.reject(head.Type, p.next(head.Type), line, "", invalid("invalid pull request record"))
+			return
+		}
+		p.pullRequest(rec.TransferPullRequest, line)
+	default:
+		p.reject(head.Type, p.next(head.Type), line, "", invalid("type must be team or pull_request"))
+	}
+}
+
+func (p *parser) team(t dto.Team, line int) {
+	kind := string(transfer.KindTeam)
+	index := p.next(kind)
+	if e, ok := p.validate(t); !ok {
+		p.reject(kind, index, line, t.TeamName, e)
+		return
+	}
+	team, err := t.ToDomain()
+	if err != nil {
+		p.reject(kind, index, line, t.TeamName, recordError(err))
+		return
+	}
+	p.lines[transfer.KindTeam] = append(p.lines[transfer.KindTeam], line)
+	p.data.Teams = append(p.data.Teams, team)
+}
+
+func (p *parser) pullRequest(pr dto.TransferPullRequest, line int) {
+	kind := string(transfer.KindPullRequest)
+	index := p.next(kind)
+	if e, ok := p.validate(pr); !ok {
+		p.reject(kind, index, line, pr.PullRequestID, e)
+		return
+	}
+	record, err := pr.ToDomain()
+	if err != nil {
+		p.reject(kind, index, line, pr.PullRequestID, recordError(err))
+		return
+	}
+	p.lines[transfer.KindPullRequest] = append(p.lines[transfer.KindPullRequest], line)
+	p.data.PullRequests = append(p.data.PullRequests, record)
+}
+
+func (p *parser) next(kind string) int {
+	index := p.seen[kind]
+	p.seen[kind]++
+	return index
+}
+
+func (p *parser) validate(v any) (dto.ImportError, bool) {
+	if p.validator == nil {
+		return dto.ImportError{}, true
+	}
+	if err := p.validator.ValidateStruct(v); err != nil {
+		_, resp := httperror.Validation(err)
+		return dto.ImportError{Code: resp.Error.Code, Message: resp.Error.Message, Errors: resp.Violations}, false
+	}
+	return dto.ImportError{}, true
+}
+
+func (p *parser) reject(kind string, index, line int, id string, e dto.ImportError) {
+	e.Kind, e.Index, e.Line, e.ID = kind, index, line, id
+	p.errors = append(p.errors, e)
+}
+
+// reportError переводит ошибку из отчёта сервиса
+func (p *parser) reportError(e transfer.RecordError) dto.ImportError {
+	out := recordError(e.Err)
+	out.Kind, out.Index, out.ID = string(e.Kind), e.Index, e.ID
+	if lines := p.lines[e.Kind]; e.Index < len(lines) {
+		out.Line = lines[e.Index]
+	}
+	return out
+}
+
+func invalid(message string) dto.ImportError {
+	_, resp := httperror.InvalidRequest(message)
+	return dto.ImportError{Code: resp.Error.Code, Message: resp.Error.Message}
+}
+
+// recordError отдаёт текст доменной ошибки целиком: в нём есть подробности, например id
+// уже существующих пользователей. Текст внутренней ошибки не раскрывается
+func recordError(err error) dto.ImportError {
+	status, resp := httperror.FromError(err)
+	message := resp.Error.Message
+	if status < http.StatusInternalServerError {
+		message = err.Error()
+	}
+	return dto.ImportError{Code: resp.Error.Code, Message: message}
+}
+
+func logFields(r *http.Request, extra ...any) []any {
+	fields := []any{"method", r.Method, "path", r.URL.Path}
+	if claims, ok := mw.ClaimsFromContext(r.Context()); ok && claims.Subject != "" {
+		fields = append(fields, "user_id", claims.Subject)
+	}
+	return append(fields, extra...)
+}
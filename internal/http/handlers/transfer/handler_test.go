@@ -0,0 +1,187 @@
+package transferhandler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
+	"github.com/mashhkensss/PR-service/internal/domain/transfer"
+	domainuser "github.com/mashhkensss/PR-service/internal/domain/user"
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+	"github.com/mashhkensss/PR-service/internal/http/httperror"
+	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
+	transferservice "github.com/mashhkensss/PR-service/internal/service/transfer"
+)
+
+type serviceStub struct {
+	exportErr error
+	imported  *transfer.Dataset
+	mode      transfer.Mode
+	report    transfer.Report
+}
+
+func (s *serviceStub) Export(_ context.Context, _ requester.Requester, sink transferservice.Sink) error {
+	if s.exportErr != nil {
+		return s.exportErr
+	}
+	u, _ := domainuser.New("u1", "Alice", "backend", true)
+	team, _ := domainteam.New("backend", []domainuser.User{u})
+	if err := sink.Team(team); err != nil {
+		return err
+	}
+	merged := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	pr, _ := transfer.NewPullRequest("pr-1", "Fix", "u1", domain.PullRequestStatusMerged,
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), &merged, []transfer.Assignment{{ReviewerID: "u2", Verdict: domain.ReviewVerdictApproved}})
+	return sink.PullRequest(pr)
+}
+
+func (s *serviceStub) Import(_ context.Context, _ requester.Requester, data transfer.Dataset, mode transfer.Mode) (transfer.Report, error) {
+	s.imported, s.mode = &data, mode
+	return s.report, nil
+}
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func serve(fn http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	mw.NewValidatorMiddleware(mw.NewTagValidator())(fn).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestExport_JSONDocument(t *testing.T) {
+	h := New(&serviceStub{}, 1024, newTestLogger())
+	rr := serve(h.Export, httptest.NewRequest(http.MethodGet, "/admin/export", nil))
+
+	var doc dto.TransferDocument
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("unexpected export %d %q: %v", rr.Code, rr.Body.String(), err)
+	}
+	if len(doc.Teams) != 1 || len(doc.PullRequests) != 1 {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+	pr := doc.PullRequests[0]
+	if pr.Status != "MERGED" || pr.MergedAt == nil || len(pr.Reviewers) != 1 || pr.Reviewers[0].Verdict != "APPROVED" {
+		t.Fatalf("unexpected pull request %+v", pr)
+	}
+}
+
+func TestExport_NDJSONByAccept(t *testing.T) {
+	h := New(&serviceStub{}, 1024, newTestLogger())
+	req := httptest.NewRequest(http.MethodGet, "/admin/export", nil)
+	req.Header.Set("Accept", dto.NDJSONContentType)
+	rr := serve(h.Export, req)
+
+	if rr.Header().Get("Content-Type") != dto.NDJSONContentType {
+		t.Fatalf("unexpected content type %q", rr.Header().Get("Content-Type"))
+	}
+	var types []string
+	scanner := bufio.NewScanner(rr.Body)
+	for scanner.Scan() {
+		var head struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &head); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		types = append(types, head.Type)
+	}
+	if strings.Join(types, ",") != "team,pull_request" {
+		t.Fatalf("unexpected records %v", types)
+	}
+}
+
+func TestExport_ErrorBeforeFirstRecord(t *testing.T) {
+	h := New(&serviceStub{exportErr: domain.ErrPermissionDenied}, 1024, newTestLogger())
+	rr := serve(h.Export, httptest.NewRequest(http.MethodGet, "/admin/export?format=ndjson", nil))
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rr.Code)
+	}
+}
+
+func TestImport_RejectsInvalidRecordsWithoutService(t *testing.T) {
+	svc := &serviceStub{}
+	h := New(svc, 1024, newTestLogger())
+	body := `{"type":"team","team_name":"backend","members":[{"user_id":"u1","username":"Alice","is_active":true}]}
+
+{"type":"pull_request","pull_request_id":"pr-1","pull_request_name":"Fix","author_id":"u1","status":"OPEN","created_at":"2025-01-01T00:00:00Z","merged_at":"2025-01-02T00:00:00Z"}
+{"type":"user"}
+`
+	req := httptest.NewRequest(http.MethodPost, "/admin/import", strings.NewReader(body))
+	req.Header.Set("Content-Type", dto.NDJSONContentType+"; charset=utf-8")
+	rr := serve(h.Import, req)
+
+	var resp dto.ImportResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	if rr.Code != http.StatusUnprocessableEntity || resp.Committed || svc.imported != nil || len(resp.Errors) != 2 {
+		t.Fatalf("unexpected import %d %+v", rr.Code, resp)
+	}
+	if e := resp.Errors[0]; e.Kind != "pull_request" || e.Line != 3 || e.ID != "pr-1" || e.Code != httperror.CodeInvalidInput {
+		t.Fatalf("unexpected pull request error %+v", e)
+	}
+	if e := resp.Errors[1]; e.Kind != "user" || e.Line != 4 {
+		t.Fatalf("unexpected record error %+v", e)
+	}
+}
+
+func TestImport_ReportsServiceConflicts(t *testing.T) {
+	svc := &serviceStub{report: transfer.Report{
+		Teams:  transfer.Counts{Created: 1},
+		Errors: []transfer.RecordError{{Kind: transfer.KindTeam, Index: 1, ID: "frontend", Err: domain.ErrTeamExists}},
+	}}
+	h := New(svc, 1024, newTestLogger())
+	body := `{"teams":[{"team_name":"backend","members":[]},{"team_name":"frontend","members":[]}],"pull_requests":[]}`
+	rr := serve(h.Import, httptest.NewRequest(http.MethodPost, "/admin/import", strings.NewReader(body)))
+
+	var resp dto.ImportResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	if rr.Code != http.StatusUnprocessableEntity || resp.Committed || resp.Mode != "fail" || len(svc.imported.Teams) != 2 {
+		t.Fatalf("unexpected import %d %+v", rr.Code, resp)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Index != 1 || resp.Errors[0].Code != httperror.CodeTeamExists {
+		t.Fatalf("unexpected errors %+v", resp.Errors)
+	}
+}
+
+func TestImport_Committed(t *testing.T) {
+	svc := &serviceStub{report: transfer.Report{Teams: transfer.Counts{Updated: 1}}}
+	h := New(svc, 1024, newTestLogger())
+	body := `{"teams":[{"team_name":"backend","members":[]}]}`
+	rr := serve(h.Import, httptest.NewRequest(http.MethodPost, "/admin/import?mode=upsert", strings.NewReader(body)))
+
+	var resp dto.ImportResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	if rr.Code != http.StatusOK || !resp.Committed || resp.Teams.Updated != 1 || svc.mode != transfer.ModeUpsert {
+		t.Fatalf("unexpected import %d %+v", rr.Code, resp)
+	}
+}
+
+func TestImport_RejectsRequest(t *testing.T) {
+	h := New(&serviceStub{}, 16, newTestLogger())
+	cases := []struct {
+		target string
+		body   string
+		status int
+	}{
+		{"/admin/import?mode=replace", `{}`, http.StatusBadRequest},
+		{"/admin/import", `{"teams":`, http.StatusBadRequest},
+		{"/admin/import", `{"teams":[{"team_name":"backend","members":[]}]}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, c := range cases {
+		rr := serve(h.Import, httptest.NewRequest(http.MethodPost, c.target, strings.NewReader(c.body)))
+		if rr.Code != c.status {
+			t.Fatalf("%s %s: expected %d, got %d", c.target, c.body, c.status, rr.Code)
+		}
+	}
+}
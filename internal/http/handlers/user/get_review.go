@@ -2,6 +2,7 @@ package userhandler
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/mashhkensss/PR-service/internal/domain"
 	"github.com/mashhkensss/PR-service/internal/http/dto"
@@ -14,19 +15,19 @@ func (h *handler) GetReview(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
 		status, resp := httperror.InvalidRequest("user_id is required")
-		httperror.Write(w, status, resp, h.logger, logFields(r)...)
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
 		return
 	}
-	if claims, ok := mw.ClaimsFromContext(r.Context()); ok {
-		if claims.Role != "admin" && claims.Subject != userID {
-			status, resp := httperror.Forbidden("cannot view assignments of another user")
-			httperror.Write(w, status, resp, h.logger, logFields(r)...)
-			return
-		}
-	}
-	prs, err := h.service.GetReviewAssignments(r.Context(), domain.UserID(userID))
+	prs, err := h.service.GetReviewAssignments(r.Context(), mw.RequesterFromContext(r.Context()), domain.UserID(userID))
 	if err != nil {
-		httperror.Respond(w, err, h.logger, logFields(r, "reviewer_id", userID)...)
+		httperror.Respond(w, r, err, h.logger, logFields(r, "reviewer_id", userID)...)
+		return
+	}
+	tags := make([]string, 0, len(prs))
+	for _, pr := range prs {
+		tags = append(tags, string(pr.PullRequestID())+":"+strconv.FormatInt(int64(pr.Version()), 10))
+	}
+	if response.NotModified(w, r, response.WeakETag(tags...)) {
 		return
 	}
 	respPayload := struct {
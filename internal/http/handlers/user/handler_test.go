@@ -3,11 +3,7 @@ package userhandler
 import (
 	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
 	"encoding/json"
-	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
@@ -16,27 +12,30 @@ import (
 	"testing"
 	"time"
 
+	"github.com/mashhkensss/PR-service/internal/auth"
 	"github.com/mashhkensss/PR-service/internal/domain"
 	domainpr "github.com/mashhkensss/PR-service/internal/domain/pullrequest"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
 	domainuser "github.com/mashhkensss/PR-service/internal/domain/user"
 	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
 )
 
 type userServiceMock struct {
 	setFn  func(ctx context.Context, id domain.UserID, active bool) (domainuser.User, error)
-	listFn func(ctx context.Context, id domain.UserID) ([]domainpr.PullRequest, error)
+	listFn func(ctx context.Context, actor requester.Requester, id domain.UserID) ([]domainpr.PullRequest, error)
 }
 
-func (m userServiceMock) SetIsActive(ctx context.Context, id domain.UserID, active bool) (domainuser.User, error) {
+func (m userServiceMock) SetIsActive(ctx context.Context, actor requester.Requester, id domain.UserID, active bool, _ domain.Version) (domainuser.User, error) {
 	if m.setFn != nil {
 		return m.setFn(ctx, id, active)
 	}
 	return domainuser.New(id, "user", "team", active)
 }
 
-func (m userServiceMock) GetReviewAssignments(ctx context.Context, id domain.UserID) ([]domainpr.PullRequest, error) {
+func (m userServiceMock) GetReviewAssignments(ctx context.Context, actor requester.Requester, id domain.UserID) ([]domainpr.PullRequest, error) {
 	if m.listFn != nil {
-		return m.listFn(ctx, id)
+		return m.listFn(ctx, actor, id)
 	}
 	return nil, nil
 }
@@ -73,10 +72,10 @@ func TestSetIsActive_InvalidPayload(t *testing.T) {
 
 func TestSetIsActive_Unauthorized(t *testing.T) {
 	h := &handler{service: userServiceMock{}, logger: userTestLogger()}
-	auth := mw.NewAuthorization([]byte("admin-secret"), []byte("user-secret"))
+	authz := newAuthorization()
 	req := httptest.NewRequest(http.MethodPost, "/users/setIsActive", bytes.NewBufferString(`{}`))
 	rr := httptest.NewRecorder()
-	auth.RequireAdmin(http.HandlerFunc(h.SetIsActive)).ServeHTTP(rr, req)
+	authz.RequireAuthenticated(http.HandlerFunc(h.SetIsActive)).ServeHTTP(rr, req)
 	if rr.Code != http.StatusUnauthorized {
 		t.Fatalf("expected 401, got %d", rr.Code)
 	}
@@ -85,17 +84,17 @@ func TestSetIsActive_Unauthorized(t *testing.T) {
 func TestGetReview_Success(t *testing.T) {
 	pr, _ := domainpr.New("pr-1", "Feature", "author", time.Now())
 	svc := userServiceMock{
-		listFn: func(ctx context.Context, id domain.UserID) ([]domainpr.PullRequest, error) {
+		listFn: func(ctx context.Context, actor requester.Requester, id domain.UserID) ([]domainpr.PullRequest, error) {
 			return []domainpr.PullRequest{pr}, nil
 		},
 	}
 	h := &handler{service: svc, logger: userTestLogger()}
 
-	auth := mw.NewAuthorization([]byte("admin-secret"), []byte("user-secret"))
+	authz := newAuthorization()
 	req := httptest.NewRequest(http.MethodGet, "/users/getReview?user_id=u1", nil)
-	req.Header.Set("Authorization", "Bearer "+newToken([]byte("user-secret"), "u1", "user"))
+	req.Header.Set("Authorization", "Bearer "+newToken("u1", "user"))
 	rr := httptest.NewRecorder()
-	auth.RequireUserOrAdmin(http.HandlerFunc(h.GetReview)).ServeHTTP(rr, req)
+	authz.RequireAuthenticated(http.HandlerFunc(h.GetReview)).ServeHTTP(rr, req)
 	if rr.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", rr.Code)
 	}
@@ -113,28 +112,35 @@ func TestGetReview_Success(t *testing.T) {
 func TestGetReview_Forbidden(t *testing.T) {
 	h := &handler{
 		service: userServiceMock{
-			listFn: func(ctx context.Context, id domain.UserID) ([]domainpr.PullRequest, error) {
+			listFn: func(ctx context.Context, actor requester.Requester, id domain.UserID) ([]domainpr.PullRequest, error) {
+				if !actor.CanViewAssignments(id, domainteam.Team{}) {
+					return nil, domain.ErrPermissionDenied
+				}
 				return nil, nil
 			},
 		},
 		logger: userTestLogger(),
 	}
-	auth := mw.NewAuthorization([]byte("admin-secret"), []byte("user-secret"))
+	authz := newAuthorization()
 	req := httptest.NewRequest(http.MethodGet, "/users/getReview?user_id=u1", nil)
-	req.Header.Set("Authorization", "Bearer "+newToken([]byte("user-secret"), "other", "user"))
+	req.Header.Set("Authorization", "Bearer "+newToken("other", "user"))
 	rr := httptest.NewRecorder()
-	auth.RequireUserOrAdmin(http.HandlerFunc(h.GetReview)).ServeHTTP(rr, req)
+	authz.RequireAuthenticated(http.HandlerFunc(h.GetReview)).ServeHTTP(rr, req)
 	if rr.Code != http.StatusForbidden {
 		t.Fatalf("expected 403, got %d", rr.Code)
 	}
 }
 
-func newToken(secret []byte, subject, role string) string {
-	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
-	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"%s","role":"%s","exp":%d}`, subject, role, time.Now().Add(time.Hour).Unix())))
-	data := header + "." + payload
-	mac := hmac.New(sha256.New, secret)
-	mac.Write([]byte(data))
-	signature := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
-	return data + "." + signature
+var testKeys = auth.StaticKeys{
+	auth.HMACKey("", []byte("admin-secret"), auth.RoleAdmin),
+	auth.HMACKey("", []byte("user-secret"), auth.RoleUser),
+}
+
+func newAuthorization() *mw.Authorization {
+	return mw.NewAuthorization(auth.NewVerifier(auth.VerifierOptions{}, testKeys), nil)
+}
+
+func newToken(subject, role string) string {
+	token, _, _ := auth.NewSigner(auth.SignerOptions{}, testKeys...).Issue(auth.TokenRequest{Subject: subject, Role: role, TTL: time.Hour})
+	return token
 }
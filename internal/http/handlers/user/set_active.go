@@ -15,24 +15,29 @@ func (h *handler) SetIsActive(w http.ResponseWriter, r *http.Request) {
 	var payload dto.SetUserActiveRequest
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		status, resp := httperror.InvalidRequest("invalid JSON payload")
-		httperror.Write(w, status, resp, h.logger, logFields(r)...)
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
 		return
 	}
 	if validator, ok := mw.ValidatorFromContext(r.Context()); ok {
 		if err := validator.ValidateStruct(payload); err != nil {
-			status, resp := httperror.InvalidRequest(err.Error())
-			httperror.Write(w, status, resp, h.logger, logFields(r)...)
+			status, resp := httperror.Validation(err)
+			httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
 			return
 		}
 	}
 	if err := payload.Validate(); err != nil {
 		status, resp := httperror.InvalidRequest(err.Error())
-		httperror.Write(w, status, resp, h.logger, logFields(r)...)
+		httperror.Write(w, r, status, resp, h.logger, logFields(r)...)
 		return
 	}
-	updated, err := h.service.SetIsActive(r.Context(), domain.UserID(payload.UserID), payload.IsActiveValue())
+	expected, err := response.IfMatch(r)
 	if err != nil {
-		httperror.Respond(w, err, h.logger, logFields(r)...)
+		httperror.Respond(w, r, err, h.logger, logFields(r)...)
+		return
+	}
+	updated, err := h.service.SetIsActive(r.Context(), mw.RequesterFromContext(r.Context()), domain.UserID(payload.UserID), payload.IsActiveValue(), expected)
+	if err != nil {
+		httperror.Respond(w, r, err, h.logger, logFields(r)...)
 		return
 	}
 	resp := struct {
@@ -40,5 +45,6 @@ func (h *handler) SetIsActive(w http.ResponseWriter, r *http.Request) {
 	}{
 		User: dto.UserFromDomain(updated),
 	}
+	w.Header().Set("ETag", response.ETag(updated.Version()))
 	response.JSON(w, http.StatusOK, resp)
 }
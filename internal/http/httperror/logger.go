@@ -1,29 +1,48 @@
 package httperror
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 
 	"github.com/mashhkensss/PR-service/internal/http/dto"
 	"github.com/mashhkensss/PR-service/internal/http/response"
+	"github.com/mashhkensss/PR-service/internal/tracing"
 )
 
-func Write(w http.ResponseWriter, status int, resp dto.ErrorResponse, log *slog.Logger, fields ...any) {
+func Write(w http.ResponseWriter, r *http.Request, status int, resp dto.ErrorResponse, log *slog.Logger, fields ...any) {
 	if log != nil {
 		args := []any{
 			"status", status,
 			"code", resp.Error.Code,
 			"message", resp.Error.Message,
 		}
+		if traceID := w.Header().Get(tracing.TraceIDHeader); traceID != "" {
+			args = append(args, "trace_id", traceID)
+		}
 		if len(fields) > 0 {
 			args = append(args, fields...)
 		}
 		log.Error("http_error", args...)
 	}
-	response.ErrorResponse(w, status, resp)
+	response.ErrorResponse(w, r, status, resp)
 }
 
-func Respond(w http.ResponseWriter, err error, log *slog.Logger, fields ...any) {
+func Respond(w http.ResponseWriter, r *http.Request, err error, log *slog.Logger, fields ...any) {
+	if r != nil {
+		if cause, ok := r.Context().Value(causeKey{}).(*error); ok {
+			*cause = err
+		}
+	}
 	status, resp := FromError(err)
-	Write(w, status, resp, log, fields...)
+	Write(w, r, status, resp, log, fields...)
+}
+
+type causeKey struct{}
+
+// WithCause — контекст, в который Respond запишет исходную ошибку. Нужен вызывающим хендлеры
+// внутри процесса (batch): по коду ответа не понять, например, что транзакцию стоит повторить
+func WithCause(ctx context.Context) (context.Context, *error) {
+	cause := new(error)
+	return context.WithValue(ctx, causeKey{}, cause), cause
 }
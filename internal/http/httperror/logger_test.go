@@ -10,6 +10,7 @@ import (
 
 	"github.com/mashhkensss/PR-service/internal/domain"
 	"github.com/mashhkensss/PR-service/internal/http/dto"
+	"github.com/mashhkensss/PR-service/internal/tracing"
 )
 
 func TestWrite_LogsAndResponds(t *testing.T) {
@@ -20,7 +21,7 @@ func TestWrite_LogsAndResponds(t *testing.T) {
 	rr := httptest.NewRecorder()
 
 	payload := dto.NewErrorResponse("PR_EXISTS", "pull request exists")
-	Write(rr, http.StatusConflict, payload, logger, "pull_request_id", "pr-1")
+	Write(rr, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusConflict, payload, logger, "pull_request_id", "pr-1")
 
 	if rr.Code != http.StatusConflict {
 		t.Fatalf("unexpected status: %d", rr.Code)
@@ -62,7 +63,7 @@ func TestRespond_MapsDomainError(t *testing.T) {
 	logger := slog.New(handler)
 	rr := httptest.NewRecorder()
 
-	Respond(rr, domain.ErrTeamExists, logger)
+	Respond(rr, httptest.NewRequest(http.MethodGet, "/", nil), domain.ErrTeamExists, logger)
 
 	if rr.Code != http.StatusBadRequest {
 		t.Fatalf("unexpected status: %d", rr.Code)
@@ -83,7 +84,7 @@ func TestWrite_NoLogger(t *testing.T) {
 	t.Parallel()
 
 	rr := httptest.NewRecorder()
-	Write(rr, http.StatusBadRequest, dto.NewErrorResponse("INVALID", "bad input"), nil)
+	Write(rr, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusBadRequest, dto.NewErrorResponse("INVALID", "bad input"), nil)
 
 	if rr.Code != http.StatusBadRequest {
 		t.Fatalf("unexpected status: %d", rr.Code)
@@ -96,6 +97,20 @@ type logRecord struct {
 	Attrs   map[string]any
 }
 
+func TestWrite_LogsTraceID(t *testing.T) {
+	t.Parallel()
+
+	handler := newRecordingHandler()
+	rr := httptest.NewRecorder()
+	rr.Header().Set(tracing.TraceIDHeader, "4bf92f3577b34da6a3ce929d0e0e4736")
+
+	Write(rr, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusBadRequest, dto.NewErrorResponse("INVALID_REQUEST", "bad"), slog.New(handler))
+
+	if len(handler.records) != 1 || handler.records[0].Attrs["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("trace_id not logged: %+v", handler.records)
+	}
+}
+
 type recordingHandler struct {
 	records []logRecord
 	attrs   []slog.Attr
@@ -14,6 +14,7 @@ const (
 	CodeTeamMismatch   = "TEAM_MISMATCH"
 	CodeTeamForbidden  = "TEAM_FORBIDDEN"
 	CodeUserExists     = "USER_EXISTS"
+	CodeMemberHistory  = "MEMBER_HAS_HISTORY"
 	CodePRExists       = "PR_EXISTS"
 	CodePRMerged       = "PR_MERGED"
 	CodeNotAssigned    = "NOT_ASSIGNED"
@@ -27,6 +28,11 @@ const (
 	CodeUnauthorized   = "UNAUTHORIZED"
 	CodeForbidden      = "FORBIDDEN"
 	CodeRateLimited    = "RATE_LIMITED"
+	CodeInProgress     = "IN_PROGRESS"
+	CodePrecondition   = "PRECONDITION_FAILED"
+	// Коды операций атомарного batch, не попавших в зафиксированную транзакцию
+	CodeRolledBack  = "ROLLED_BACK"
+	CodeNotExecuted = "NOT_EXECUTED"
 )
 
 func FromError(err error) (int, dto.ErrorResponse) {
@@ -39,8 +45,14 @@ func FromError(err error) (int, dto.ErrorResponse) {
 		return http.StatusBadRequest, dto.NewErrorResponse(CodeTeamMismatch, domain.ErrTeamMismatch.Error())
 	case errors.Is(err, domain.ErrTeamAccessDenied):
 		return http.StatusForbidden, dto.NewErrorResponse(CodeTeamForbidden, domain.ErrTeamAccessDenied.Error())
+	case errors.Is(err, domain.ErrAPIKeyRejected):
+		return Unauthorized()
+	case errors.Is(err, domain.ErrPermissionDenied):
+		return http.StatusForbidden, dto.NewErrorResponse(CodeForbidden, domain.ErrPermissionDenied.Error())
 	case errors.Is(err, domain.ErrUserExists):
 		return http.StatusConflict, dto.NewErrorResponse(CodeUserExists, domain.ErrUserExists.Error())
+	case errors.Is(err, domain.ErrMemberHasHistory):
+		return http.StatusConflict, dto.NewErrorResponse(CodeMemberHistory, domain.ErrMemberHasHistory.Error())
 	case errors.Is(err, domain.ErrPullRequestExists):
 		return http.StatusConflict, dto.NewErrorResponse(CodePRExists, domain.ErrPullRequestExists.Error())
 	case errors.Is(err, domain.ErrPullRequestAlreadyMerged):
@@ -55,7 +67,14 @@ func FromError(err error) (int, dto.ErrorResponse) {
 		return http.StatusConflict, dto.NewErrorResponse(CodeNotAssigned, domain.ErrReviewerNotAssigned.Error())
 	case errors.Is(err, domain.ErrNoActiveCandidate):
 		return http.StatusConflict, dto.NewErrorResponse(CodeNoCandidate, domain.ErrNoActiveCandidate.Error())
-	case errors.Is(err, sql.ErrNoRows):
+	case errors.Is(err, domain.ErrInvalidSLAPolicy), errors.Is(err, domain.ErrInvalidStatsFilter), errors.Is(err, domain.ErrInvalidReviewVerdict),
+		errors.Is(err, domain.ErrInvalidAPIKey), errors.Is(err, domain.ErrInvalidRevocation),
+		errors.Is(err, domain.ErrInvalidIdentifier), errors.Is(err, domain.ErrInvalidName), errors.Is(err, domain.ErrInvalidPullRequestStatus),
+		errors.Is(err, domain.ErrInvalidImportMode):
+		return http.StatusBadRequest, dto.NewErrorResponse(CodeInvalidInput, err.Error())
+	case errors.Is(err, domain.ErrVersionMismatch):
+		return http.StatusPreconditionFailed, dto.NewErrorResponse(CodePrecondition, domain.ErrVersionMismatch.Error())
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, domain.ErrUserNotFound):
 		return http.StatusNotFound, dto.NewErrorResponse(CodeNotFound, "resource not found")
 	default:
 		return http.StatusInternalServerError, dto.NewErrorResponse(CodeInternalError, "internal server error")
@@ -0,0 +1,55 @@
+package httperror
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/mashhkensss/PR-service/internal/http/dto"
+)
+
+// Validation — как InvalidRequest(err.Error()), но нарушения из validator.ValidationErrors
+// сохраняются по полям для errors[] в problem+json
+func Validation(err error) (int, dto.ErrorResponse) {
+	status, resp := InvalidRequest(err.Error())
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return status, resp
+	}
+	resp.Violations = make([]dto.FieldViolation, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		resp.Violations = append(resp.Violations, dto.FieldViolation{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: violationMessage(fe),
+		})
+	}
+	return status, resp
+}
+
+// fieldPath убирает из namespace имя корневой структуры: Team.members[0].user_id -> members[0].user_id.
+// Имена полей — из json-тегов, их регистрирует middleware.NewTagValidator
+func fieldPath(fe validator.FieldError) string {
+	if _, path, ok := strings.Cut(fe.Namespace(), "."); ok {
+		return path
+	}
+	return fe.Field()
+}
+
+func violationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "max":
+		return "must be at most " + fe.Param()
+	case "min":
+		return "must be at least " + fe.Param()
+	default:
+		return fmt.Sprintf("failed %q rule", fe.Tag())
+	}
+}
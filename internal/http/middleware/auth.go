@@ -1,52 +1,50 @@
 package middleware
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
-	"encoding/json"
+	"context"
 	"errors"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/mashhkensss/PR-service/internal/auth"
+	"github.com/mashhkensss/PR-service/internal/domain/apikey"
 	"github.com/mashhkensss/PR-service/internal/http/httperror"
 	"github.com/mashhkensss/PR-service/internal/http/response"
 )
 
-type Claims struct {
-	Subject   string `json:"sub"`
-	Role      string `json:"role"`
-	ExpiresAt int64  `json:"exp"`
+type Claims = auth.Claims
+
+// TokenVerifier проверяет подпись и стандартные claims токена
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (auth.Claims, error)
 }
 
-type Authorization struct {
-	adminSecret []byte
-	userSecret  []byte
+// APIKeyAuthenticator находит активный API-ключ по значению из X-API-Key
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, plain string, now time.Time) (apikey.Key, error)
 }
 
-func NewAuthorization(adminSecret, userSecret []byte) *Authorization {
-	return &Authorization{adminSecret: adminSecret, userSecret: userSecret}
+// APIKeyHeader — альтернатива Bearer-токену для сервисных клиентов
+const APIKeyHeader = "X-API-Key"
+
+type Authorization struct {
+	verifier TokenVerifier
+	apiKeys  APIKeyAuthenticator
 }
 
-func (a *Authorization) RequireAdmin(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		claims, err := a.verifyToken(r.Header.Get("Authorization"))
-		if err != nil || claims.Role != "admin" {
-			status, resp := httperror.Unauthorized()
-			response.ErrorResponse(w, status, resp)
-			return
-		}
-		ctx := contextWithClaims(r.Context(), claims)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+// NewAuthorization принимает JWT и, если apiKeys не nil, API-ключи
+func NewAuthorization(verifier TokenVerifier, apiKeys APIKeyAuthenticator) *Authorization {
+	return &Authorization{verifier: verifier, apiKeys: apiKeys}
 }
 
-func (a *Authorization) RequireUserOrAdmin(next http.Handler) http.Handler {
+// RequireAuthenticated пропускает любую известную роль; права на конкретное действие проверяет сервис
+func (a *Authorization) RequireAuthenticated(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		claims, err := a.verifyToken(r.Header.Get("Authorization"))
-		if err != nil {
+		claims, err := a.authenticate(r)
+		if err != nil || claims.Subject == "" || !knownRole(claims.Role) {
 			status, resp := httperror.Unauthorized()
-			response.ErrorResponse(w, status, resp)
+			response.ErrorResponse(w, r, status, resp)
 			return
 		}
 		ctx := contextWithClaims(r.Context(), claims)
@@ -54,85 +52,65 @@ func (a *Authorization) RequireUserOrAdmin(next http.Handler) http.Handler {
 	})
 }
 
-func (a *Authorization) verifyToken(header string) (Claims, error) {
-	var empty Claims
-	const prefix = "Bearer "
-
-	if header == "" || len(header) <= len(prefix) {
-		return empty, errors.New("missing token")
-	}
-
-	if header[:len(prefix)] != prefix {
-		return empty, errors.New("invalid header")
-	}
-
-	token := header[len(prefix):]
-	parts := splitToken(token)
-	if len(parts) != 3 {
-		return empty, errors.New("invalid token")
-	}
-
-	if err := verifyHS256(parts[0], parts[1], parts[2], a.adminSecret, a.userSecret); err != nil {
-		return empty, err
-	}
-
-	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+// Authenticate проверяет учётные данные так же, как RequireAuthenticated, для транспортов без
+// http.Request: gRPC передаёт значения метаданных authorization и x-api-key
+func (a *Authorization) Authenticate(ctx context.Context, authorization, apiKey string) (context.Context, error) {
+	claims, err := a.credentials(ctx, authorization, apiKey)
 	if err != nil {
-		return empty, err
+		return ctx, err
 	}
-
-	var claims Claims
-
-	if err := json.Unmarshal(payload, &claims); err != nil {
-		return empty, err
+	if claims.Subject == "" || !knownRole(claims.Role) {
+		return ctx, errors.New("unknown subject or role")
 	}
-
-	if claims.ExpiresAt != 0 {
-		exp := time.Unix(claims.ExpiresAt, 0)
-		if time.Now().After(exp) {
-			return empty, errors.New("token expired")
-		}
-	}
-
-	return claims, nil
+	return contextWithClaims(ctx, claims), nil
 }
 
-func splitToken(token string) []string {
-	parts := make([]string, 0, 3)
-	start := 0
-
-	for i := 0; i < len(token); i++ {
-		if token[i] == '.' {
-			parts = append(parts, token[start:i])
-			start = i + 1
-		}
+func knownRole(role string) bool {
+	switch role {
+	case auth.RoleAdmin, auth.RoleTeamLead, auth.RoleUser:
+		return true
 	}
-	parts = append(parts, token[start:])
-	return parts
+	return false
 }
 
-func verifyHS256(header, payload, signature string, adminSecret, userSecret []byte) error {
-	data := header + "." + payload
-	sig, err := base64.RawURLEncoding.DecodeString(signature)
+func (a *Authorization) authenticate(r *http.Request) (Claims, error) {
+	return a.credentials(r.Context(), r.Header.Get("Authorization"), r.Header.Get(APIKeyHeader))
+}
 
-	if err != nil {
-		return err
+func (a *Authorization) credentials(ctx context.Context, authorization, key string) (Claims, error) {
+	if key == "" {
+		return a.verifyToken(ctx, authorization)
 	}
-
-	if len(adminSecret) > 0 && validHMAC(data, sig, adminSecret) {
-		return nil
+	// два способа сразу — неоднозначно, чьи права применять
+	if a.apiKeys == nil || authorization != "" {
+		return Claims{}, errors.New("unexpected api key")
 	}
-	if len(userSecret) > 0 && validHMAC(data, sig, userSecret) {
-		return nil
+	k, err := a.apiKeys.Authenticate(ctx, key, time.Now())
+	if err != nil {
+		return Claims{}, err
 	}
+	scopes := make([]string, 0, len(k.Scopes()))
+	for _, s := range k.Scopes() {
+		scopes = append(scopes, string(s))
+	}
+	return Claims{
+		Subject: APIKeySubject(k.ID()),
+		Role:    string(k.Role()),
+		Scope:   strings.Join(scopes, " "),
+	}, nil
+}
 
-	return errors.New("invalid signature")
+// APIKeySubject — sub в claims запроса, сделанного по API-ключу
+func APIKeySubject(id string) string {
+	return "apikey:" + id
 }
 
-func validHMAC(data string, signature []byte, secret []byte) bool {
-	mac := hmac.New(sha256.New, secret)
-	mac.Write([]byte(data))
-	expected := mac.Sum(nil)
+func (a *Authorization) verifyToken(ctx context.Context, header string) (Claims, error) {
+	const prefix = "Bearer "
 
-	return hmac.Equal(expected, signature)
+	token, ok := strings.CutPrefix(header, prefix)
+	if !ok || token == "" {
+		return Claims{}, errors.New("missing token")
+	}
+	return a.verifier.Verify(ctx, token)
 }
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/auth"
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/apikey"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+)
+
+type stubAPIKeys map[string]apikey.Key
+
+func (s stubAPIKeys) Authenticate(ctx context.Context, plain string, now time.Time) (apikey.Key, error) {
+	k, ok := s[plain]
+	if !ok {
+		return apikey.Key{}, domain.ErrAPIKeyRejected
+	}
+	return k, nil
+}
+
+func TestAuthorization_APIKey(t *testing.T) {
+	k, err := apikey.New("abc", "ci-bot", requester.RoleAdmin, []requester.Scope{requester.ScopeStatsRead}, time.Now(), nil)
+	if err != nil {
+		t.Fatalf("new key: %v", err)
+	}
+	authz := NewAuthorization(auth.NewVerifier(auth.VerifierOptions{}, auth.StaticKeys{}), stubAPIKeys{"prs_abc_secret": k})
+
+	var got requester.Requester
+	handler := authz.RequireAuthenticated(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _ := ClaimsFromContext(r.Context())
+		if claims.Subject != APIKeySubject("abc") {
+			t.Errorf("unexpected subject %q", claims.Subject)
+		}
+		got = RequesterFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cases := map[string]struct {
+		key, bearer string
+		status      int
+	}{
+		"valid key":       {key: "prs_abc_secret", status: http.StatusOK},
+		"unknown key":     {key: "prs_abc_other", status: http.StatusUnauthorized},
+		"key with bearer": {key: "prs_abc_secret", bearer: "Bearer x", status: http.StatusUnauthorized},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/stats/summary", nil)
+			req.Header.Set(APIKeyHeader, tc.key)
+			if tc.bearer != "" {
+				req.Header.Set("Authorization", tc.bearer)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Fatalf("expected %d, got %d", tc.status, rec.Code)
+			}
+		})
+	}
+
+	// права ключа ограничены его scopes, даже если роль шире
+	if !got.CanViewStats() || got.CanCreateTeam() {
+		t.Fatalf("api key scopes must narrow admin role")
+	}
+}
+
+func TestAuthorization_APIKeyDisabled(t *testing.T) {
+	authz := NewAuthorization(auth.NewVerifier(auth.VerifierOptions{}, auth.StaticKeys{}), nil)
+	handler := authz.RequireAuthenticated(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/team/get", nil)
+	req.Header.Set(APIKeyHeader, "prs_abc_secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+}
+
+func TestRequesterFromContext_WithoutClaimsDeniesEverything(t *testing.T) {
+	r := RequesterFromContext(context.Background())
+	if r.IsAdmin() || r.CanCreateTeam() || r.CanViewStats() || r.CanImportData() {
+		t.Fatalf("requester without claims must have no rights")
+	}
+}
@@ -1,6 +1,11 @@
 package middleware
 
-import "context"
+import (
+	"context"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+)
 
 type contextKey string
 
@@ -18,6 +23,16 @@ func ClaimsFromContext(ctx context.Context) (Claims, bool) {
 	return val, ok
 }
 
+// RequesterFromContext строит субъекта из claims запроса. Без claims — Anonymous без прав,
+// чтобы маршрут, оставшийся без middleware аутентификации, не получил полный доступ
+func RequesterFromContext(ctx context.Context) requester.Requester {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return requester.Anonymous()
+	}
+	return requester.New(domain.UserID(claims.Subject), requester.Role(claims.Role), requester.ParseScopes(claims.Scope)...)
+}
+
 func contextWithValidator(ctx context.Context, v Validator) context.Context {
 	return context.WithValue(ctx, validatorKey, v)
 }
@@ -2,10 +2,15 @@ package middleware
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mashhkensss/PR-service/internal/http/dto"
@@ -17,15 +22,61 @@ const maxIdempotencyBodyBytes = 1 << 20
 
 var errRequestBodyTooLarge = errors.New("idempotency request body too large")
 
-func NewIdempotencyMiddleware(store idempotency.Storage, ttl time.Duration, log *slog.Logger) func(http.Handler) http.Handler {
+// Исходы обработки запроса с Idempotency-Key
+const (
+	IdempotencyHit      = "hit"
+	IdempotencyMiss     = "miss"
+	IdempotencyConflict = "conflict"
+	// IdempotencyInFlight — дубль не дождался завершения первого запроса и получил 409
+	IdempotencyInFlight = "in_flight"
+)
+
+// IdempotencyObserver получает исход каждого запроса с Idempotency-Key
+type IdempotencyObserver interface {
+	Idempotency(result string)
+}
+
+type IdempotencyConfig struct {
+	// TTL — сколько хранится ответ
+	TTL time.Duration
+	// LockTTL — сколько живёт pending-запись, если экземпляр упал, не завершив запрос
+	LockTTL time.Duration
+	// Wait — сколько дубль ждёт завершения первого запроса, прежде чем получить 409
+	Wait time.Duration
+	// CacheStatuses — коды 4xx, которые сохраняются как окончательный ответ. Остальные ошибки
+	// снимают ключ, и повтор выполняется заново
+	CacheStatuses []int
+}
+
+// IdempotentReplayedHeader отмечает ответ, взятый из хранилища, а не полученный от обработчика
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+func (cfg IdempotencyConfig) cacheable(status int) bool {
+	if status < http.StatusBadRequest {
+		return true
+	}
+	return status < http.StatusInternalServerError && slices.Contains(cfg.CacheStatuses, status)
+}
+
+const idempotencyPollInterval = 50 * time.Millisecond
+
+func NewIdempotencyMiddleware(store idempotency.Storage, cfg IdempotencyConfig, log *slog.Logger, observer IdempotencyObserver) func(http.Handler) http.Handler {
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = 30 * time.Second
+	}
+	observe := func(result string) {
+		if observer != nil {
+			observer.Idempotency(result)
+		}
+	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if store == nil || r.Method != http.MethodPost {
 				next.ServeHTTP(w, r)
 				return
 			}
-			key := r.Header.Get("Idempotency-Key")
-			if key == "" {
+			value := r.Header.Get("Idempotency-Key")
+			if value == "" {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -33,11 +84,11 @@ func NewIdempotencyMiddleware(store idempotency.Storage, ttl time.Duration, log
 			if err != nil {
 				if errors.Is(err, errRequestBodyTooLarge) {
 					resp := dto.NewErrorResponse(httperror.CodeInvalidInput, "request body too large")
-					httperror.Write(w, http.StatusRequestEntityTooLarge, resp, log)
+					httperror.Write(w, r, http.StatusRequestEntityTooLarge, resp, log)
 					return
 				}
 				status, resp := httperror.InvalidRequest("invalid request body")
-				httperror.Write(w, status, resp, log)
+				httperror.Write(w, r, status, resp, log)
 				return
 			}
 
@@ -52,38 +103,103 @@ func NewIdempotencyMiddleware(store idempotency.Storage, ttl time.Duration, log
 				Body:   append([]byte(nil), body...),
 			}
 
-			if storedReq, cached, ok, err := store.Get(r.Context(), key); err == nil && ok {
-				if !sameRequest(storedReq, incomingReq) {
+			key := scopedKey(r, value)
+			res, err := reserve(r.Context(), store, key, incomingReq, cfg)
+			if err != nil {
+				// без хранилища лучше выполнить запрос без защиты от дублей, чем отказать
+				if log != nil {
+					log.Error("idempotency reserve failed", "error", err)
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !res.Acquired {
+				if !sameRequest(res.Request, incomingReq) {
+					observe(IdempotencyConflict)
 					status, resp := httperror.InvalidRequest("idempotency key replay with different request")
-					httperror.Write(w, status, resp, log)
+					httperror.Write(w, r, status, resp, log)
+					return
+				}
+				if res.Pending {
+					observe(IdempotencyInFlight)
+					w.Header().Set("Retry-After", "1")
+					resp := dto.NewErrorResponse(httperror.CodeInProgress, "request with this idempotency key is still in progress")
+					httperror.Write(w, r, http.StatusConflict, resp, log)
 					return
 				}
-				writeStoredResponse(w, cached)
+				observe(IdempotencyHit)
+				writeStoredResponse(w, res.Response)
 				return
-			} else if err != nil && log != nil {
-				log.Error("idempotency lookup failed", "error", err)
 			}
 
+			observe(IdempotencyMiss)
+			// завершаем запись и при обрыве соединения клиента, иначе ключ останется занятым до LockTTL
+			finishCtx := context.WithoutCancel(r.Context())
 			rec := newResponseRecorder(w)
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				if err := store.Release(finishCtx, key, res.Token); err != nil && log != nil {
+					log.Error("idempotency release failed", "error", err)
+				}
+			}()
 			next.ServeHTTP(rec, r)
 
+			if !cfg.cacheable(rec.status) {
+				return
+			}
 			resp := idempotency.StoredResponse{
 				Status: rec.status,
 				Body:   append([]byte(nil), rec.body.Bytes()...),
 				Header: rec.header(),
 			}
-
-			if rec.status >= http.StatusBadRequest {
+			if err := store.Complete(finishCtx, key, res.Token, resp, cfg.TTL); err != nil {
+				if errors.Is(err, idempotency.ErrReservationLost) {
+					// запрос шёл дольше LockTTL, и ключ перехватил повтор: его запись не трогаем
+					completed = true
+					if log != nil {
+						log.Warn("idempotency reservation expired before completion", "path", uri)
+					}
+					return
+				}
+				if log != nil {
+					log.Error("idempotency save failed", "error", err)
+				}
 				return
 			}
-
-			if err := store.Save(r.Context(), key, incomingReq, ttl, resp); err != nil && log != nil {
-				log.Error("idempotency save failed", "error", err)
-			}
+			completed = true
 		})
 	}
 }
 
+// reserve занимает ключ; если его держит выполняющийся запрос, ждёт до cfg.Wait
+func reserve(ctx context.Context, store idempotency.Storage, key idempotency.Key, req idempotency.StoredRequest, cfg IdempotencyConfig) (idempotency.Reservation, error) {
+	deadline := time.Now().Add(cfg.Wait)
+	for {
+		res, err := store.Reserve(ctx, key, req, cfg.LockTTL)
+		if err != nil || !res.Pending || !sameRequest(res.Request, req) || !time.Now().Before(deadline) {
+			return res, err
+		}
+		select {
+		case <-ctx.Done():
+			return res, nil
+		case <-time.After(idempotencyPollInterval):
+		}
+	}
+}
+
+// scopedKey привязывает ключ к субъекту и эндпоинту: одинаковые ключи разных клиентов
+// или разных ручек не пересекаются. Поэтому middleware должен стоять после аутентификации
+func scopedKey(r *http.Request, value string) idempotency.Key {
+	key := idempotency.Key{Method: r.Method, Path: r.URL.Path, Value: value}
+	if claims, ok := ClaimsFromContext(r.Context()); ok {
+		key.Subject = claims.Subject
+	}
+	return key
+}
+
 func sameRequest(a, b idempotency.StoredRequest) bool {
 	if a.Method != b.Method || a.Path != b.Path {
 		return false
@@ -92,9 +208,15 @@ func sameRequest(a, b idempotency.StoredRequest) bool {
 }
 
 func writeStoredResponse(w http.ResponseWriter, resp idempotency.StoredResponse) {
+	h := w.Header()
 	for k, v := range resp.Header {
-		w.Header().Set(k, v)
+		// заголовки текущего запроса (например, X-Trace-Id) не перетираем сохранёнными
+		if len(h.Values(k)) > 0 {
+			continue
+		}
+		h[k] = append([]string(nil), v...)
 	}
+	h.Set(IdempotentReplayedHeader, "true")
 	w.WriteHeader(resp.Status)
 	_, _ = w.Write(resp.Body)
 }
@@ -144,12 +266,28 @@ func (r *responseRecorder) WriteHeader(statusCode int) {
 	r.w.WriteHeader(statusCode)
 }
 
-func (r *responseRecorder) header() map[string]string {
-	result := make(map[string]string)
-	for k, v := range r.Header() {
-		if len(v) > 0 {
-			result[k] = v[0]
+func (r *responseRecorder) header() http.Header {
+	return r.Header().Clone()
+}
+
+// ParseCacheStatuses разбирает IDEMPOTENCY_CACHE_STATUSES: "400,404,409". Допускаются только 4xx,
+// кроме кодов, зависящих от момента запроса, а не от его содержимого
+func ParseCacheStatuses(raw string) ([]int, error) {
+	var statuses []int
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		status, err := strconv.Atoi(part)
+		if err != nil || status < http.StatusBadRequest || status >= http.StatusInternalServerError {
+			return nil, fmt.Errorf("invalid cache status %q: expected a 4xx code", part)
+		}
+		switch status {
+		case http.StatusUnauthorized, http.StatusRequestTimeout, http.StatusTooManyRequests:
+			return nil, fmt.Errorf("invalid cache status %d: response is not deterministic", status)
 		}
+		statuses = append(statuses, status)
 	}
-	return result
+	return statuses, nil
 }
@@ -3,53 +3,79 @@ package middleware
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/mashhkensss/PR-service/internal/idempotency"
 )
 
+type memoryEntry struct {
+	req     idempotency.StoredRequest
+	resp    idempotency.StoredResponse
+	pending bool
+	token   string
+}
+
 type memoryStore struct {
 	mu      sync.Mutex
-	entries map[string]struct {
-		req  idempotency.StoredRequest
-		resp idempotency.StoredResponse
-	}
+	entries map[idempotency.Key]memoryEntry
+	tokens  int
 }
 
 func newMemoryStore() *memoryStore {
-	return &memoryStore{entries: make(map[string]struct {
-		req  idempotency.StoredRequest
-		resp idempotency.StoredResponse
-	})}
+	return &memoryStore{entries: make(map[idempotency.Key]memoryEntry)}
 }
 
-func (m *memoryStore) Get(_ context.Context, key string) (idempotency.StoredRequest, idempotency.StoredResponse, bool, error) {
+func (m *memoryStore) Reserve(_ context.Context, key idempotency.Key, req idempotency.StoredRequest, _ time.Duration) (idempotency.Reservation, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	entry, ok := m.entries[key]
-	if !ok {
-		return idempotency.StoredRequest{}, idempotency.StoredResponse{}, false, nil
+	if entry, ok := m.entries[key]; ok {
+		return idempotency.Reservation{Pending: entry.pending, Request: entry.req, Response: entry.resp}, nil
 	}
-	return entry.req, entry.resp, true, nil
+	m.tokens++
+	token := fmt.Sprintf("owner-%d", m.tokens)
+	m.entries[key] = memoryEntry{req: req, pending: true, token: token}
+	return idempotency.Reservation{Acquired: true, Token: token}, nil
+}
+
+// takeOver имитирует повтор, перехвативший истёкшую pending-запись
+func (m *memoryStore) takeOver(key idempotency.Key) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := m.entries[key]
+	entry.token = "retry"
+	m.entries[key] = entry
 }
 
-func (m *memoryStore) Save(_ context.Context, key string, req idempotency.StoredRequest, _ time.Duration, resp idempotency.StoredResponse) error {
+func (m *memoryStore) Complete(_ context.Context, key idempotency.Key, token string, resp idempotency.StoredResponse, _ time.Duration) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.entries[key] = struct {
-		req  idempotency.StoredRequest
-		resp idempotency.StoredResponse
-	}{req: req, resp: resp}
+	entry := m.entries[key]
+	if !entry.pending || entry.token != token {
+		return idempotency.ErrReservationLost
+	}
+	entry.resp, entry.pending = resp, false
+	m.entries[key] = entry
+	return nil
+}
+
+func (m *memoryStore) Release(_ context.Context, key idempotency.Key, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entry := m.entries[key]; entry.pending && entry.token == token {
+		delete(m.entries, key)
+	}
 	return nil
 }
 
 func TestIdempotencyMiddlewareCachesResponse(t *testing.T) {
 	store := newMemoryStore()
-	wrapped := NewIdempotencyMiddleware(store, time.Minute, nil)
+	wrapped := NewIdempotencyMiddleware(store, IdempotencyConfig{TTL: time.Minute}, nil, nil)
 
 	calls := 0
 	handler := wrapped(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -84,9 +110,29 @@ func TestIdempotencyMiddlewareCachesResponse(t *testing.T) {
 	}
 }
 
+func TestIdempotencyMiddlewareKeepsTakenOverReservation(t *testing.T) {
+	store := newMemoryStore()
+	key := idempotency.Key{Method: http.MethodPost, Path: "/batch", Value: "key-slow"}
+	for _, status := range []int{http.StatusCreated, http.StatusInternalServerError} {
+		handler := NewIdempotencyMiddleware(store, IdempotencyConfig{TTL: time.Minute}, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// обработчик шёл дольше LockTTL, и повтор перехватил ключ
+			store.takeOver(key)
+			w.WriteHeader(status)
+		}))
+		req := httptest.NewRequest(http.MethodPost, "/batch", bytes.NewBufferString(`{}`))
+		req.Header.Set("Idempotency-Key", key.Value)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		entry, ok := store.entries[key]
+		if !ok || !entry.pending || entry.token != "retry" {
+			t.Fatalf("status %d: the retry's reservation must survive, got %+v (present %v)", status, entry, ok)
+		}
+	}
+}
+
 func TestIdempotencyMiddlewareRejectsMismatchedRequest(t *testing.T) {
 	store := newMemoryStore()
-	wrapped := NewIdempotencyMiddleware(store, time.Minute, nil)
+	wrapped := NewIdempotencyMiddleware(store, IdempotencyConfig{TTL: time.Minute}, nil, nil)
 	handler := wrapped(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -107,3 +153,169 @@ func TestIdempotencyMiddlewareRejectsMismatchedRequest(t *testing.T) {
 		t.Fatalf("expected 400 for mismatched request, got %d", rr2.Result().StatusCode)
 	}
 }
+
+func TestIdempotencyMiddlewareConcurrentDuplicates(t *testing.T) {
+	store := newMemoryStore()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var calls atomic.Int32
+	handler := NewIdempotencyMiddleware(store, IdempotencyConfig{TTL: time.Minute}, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/pullRequest/create", bytes.NewBufferString(`{"a":1}`))
+		req.Header.Set("Idempotency-Key", "key-3")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- send() }()
+	<-started
+
+	// дубль, пришедший во время выполнения первого запроса, обработчик не запускает
+	dup := send()
+	if dup.Code != http.StatusConflict || dup.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 409 with Retry-After, got %d", dup.Code)
+	}
+	close(release)
+	if rr := <-first; rr.Code != http.StatusCreated {
+		t.Fatalf("unexpected first status %d", rr.Code)
+	}
+	if replay := send(); replay.Code != http.StatusCreated || calls.Load() != 1 {
+		t.Fatalf("expected cached replay, got %d after %d calls", replay.Code, calls.Load())
+	}
+}
+
+func TestIdempotencyMiddlewareDuplicateWaitsForResult(t *testing.T) {
+	store := newMemoryStore()
+	started := make(chan struct{})
+	var calls atomic.Int32
+	handler := NewIdempotencyMiddleware(store, IdempotencyConfig{TTL: time.Minute, Wait: 5 * time.Second}, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/pullRequest/create", bytes.NewBufferString(`{"a":1}`))
+		req.Header.Set("Idempotency-Key", "key-4")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	go send()
+	<-started
+	if dup := send(); dup.Code != http.StatusCreated || calls.Load() != 1 {
+		t.Fatalf("waiting duplicate must get the first response, got %d after %d calls", dup.Code, calls.Load())
+	}
+}
+
+func TestIdempotencyMiddlewareReleasesOnError(t *testing.T) {
+	store := newMemoryStore()
+	status := http.StatusInternalServerError
+	handler := NewIdempotencyMiddleware(store, IdempotencyConfig{TTL: time.Minute}, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	send := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/resource", bytes.NewBufferString(`{}`))
+		req.Header.Set("Idempotency-Key", "key-5")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := send(); code != http.StatusInternalServerError {
+		t.Fatalf("unexpected status %d", code)
+	}
+	status = http.StatusOK
+	if code := send(); code != http.StatusOK {
+		t.Fatalf("failed request must release the key for a retry, got %d", code)
+	}
+}
+
+func TestIdempotencyMiddlewareScopesKeys(t *testing.T) {
+	store := newMemoryStore()
+	calls := 0
+	handler := NewIdempotencyMiddleware(store, IdempotencyConfig{TTL: time.Minute}, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusCreated)
+	}))
+	send := func(subject, path string) int {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(`{}`))
+		req.Header.Set("Idempotency-Key", "shared")
+		req = req.WithContext(contextWithClaims(req.Context(), Claims{Subject: subject}))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// один и тот же ключ у разных субъектов и на разных ручках — независимые запросы
+	for _, tc := range []struct{ subject, path string }{
+		{"alice", "/team/add"},
+		{"bob", "/team/add"},
+		{"alice", "/users/setIsActive"},
+	} {
+		if code := send(tc.subject, tc.path); code != http.StatusCreated {
+			t.Fatalf("unexpected status %d for %+v", code, tc)
+		}
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 handler calls for differently scoped keys, got %d", calls)
+	}
+	if code := send("alice", "/team/add"); code != http.StatusCreated || calls != 3 {
+		t.Fatalf("expected replay for the same scope, got %d after %d calls", code, calls)
+	}
+}
+
+func TestIdempotencyMiddlewareCachesConfiguredErrors(t *testing.T) {
+	store := newMemoryStore()
+	calls := 0
+	status := http.StatusConflict
+	handler := NewIdempotencyMiddleware(store, IdempotencyConfig{TTL: time.Minute, CacheStatuses: []int{http.StatusConflict}}, nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Add("X-Reason", "exists")
+		w.Header().Add("X-Reason", "merged")
+		w.WriteHeader(status)
+	}))
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/pullRequest/create", bytes.NewBufferString(`{}`))
+		req.Header.Set("Idempotency-Key", "key-6")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if first := send(); first.Code != http.StatusConflict || first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("unexpected first response %d %v", first.Code, first.Header())
+	}
+	// повтор не должен выполниться заново, даже если теперь обработчик ответил бы успехом
+	status = http.StatusCreated
+	replay := send()
+	if replay.Code != http.StatusConflict || calls != 1 {
+		t.Fatalf("expected cached 409, got %d after %d calls", replay.Code, calls)
+	}
+	if replay.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Fatalf("replay must be marked with %s", IdempotentReplayedHeader)
+	}
+	if got := replay.Header().Values("X-Reason"); len(got) != 2 || got[1] != "merged" {
+		t.Fatalf("multi-value header lost on replay: %v", got)
+	}
+}
+
+func TestParseCacheStatuses(t *testing.T) {
+	statuses, err := ParseCacheStatuses(" 400, 404 ,409,")
+	if err != nil || len(statuses) != 3 || statuses[2] != http.StatusConflict {
+		t.Fatalf("unexpected statuses %v err=%v", statuses, err)
+	}
+	for _, raw := range []string{"500", "200", "abc", "429", "401"} {
+		if _, err := ParseCacheStatuses(raw); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
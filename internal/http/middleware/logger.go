@@ -7,6 +7,8 @@ import (
 	"time"
 
 	chimw "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/mashhkensss/PR-service/internal/tracing"
 )
 
 type Logger struct {
@@ -20,20 +22,28 @@ func NewLogger(l *slog.Logger) *Logger {
 func (l *Logger) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		rec := newResponseRecorder(w)
-		next.ServeHTTP(rec, r)
+		// тело не копируется: ответ может быть долгим потоком (SSE), которому нужен Flush
+		ww := chimw.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
 		if l.logger != nil {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
 			reqID := chimw.GetReqID(r.Context())
 			clientIP := remoteAddr(r)
-			l.logger.Info(
-				"http_request",
+			fields := []any{
 				"method", r.Method,
 				"path", r.URL.Path,
 				"request_id", reqID,
 				"client_ip", clientIP,
-				"status", rec.status,
+				"status", status,
 				"duration", time.Since(start),
-			)
+			}
+			if traceID := tracing.TraceID(r.Context()); traceID != "" {
+				fields = append(fields, "trace_id", traceID)
+			}
+			l.logger.Info("http_request", fields...)
 		}
 	})
 }
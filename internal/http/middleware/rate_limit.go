@@ -1,121 +1,162 @@
 package middleware
 
 import (
+	"fmt"
 	"net"
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+
 	"github.com/mashhkensss/PR-service/internal/http/httperror"
 	"github.com/mashhkensss/PR-service/internal/http/response"
+	"github.com/mashhkensss/PR-service/internal/ratelimit"
 )
 
-type bucket struct {
-	tokens     int
-	lastRefill time.Time
-	lastSeen   time.Time
+// RouteLimit переопределяет лимит для шаблона маршрута chi. Шаблон с * на конце
+// задаёт префикс: /stats/* покрывает все /stats/...
+type RouteLimit struct {
+	Pattern  string
+	Limit    int
+	Interval time.Duration
 }
 
 type RateLimiter struct {
 	limit          int
 	interval       time.Duration
-	ttl            time.Duration
+	routes         []RouteLimit
 	trustForwarded bool
-	buckets        map[string]*bucket
-	mu             sync.Mutex
-	nextCleanup    time.Time
+	byIP           bool
+	store          ratelimit.Storage
+	onReject       func(r *http.Request)
+	onError        func(r *http.Request, err error)
 }
 
+// NewRateLimiter по умолчанию хранит бакеты в памяти процесса; общий для реплик бакет задаётся через WithStore
 func NewRateLimiter(limit int, interval time.Duration, trustForwarded bool) *RateLimiter {
 	if interval <= 0 {
 		interval = time.Second
 	}
-	ttl := interval * 10
-	if ttl <= 0 {
-		ttl = time.Minute
-	}
 	return &RateLimiter{
 		limit:          limit,
 		interval:       interval,
-		ttl:            ttl,
 		trustForwarded: trustForwarded,
-		buckets:        make(map[string]*bucket),
-		nextCleanup:    time.Now().Add(ttl),
+		store:          ratelimit.NewMemory(interval * 10),
 	}
 }
 
+// WithStore заменяет хранилище бакетов
+func (rl *RateLimiter) WithStore(store ratelimit.Storage) *RateLimiter {
+	rl.store = store
+	return rl
+}
+
+// OnReject регистрирует колбэк, который вызывается на каждый отклонённый запрос
+func (rl *RateLimiter) OnReject(fn func(r *http.Request)) {
+	rl.onReject = fn
+}
+
+// OnError регистрирует колбэк на ошибку хранилища. Запрос при этом пропускается:
+// недоступность базы не должна превращаться в отказ всем клиентам
+func (rl *RateLimiter) OnError(fn func(r *http.Request, err error)) {
+	rl.onError = fn
+}
+
+// ByIP переключает лимитер на ключ по IP клиента без учёта маршрута и subject. Такой лимитер
+// ставится до аутентификации и маршрутизации и ограничивает то, что до них не доходит:
+// запросы с плохим токеном, перебор API-ключей, несуществующие маршруты
+func (rl *RateLimiter) ByIP() *RateLimiter {
+	rl.byIP = true
+	return rl
+}
+
+// WithRoutes задаёт отдельные лимиты для маршрутов. У такого маршрута свой бакет,
+// и дорогие запросы не расходуют общий бюджет субъекта
+func (rl *RateLimiter) WithRoutes(routes ...RouteLimit) *RateLimiter {
+	rl.routes = append(rl.routes, routes...)
+	return rl
+}
+
+// Middleware должен стоять после аутентификации и маршрутизации: ключ бакета — subject из claims
+// (без него — IP клиента), лимит выбирается по шаблону маршрута
 func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		key := rl.clientIP(r)
-		if allowed, retryAfter := rl.allow(key); !allowed {
-			if retryAfter > 0 {
-				secs := int(retryAfter / time.Second)
-				if retryAfter%time.Second != 0 {
-					secs++
-				}
-				if secs <= 0 {
-					secs = 1
-				}
-				w.Header().Set("Retry-After", strconv.Itoa(secs))
+		limit, interval, key := rl.limitAndKey(r)
+		res, err := rl.store.Take(r.Context(), key, limit, interval, time.Now())
+		if err != nil {
+			if rl.onError != nil {
+				rl.onError(r, err)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		if !res.Allowed {
+			if rl.onReject != nil {
+				rl.onReject(r)
 			}
+			h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.Reset)))
 			status, resp := httperror.RateLimited()
-			response.ErrorResponse(w, status, resp)
+			response.ErrorResponse(w, r, status, resp)
 			return
 		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-func (rl *RateLimiter) allow(key string) (bool, time.Duration) {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	now := time.Now()
-	if now.After(rl.nextCleanup) {
-		rl.cleanup(now)
+func (rl *RateLimiter) limitAndKey(r *http.Request) (int, time.Duration, string) {
+	if rl.byIP {
+		// свой префикс: в общем хранилище бакет не пересекается с IP-бакетом лимитера маршрутов
+		return rl.limit, rl.interval, "net:" + rl.clientIP(r)
 	}
-
-	b := rl.buckets[key]
-	if b == nil {
-		b = &bucket{
-			tokens:     rl.limit,
-			lastRefill: now,
-			lastSeen:   now,
-		}
-		rl.buckets[key] = b
+	limit, interval, scope := rl.routeLimit(r)
+	key := rl.clientKey(r)
+	if scope != "" {
+		key += "|" + scope
 	}
+	return limit, interval, key
+}
 
-	if since := now.Sub(b.lastRefill); since >= rl.interval {
-		refills := int(since / rl.interval)
-		b.tokens += refills * rl.limit
-		if b.tokens > rl.limit {
-			b.tokens = rl.limit
-		}
-		b.lastRefill = now
+// routeLimit возвращает лимит для запроса и шаблон переопределения, если оно сработало
+func (rl *RateLimiter) routeLimit(r *http.Request) (int, time.Duration, string) {
+	pattern := ""
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		pattern = rctx.RoutePattern()
 	}
-	b.lastSeen = now
-
-	if b.tokens <= 0 {
-		wait := rl.interval - now.Sub(b.lastRefill)
-		if wait <= 0 {
-			wait = rl.interval
+	if pattern == "" {
+		return rl.limit, rl.interval, ""
+	}
+	var (
+		best    RouteLimit
+		matched bool
+	)
+	for _, rt := range rl.routes {
+		if rt.Pattern == pattern {
+			return rt.Limit, rt.Interval, rt.Pattern
 		}
-		return false, wait
+		prefix, ok := strings.CutSuffix(rt.Pattern, "*")
+		if ok && strings.HasPrefix(pattern, prefix) && len(rt.Pattern) > len(best.Pattern) {
+			best, matched = rt, true
+		}
+	}
+	if matched {
+		return best.Limit, best.Interval, best.Pattern
 	}
-	b.tokens--
-	return true, 0
+	return rl.limit, rl.interval, ""
 }
 
-func (rl *RateLimiter) cleanup(now time.Time) {
-	expireBefore := now.Add(-rl.ttl)
-	for key, b := range rl.buckets {
-		if b.lastSeen.Before(expireBefore) {
-			delete(rl.buckets, key)
-		}
+// clientKey разделяет клиентов за общим NAT: аутентифицированный запрос считается по subject
+func (rl *RateLimiter) clientKey(r *http.Request) string {
+	if claims, ok := ClaimsFromContext(r.Context()); ok && claims.Subject != "" {
+		return "sub:" + claims.Subject
 	}
-	rl.nextCleanup = now.Add(rl.ttl)
+	return "ip:" + rl.clientIP(r)
 }
 
 func (rl *RateLimiter) clientIP(r *http.Request) string {
@@ -138,3 +179,44 @@ func (rl *RateLimiter) clientIP(r *http.Request) string {
 	}
 	return host
 }
+
+func ceilSeconds(d time.Duration) int {
+	secs := int(d / time.Second)
+	if d%time.Second != 0 {
+		secs++
+	}
+	if secs <= 0 {
+		secs = 1
+	}
+	return secs
+}
+
+// ParseRouteLimits разбирает RATE_LIMIT_ROUTES: "/stats/*=2/1s;/pullRequest/create=5/1m"
+func ParseRouteLimits(raw string) ([]RouteLimit, error) {
+	var routes []RouteLimit
+	for _, part := range strings.Split(raw, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		pattern, spec, ok := strings.Cut(part, "=")
+		pattern = strings.TrimSpace(pattern)
+		if !ok || !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("invalid route limit %q: expected /pattern=N/interval", part)
+		}
+		count, period, ok := strings.Cut(strings.TrimSpace(spec), "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid route limit %q: expected /pattern=N/interval", part)
+		}
+		limit, err := strconv.Atoi(count)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid route limit %q: limit must be a positive integer", part)
+		}
+		interval, err := time.ParseDuration(period)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid route limit %q: interval must be a positive duration", part)
+		}
+		routes = append(routes, RouteLimit{Pattern: pattern, Limit: limit, Interval: interval})
+	}
+	return routes, nil
+}
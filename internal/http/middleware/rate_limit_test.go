@@ -1,10 +1,16 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/mashhkensss/PR-service/internal/ratelimit"
 )
 
 func TestRateLimiter_AllowsWithinLimit(t *testing.T) {
@@ -71,3 +77,136 @@ func TestRateLimiter_TrustForwardedHeader(t *testing.T) {
 		t.Fatalf("request from another forwarded IP should pass, got %d", recB.Result().StatusCode)
 	}
 }
+
+func TestRateLimiter_OnReject(t *testing.T) {
+	rl := NewRateLimiter(1, time.Hour, false)
+	rejected := 0
+	rl.OnReject(func(r *http.Request) { rejected++ })
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 3; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+	}
+	if rejected != 2 {
+		t.Fatalf("expected 2 rejections, got %d", rejected)
+	}
+}
+
+func TestRateLimiter_KeysBySubject(t *testing.T) {
+	rl := NewRateLimiter(1, time.Hour, false)
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// оба запроса с одного IP (общий NAT), но от разных субъектов
+	for _, sub := range []string{"u1", "u2"} {
+		req := httptest.NewRequest(http.MethodGet, "/team/get", nil)
+		req = req.WithContext(contextWithClaims(req.Context(), Claims{Subject: sub}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("subject %s should have its own bucket, got %d", sub, rec.Code)
+		}
+	}
+}
+
+func TestRateLimiter_RouteOverrides(t *testing.T) {
+	routes, err := ParseRouteLimits("/stats/*=1/1h; /team/add=5/1m")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	rl := NewRateLimiter(3, time.Hour, false).WithRoutes(routes...)
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	r := chi.NewRouter()
+	r.Route("/stats", func(r chi.Router) {
+		r.With(rl.Middleware).Get("/summary", ok)
+		r.With(rl.Middleware).Get("/fairness", ok)
+	})
+	r.With(rl.Middleware).Get("/team/get", ok)
+
+	call := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	first := call("/stats/summary")
+	if first.Code != http.StatusOK {
+		t.Fatalf("first stats call should pass, got %d", first.Code)
+	}
+	if first.Header().Get("RateLimit-Limit") != "1" || first.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("unexpected headers %v", first.Header())
+	}
+	if rec := call("/stats/fairness"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("stats routes share the override bucket, got %d", rec.Code)
+	}
+
+	// общий бюджет не тронут запросами к stats
+	rec := call("/team/get")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("default bucket should be untouched, got %d", rec.Code)
+	}
+	if rec.Header().Get("RateLimit-Limit") != "3" || rec.Header().Get("RateLimit-Remaining") != "2" || rec.Header().Get("RateLimit-Reset") == "" {
+		t.Fatalf("unexpected headers %v", rec.Header())
+	}
+}
+
+func TestRateLimiter_ByIPCoversRejectedAndUnknownRoutes(t *testing.T) {
+	rl := NewRateLimiter(2, time.Hour, false).ByIP()
+	r := chi.NewRouter()
+	r.Use(rl.Middleware)
+	r.Get("/team/get", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	call := func(path, sub string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req = req.WithContext(contextWithClaims(req.Context(), Claims{Subject: sub}))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := call("/team/get", "u1")
+	if first.Code != http.StatusUnauthorized || first.Header().Get("RateLimit-Limit") != "2" {
+		t.Fatalf("rejected request must still carry limit headers, got %d %v", first.Code, first.Header())
+	}
+	if rec := call("/missing", "u2"); rec.Code != http.StatusNotFound || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("unknown route must spend the same IP bucket, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := call("/team/get", "u3"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("IP bucket must ignore subject, got %d", rec.Code)
+	}
+}
+
+func TestParseRouteLimits_Invalid(t *testing.T) {
+	for _, raw := range []string{"stats=1/1s", "/stats=1", "/stats=0/1s", "/stats=1/soon"} {
+		if _, err := ParseRouteLimits(raw); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, int, time.Duration, time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("db down")
+}
+
+func TestRateLimiter_StoreErrorFailsOpen(t *testing.T) {
+	var storeErr error
+	rl := NewRateLimiter(1, time.Hour, false).WithStore(failingStore{})
+	rl.OnError(func(r *http.Request, err error) { storeErr = err })
+	handler := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+	if rec.Code != http.StatusOK || storeErr == nil {
+		t.Fatalf("store failure must let the request through and be reported, got %d", rec.Code)
+	}
+}
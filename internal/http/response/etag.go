@@ -0,0 +1,67 @@
+package response
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+)
+
+// ETag — сильный тег версии агрегата
+func ETag(v domain.Version) string {
+	return `"` + strconv.FormatInt(int64(v), 10) + `"`
+}
+
+// WeakETag — тег для списков: у списка нет своей версии, поэтому хешируем версии входящих в него агрегатов
+func WeakETag(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// IfMatch разбирает заголовок If-Match. Без заголовка и для "*" возвращает AnyVersion.
+// Поддерживается один сильный тег версии: слабый, составной или чужой тег совпасть не может,
+// поэтому запрос отклоняется как несовпадение версии
+func IfMatch(r *http.Request) (domain.Version, error) {
+	raw := strings.TrimSpace(r.Header.Get("If-Match"))
+	if raw == "" || raw == "*" {
+		return domain.AnyVersion, nil
+	}
+	tag, ok := strings.CutPrefix(raw, `"`)
+	if !ok {
+		return 0, domain.ErrVersionMismatch
+	}
+	tag, ok = strings.CutSuffix(tag, `"`)
+	if !ok {
+		return 0, domain.ErrVersionMismatch
+	}
+	v, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || v <= 0 {
+		return 0, domain.ErrVersionMismatch
+	}
+	return domain.Version(v), nil
+}
+
+// NotModified выставляет ETag и, если он есть в If-None-Match, отвечает 304 без тела.
+// Теги сравниваются слабо, как требует RFC 9110 для If-None-Match
+func NotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -3,18 +3,29 @@ package response
 import (
 	"bytes"
 	"encoding/json"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
 
 	"github.com/mashhkensss/PR-service/internal/http/dto"
 )
 
+const ProblemContentType = "application/problem+json"
+
 func JSON(w http.ResponseWriter, status int, payload any) {
+	write(w, status, "application/json", payload)
+}
+
+func write(w http.ResponseWriter, status int, contentType string, payload any) {
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(payload); err != nil {
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(status)
 	_, _ = w.Write(buf.Bytes())
 }
@@ -23,6 +34,31 @@ func Error(w http.ResponseWriter, status int, code, message string) {
 	JSON(w, status, dto.NewErrorResponse(code, message))
 }
 
-func ErrorResponse(w http.ResponseWriter, status int, resp dto.ErrorResponse) {
-	JSON(w, status, resp)
+// ErrorResponse отвечает problem+json, если клиент перечислил его в Accept,
+// иначе прежним {"error": {...}}, чтобы не ломать существующих клиентов
+func ErrorResponse(w http.ResponseWriter, r *http.Request, status int, resp dto.ErrorResponse) {
+	w.Header().Add("Vary", "Accept")
+	if r == nil || !AcceptsProblem(r) {
+		JSON(w, status, resp)
+		return
+	}
+	write(w, status, ProblemContentType, resp.Problem(status, http.StatusText(status), chimw.GetReqID(r.Context())))
+}
+
+// AcceptsProblem — в Accept есть application/problem+json с ненулевым q.
+// */* и application/json его не включают: старые клиенты присылают их по умолчанию
+func AcceptsProblem(r *http.Request) bool {
+	for _, part := range strings.Split(strings.Join(r.Header.Values("Accept"), ","), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != ProblemContentType {
+			continue
+		}
+		q, ok := params["q"]
+		if !ok {
+			return true
+		}
+		weight, err := strconv.ParseFloat(q, 64)
+		return err == nil && weight > 0
+	}
+	return false
 }
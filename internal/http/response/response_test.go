@@ -1,11 +1,17 @@
 package response
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
 
+	chimw "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
 	"github.com/mashhkensss/PR-service/internal/http/dto"
 )
 
@@ -37,7 +43,7 @@ func TestJSONAndErrorResponses(t *testing.T) {
 		{
 			name: "error response helper",
 			write: func(w http.ResponseWriter) {
-				ErrorResponse(w, http.StatusBadRequest, dto.NewErrorResponse("INVALID", "bad input"))
+				ErrorResponse(w, httptest.NewRequest(http.MethodGet, "/", nil), http.StatusBadRequest, dto.NewErrorResponse("INVALID", "bad input"))
 			},
 			wantStatus: http.StatusBadRequest,
 			wantBody:   dto.NewErrorResponse("INVALID", "bad input"),
@@ -106,6 +112,59 @@ func TestJSON_EncodeError(t *testing.T) {
 	}
 }
 
+func TestIfMatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		header  string
+		want    domain.Version
+		wantErr bool
+	}{
+		{header: "", want: domain.AnyVersion},
+		{header: "*", want: domain.AnyVersion},
+		{header: `"7"`, want: 7},
+		{header: `W/"7"`, wantErr: true},
+		{header: `"7", "8"`, wantErr: true},
+		{header: `"abc"`, wantErr: true},
+		{header: "7", wantErr: true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("If-Match", tt.header)
+		got, err := IfMatch(req)
+		if tt.wantErr {
+			if !errors.Is(err, domain.ErrVersionMismatch) {
+				t.Fatalf("%q: expected ErrVersionMismatch, got %v", tt.header, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Fatalf("%q: got %d err=%v", tt.header, got, err)
+		}
+	}
+}
+
+func TestNotModifiedComparesWeakly(t *testing.T) {
+	t.Parallel()
+
+	etag := WeakETag("pr-1:2", "pr-2:1")
+	if etag != WeakETag("pr-1:2", "pr-2:1") || etag == WeakETag("pr-1:3", "pr-2:1") {
+		t.Fatalf("weak tag must depend only on its parts")
+	}
+	for _, header := range []string{etag, `"x", ` + etag, "*"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("If-None-Match", header)
+		rr := httptest.NewRecorder()
+		if !NotModified(rr, req, etag) || rr.Code != http.StatusNotModified {
+			t.Fatalf("%q: expected 304, got %d", header, rr.Code)
+		}
+	}
+	rr := httptest.NewRecorder()
+	if NotModified(rr, httptest.NewRequest(http.MethodGet, "/", nil), etag) || rr.Header().Get("ETag") != etag {
+		t.Fatalf("request without If-None-Match must only get the ETag")
+	}
+}
+
 func equalJSONBodies(got, want any) bool {
 	gotBytes, err := json.Marshal(got)
 	if err != nil {
@@ -117,3 +176,70 @@ func equalJSONBodies(got, want any) bool {
 	}
 	return string(gotBytes) == string(wantBytes)
 }
+
+func TestErrorResponse_NegotiatesProblem(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		accept      string
+		wantProblem bool
+	}{
+		{accept: "", wantProblem: false},
+		{accept: "*/*", wantProblem: false},
+		{accept: "application/json", wantProblem: false},
+		{accept: "application/problem+json", wantProblem: true},
+		{accept: "application/json;q=0.9, application/problem+json", wantProblem: true},
+		{accept: "application/problem+json;q=0", wantProblem: false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), chimw.RequestIDKey, "req-1"))
+		resp := dto.NewErrorResponse("INVALID_REQUEST", "bad input")
+		resp.Violations = []dto.FieldViolation{{Field: "user_id", Rule: "required", Message: "is required"}}
+		rr := httptest.NewRecorder()
+
+		ErrorResponse(rr, req, http.StatusBadRequest, resp)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("%q: status = %d", tt.accept, rr.Code)
+		}
+		if vary := rr.Header().Get("Vary"); vary != "Accept" {
+			t.Fatalf("%q: Vary = %q", tt.accept, vary)
+		}
+		if !tt.wantProblem {
+			if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+				t.Fatalf("%q: content-type = %s", tt.accept, ct)
+			}
+			var legacy map[string]any
+			if err := json.Unmarshal(rr.Body.Bytes(), &legacy); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if len(legacy) != 1 || legacy["error"] == nil {
+				t.Fatalf("%q: legacy body changed: %s", tt.accept, rr.Body.String())
+			}
+			continue
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != ProblemContentType {
+			t.Fatalf("%q: content-type = %s", tt.accept, ct)
+		}
+		var problem dto.Problem
+		if err := json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("decode problem: %v", err)
+		}
+		want := dto.Problem{
+			Type:     "urn:pr-service:problem:invalid-request",
+			Title:    "Bad Request",
+			Status:   http.StatusBadRequest,
+			Detail:   "bad input",
+			Instance: "req-1",
+			Code:     "INVALID_REQUEST",
+			Errors:   resp.Violations,
+		}
+		if !reflect.DeepEqual(problem, want) {
+			t.Fatalf("%q: problem = %+v, want %+v", tt.accept, problem, want)
+		}
+	}
+}
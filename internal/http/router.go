@@ -6,10 +6,18 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 
+	apikeyhandler "github.com/mashhkensss/PR-service/internal/http/handlers/apikey"
+	authhandler "github.com/mashhkensss/PR-service/internal/http/handlers/auth"
+	batchhandler "github.com/mashhkensss/PR-service/internal/http/handlers/batch"
 	healthhandler "github.com/mashhkensss/PR-service/internal/http/handlers/health"
+	idempotencyhandler "github.com/mashhkensss/PR-service/internal/http/handlers/idempotency"
 	prhandler "github.com/mashhkensss/PR-service/internal/http/handlers/pullrequest"
+	revocationhandler "github.com/mashhkensss/PR-service/internal/http/handlers/revocation"
+	slahandler "github.com/mashhkensss/PR-service/internal/http/handlers/sla"
 	statshandler "github.com/mashhkensss/PR-service/internal/http/handlers/stats"
+	streamhandler "github.com/mashhkensss/PR-service/internal/http/handlers/stream"
 	teamhandler "github.com/mashhkensss/PR-service/internal/http/handlers/team"
+	transferhandler "github.com/mashhkensss/PR-service/internal/http/handlers/transfer"
 	userhandler "github.com/mashhkensss/PR-service/internal/http/handlers/user"
 	mw "github.com/mashhkensss/PR-service/internal/http/middleware"
 )
@@ -19,18 +27,40 @@ type RouterConfig struct {
 	UserHandler   userhandler.Handler
 	PRHandler     prhandler.Handler
 	StatsHandler  statshandler.Handler
-	HealthHandler healthhandler.Handler
-
-	Auth        *mw.Authorization
+	SLAHandler    slahandler.Handler
+	APIKeyHandler apikeyhandler.Handler
+	TokenHandler  authhandler.Handler
+	// RevocationHandler не зависит от TokenHandler: отзывать можно и токены внешнего IdP
+	RevocationHandler  revocationhandler.Handler
+	IdempotencyHandler idempotencyhandler.Handler
+	StreamHandler      streamhandler.Handler
+	BatchHandler       batchhandler.Handler
+	TransferHandler    transferhandler.Handler
+	HealthHandler      healthhandler.Handler
+
+	Auth *mw.Authorization
+	// Idempotency ставится после аутентификации и лимита: ключ привязан к subject,
+	// а отклонённый лимитом запрос не должен занимать ключ
 	Idempotency func(http.Handler) http.Handler
+	// IPRateLimiter ставится на весь роутер до аутентификации: ограничивает по IP и то, что
+	// отклоняется раньше RateLimiter, — неверные токены и ключи, несуществующие маршруты
+	IPRateLimiter func(http.Handler) http.Handler
+	// RateLimiter ставится на каждый маршрут после аутентификации, чтобы видеть subject и шаблон маршрута
 	RateLimiter func(http.Handler) http.Handler
 	Validator   func(http.Handler) http.Handler
 	Logger      func(http.Handler) http.Handler
+	Metrics     func(http.Handler) http.Handler
+	Tracing     func(http.Handler) http.Handler
+	// MetricsHandler, если задан, отдаётся на GET /metrics без авторизации
+	MetricsHandler http.Handler
 }
 
 func NewRouter(cfg RouterConfig) http.Handler {
 	r := chi.NewRouter()
 
+	if cfg.Tracing != nil {
+		r.Use(cfg.Tracing)
+	}
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Recoverer)
@@ -38,57 +68,119 @@ func NewRouter(cfg RouterConfig) http.Handler {
 	if cfg.Logger != nil {
 		r.Use(cfg.Logger)
 	}
-	if cfg.RateLimiter != nil {
-		r.Use(cfg.RateLimiter)
+	if cfg.Metrics != nil {
+		r.Use(cfg.Metrics)
+	}
+	if cfg.IPRateLimiter != nil {
+		r.Use(cfg.IPRateLimiter)
 	}
 	if cfg.Validator != nil {
 		r.Use(cfg.Validator)
 	}
-	if cfg.Idempotency != nil {
-		r.Use(cfg.Idempotency)
+	if cfg.MetricsHandler != nil {
+		r.With(cfg.limited()).Method(http.MethodGet, "/metrics", cfg.MetricsHandler)
 	}
 
 	if cfg.HealthHandler != nil {
 		r.Route("/health", func(r chi.Router) {
-			r.Get("/live", cfg.HealthHandler.Liveness)
-			r.Get("/ready", cfg.HealthHandler.Readiness)
+			r.With(cfg.limited()).Get("/live", cfg.HealthHandler.Liveness)
+			r.With(cfg.limited()).Get("/ready", cfg.HealthHandler.Readiness)
 		})
 	}
 
 	r.Route("/team", func(r chi.Router) {
-		r.With(cfg.adminOnly()).Post("/add", cfg.TeamHandler.AddTeam)
-		r.With(cfg.userOrAdmin()).Get("/get", cfg.TeamHandler.GetTeam)
+		r.With(cfg.authenticated()).Post("/add", cfg.TeamHandler.AddTeam)
+		r.With(cfg.authenticated()).Get("/get", cfg.TeamHandler.GetTeam)
+		r.With(cfg.authenticated()).Post("/addMember", cfg.TeamHandler.AddMember)
+		r.With(cfg.authenticated()).Post("/removeMember", cfg.TeamHandler.RemoveMember)
 	})
 
 	r.Route("/users", func(r chi.Router) {
-		r.With(cfg.adminOnly()).Post("/setIsActive", cfg.UserHandler.SetIsActive)
-		r.With(cfg.userOrAdmin()).Get("/getReview", cfg.UserHandler.GetReview)
+		r.With(cfg.authenticated()).Post("/setIsActive", cfg.UserHandler.SetIsActive)
+		r.With(cfg.authenticated()).Get("/getReview", cfg.UserHandler.GetReview)
 	})
 
 	r.Route("/pullRequest", func(r chi.Router) {
-		r.With(cfg.adminOnly()).Post("/create", cfg.PRHandler.CreatePullRequest)
-		r.With(cfg.adminOnly()).Post("/merge", cfg.PRHandler.MergePullRequest)
-		r.With(cfg.adminOnly()).Post("/reassign", cfg.PRHandler.ReassignReviewer)
+		r.With(cfg.authenticated()).Post("/create", cfg.PRHandler.CreatePullRequest)
+		r.With(cfg.authenticated()).Post("/merge", cfg.PRHandler.MergePullRequest)
+		r.With(cfg.authenticated()).Post("/reassign", cfg.PRHandler.ReassignReviewer)
+		r.With(cfg.authenticated()).Post("/review", cfg.PRHandler.SubmitReview)
 	})
 
 	r.Route("/stats", func(r chi.Router) {
-		r.With(cfg.adminOnly()).Get("/assignments", cfg.StatsHandler.GetAssignmentStats)
-		r.With(cfg.adminOnly()).Get("/summary", cfg.StatsHandler.GetSummary)
+		r.With(cfg.authenticated()).Get("/assignments", cfg.StatsHandler.GetAssignmentStats)
+		r.With(cfg.authenticated()).Get("/summary", cfg.StatsHandler.GetSummary)
+		r.With(cfg.authenticated()).Get("/cycle-time", cfg.StatsHandler.GetCycleTime)
+		r.With(cfg.authenticated()).Get("/fairness", cfg.StatsHandler.GetFairness)
 	})
 
+	if cfg.BatchHandler != nil {
+		r.With(cfg.authenticated()).Post("/batch", cfg.BatchHandler.Execute)
+	}
+
+	if cfg.TransferHandler != nil {
+		r.Route("/admin", func(r chi.Router) {
+			r.With(cfg.authenticated()).Get("/export", cfg.TransferHandler.Export)
+			r.With(cfg.authenticated()).Post("/import", cfg.TransferHandler.Import)
+		})
+	}
+
+	if cfg.SLAHandler != nil {
+		r.Route("/sla", func(r chi.Router) {
+			r.With(cfg.authenticated()).Post("/policy", cfg.SLAHandler.SetPolicy)
+			r.With(cfg.authenticated()).Get("/policy", cfg.SLAHandler.GetPolicy)
+			r.With(cfg.authenticated()).Get("/breaches", cfg.SLAHandler.ListBreaches)
+		})
+	}
+
+	if cfg.StreamHandler != nil {
+		r.With(cfg.authenticated()).Get("/events/stream", cfg.StreamHandler.Stream)
+	}
+
+	if cfg.TokenHandler != nil {
+		r.With(cfg.authenticated()).Post("/auth/token", cfg.TokenHandler.IssueToken)
+	}
+	if cfg.RevocationHandler != nil {
+		r.With(cfg.authenticated()).Post("/auth/revoke", cfg.RevocationHandler.Revoke)
+		r.With(cfg.authenticated()).Get("/auth/revocations", cfg.RevocationHandler.List)
+	}
+
+	if cfg.IdempotencyHandler != nil {
+		r.Route("/idempotencyKeys", func(r chi.Router) {
+			r.With(cfg.authenticated()).Get("/get", cfg.IdempotencyHandler.Get)
+			r.With(cfg.authenticated()).Post("/delete", cfg.IdempotencyHandler.Delete)
+		})
+	}
+
+	if cfg.APIKeyHandler != nil {
+		r.Route("/apiKeys", func(r chi.Router) {
+			r.With(cfg.authenticated()).Post("/create", cfg.APIKeyHandler.Create)
+			r.With(cfg.authenticated()).Get("/list", cfg.APIKeyHandler.List)
+			r.With(cfg.authenticated()).Post("/revoke", cfg.APIKeyHandler.Revoke)
+		})
+	}
+
 	return r
 }
 
-func (cfg RouterConfig) adminOnly() func(http.Handler) http.Handler {
-	if cfg.Auth == nil {
-		return func(next http.Handler) http.Handler { return next }
+// authenticated проверяет токен, затем лимит запросов субъекта и ключ идемпотентности
+func (cfg RouterConfig) authenticated() func(http.Handler) http.Handler {
+	limited := cfg.limited()
+	return func(next http.Handler) http.Handler {
+		if cfg.Idempotency != nil {
+			next = cfg.Idempotency(next)
+		}
+		next = limited(next)
+		if cfg.Auth == nil {
+			return next
+		}
+		return cfg.Auth.RequireAuthenticated(next)
 	}
-	return cfg.Auth.RequireAdmin
 }
 
-func (cfg RouterConfig) userOrAdmin() func(http.Handler) http.Handler {
-	if cfg.Auth == nil {
+func (cfg RouterConfig) limited() func(http.Handler) http.Handler {
+	if cfg.RateLimiter == nil {
 		return func(next http.Handler) http.Handler { return next }
 	}
-	return cfg.Auth.RequireUserOrAdmin
+	return cfg.RateLimiter
 }
@@ -2,13 +2,28 @@ package idempotency
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"time"
 )
 
+// ErrReservationLost — pending-запись истекла и её перехватил другой запрос; ответ этого запроса не сохраняется
+var ErrReservationLost = errors.New("idempotency reservation taken over")
+
+// Key — Idempotency-Key в области субъекта и эндпоинта: одинаковые ключи разных пользователей
+// или разных методов не пересекаются
+type Key struct {
+	Subject string
+	Method  string
+	Path    string
+	Value   string
+}
+
+// StoredResponse — ответ, отдаваемый на повторы; заголовки хранятся со всеми значениями
 type StoredResponse struct {
 	Status int
 	Body   []byte
-	Header map[string]string
+	Header http.Header
 }
 
 type StoredRequest struct {
@@ -17,7 +32,36 @@ type StoredRequest struct {
 	Body   []byte
 }
 
+// Reservation — итог попытки занять ключ
+type Reservation struct {
+	// Acquired — ключ занят этим запросом: выполнить обработчик и затем вызвать Complete или Release с Token
+	Acquired bool
+	// Token — владелец pending-записи. Запрос, чья запись истекла и перехвачена повтором,
+	// не может завершить или снять запись нового владельца
+	Token string
+	// Pending — ключ занят другим запросом, который ещё выполняется
+	Pending bool
+	// Request — запрос, которым ключ был занят; Response заполнен, если тот запрос завершился
+	Request  StoredRequest
+	Response StoredResponse
+}
+
+// Storage реализует протокол reserve-then-complete: запись создаётся до выполнения обработчика,
+// поэтому параллельные дубли не выполняются дважды
 type Storage interface {
-	Get(ctx context.Context, key string) (StoredRequest, StoredResponse, bool, error)
-	Save(ctx context.Context, key string, req StoredRequest, ttl time.Duration, resp StoredResponse) error
+	// Reserve атомарно создаёт pending-запись на lockTTL. Если ключ уже занят, возвращает существующую запись.
+	// Просроченная pending-запись (экземпляр упал посреди запроса) перехватывается
+	Reserve(ctx context.Context, key Key, req StoredRequest, lockTTL time.Duration) (Reservation, error)
+	// Complete сохраняет ответ и держит его ttl. ErrReservationLost — запись token уже перехвачена
+	Complete(ctx context.Context, key Key, token string, resp StoredResponse, ttl time.Duration) error
+	// Release снимает pending-запись token, чтобы повтор выполнился заново; перехваченная запись не трогается
+	Release(ctx context.Context, key Key, token string) error
+}
+
+// Record — сведения о записи для администратора; тела запроса и ответа не раскрываются
+type Record struct {
+	Key       Key
+	Pending   bool
+	Status    int
+	ExpiresAt time.Time
 }
@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rr.Body)
+	if err != nil {
+		t.Fatalf("read metrics: %v", err)
+	}
+	return string(body)
+}
+
+func TestMiddleware_LabelsByRoutePattern(t *testing.T) {
+	m := New()
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/team/{name}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/team/backend", nil))
+
+	out := scrape(t, m)
+	want := `pr_service_http_requests_total{method="GET",route="/team/{name}",status="404"} 1`
+	if !strings.Contains(out, want) {
+		t.Fatalf("expected %q in output:\n%s", want, out)
+	}
+	if !strings.Contains(out, `pr_service_http_request_duration_seconds_bucket{method="GET",route="/team/{name}",status="404",le="0.3"} 1`) {
+		t.Fatalf("expected latency histogram with 0.3s bucket:\n%s", out)
+	}
+}
+
+func TestDomainCounters(t *testing.T) {
+	m := New()
+	m.PullRequestCreated(1, 2)
+	m.PullRequestCreated(2, 2)
+	m.PullRequestMerged()
+	m.ReviewerReassigned()
+	m.Idempotency("hit")
+	m.RateLimited(nil)
+
+	out := scrape(t, m)
+	for _, want := range []string{
+		"pr_service_pull_requests_created_total 2",
+		"pr_service_reviewer_slots_empty_total 1",
+		"pr_service_pull_requests_merged_total 1",
+		"pr_service_reviewers_reassigned_total 1",
+		`pr_service_idempotency_requests_total{result="hit"} 1`,
+		"pr_service_rate_limit_rejections_total 1",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Message — событие, сохранённое в outbox и ожидающее доставки
+type Message struct {
+	ID            int64
+	AggregateType string
+	AggregateID   string
+	EventType     string
+	Payload       json.RawMessage
+	OccurredAt    time.Time
+	// Attempts — номер текущей попытки доставки, начиная с 1
+	Attempts int
+}
+
+// Envelope — формат, в котором событие уходит во внешние системы. ID стабилен между
+// повторными доставками, по нему получатель отбрасывает дубли
+type Envelope struct {
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Attempt       int             `json:"attempt"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+func (m Message) Envelope() Envelope {
+	return Envelope{
+		ID:            m.ID,
+		Type:          m.EventType,
+		AggregateType: m.AggregateType,
+		AggregateID:   m.AggregateID,
+		OccurredAt:    m.OccurredAt,
+		Attempt:       m.Attempts,
+		Payload:       m.Payload,
+	}
+}
+
+// Storage выдаёт сообщения релею. Порядок внутри агрегата обеспечивается тем, что Claim
+// отдаёт только самое раннее неопубликованное сообщение каждого агрегата
+type Storage interface {
+	// Claim забирает до limit готовых к отправке сообщений и прячет их от других релеев на lease.
+	// Если релей упадёт, не подтвердив отправку, сообщение будет выдано снова после lease
+	Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]Message, error)
+	MarkPublished(ctx context.Context, id int64, at time.Time) error
+	// MarkFailed откладывает следующую попытку до retryAt; более поздние события агрегата ждут
+	MarkFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error
+	// MarkDead прекращает попытки доставки; следующие события агрегата идут дальше без него
+	MarkDead(ctx context.Context, id int64, reason string, at time.Time) error
+	// PurgePublished удаляет до limit сообщений, опубликованных до before
+	PurgePublished(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// Sink доставляет сообщение получателю. Ошибка означает, что доставка будет повторена
+type Sink interface {
+	Publish(ctx context.Context, msg Message) error
+}
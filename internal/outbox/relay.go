@@ -0,0 +1,141 @@
+package outbox
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+)
+
+type RelayOptions struct {
+	// Interval — пауза между опросами, когда outbox пуст
+	Interval time.Duration
+	Batch    int
+	// Lease — сколько сообщение скрыто от других релеев, пока идёт отправка
+	Lease time.Duration
+	// BaseBackoff и MaxBackoff задают экспоненциальную паузу между повторами одного сообщения
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// Retention — сколько хранить опубликованные сообщения; 0 отключает очистку
+	Retention time.Duration
+	// MaxAttempts — после стольких неудачных попыток сообщение становится мёртвым
+	MaxAttempts int
+}
+
+// Relay доставляет сообщения из outbox в Sink с гарантией at-least-once: сообщение помечается
+// опубликованным только после успешной отправки
+type Relay struct {
+	store  Storage
+	sink   Sink
+	opts   RelayOptions
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+func NewRelay(store Storage, sink Sink, opts RelayOptions, logger *slog.Logger) *Relay {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.Batch <= 0 {
+		opts.Batch = 100
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = 30 * time.Second
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = time.Second
+	}
+	if opts.MaxBackoff < opts.BaseBackoff {
+		opts.MaxBackoff = 5 * time.Minute
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 20
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return &Relay{store: store, sink: sink, opts: opts, logger: logger, now: func() time.Time { return time.Now().UTC() }}
+}
+
+// Run блокируется до отмены ctx
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.tick(ctx)
+		}
+	}
+}
+
+func (r *Relay) tick(ctx context.Context) {
+	// полная пачка — вероятно, есть ещё: забираем сразу, не дожидаясь тикера
+	for ctx.Err() == nil {
+		n, err := r.Drain(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.logger.Error("outbox claim failed", "error", err)
+			}
+			return
+		}
+		if n < r.opts.Batch {
+			break
+		}
+	}
+	if r.opts.Retention > 0 {
+		n, err := r.store.PurgePublished(ctx, r.now().Add(-r.opts.Retention), r.opts.Batch)
+		if err != nil && ctx.Err() == nil {
+			r.logger.Error("outbox purge failed", "error", err)
+		}
+		if n > 0 {
+			r.logger.Debug("outbox messages purged", "purged", n)
+		}
+	}
+}
+
+// Drain отправляет одну пачку и возвращает, сколько сообщений было забрано
+func (r *Relay) Drain(ctx context.Context) (int, error) {
+	msgs, err := r.store.Claim(ctx, r.now(), r.opts.Batch, r.opts.Lease)
+	if err != nil {
+		return 0, err
+	}
+	// в пачке не больше одного сообщения на агрегат, поэтому порядок между ними не важен
+	for _, msg := range msgs {
+		r.publish(ctx, msg)
+	}
+	return len(msgs), nil
+}
+
+func (r *Relay) publish(ctx context.Context, msg Message) {
+	if err := r.sink.Publish(ctx, msg); err != nil {
+		if msg.Attempts >= r.opts.MaxAttempts {
+			r.logger.Error("outbox message dead-lettered", "id", msg.ID, "type", msg.EventType, "aggregate_id", msg.AggregateID, "attempt", msg.Attempts, "error", err)
+			if markErr := r.store.MarkDead(ctx, msg.ID, err.Error(), r.now()); markErr != nil && ctx.Err() == nil {
+				r.logger.Error("outbox mark dead failed", "id", msg.ID, "error", markErr)
+			}
+			return
+		}
+		retryAt := r.now().Add(r.backoff(msg.Attempts))
+		r.logger.Warn("outbox publish failed", "id", msg.ID, "type", msg.EventType, "attempt", msg.Attempts, "retry_at", retryAt, "error", err)
+		if markErr := r.store.MarkFailed(ctx, msg.ID, err.Error(), retryAt); markErr != nil && ctx.Err() == nil {
+			// сообщение вернётся после lease
+			r.logger.Error("outbox mark failed", "id", msg.ID, "error", markErr)
+		}
+		return
+	}
+	if err := r.store.MarkPublished(ctx, msg.ID, r.now()); err != nil && ctx.Err() == nil {
+		// отправка повторится после lease: получатель отбросит дубль по id
+		r.logger.Error("outbox mark published failed", "id", msg.ID, "error", err)
+	}
+}
+
+func (r *Relay) backoff(attempt int) time.Duration {
+	d := r.opts.BaseBackoff
+	for i := 1; i < attempt && d < r.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, r.opts.MaxBackoff)
+}
@@ -0,0 +1,150 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type memoryStorage struct {
+	pending   []Message
+	published []int64
+	failed    map[int64]time.Time
+	dead      []int64
+}
+
+func (s *memoryStorage) Claim(_ context.Context, _ time.Time, limit int, _ time.Duration) ([]Message, error) {
+	n := min(limit, len(s.pending))
+	claimed := s.pending[:n]
+	s.pending = s.pending[n:]
+	for i := range claimed {
+		claimed[i].Attempts++
+	}
+	return claimed, nil
+}
+
+func (s *memoryStorage) MarkPublished(_ context.Context, id int64, _ time.Time) error {
+	s.published = append(s.published, id)
+	return nil
+}
+
+func (s *memoryStorage) MarkFailed(_ context.Context, id int64, _ string, retryAt time.Time) error {
+	s.failed[id] = retryAt
+	return nil
+}
+
+func (s *memoryStorage) MarkDead(_ context.Context, id int64, _ string, _ time.Time) error {
+	s.dead = append(s.dead, id)
+	return nil
+}
+
+func (s *memoryStorage) PurgePublished(context.Context, time.Time, int) (int64, error) {
+	return 0, nil
+}
+
+type sinkFunc func(ctx context.Context, msg Message) error
+
+func (f sinkFunc) Publish(ctx context.Context, msg Message) error { return f(ctx, msg) }
+
+func TestRelayMarksPublishedAndBacksOffFailures(t *testing.T) {
+	store := &memoryStorage{
+		pending: []Message{{ID: 1, EventType: "A"}, {ID: 2, EventType: "B", Attempts: 3}, {ID: 3, EventType: "A"}},
+		failed:  map[int64]time.Time{},
+	}
+	sink := sinkFunc(func(_ context.Context, msg Message) error {
+		if msg.EventType == "B" {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	relay := NewRelay(store, sink, RelayOptions{Batch: 2, BaseBackoff: time.Second, MaxBackoff: time.Minute}, nil)
+	relay.now = func() time.Time { return now }
+
+	relay.tick(context.Background())
+
+	if len(store.published) != 2 || store.published[0] != 1 || store.published[1] != 3 {
+		t.Fatalf("unexpected published %v", store.published)
+	}
+	// четвёртая попытка: 1s * 2^3
+	if got := store.failed[2]; !got.Equal(now.Add(8 * time.Second)) {
+		t.Fatalf("unexpected retry time %v", got)
+	}
+}
+
+func TestRelayDeadLettersAfterMaxAttempts(t *testing.T) {
+	store := &memoryStorage{
+		pending: []Message{{ID: 1, Attempts: 4}, {ID: 2, Attempts: 2}},
+		failed:  map[int64]time.Time{},
+	}
+	sink := sinkFunc(func(context.Context, Message) error { return errors.New("rejected") })
+	relay := NewRelay(store, sink, RelayOptions{Batch: 10, MaxAttempts: 5}, nil)
+
+	relay.tick(context.Background())
+
+	if len(store.dead) != 1 || store.dead[0] != 1 {
+		t.Fatalf("message on its last attempt must be dead-lettered, got %v", store.dead)
+	}
+	if _, retried := store.failed[1]; retried {
+		t.Fatalf("dead message must not be scheduled for retry")
+	}
+	if _, retried := store.failed[2]; !retried {
+		t.Fatalf("message with attempts left must be retried")
+	}
+}
+
+func TestRelayBackoffIsCapped(t *testing.T) {
+	relay := NewRelay(&memoryStorage{}, sinkFunc(nil), RelayOptions{BaseBackoff: time.Second, MaxBackoff: 10 * time.Second}, nil)
+	if got := relay.backoff(1); got != time.Second {
+		t.Fatalf("first retry must wait the base backoff, got %v", got)
+	}
+	if got := relay.backoff(50); got != 10*time.Second {
+		t.Fatalf("backoff must be capped, got %v", got)
+	}
+}
+
+func TestHTTPSinkPostsEnvelope(t *testing.T) {
+	var got Envelope
+	status := http.StatusAccepted
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Idempotency-Key") != "outbox-7" {
+			t.Errorf("unexpected idempotency key %q", r.Header.Get("Idempotency-Key"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink := NewHTTPSink(srv.URL, time.Second)
+	msg := Message{ID: 7, EventType: "PullRequestMerged", AggregateType: "pull_request", AggregateID: "pr-1", Payload: json.RawMessage(`{"pull_request_id":"pr-1"}`), Attempts: 1}
+	if err := sink.Publish(context.Background(), msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.ID != 7 || got.Type != "PullRequestMerged" || got.AggregateID != "pr-1" || !bytes.Contains(got.Payload, []byte("pr-1")) {
+		t.Fatalf("unexpected envelope %+v", got)
+	}
+
+	status = http.StatusInternalServerError
+	if err := sink.Publish(context.Background(), msg); err == nil {
+		t.Fatalf("non-2xx response must be retried")
+	}
+}
+
+func TestWriterSinkWritesJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+	for id := int64(1); id <= 2; id++ {
+		if err := sink.Publish(context.Background(), Message{ID: id, Payload: json.RawMessage(`{}`)}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 || !json.Valid(lines[1]) {
+		t.Fatalf("unexpected output %q", buf.String())
+	}
+}
@@ -0,0 +1,164 @@
+package apikeyrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	domainapikey "github.com/mashhkensss/PR-service/internal/domain/apikey"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/persistence/postgres"
+)
+
+var keyColumns = []string{"id", "name", "role", "scopes", "created_at", "expires_at", "last_used_at", "revoked_at"}
+
+type Repository struct {
+	db  *sql.DB
+	sql sq.StatementBuilderType
+}
+
+func New(db *sql.DB) *Repository {
+	return &Repository{
+		db:  db,
+		sql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *Repository) CreateAPIKey(ctx context.Context, k domainapikey.Key, hash []byte) error {
+	query, args, err := r.sql.Insert("api_keys").
+		Columns("id", "name", "key_hash", "role", "scopes", "created_at", "expires_at").
+		Values(k.ID(), k.Name(), hash, string(k.Role()), joinScopes(k.Scopes()), k.CreatedAt(), k.ExpiresAt()).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("insert api key: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKey возвращает ключ вместе с хешем секрета для сверки
+func (r *Repository) GetAPIKey(ctx context.Context, id string) (domainapikey.Key, []byte, error) {
+	query, args, err := r.sql.Select(append(keyColumns, "key_hash")...).
+		From("api_keys").
+		Where("id = ?", id).
+		ToSql()
+	if err != nil {
+		return domainapikey.Key{}, nil, err
+	}
+	var hash []byte
+	row := postgres.ExecutorFromContext(ctx, r.db).QueryRowContext(ctx, query, args...)
+	k, err := scanKey(row, &hash)
+	if err != nil {
+		return domainapikey.Key{}, nil, fmt.Errorf("get api key: %w", err)
+	}
+	return k, hash, nil
+}
+
+func (r *Repository) ListAPIKeys(ctx context.Context) ([]domainapikey.Key, error) {
+	query, args, err := r.sql.Select(keyColumns...).
+		From("api_keys").
+		OrderBy("created_at ASC", "id ASC").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := postgres.ExecutorFromContext(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]domainapikey.Key, 0)
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey отзывает ключ; повторный отзыв сохраняет исходное время
+func (r *Repository) RevokeAPIKey(ctx context.Context, id string, at time.Time) error {
+	query, args, err := r.sql.Update("api_keys").
+		Set("revoked_at", sq.Expr("COALESCE(revoked_at, ?)", at.UTC())).
+		Where("id = ?", id).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	res, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// TouchAPIKey обновляет last_used_at, только если прошлая отметка старше since, чтобы не писать
+// в базу на каждый запрос бота
+func (r *Repository) TouchAPIKey(ctx context.Context, id string, at, since time.Time) error {
+	query, args, err := r.sql.Update("api_keys").
+		Set("last_used_at", at.UTC()).
+		Where("id = ?", id).
+		Where(sq.Or{sq.Eq{"last_used_at": nil}, sq.Lt{"last_used_at": since.UTC()}}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("touch api key: %w", err)
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanKey(row scanner, extra ...any) (domainapikey.Key, error) {
+	var (
+		id, name, role, scopes string
+		createdAt              time.Time
+		expiresAt, lastUsedAt  sql.NullTime
+		revokedAt              sql.NullTime
+	)
+	dest := append([]any{&id, &name, &role, &scopes, &createdAt, &expiresAt, &lastUsedAt, &revokedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return domainapikey.Key{}, err
+	}
+	// срок уже мог истечь, поэтому валидируем относительно момента создания
+	k, err := domainapikey.New(id, name, requester.Role(role), requester.ParseScopes(scopes), createdAt, nullTime(expiresAt))
+	if err != nil {
+		return domainapikey.Key{}, err
+	}
+	return k.WithUsage(nullTime(lastUsedAt), nullTime(revokedAt)), nil
+}
+
+func joinScopes(scopes []requester.Scope) string {
+	parts := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		parts = append(parts, string(s))
+	}
+	return strings.Join(parts, " ")
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
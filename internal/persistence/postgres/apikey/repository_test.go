@@ -0,0 +1,79 @@
+package apikeyrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+)
+
+func TestGetAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	created := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	used := created.Add(time.Hour)
+	rows := sqlmock.NewRows([]string{"id", "name", "role", "scopes", "created_at", "expires_at", "last_used_at", "revoked_at", "key_hash"}).
+		AddRow("abc", "ci-bot", "admin", "pull_requests:write stats:read", created, nil, used, nil, []byte("hash"))
+	mock.ExpectQuery(`SELECT id, name, role, scopes, created_at, expires_at, last_used_at, revoked_at, key_hash FROM api_keys WHERE id = \$1`).
+		WithArgs("abc").
+		WillReturnRows(rows)
+
+	k, hash, err := New(db).GetAPIKey(context.Background(), "abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(hash) != "hash" || k.Name() != "ci-bot" || k.Role() != requester.RoleAdmin {
+		t.Fatalf("unexpected key %+v", k)
+	}
+	if len(k.Scopes()) != 2 || k.LastUsedAt() == nil || !k.LastUsedAt().Equal(used) {
+		t.Fatalf("unexpected scopes or usage %+v", k)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRevokeAPIKeyNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE api_keys SET revoked_at = COALESCE\(revoked_at, \$1\) WHERE id = \$2`).
+		WithArgs(sqlmock.AnyArg(), "missing").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := New(db).RevokeAPIKey(context.Background(), "missing", time.Now()); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+func TestTouchAPIKeyThrottled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE api_keys SET last_used_at = \$1 WHERE id = \$2 AND \(last_used_at IS NULL OR last_used_at < \$3\)`).
+		WithArgs(sqlmock.AnyArg(), "abc", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	now := time.Now()
+	if err := New(db).TouchAPIKey(context.Background(), "abc", now, now.Add(-time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
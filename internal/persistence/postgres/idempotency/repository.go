@@ -2,10 +2,15 @@ package idempotencyrepo
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
@@ -14,27 +19,92 @@ import (
 	"github.com/mashhkensss/PR-service/internal/persistence/postgres"
 )
 
+const (
+	statePending   = "pending"
+	stateCompleted = "completed"
+)
+
+// reserveAttempts ограничивает гонку с Release: запись может исчезнуть между INSERT и SELECT
+const reserveAttempts = 3
+
 type Repository struct {
 	db  *sql.DB
 	sql sq.StatementBuilderType
 }
 
-func New(db *sql.DB) idempotency.Storage {
+func New(db *sql.DB) *Repository {
 	return &Repository{
 		db:  db,
 		sql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
 	}
 }
 
-func (r *Repository) Get(ctx context.Context, key string) (idempotency.StoredRequest, idempotency.StoredResponse, bool, error) {
-	exec := postgres.ExecutorFromContext(ctx, r.db)
-	query, args, err := r.sql.Select("method", "path", "request_body", "response_body", "response_headers", "status_code").
+func (r *Repository) Reserve(ctx context.Context, key idempotency.Key, req idempotency.StoredRequest, lockTTL time.Duration) (idempotency.Reservation, error) {
+	for i := 0; i < reserveAttempts; i++ {
+		now := time.Now().UTC()
+		token, err := newToken()
+		if err != nil {
+			return idempotency.Reservation{}, err
+		}
+		acquired, err := r.insertPending(ctx, key, token, req, now, lockTTL)
+		if err != nil {
+			return idempotency.Reservation{}, err
+		}
+		if acquired {
+			return idempotency.Reservation{Acquired: true, Token: token}, nil
+		}
+		res, found, err := r.load(ctx, key, now)
+		if err != nil {
+			return idempotency.Reservation{}, err
+		}
+		if found {
+			return res, nil
+		}
+	}
+	return idempotency.Reservation{}, errors.New("reserve idempotency key: record keeps changing")
+}
+
+// insertPending вставляет pending-запись или перехватывает истёкшую; занятая запись не меняется
+func (r *Repository) insertPending(ctx context.Context, key idempotency.Key, token string, req idempotency.StoredRequest, now time.Time, lockTTL time.Duration) (bool, error) {
+	query, args, err := r.sql.Insert("idempotency_keys").
+		Columns("key_hash", "subject", "idempotency_key", "method", "path", "request_body", "response_body", "response_headers", "status_code", "state", "owner_token", "expires_at").
+		Values(hashKey(key), key.Subject, key.Value, req.Method, req.Path, safeBytes(req.Body), []byte{}, []byte("{}"), 0, statePending, token, now.Add(lockTTL)).
+		Suffix(`
+			ON CONFLICT (key_hash) DO UPDATE
+			SET method = EXCLUDED.method,
+			    path = EXCLUDED.path,
+			    request_body = EXCLUDED.request_body,
+			    response_body = EXCLUDED.response_body,
+			    response_headers = EXCLUDED.response_headers,
+			    status_code = EXCLUDED.status_code,
+			    state = EXCLUDED.state,
+			    owner_token = EXCLUDED.owner_token,
+			    expires_at = EXCLUDED.expires_at
+			WHERE idempotency_keys.expires_at <= ?
+			RETURNING state`, now).
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+	var state string
+	err = postgres.ExecutorFromContext(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reserve idempotency key: %w", err)
+	}
+	return true, nil
+}
+
+func (r *Repository) load(ctx context.Context, key idempotency.Key, now time.Time) (idempotency.Reservation, bool, error) {
+	query, args, err := r.sql.Select("method", "path", "request_body", "response_body", "response_headers", "status_code", "state").
 		From("idempotency_keys").
 		Where("key_hash = ?", hashKey(key)).
-		Where("expires_at > ?", time.Now().UTC()).
+		Where("expires_at > ?", now).
 		ToSql()
 	if err != nil {
-		return idempotency.StoredRequest{}, idempotency.StoredResponse{}, false, err
+		return idempotency.Reservation{}, false, err
 	}
 
 	var (
@@ -44,75 +114,132 @@ func (r *Repository) Get(ctx context.Context, key string) (idempotency.StoredReq
 		body       []byte
 		headerJSON []byte
 		status     int
+		state      string
 	)
-
-	if err := exec.QueryRowContext(ctx, query, args...).Scan(&method, &path, &reqBody, &body, &headerJSON, &status); err != nil {
-		if err == sql.ErrNoRows {
-			return idempotency.StoredRequest{}, idempotency.StoredResponse{}, false, nil
-		}
-		return idempotency.StoredRequest{}, idempotency.StoredResponse{}, false, fmt.Errorf("query idempotency key: %w", err)
+	err = postgres.ExecutorFromContext(ctx, r.db).QueryRowContext(ctx, query, args...).
+		Scan(&method, &path, &reqBody, &body, &headerJSON, &status, &state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return idempotency.Reservation{}, false, nil
 	}
-
-	headers := make(map[string]string)
-	if len(headerJSON) > 0 {
-		if err := json.Unmarshal(headerJSON, &headers); err != nil {
-			return idempotency.StoredRequest{}, idempotency.StoredResponse{}, false, fmt.Errorf("decode response headers: %w", err)
-		}
+	if err != nil {
+		return idempotency.Reservation{}, false, fmt.Errorf("query idempotency key: %w", err)
 	}
 
-	req := idempotency.StoredRequest{
-		Method: method,
-		Path:   path,
-		Body:   append([]byte(nil), reqBody...),
+	res := idempotency.Reservation{
+		Pending: state == statePending,
+		Request: idempotency.StoredRequest{
+			Method: method,
+			Path:   path,
+			Body:   append([]byte(nil), reqBody...),
+		},
 	}
-
-	resp := idempotency.StoredResponse{
-		Status: status,
-		Body:   append([]byte(nil), body...),
-		Header: headers,
+	if !res.Pending {
+		headers, err := decodeHeaders(headerJSON)
+		if err != nil {
+			return idempotency.Reservation{}, false, err
+		}
+		res.Response = idempotency.StoredResponse{
+			Status: status,
+			Body:   append([]byte(nil), body...),
+			Header: headers,
+		}
 	}
-
-	return req, resp, true, nil
+	return res, true, nil
 }
 
-func (r *Repository) Save(ctx context.Context, key string, req idempotency.StoredRequest, ttl time.Duration, resp idempotency.StoredResponse) error {
-	exec := postgres.ExecutorFromContext(ctx, r.db)
+func (r *Repository) Complete(ctx context.Context, key idempotency.Key, token string, resp idempotency.StoredResponse, ttl time.Duration) error {
 	headers := resp.Header
 	if headers == nil {
-		headers = make(map[string]string)
+		headers = make(http.Header)
 	}
 	headerJSON, err := json.Marshal(headers)
 	if err != nil {
 		return fmt.Errorf("encode response headers: %w", err)
 	}
 
-	query, args, err := r.sql.Insert("idempotency_keys").
-		Columns("key_hash", "method", "path", "request_body", "response_body", "response_headers", "status_code", "expires_at").
-		Values(hashKey(key), req.Method, req.Path, safeBytes(req.Body), resp.Body, headerJSON, resp.Status, time.Now().UTC().Add(ttl)).
-		Suffix(`
-			ON CONFLICT (key_hash) DO UPDATE
-			SET method = EXCLUDED.method,
-			    path = EXCLUDED.path,
-			    request_body = EXCLUDED.request_body,
-			    response_body = EXCLUDED.response_body,
-			    response_headers = EXCLUDED.response_headers,
-			    status_code = EXCLUDED.status_code,
-			    expires_at = EXCLUDED.expires_at`).
+	query, args, err := r.sql.Update("idempotency_keys").
+		Set("response_body", safeBytes(resp.Body)).
+		Set("response_headers", headerJSON).
+		Set("status_code", resp.Status).
+		Set("state", stateCompleted).
+		Set("expires_at", time.Now().UTC().Add(ttl)).
+		Where("key_hash = ?", hashKey(key)).
+		Where("state = ?", statePending).
+		Where("owner_token = ?", token).
 		ToSql()
 	if err != nil {
 		return err
 	}
-
-	if _, err := exec.ExecContext(ctx, query, args...); err != nil {
+	res, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
 		return fmt.Errorf("save idempotency response: %w", err)
 	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("save idempotency response: %w", err)
+	}
+	if n == 0 {
+		return idempotency.ErrReservationLost
+	}
+	return nil
+}
 
+func (r *Repository) Release(ctx context.Context, key idempotency.Key, token string) error {
+	query, args, err := r.sql.Delete("idempotency_keys").
+		Where("key_hash = ?", hashKey(key)).
+		Where("state = ?", statePending).
+		Where("owner_token = ?", token).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
 	return nil
 }
 
-func hashKey(key string) []byte {
-	sum := sha256.Sum256([]byte(key))
-	return sum[:]
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate idempotency owner token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashKey хеширует ключ вместе с областью; поля разделены NUL, чтобы ("a", "b/c") и ("a/b", "c") не совпали
+func hashKey(key idempotency.Key) []byte {
+	h := sha256.New()
+	for _, part := range []string{key.Subject, key.Method, key.Path, key.Value} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return h.Sum(nil)
+}
+
+// decodeHeaders читает и записи, сохранённые до перехода на многозначные заголовки,
+// где каждому имени соответствовала одна строка
+func decodeHeaders(data []byte) (http.Header, error) {
+	headers := make(http.Header)
+	if len(data) == 0 {
+		return headers, nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("decode response headers: %w", err)
+	}
+	for name, value := range raw {
+		var values []string
+		if err := json.Unmarshal(value, &values); err != nil {
+			var single string
+			if err := json.Unmarshal(value, &single); err != nil {
+				return nil, fmt.Errorf("decode response header %s: %w", name, err)
+			}
+			values = []string{single}
+		}
+		headers[name] = values
+	}
+	return headers, nil
 }
 
 func safeBytes(data []byte) []byte {
@@ -122,4 +249,74 @@ func safeBytes(data []byte) []byte {
 	return data
 }
 
+// ListRecords возвращает действующие записи ключа субъекта по всем эндпоинтам
+func (r *Repository) ListRecords(ctx context.Context, subject, value string) ([]idempotency.Record, error) {
+	query, args, err := r.sql.Select("subject", "idempotency_key", "method", "path", "state", "status_code", "expires_at").
+		From("idempotency_keys").
+		Where("subject = ?", subject).
+		Where("idempotency_key = ?", value).
+		Where("expires_at > ?", time.Now().UTC()).
+		OrderBy("path ASC").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := postgres.ExecutorFromContext(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list idempotency keys: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]idempotency.Record, 0)
+	for rows.Next() {
+		var (
+			rec   idempotency.Record
+			state string
+		)
+		if err := rows.Scan(&rec.Key.Subject, &rec.Key.Value, &rec.Key.Method, &rec.Key.Path, &state, &rec.Status, &rec.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("scan idempotency key: %w", err)
+		}
+		// path в записи хранится с query-строкой, а в области ключа — без неё
+		rec.Key.Path, _, _ = strings.Cut(rec.Key.Path, "?")
+		rec.Pending = state == statePending
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+// DeleteRecords удаляет все записи ключа субъекта, в том числе незавершённые
+func (r *Repository) DeleteRecords(ctx context.Context, subject, value string) (int64, error) {
+	query, args, err := r.sql.Delete("idempotency_keys").
+		Where("subject = ?", subject).
+		Where("idempotency_key = ?", value).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+	res, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("delete idempotency keys: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// PurgeExpired удаляет до limit истёкших записей за раз, чтобы не держать долгую блокировку
+func (r *Repository) PurgeExpired(ctx context.Context, now time.Time, limit int) (int64, error) {
+	batch := r.sql.Select("key_hash").
+		From("idempotency_keys").
+		Where("expires_at <= ?", now.UTC()).
+		Limit(uint64(limit))
+	query, args, err := r.sql.Delete("idempotency_keys").
+		Where(sq.Expr("key_hash IN (?)", batch)).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+	res, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("purge idempotency keys: %w", err)
+	}
+	return res.RowsAffected()
+}
+
 var _ idempotency.Storage = (*Repository)(nil)
@@ -3,6 +3,7 @@ package idempotencyrepo
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"testing"
 	"time"
@@ -12,7 +13,9 @@ import (
 	"github.com/mashhkensss/PR-service/internal/idempotency"
 )
 
-func TestSaveAndGet(t *testing.T) {
+var testKey = idempotency.Key{Subject: "u1", Method: http.MethodPost, Path: "/foo", Value: "key"}
+
+func TestReserveAcquiresAndCompletes(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("sqlmock: %v", err)
@@ -21,28 +24,186 @@ func TestSaveAndGet(t *testing.T) {
 
 	repo := New(db)
 	req := idempotency.StoredRequest{Method: http.MethodPost, Path: "/foo", Body: []byte(`{"a":1}`)}
-	resp := idempotency.StoredResponse{Status: http.StatusCreated, Body: []byte(`ok`), Header: map[string]string{"Content-Type": "application/json"}}
+	resp := idempotency.StoredResponse{Status: http.StatusCreated, Body: []byte(`ok`), Header: http.Header{"Content-Type": {"application/json"}, "Set-Cookie": {"a=1", "b=2"}}}
+
+	mock.ExpectQuery(`INSERT INTO idempotency_keys .* ON CONFLICT \(key_hash\) DO UPDATE .* owner_token = EXCLUDED.owner_token.* WHERE idempotency_keys.expires_at <= \$13\s+RETURNING state`).
+		WithArgs(sqlmock.AnyArg(), testKey.Subject, testKey.Value, req.Method, req.Path, req.Body, []byte{}, []byte("{}"), 0, "pending", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow("pending"))
+
+	res, err := repo.Reserve(context.Background(), testKey, req, time.Minute)
+	if err != nil || !res.Acquired || res.Token == "" {
+		t.Fatalf("expected reservation, got %+v err=%v", res, err)
+	}
 
 	headerJSON, _ := json.Marshal(resp.Header)
-	mock.ExpectExec(`INSERT INTO idempotency_keys`).
-		WithArgs(sqlmock.AnyArg(), req.Method, req.Path, sqlmock.AnyArg(), resp.Body, headerJSON, resp.Status, sqlmock.AnyArg()).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE idempotency_keys SET response_body = \$1, response_headers = \$2, status_code = \$3, state = \$4, expires_at = \$5 WHERE key_hash = \$6 AND state = \$7 AND owner_token = \$8`).
+		WithArgs(resp.Body, headerJSON, resp.Status, "completed", sqlmock.AnyArg(), sqlmock.AnyArg(), "pending", res.Token).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := repo.Complete(context.Background(), testKey, res.Token, resp, time.Minute); err != nil {
+		t.Fatalf("unexpected complete error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
 
-	if err := repo.Save(context.Background(), "key", req, time.Minute, resp); err != nil {
-		t.Fatalf("unexpected save error: %v", err)
+func TestReserveReturnsExistingRecord(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
 	}
+	defer db.Close()
+
+	repo := New(db)
+	req := idempotency.StoredRequest{Method: http.MethodPost, Path: "/foo", Body: []byte(`{"a":1}`)}
+	// запись в старом формате: по одной строке на заголовок
+	headerJSON := []byte(`{"Content-Type":"application/json"}`)
 
-	rows := sqlmock.NewRows([]string{"method", "path", "request_body", "response_body", "response_headers", "status_code"}).
-		AddRow(req.Method, req.Path, req.Body, resp.Body, headerJSON, resp.Status)
-	mock.ExpectQuery(`SELECT method`).
+	// ключ занят и не истёк: ON CONFLICT ... WHERE не обновляет строку и ничего не возвращает
+	mock.ExpectQuery(`INSERT INTO idempotency_keys`).
+		WillReturnRows(sqlmock.NewRows([]string{"state"}))
+	mock.ExpectQuery(`SELECT method, path, request_body, response_body, response_headers, status_code, state FROM idempotency_keys WHERE key_hash = \$1 AND expires_at > \$2`).
 		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
-		WillReturnRows(rows)
+		WillReturnRows(sqlmock.NewRows([]string{"method", "path", "request_body", "response_body", "response_headers", "status_code", "state"}).
+			AddRow(req.Method, req.Path, req.Body, []byte(`ok`), headerJSON, http.StatusCreated, "completed"))
+
+	res, err := repo.Reserve(context.Background(), testKey, req, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Acquired || res.Pending || res.Response.Status != http.StatusCreated || res.Request.Path != req.Path {
+		t.Fatalf("unexpected reservation %+v", res)
+	}
+	if res.Response.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("headers not decoded: %+v", res.Response.Header)
+	}
+}
+
+func TestReleaseDeletesOnlyPending(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM idempotency_keys WHERE key_hash = \$1 AND state = \$2 AND owner_token = \$3`).
+		WithArgs(sqlmock.AnyArg(), "pending", "owner").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := New(db).Release(context.Background(), testKey, "owner"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestCompleteAfterTakeoverKeepsNewOwner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	req := idempotency.StoredRequest{Method: http.MethodPost, Path: "/batch", Body: []byte(`{}`)}
+	// первый запрос занял ключ, его pending-запись истекла, и повтор перехватил её своим токеном
+	mock.ExpectQuery(`INSERT INTO idempotency_keys`).WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow("pending"))
+	mock.ExpectQuery(`INSERT INTO idempotency_keys`).WillReturnRows(sqlmock.NewRows([]string{"state"}).AddRow("pending"))
+	first, err := repo.Reserve(context.Background(), testKey, req, time.Millisecond)
+	if err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	retry, err := repo.Reserve(context.Background(), testKey, req, time.Minute)
+	if err != nil || !retry.Acquired || retry.Token == first.Token {
+		t.Fatalf("takeover must get a new owner token, got %+v and %+v err=%v", first, retry, err)
+	}
+
+	// строка уже принадлежит повтору: UPDATE и DELETE первого запроса её не находят
+	mock.ExpectExec(`UPDATE idempotency_keys .* AND owner_token = \$8`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "pending", first.Token).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM idempotency_keys .* AND owner_token = \$3`).
+		WithArgs(sqlmock.AnyArg(), "pending", first.Token).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.Complete(context.Background(), testKey, first.Token, idempotency.StoredResponse{Status: http.StatusOK}, time.Minute)
+	if !errors.Is(err, idempotency.ErrReservationLost) {
+		t.Fatalf("expected ErrReservationLost, got %v", err)
+	}
+	if err := repo.Release(context.Background(), testKey, first.Token); err != nil {
+		t.Fatalf("release of a lost reservation must be a no-op, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestHashKeyIsScoped(t *testing.T) {
+	other := testKey
+	other.Subject = "u2"
+	if string(hashKey(testKey)) == string(hashKey(other)) {
+		t.Fatalf("same key of different subjects must not collide")
+	}
+	shifted := idempotency.Key{Subject: "u1", Method: http.MethodPost, Path: "/fo", Value: "okey"}
+	if string(hashKey(testKey)) == string(hashKey(shifted)) {
+		t.Fatalf("field boundaries must be part of the hash")
+	}
+}
+
+func TestPurgeExpiredInBatches(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
 
-	storedReq, storedResp, ok, err := repo.Get(context.Background(), "key")
-	if err != nil || !ok {
-		t.Fatalf("unexpected get error: %v ok=%v", err, ok)
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`DELETE FROM idempotency_keys WHERE key_hash IN \(SELECT key_hash FROM idempotency_keys WHERE expires_at <= \$1 LIMIT 500\)`).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 500))
+
+	n, err := New(db).PurgeExpired(context.Background(), now, 500)
+	if err != nil || n != 500 {
+		t.Fatalf("expected 500 purged rows, got %d err=%v", n, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestListRecords(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	expires := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT subject, idempotency_key, method, path, state, status_code, expires_at FROM idempotency_keys WHERE subject = \$1 AND idempotency_key = \$2 AND expires_at > \$3 ORDER BY path ASC`).
+		WithArgs("u1", "key", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"subject", "idempotency_key", "method", "path", "state", "status_code", "expires_at"}).
+			AddRow("u1", "key", http.MethodPost, "/foo?x=1", "completed", http.StatusCreated, expires))
+
+	records, err := New(db).ListRecords(context.Background(), "u1", "key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].Key != testKey || records[0].Pending || records[0].Status != http.StatusCreated {
+		t.Fatalf("unexpected records %+v", records)
+	}
+}
+
+func TestDecodeHeadersKeepsAllValues(t *testing.T) {
+	headers, err := decodeHeaders([]byte(`{"Set-Cookie":["a=1","b=2"],"Content-Type":"application/json"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := headers.Values("Set-Cookie"); len(got) != 2 || got[1] != "b=2" {
+		t.Fatalf("multi-value header lost: %v", got)
 	}
-	if storedReq.Path != req.Path || storedResp.Status != resp.Status {
-		t.Fatalf("unexpected stored values %+v %+v", storedReq, storedResp)
+	if headers.Get("Content-Type") != "application/json" {
+		t.Fatalf("legacy single-value header not decoded: %v", headers)
 	}
 }
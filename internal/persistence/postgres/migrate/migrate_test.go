@@ -0,0 +1,150 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/mashhkensss/PR-service/migrations"
+)
+
+var testFS = fstest.MapFS{
+	"0001_init.up.sql":      {Data: []byte("CREATE TABLE a (id INT);")},
+	"0001_init.down.sql":    {Data: []byte("DROP TABLE a;")},
+	"0002_more.up.sql":      {Data: []byte("CREATE TABLE b (id INT);")},
+	"0002_more.down.sql":    {Data: []byte("DROP TABLE b;")},
+	"0010_indexes.up.sql":   {Data: []byte("CREATE INDEX idx_b ON b(id);")},
+	"0010_indexes.down.sql": {Data: []byte("DROP INDEX idx_b;")},
+}
+
+func expectLocked(mock sqlmock.Sqlmock, version int64, dirty bool) {
+	mock.ExpectExec(`SELECT pg_advisory_lock\(\$1\)`).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"version", "dirty"})
+	if version > 0 {
+		rows.AddRow(version, dirty)
+	}
+	mock.ExpectQuery(`SELECT version, dirty FROM schema_migrations LIMIT 1`).WillReturnRows(rows)
+}
+
+func expectApply(mock sqlmock.Sqlmock, body string, version int64) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(body)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 1))
+	if version > 0 {
+		mock.ExpectExec(`INSERT INTO schema_migrations \(version,dirty\) VALUES \(\$1,\$2\)`).
+			WithArgs(version, false).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+}
+
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(\$1\)`).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestUpAppliesPendingInOrder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	m, err := New(db, testFS)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	expectLocked(mock, 1, false)
+	expectApply(mock, "CREATE TABLE b (id INT);", 2)
+	expectApply(mock, "CREATE INDEX idx_b ON b(id);", 10)
+	expectUnlock(mock)
+
+	applied, err := m.Up(context.Background())
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if len(applied) != 2 || applied[0].Version != 2 || applied[1].Name != "indexes" {
+		t.Fatalf("unexpected applied %+v", applied)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestDownRevertsToPreviousVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	m, err := New(db, testFS)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	expectLocked(mock, 2, false)
+	expectApply(mock, "DROP TABLE b;", 1)
+	expectApply(mock, "DROP TABLE a;", 0)
+	expectUnlock(mock)
+
+	reverted, err := m.Down(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if len(reverted) != 2 || reverted[0].Version != 2 {
+		t.Fatalf("unexpected reverted %+v", reverted)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestUpRefusesDirtyDatabase(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	m, err := New(db, testFS)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	expectLocked(mock, 2, true)
+	expectUnlock(mock)
+
+	if _, err := m.Up(context.Background()); !errors.Is(err, ErrDirty) {
+		t.Fatalf("expected ErrDirty, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestLoadRejectsMalformedFiles(t *testing.T) {
+	for name, fsys := range map[string]fstest.MapFS{
+		"no direction": {"0001_init.sql": {}},
+		"bad version":  {"abc_init.up.sql": {}},
+		"missing up":   {"0001_init.down.sql": {Data: []byte("DROP TABLE a;")}},
+	} {
+		if _, err := New(nil, fsys); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestEmbeddedMigrationsAreComplete(t *testing.T) {
+	m, err := New(nil, migrations.FS)
+	if err != nil {
+		t.Fatalf("embedded migrations: %v", err)
+	}
+	for i, mig := range m.migrations {
+		if mig.Version != uint(i+1) || mig.down == "" {
+			t.Fatalf("migration %d_%s: versions must be contiguous and reversible", mig.Version, mig.Name)
+		}
+	}
+}
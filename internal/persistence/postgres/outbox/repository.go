@@ -0,0 +1,169 @@
+package outboxrepo
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/mashhkensss/PR-service/internal/domain/event"
+	"github.com/mashhkensss/PR-service/internal/outbox"
+	"github.com/mashhkensss/PR-service/internal/persistence/postgres"
+)
+
+type Repository struct {
+	db  *sql.DB
+	sql sq.StatementBuilderType
+}
+
+func New(db *sql.DB) *Repository {
+	return &Repository{
+		db:  db,
+		sql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+// Append пишет события через исполнитель из контекста, то есть в транзакции сервиса
+func (r *Repository) Append(ctx context.Context, events ...event.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	builder := r.sql.Insert("outbox").
+		Columns("aggregate_type", "aggregate_id", "event_type", "payload", "occurred_at")
+	for _, e := range events {
+		payload, err := json.Marshal(e.Payload)
+		if err != nil {
+			return fmt.Errorf("encode %s payload: %w", e.Type, err)
+		}
+		builder = builder.Values(string(e.AggregateType), e.AggregateID, string(e.Type), payload, e.OccurredAt.UTC())
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("append outbox events: %w", err)
+	}
+	return nil
+}
+
+// Claim выбирает только голову очереди каждого агрегата: пока раннее событие не опубликовано,
+// следующие за ним не выдаются, даже если раннее сейчас отправляет другой релей или оно ждёт повтора.
+// Мёртвое сообщение головой не считается, иначе одно отравленное событие навсегда остановило бы агрегат
+func (r *Repository) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]outbox.Message, error) {
+	// вложенный запрос собирается с ?-плейсхолдерами: внешний билдер пронумерует их сам
+	heads := sq.Select("o.id").
+		From("outbox o").
+		Where("o.published_at IS NULL").
+		Where("o.dead_at IS NULL").
+		Where("o.available_at <= ?", now.UTC()).
+		Where("NOT EXISTS (SELECT 1 FROM outbox p WHERE p.aggregate_type = o.aggregate_type AND p.aggregate_id = o.aggregate_id AND p.published_at IS NULL AND p.dead_at IS NULL AND p.id < o.id)").
+		OrderBy("o.id").
+		Limit(uint64(limit)).
+		Suffix("FOR UPDATE SKIP LOCKED")
+	query, args, err := r.sql.Update("outbox").
+		Set("available_at", now.Add(lease).UTC()).
+		Set("attempts", sq.Expr("attempts + 1")).
+		Where(sq.Expr("id IN (?)", heads)).
+		Suffix("RETURNING id, aggregate_type, aggregate_id, event_type, payload, occurred_at, attempts").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := postgres.ExecutorFromContext(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []outbox.Message
+	for rows.Next() {
+		var (
+			msg     outbox.Message
+			payload []byte
+		)
+		if err := rows.Scan(&msg.ID, &msg.AggregateType, &msg.AggregateID, &msg.EventType, &payload, &msg.OccurredAt, &msg.Attempts); err != nil {
+			return nil, fmt.Errorf("scan outbox message: %w", err)
+		}
+		msg.Payload = payload
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// RETURNING не гарантирует порядок
+	slices.SortFunc(messages, func(a, b outbox.Message) int { return cmp.Compare(a.ID, b.ID) })
+	return messages, nil
+}
+
+func (r *Repository) MarkPublished(ctx context.Context, id int64, at time.Time) error {
+	query, args, err := r.sql.Update("outbox").
+		Set("published_at", at.UTC()).
+		Set("last_error", nil).
+		Where("id = ?", id).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("mark outbox message published: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) MarkFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	query, args, err := r.sql.Update("outbox").
+		Set("last_error", reason).
+		Set("available_at", retryAt.UTC()).
+		Where("id = ?", id).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("mark outbox message failed: %w", err)
+	}
+	return nil
+}
+
+// MarkDead выводит сообщение из доставки. Запись остаётся в таблице для разбора и ручного повтора
+func (r *Repository) MarkDead(ctx context.Context, id int64, reason string, at time.Time) error {
+	query, args, err := r.sql.Update("outbox").
+		Set("last_error", reason).
+		Set("dead_at", at.UTC()).
+		Where("id = ?", id).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("mark outbox message dead: %w", err)
+	}
+	return nil
+}
+
+// PurgePublished удаляет до limit опубликованных сообщений за раз, чтобы не держать долгую блокировку
+func (r *Repository) PurgePublished(ctx context.Context, before time.Time, limit int) (int64, error) {
+	batch := r.sql.Select("id").
+		From("outbox").
+		Where("published_at <= ?", before.UTC()).
+		Limit(uint64(limit))
+	query, args, err := r.sql.Delete("outbox").
+		Where(sq.Expr("id IN (?)", batch)).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+	res, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("purge outbox: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+var _ outbox.Storage = (*Repository)(nil)
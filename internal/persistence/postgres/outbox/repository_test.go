@@ -0,0 +1,119 @@
+package outboxrepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/mashhkensss/PR-service/internal/domain/event"
+)
+
+func TestAppendWritesAllEventsInOneStatement(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	at := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	events := []event.Event{
+		event.ForPullRequest("pr-1", event.TypePullRequestCreated, at, event.PullRequestCreated{PullRequestID: "pr-1", PullRequestName: "Feature", AuthorID: "u1"}),
+		event.ForPullRequest("pr-1", event.TypeReviewerAssigned, at, event.ReviewerAssigned{PullRequestID: "pr-1", ReviewerID: "u2"}),
+	}
+	mock.ExpectExec(`INSERT INTO outbox \(aggregate_type,aggregate_id,event_type,payload,occurred_at\) VALUES \(\$1,\$2,\$3,\$4,\$5\),\(\$6,\$7,\$8,\$9,\$10\)`).
+		WithArgs(
+			"pull_request", "pr-1", "PullRequestCreated", []byte(`{"pull_request_id":"pr-1","pull_request_name":"Feature","author_id":"u1"}`), at,
+			"pull_request", "pr-1", "ReviewerAssigned", []byte(`{"pull_request_id":"pr-1","reviewer_id":"u2"}`), at,
+		).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	if err := New(db).Append(context.Background(), events...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestClaimTakesHeadOfEachAggregate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`UPDATE outbox SET available_at = \$1, attempts = attempts \+ 1 WHERE id IN \(SELECT o.id FROM outbox o WHERE o.published_at IS NULL AND o.dead_at IS NULL AND o.available_at <= \$2 AND NOT EXISTS \(.*p.published_at IS NULL AND p.dead_at IS NULL AND p.id < o.id\) ORDER BY o.id LIMIT 10 FOR UPDATE SKIP LOCKED\) RETURNING id`).
+		WithArgs(now.Add(time.Minute), now).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "aggregate_type", "aggregate_id", "event_type", "payload", "occurred_at", "attempts"}).
+			AddRow(int64(5), "user", "u1", "UserActivityChanged", []byte(`{}`), now, 1).
+			AddRow(int64(3), "pull_request", "pr-1", "PullRequestMerged", []byte(`{}`), now, 2))
+
+	msgs, err := New(db).Claim(context.Background(), now, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].ID != 3 || msgs[0].Attempts != 2 || msgs[1].AggregateID != "u1" {
+		t.Fatalf("unexpected messages %+v", msgs)
+	}
+}
+
+func TestMarkFailedDelaysRetry(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	retryAt := time.Date(2025, 3, 1, 10, 0, 8, 0, time.UTC)
+	mock.ExpectExec(`UPDATE outbox SET last_error = \$1, available_at = \$2 WHERE id = \$3`).
+		WithArgs("boom", retryAt, int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := New(db).MarkFailed(context.Background(), 3, "boom", retryAt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestMarkDeadStopsDelivery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	at := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`UPDATE outbox SET last_error = \$1, dead_at = \$2 WHERE id = \$3`).
+		WithArgs("rejected", at, int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := New(db).MarkDead(context.Background(), 3, "rejected", at); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestPurgePublishedInBatches(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	before := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`DELETE FROM outbox WHERE id IN \(SELECT id FROM outbox WHERE published_at <= \$1 LIMIT 100\)`).
+		WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 100))
+
+	n, err := New(db).PurgePublished(context.Background(), before, 100)
+	if err != nil || n != 100 {
+		t.Fatalf("expected 100 purged rows, got %d err=%v", n, err)
+	}
+}
@@ -45,6 +45,8 @@ func (r *Repository) CreatePullRequest(ctx context.Context, pr domainpr.PullRequ
 	return r.replaceReviewers(ctx, exec, pr)
 }
 
+// UpdatePullRequest пишет агрегат, только если версия в базе совпадает с прочитанной, и увеличивает её.
+// Иначе между чтением и записью PR изменил кто-то другой — ErrVersionMismatch
 func (r *Repository) UpdatePullRequest(ctx context.Context, pr domainpr.PullRequest) error {
 	exec := postgres.ExecutorFromContext(ctx, r.db)
 	query, args, err := r.sql.Update("pull_requests").
@@ -54,31 +56,44 @@ func (r *Repository) UpdatePullRequest(ctx context.Context, pr domainpr.PullRequ
 		Set("created_at", pr.CreatedAt()).
 		Set("merged_at", nullTime(pr.MergedAt())).
 		Set("updated_at", time.Now().UTC()).
+		Set("version", sq.Expr("version + 1")).
 		Where("pull_request_id = ?", pr.PullRequestID()).
+		Where("version = ?", pr.Version()).
 		ToSql()
 	if err != nil {
 		return err
 	}
-	if _, err := exec.ExecContext(ctx, query, args...); err != nil {
+	res, err := exec.ExecContext(ctx, query, args...)
+	if err != nil {
 		return fmt.Errorf("update pull request: %w", err)
 	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return domain.ErrVersionMismatch
+	}
 	return r.replaceReviewers(ctx, exec, pr)
 }
 
+// replaceReviewers синхронизирует строки ревьюверов с агрегатом, не трогая уже назначенных:
+// их assigned_at/reviewed_at нужны для SLA
 func (r *Repository) replaceReviewers(ctx context.Context, exec postgres.DBTX, pr domainpr.PullRequest) error {
-	delQuery, delArgs, err := r.sql.Delete("pull_request_reviewers").
-		Where("pull_request_id = ?", pr.PullRequestID()).
-		ToSql()
+	reviewers := pr.AssignedReviewers()
+	del := r.sql.Delete("pull_request_reviewers").
+		Where("pull_request_id = ?", pr.PullRequestID())
+	if len(reviewers) > 0 {
+		del = del.Where(sq.NotEq{"reviewer_id": reviewers})
+	}
+	delQuery, delArgs, err := del.ToSql()
 	if err != nil {
 		return err
 	}
 	if _, err := exec.ExecContext(ctx, delQuery, delArgs...); err != nil {
 		return fmt.Errorf("delete reviewers: %w", err)
 	}
-	for i, reviewer := range pr.AssignedReviewers() {
+	for i, reviewer := range reviewers {
 		query, args, err := r.sql.Insert("pull_request_reviewers").
 			Columns("pull_request_id", "reviewer_id", "slot").
 			Values(pr.PullRequestID(), reviewer, i+1).
+			Suffix("ON CONFLICT (pull_request_id, reviewer_id) DO UPDATE SET slot = EXCLUDED.slot").
 			ToSql()
 		if err != nil {
 			return err
@@ -90,6 +105,60 @@ func (r *Repository) replaceReviewers(ctx context.Context, exec postgres.DBTX, p
 	return nil
 }
 
+// MarkReviewed пишет последний вердикт ревьювера; reviewed_at хранит только первую реакцию — по ней считается SLA
+func (r *Repository) MarkReviewed(ctx context.Context, id domain.PullRequestID, reviewerID domain.UserID, verdict domain.ReviewVerdict, at time.Time) error {
+	query, args, err := r.sql.Update("pull_request_reviewers").
+		Set("reviewed_at", sq.Expr("COALESCE(reviewed_at, ?)", at.UTC())).
+		Set("verdict", verdict).
+		Set("verdict_at", at.UTC()).
+		Where("pull_request_id = ?", id).
+		Where("reviewer_id = ?", reviewerID).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	exec := postgres.ExecutorFromContext(ctx, r.db)
+	res, err := exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("mark reviewed: %w", err)
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return domain.ErrReviewerNotAssigned
+	}
+	return r.bumpVersion(ctx, exec, id)
+}
+
+// bumpVersion увеличивает версию PR при записи, которая не проходит через UpdatePullRequest
+func (r *Repository) bumpVersion(ctx context.Context, exec postgres.DBTX, id domain.PullRequestID) error {
+	query, args, err := r.sql.Update("pull_requests").
+		Set("version", sq.Expr("version + 1")).
+		Set("updated_at", time.Now().UTC()).
+		Where("pull_request_id = ?", id).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := exec.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("bump pull request version: %w", err)
+	}
+	return nil
+}
+
+// RecordReassignment пишет замену ревьювера в журнал, по нему считается доля переназначений
+func (r *Repository) RecordReassignment(ctx context.Context, id domain.PullRequestID, oldReviewer, newReviewer domain.UserID, at time.Time) error {
+	query, args, err := r.sql.Insert("pull_request_reassignments").
+		Columns("pull_request_id", "old_reviewer_id", "new_reviewer_id", "reassigned_at").
+		Values(id, oldReviewer, newReviewer, at.UTC()).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("record reassignment: %w", err)
+	}
+	return nil
+}
+
 func (r *Repository) GetPullRequest(ctx context.Context, id domain.PullRequestID) (domainpr.PullRequest, error) {
 	return r.fetchPullRequest(ctx, id, false)
 }
@@ -100,7 +169,7 @@ func (r *Repository) GetPullRequestForUpdate(ctx context.Context, id domain.Pull
 
 func (r *Repository) fetchPullRequest(ctx context.Context, id domain.PullRequestID, forUpdate bool) (domainpr.PullRequest, error) {
 	exec := postgres.ExecutorFromContext(ctx, r.db)
-	builder := r.sql.Select("pull_request_id", "pull_request_name", "author_id", "status", "created_at", "merged_at", "updated_at").
+	builder := r.sql.Select("pull_request_id", "pull_request_name", "author_id", "status", "created_at", "merged_at", "updated_at", "version").
 		From("pull_requests").
 		Where("pull_request_id = ?", id)
 	if forUpdate {
@@ -119,8 +188,9 @@ func (r *Repository) fetchPullRequest(ctx context.Context, id domain.PullRequest
 		created time.Time
 		merged  sql.NullTime
 		updated time.Time
+		version int64
 	)
-	if err := row.Scan(&prID, &name, &author, &status, &created, &merged, &updated); err != nil {
+	if err := row.Scan(&prID, &name, &author, &status, &created, &merged, &updated, &version); err != nil {
 		return domainpr.PullRequest{}, fmt.Errorf("get pull request: %w", err)
 	}
 	pr, err := domainpr.New(domain.PullRequestID(prID), name, domain.UserID(author), created)
@@ -157,7 +227,8 @@ func (r *Repository) fetchPullRequest(ctx context.Context, id domain.PullRequest
 	if status == string(domain.PullRequestStatusMerged) && merged.Valid {
 		pr.Merge(merged.Time)
 	}
-	return pr, nil
+	pr.ClearEvents()
+	return pr.WithVersion(domain.Version(version)), nil
 }
 
 func (r *Repository) ListPullRequestsByReviewer(ctx context.Context, reviewerID domain.UserID) ([]domainpr.PullRequest, error) {
@@ -170,6 +241,7 @@ func (r *Repository) ListPullRequestsByReviewer(ctx context.Context, reviewerID
 		"pr.created_at",
 		"pr.merged_at",
 		"pr.updated_at",
+		"pr.version",
 	).From("pull_requests pr").
 		Join("pull_request_reviewers r ON r.pull_request_id = pr.pull_request_id").
 		Where("r.reviewer_id = ?", reviewerID).
@@ -194,8 +266,9 @@ func (r *Repository) ListPullRequestsByReviewer(ctx context.Context, reviewerID
 			created time.Time
 			merged  sql.NullTime
 			updated time.Time
+			version int64
 		)
-		if err := rows.Scan(&prID, &name, &author, &status, &created, &merged, &updated); err != nil {
+		if err := rows.Scan(&prID, &name, &author, &status, &created, &merged, &updated, &version); err != nil {
 			return nil, fmt.Errorf("scan row: %w", err)
 		}
 		pr, err := domainpr.New(domain.PullRequestID(prID), name, domain.UserID(author), created)
@@ -205,7 +278,8 @@ func (r *Repository) ListPullRequestsByReviewer(ctx context.Context, reviewerID
 		if status == string(domain.PullRequestStatusMerged) && merged.Valid {
 			pr.Merge(merged.Time)
 		}
-		result = append(result, pr)
+		pr.ClearEvents()
+		result = append(result, pr.WithVersion(domain.Version(version)))
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows error: %w", err)
@@ -48,3 +48,47 @@ func TestRepository_CreatePullRequest_UniqueViolation(t *testing.T) {
 		t.Fatalf("unmet expectations: %v", err)
 	}
 }
+
+func TestRepository_MarkReviewed_NotAssigned(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	mock.ExpectExec(`UPDATE pull_request_reviewers SET reviewed_at = COALESCE`).
+		WithArgs(sqlmock.AnyArg(), domain.ReviewVerdictApproved, sqlmock.AnyArg(), "pr-1", "stranger").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = repo.MarkReviewed(context.Background(), "pr-1", "stranger", domain.ReviewVerdictApproved, time.Now())
+	if !errors.Is(err, domain.ErrReviewerNotAssigned) {
+		t.Fatalf("expected ErrReviewerNotAssigned, got %v", err)
+	}
+}
+
+func TestRepository_UpdatePullRequest_VersionMismatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	pr, err := domainpr.New("pr-1", "Feature", "author", time.Now())
+	if err != nil {
+		t.Fatalf("build domain pull request: %v", err)
+	}
+	pr = pr.WithVersion(3)
+
+	// версию в базе уже увеличил другой писатель: строка не обновляется, ревьюверов не трогаем
+	mock.ExpectExec(`UPDATE pull_requests SET .* version = version \+ 1 WHERE pull_request_id = \$7 AND version = \$8`).
+		WithArgs(pr.PullRequestName(), pr.AuthorID(), pr.Status(), pr.CreatedAt(), sqlmock.AnyArg(), sqlmock.AnyArg(), pr.PullRequestID(), domain.Version(3)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := New(db).UpdatePullRequest(context.Background(), pr); !errors.Is(err, domain.ErrVersionMismatch) {
+		t.Fatalf("expected ErrVersionMismatch, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package ratelimitrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/mashhkensss/PR-service/internal/persistence/postgres"
+	"github.com/mashhkensss/PR-service/internal/ratelimit"
+)
+
+// takeSuffix пополняет бакет, если последнее пополнение было не позже now-interval, иначе
+// списывает токен. Всё в одном операторе: строка блокируется, и реплики не обгоняют друг друга.
+// tokens = -1 означает, что токенов не осталось
+const takeSuffix = `ON CONFLICT (key) DO UPDATE SET
+	tokens = CASE WHEN rate_limit_buckets.refilled_at <= ? THEN EXCLUDED.tokens
+		ELSE GREATEST(rate_limit_buckets.tokens - 1, -1) END,
+	refilled_at = CASE WHEN rate_limit_buckets.refilled_at <= ? THEN EXCLUDED.refilled_at
+		ELSE rate_limit_buckets.refilled_at END,
+	expires_at = EXCLUDED.expires_at
+RETURNING tokens, refilled_at`
+
+type Repository struct {
+	db  *sql.DB
+	sql sq.StatementBuilderType
+
+	cleanupEvery time.Duration
+	mu           sync.Mutex
+	nextCleanup  time.Time
+}
+
+// New раз в cleanupEvery удаляет бакеты, которые всё равно пополнились бы целиком
+func New(db *sql.DB, cleanupEvery time.Duration) *Repository {
+	if cleanupEvery <= 0 {
+		cleanupEvery = time.Minute
+	}
+	return &Repository{
+		db:           db,
+		sql:          sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+		cleanupEvery: cleanupEvery,
+		nextCleanup:  time.Now().Add(cleanupEvery),
+	}
+}
+
+func (r *Repository) Take(ctx context.Context, key string, limit int, interval time.Duration, now time.Time) (ratelimit.Result, error) {
+	now = now.UTC()
+	if r.cleanupDue(now) {
+		if err := r.deleteExpired(ctx, now); err != nil {
+			return ratelimit.Result{}, err
+		}
+	}
+
+	refillBefore := now.Add(-interval)
+	query, args, err := r.sql.Insert("rate_limit_buckets").
+		Columns("key", "tokens", "refilled_at", "expires_at").
+		Values(key, limit-1, now, now.Add(interval)).
+		Suffix(takeSuffix, refillBefore, refillBefore).
+		ToSql()
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	var (
+		tokens     int
+		refilledAt time.Time
+	)
+	if err := postgres.ExecutorFromContext(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&tokens, &refilledAt); err != nil {
+		return ratelimit.Result{}, fmt.Errorf("take rate limit token: %w", err)
+	}
+
+	reset := refilledAt.Add(interval).Sub(now)
+	if reset <= 0 {
+		reset = interval
+	}
+	if tokens < 0 {
+		return ratelimit.Result{Reset: reset}, nil
+	}
+	return ratelimit.Result{Allowed: true, Remaining: tokens, Reset: reset}, nil
+}
+
+func (r *Repository) cleanupDue(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now.Before(r.nextCleanup) {
+		return false
+	}
+	r.nextCleanup = now.Add(r.cleanupEvery)
+	return true
+}
+
+func (r *Repository) deleteExpired(ctx context.Context, now time.Time) error {
+	query, args, err := r.sql.Delete("rate_limit_buckets").
+		Where(sq.Lt{"expires_at": now}).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("delete expired rate limit buckets: %w", err)
+	}
+	return nil
+}
+
+var _ ratelimit.Storage = (*Repository)(nil)
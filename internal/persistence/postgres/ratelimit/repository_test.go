@@ -0,0 +1,71 @@
+package ratelimitrepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+)
+
+const takeQuery = `INSERT INTO rate_limit_buckets \(key,tokens,refilled_at,expires_at\) VALUES \(\$1,\$2,\$3,\$4\) ON CONFLICT \(key\) DO UPDATE SET`
+
+func TestTake(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	refilled := now.Add(-400 * time.Millisecond)
+	mock.ExpectQuery(takeQuery).
+		WithArgs("sub:u1", 4, now, now.Add(time.Second), now.Add(-time.Second), now.Add(-time.Second)).
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "refilled_at"}).AddRow(2, refilled))
+	mock.ExpectQuery(takeQuery).
+		WithArgs("sub:u1", 4, now, now.Add(time.Second), now.Add(-time.Second), now.Add(-time.Second)).
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "refilled_at"}).AddRow(-1, refilled))
+
+	repo := New(db, time.Hour)
+	res, err := repo.Take(context.Background(), "sub:u1", 5, time.Second, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.Allowed || res.Remaining != 2 || res.Reset != 600*time.Millisecond {
+		t.Fatalf("unexpected result %+v", res)
+	}
+
+	res, err = repo.Take(context.Background(), "sub:u1", 5, time.Second, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Allowed || res.Remaining != 0 {
+		t.Fatalf("exhausted bucket must reject, got %+v", res)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestTakeDeletesExpiredBuckets(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db, time.Minute)
+	now := repo.nextCleanup.Add(time.Second).UTC()
+	mock.ExpectExec(`DELETE FROM rate_limit_buckets WHERE expires_at < \$1`).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery(takeQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "refilled_at"}).AddRow(0, now))
+
+	if _, err := repo.Take(context.Background(), "ip:10.0.0.1", 1, time.Second, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
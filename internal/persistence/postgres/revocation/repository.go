@@ -0,0 +1,111 @@
+package revocationrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	domainrevocation "github.com/mashhkensss/PR-service/internal/domain/revocation"
+	"github.com/mashhkensss/PR-service/internal/persistence/postgres"
+)
+
+type Repository struct {
+	db  *sql.DB
+	sql sq.StatementBuilderType
+}
+
+func New(db *sql.DB) *Repository {
+	return &Repository{
+		db:  db,
+		sql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+// SaveRevocation сохраняет отзыв и возвращает итоговую запись. Повторный отзыв субъекта
+// сдвигает отсечку вперёд, повторный отзыв jti может только уточнить срок
+func (r *Repository) SaveRevocation(ctx context.Context, rev domainrevocation.Revocation) (domainrevocation.Revocation, error) {
+	query, args, err := r.sql.Insert("token_revocations").
+		Columns("kind", "value", "revoked_at", "expires_at").
+		Values(string(rev.Kind()), rev.Value(), rev.RevokedAt(), nullTime(rev.ExpiresAt())).
+		Suffix("ON CONFLICT (kind, value) DO UPDATE SET revoked_at = GREATEST(token_revocations.revoked_at, EXCLUDED.revoked_at), expires_at = GREATEST(token_revocations.expires_at, EXCLUDED.expires_at) RETURNING revoked_at, expires_at").
+		ToSql()
+	if err != nil {
+		return domainrevocation.Revocation{}, err
+	}
+	var (
+		revokedAt time.Time
+		expiresAt sql.NullTime
+	)
+	if err := postgres.ExecutorFromContext(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&revokedAt, &expiresAt); err != nil {
+		return domainrevocation.Revocation{}, fmt.Errorf("save revocation: %w", err)
+	}
+	return build(string(rev.Kind()), rev.Value(), revokedAt, expiresAt)
+}
+
+// ListRevocations возвращает отзывы, сделанные позже since; нулевой since — все
+func (r *Repository) ListRevocations(ctx context.Context, since time.Time) ([]domainrevocation.Revocation, error) {
+	builder := r.sql.Select("kind", "value", "revoked_at", "expires_at").
+		From("token_revocations").
+		OrderBy("revoked_at ASC")
+	if !since.IsZero() {
+		builder = builder.Where("revoked_at > ?", since)
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := postgres.ExecutorFromContext(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list revocations: %w", err)
+	}
+	defer rows.Close()
+
+	revocations := make([]domainrevocation.Revocation, 0)
+	for rows.Next() {
+		var (
+			kind, value string
+			revokedAt   time.Time
+			expiresAt   sql.NullTime
+		)
+		if err := rows.Scan(&kind, &value, &revokedAt, &expiresAt); err != nil {
+			return nil, fmt.Errorf("scan revocation: %w", err)
+		}
+		rev, err := build(kind, value, revokedAt, expiresAt)
+		if err != nil {
+			return nil, err
+		}
+		revocations = append(revocations, rev)
+	}
+	return revocations, rows.Err()
+}
+
+// PurgeExpired удаляет отзывы по jti, чьи токены истекли раньше before
+func (r *Repository) PurgeExpired(ctx context.Context, before time.Time) (int64, error) {
+	query, args, err := r.sql.Delete("token_revocations").
+		Where(sq.Eq{"kind": string(domainrevocation.KindToken)}).
+		Where("expires_at < ?", before).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+	res, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("purge revocations: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func build(kind, value string, revokedAt time.Time, expiresAt sql.NullTime) (domainrevocation.Revocation, error) {
+	rev, err := domainrevocation.New(domainrevocation.Kind(kind), value, revokedAt)
+	if err != nil || !expiresAt.Valid {
+		return rev, err
+	}
+	return rev.WithExpiry(expiresAt.Time)
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,92 @@
+package revocationrepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+
+	domainrevocation "github.com/mashhkensss/PR-service/internal/domain/revocation"
+)
+
+func TestSaveRevocationKeepsLatestCutoff(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	mock.ExpectQuery(`INSERT INTO token_revocations \(kind,value,revoked_at,expires_at\) VALUES \(\$1,\$2,\$3,\$4\) ON CONFLICT \(kind, value\) DO UPDATE SET revoked_at = GREATEST\(token_revocations.revoked_at, EXCLUDED.revoked_at\), expires_at = GREATEST\(token_revocations.expires_at, EXCLUDED.expires_at\) RETURNING revoked_at, expires_at`).
+		WithArgs("subject", "u1", now, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"revoked_at", "expires_at"}).AddRow(later, nil))
+
+	rev, _ := domainrevocation.New(domainrevocation.KindSubject, "u1", now)
+	saved, err := New(db).SaveRevocation(context.Background(), rev)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !saved.RevokedAt().Equal(later) {
+		t.Fatalf("expected stored cutoff %v, got %v", later, saved.RevokedAt())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestListRevocations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	exp := now.Add(time.Hour)
+	rows := sqlmock.NewRows([]string{"kind", "value", "revoked_at", "expires_at"}).
+		AddRow("jti", "abc", now, exp).
+		AddRow("subject", "u1", now, nil)
+	mock.ExpectQuery(`SELECT kind, value, revoked_at, expires_at FROM token_revocations ORDER BY revoked_at ASC`).
+		WillReturnRows(rows)
+
+	revs, err := New(db).ListRevocations(context.Background(), time.Time{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(revs) != 2 || revs[0].Kind() != domainrevocation.KindToken || revs[1].Value() != "u1" {
+		t.Fatalf("unexpected revocations %+v", revs)
+	}
+	if !revs[0].ExpiresAt().Equal(exp) || !revs[1].ExpiresAt().IsZero() {
+		t.Fatalf("unexpected expiry %v / %v", revs[0].ExpiresAt(), revs[1].ExpiresAt())
+	}
+
+	mock.ExpectQuery(`SELECT kind, value, revoked_at, expires_at FROM token_revocations WHERE revoked_at > \$1 ORDER BY revoked_at ASC`).
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "value", "revoked_at", "expires_at"}))
+	if _, err := New(db).ListRevocations(context.Background(), now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestPurgeExpiredDeletesOnlyTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	before := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`DELETE FROM token_revocations WHERE kind = \$1 AND expires_at < \$2`).
+		WithArgs("jti", before).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	n, err := New(db).PurgeExpired(context.Background(), before)
+	if err != nil || n != 3 {
+		t.Fatalf("expected 3 purged, got %d (%v)", n, err)
+	}
+}
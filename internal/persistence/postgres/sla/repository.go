@@ -0,0 +1,173 @@
+package slarepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	domainsla "github.com/mashhkensss/PR-service/internal/domain/sla"
+	"github.com/mashhkensss/PR-service/internal/persistence/postgres"
+)
+
+type Repository struct {
+	db  *sql.DB
+	sql sq.StatementBuilderType
+}
+
+func New(db *sql.DB) *Repository {
+	return &Repository{
+		db:  db,
+		sql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *Repository) SavePolicy(ctx context.Context, p domainsla.Policy) error {
+	query, args, err := r.sql.Insert("team_review_sla").
+		Columns("team_name", "review_within_seconds", "business_days_only", "escalation", "updated_at").
+		Values(p.TeamName(), int64(p.ReviewWithin()/time.Second), p.BusinessDaysOnly(), string(p.Escalation()), time.Now().UTC()).
+		Suffix(`
+			ON CONFLICT (team_name) DO UPDATE
+			SET review_within_seconds = EXCLUDED.review_within_seconds,
+			    business_days_only = EXCLUDED.business_days_only,
+			    escalation = EXCLUDED.escalation,
+			    updated_at = EXCLUDED.updated_at`).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		if isForeignKeyViolation(err) {
+			return fmt.Errorf("team %s: %w", p.TeamName(), sql.ErrNoRows)
+		}
+		return fmt.Errorf("save sla policy: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetPolicy(ctx context.Context, team domain.TeamName) (domainsla.Policy, error) {
+	query, args, err := r.sql.Select("team_name", "review_within_seconds", "business_days_only", "escalation").
+		From("team_review_sla").
+		Where("team_name = ?", team).
+		ToSql()
+	if err != nil {
+		return domainsla.Policy{}, err
+	}
+	var (
+		teamName string
+		seconds  int64
+		business bool
+		esc      string
+	)
+	row := postgres.ExecutorFromContext(ctx, r.db).QueryRowContext(ctx, query, args...)
+	if err := row.Scan(&teamName, &seconds, &business, &esc); err != nil {
+		return domainsla.Policy{}, fmt.Errorf("get sla policy: %w", err)
+	}
+	return domainsla.New(domain.TeamName(teamName), time.Duration(seconds)*time.Second, business, domainsla.Escalation(esc))
+}
+
+// ListPendingReviews возвращает назначения на открытые PR без ревью; team фильтрует по команде автора
+func (r *Repository) ListPendingReviews(ctx context.Context, team domain.TeamName) ([]domainsla.PendingReview, error) {
+	builder := r.sql.Select(
+		"rv.pull_request_id",
+		"rv.reviewer_id",
+		"u.team_name",
+		"rv.assigned_at",
+		"rv.sla_escalated_at",
+		"s.review_within_seconds",
+		"s.business_days_only",
+		"s.escalation",
+	).From("pull_request_reviewers rv").
+		Join("pull_requests pr ON pr.pull_request_id = rv.pull_request_id").
+		Join("users u ON u.user_id = pr.author_id").
+		LeftJoin("team_review_sla s ON s.team_name = u.team_name").
+		Where("pr.status = ?", string(domain.PullRequestStatusOpen)).
+		Where("rv.reviewed_at IS NULL").
+		OrderBy("rv.assigned_at ASC", "rv.pull_request_id ASC")
+	if team != "" {
+		builder = builder.Where("u.team_name = ?", team)
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := postgres.ExecutorFromContext(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list pending reviews: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]domainsla.PendingReview, 0)
+	for rows.Next() {
+		var (
+			prID      string
+			reviewer  string
+			teamName  string
+			assigned  time.Time
+			escalated sql.NullTime
+			seconds   sql.NullInt64
+			business  sql.NullBool
+			esc       sql.NullString
+		)
+		if err := rows.Scan(&prID, &reviewer, &teamName, &assigned, &escalated, &seconds, &business, &esc); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		pending := domainsla.PendingReview{
+			PullRequestID: domain.PullRequestID(prID),
+			ReviewerID:    domain.UserID(reviewer),
+			TeamName:      domain.TeamName(teamName),
+			AssignedAt:    assigned,
+		}
+		if escalated.Valid {
+			ts := escalated.Time
+			pending.EscalatedAt = &ts
+		}
+		if seconds.Valid {
+			policy, err := domainsla.New(pending.TeamName, time.Duration(seconds.Int64)*time.Second, business.Bool, domainsla.Escalation(esc.String))
+			if err != nil {
+				return nil, fmt.Errorf("build sla policy: %w", err)
+			}
+			pending.Policy = policy
+		}
+		result = append(result, pending)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return result, nil
+}
+
+// ClaimEscalation помечает нарушение как эскалированное; false означает, что его уже забрал
+// другой процесс
+func (r *Repository) ClaimEscalation(ctx context.Context, id domain.PullRequestID, reviewerID domain.UserID, at time.Time) (bool, error) {
+	query, args, err := r.sql.Update("pull_request_reviewers").
+		Set("sla_escalated_at", at.UTC()).
+		Where("pull_request_id = ?", id).
+		Where("reviewer_id = ?", reviewerID).
+		Where("sla_escalated_at IS NULL").
+		Where("reviewed_at IS NULL").
+		ToSql()
+	if err != nil {
+		return false, err
+	}
+	res, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("claim escalation: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("claim escalation: %w", err)
+	}
+	return rows > 0, nil
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
@@ -0,0 +1,83 @@
+package slarepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	domainsla "github.com/mashhkensss/PR-service/internal/domain/sla"
+)
+
+func TestListPendingReviews(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	assigned := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"pull_request_id", "reviewer_id", "team_name", "assigned_at", "sla_escalated_at", "review_within_seconds", "business_days_only", "escalation"}).
+		AddRow("pr-1", "rev1", "backend", assigned, nil, int64(3600), false, "REASSIGN").
+		AddRow("pr-2", "rev2", "mobile", assigned, nil, nil, nil, nil)
+	mock.ExpectQuery(`SELECT rv\.pull_request_id`).
+		WithArgs("OPEN").
+		WillReturnRows(rows)
+
+	pending, err := repo.ListPendingReviews(context.Background(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(pending))
+	}
+	if pending[0].Policy.Escalation() != domainsla.EscalationReassign || pending[0].Policy.ReviewWithin() != time.Hour {
+		t.Fatalf("unexpected policy %+v", pending[0].Policy)
+	}
+	if !pending[1].Policy.IsZero() {
+		t.Fatalf("team without settings must have empty policy")
+	}
+}
+
+func TestClaimEscalation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	mock.ExpectExec(`UPDATE pull_request_reviewers SET sla_escalated_at`).
+		WithArgs(sqlmock.AnyArg(), "pr-1", "rev1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	claimed, err := repo.ClaimEscalation(context.Background(), "pr-1", "rev1", time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claimed {
+		t.Fatalf("already escalated row must not be claimed")
+	}
+}
+
+func TestSavePolicyUnknownTeam(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	policy, _ := domainsla.New("ghost", time.Hour, true, domainsla.EscalationEvent)
+	mock.ExpectExec(`INSERT INTO team_review_sla`).
+		WillReturnError(&pgconn.PgError{Code: "23503"})
+
+	if err := repo.SavePolicy(context.Background(), policy); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows for unknown team, got %v", err)
+	}
+}
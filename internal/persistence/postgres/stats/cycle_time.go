@@ -0,0 +1,216 @@
+package statsrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	domainstats "github.com/mashhkensss/PR-service/internal/domain/stats"
+	"github.com/mashhkensss/PR-service/internal/persistence/postgres"
+)
+
+// durationSpec описывает выборку длительностей: откуда берём строки, что меряем,
+// по какой колонке режем на недели и окно, и чья команда считается командой строки
+type durationSpec struct {
+	from     string
+	joins    []string
+	duration string
+	timeCol  string
+	teamCol  string
+	reviewer string
+}
+
+// CreateToMergeTimes — перцентили времени от создания PR до merge, по команде автора и неделе merge
+func (r *Repository) CreateToMergeTimes(ctx context.Context, f domainstats.Filter) ([]domainstats.DurationGroup, error) {
+	return r.durationGroups(ctx, f, durationSpec{
+		from:     "pull_requests pr",
+		joins:    []string{"users u ON u.user_id = pr.author_id"},
+		duration: "pr.merged_at - pr.created_at",
+		timeCol:  "pr.merged_at",
+		teamCol:  "u.team_name",
+	})
+}
+
+// AssignmentToMergeTimes — перцентили времени от назначения ревьювера до merge, по команде
+// ревьювера, неделе merge и самому ревьюверу
+func (r *Repository) AssignmentToMergeTimes(ctx context.Context, f domainstats.Filter) ([]domainstats.DurationGroup, error) {
+	return r.durationGroups(ctx, f, durationSpec{
+		from: "pull_request_reviewers rv",
+		joins: []string{
+			"pull_requests pr ON pr.pull_request_id = rv.pull_request_id",
+			"users u ON u.user_id = rv.reviewer_id",
+		},
+		duration: "pr.merged_at - rv.assigned_at",
+		timeCol:  "pr.merged_at",
+		teamCol:  "u.team_name",
+		reviewer: "rv.reviewer_id",
+	})
+}
+
+func (r *Repository) durationGroups(ctx context.Context, f domainstats.Filter, spec durationSpec) ([]domainstats.DurationGroup, error) {
+	week := weekExpr(spec.timeCol)
+	reviewer := "NULL::text"
+	reviewerGrouping := "1"
+	sets := fmt.Sprintf("(%s), (%s), ()", spec.teamCol, week)
+	if spec.reviewer != "" {
+		reviewer = spec.reviewer
+		reviewerGrouping = "GROUPING(" + spec.reviewer + ")"
+		sets = fmt.Sprintf("(%s), (%s), (%s), ()", spec.teamCol, week, spec.reviewer)
+	}
+	epoch := "EXTRACT(EPOCH FROM " + spec.duration + ")"
+
+	builder := r.sql.Select(
+		"GROUPING("+spec.teamCol+")",
+		"GROUPING("+week+")",
+		reviewerGrouping,
+		spec.teamCol,
+		week,
+		reviewer,
+		"COUNT(*)",
+		"percentile_cont(0.5) WITHIN GROUP (ORDER BY "+epoch+")",
+		"percentile_cont(0.9) WITHIN GROUP (ORDER BY "+epoch+")",
+		"percentile_cont(0.99) WITHIN GROUP (ORDER BY "+epoch+")",
+	).From(spec.from).
+		Where(sq.Eq{"pr.status": string(domain.PullRequestStatusMerged)}).
+		Where(sq.NotEq{"pr.merged_at": nil}).
+		Where(windowConditions(spec.timeCol, spec.teamCol, f)).
+		GroupBy("GROUPING SETS ("+sets+")").
+		OrderBy("1 DESC", "2 DESC", "3 DESC", "4", "5", "6")
+	for _, join := range spec.joins {
+		builder = builder.Join(join)
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := postgres.ExecutorFromContext(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query cycle time: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]domainstats.DurationGroup, 0)
+	for rows.Next() {
+		var (
+			gTeam, gWeek, gReviewer int
+			team, reviewerID        sql.NullString
+			weekStart               sql.NullTime
+			p50, p90, p99           sql.NullFloat64
+			group                   domainstats.DurationGroup
+		)
+		if err := rows.Scan(&gTeam, &gWeek, &gReviewer, &team, &weekStart, &reviewerID, &group.Count, &p50, &p90, &p99); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		group.Dimension = dimension(gTeam, gWeek, gReviewer)
+		group.Team = domain.TeamName(team.String)
+		group.ReviewerID = domain.UserID(reviewerID.String)
+		if weekStart.Valid {
+			group.Week = weekStart.Time.UTC()
+		}
+		group.P50 = seconds(p50)
+		group.P90 = seconds(p90)
+		group.P99 = seconds(p99)
+		result = append(result, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return result, nil
+}
+
+// ReassignmentRates считает долю переназначенных PR по команде автора и неделе создания PR
+func (r *Repository) ReassignmentRates(ctx context.Context, f domainstats.Filter) ([]domainstats.ReassignmentGroup, error) {
+	week := weekExpr("pr.created_at")
+	query, args, err := r.sql.Select(
+		"GROUPING(u.team_name)",
+		"GROUPING("+week+")",
+		"u.team_name",
+		week,
+		"COUNT(*)",
+		"COUNT(*) FILTER (WHERE ra.reassignments > 0)",
+		"COALESCE(SUM(ra.reassignments), 0)",
+	).From("pull_requests pr").
+		Join("users u ON u.user_id = pr.author_id").
+		LeftJoin("(SELECT pull_request_id, COUNT(*) AS reassignments FROM pull_request_reassignments GROUP BY pull_request_id) ra ON ra.pull_request_id = pr.pull_request_id").
+		Where(windowConditions("pr.created_at", "u.team_name", f)).
+		GroupBy("GROUPING SETS ((u.team_name), ("+week+"), ())").
+		OrderBy("1 DESC", "2 DESC", "3", "4").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := postgres.ExecutorFromContext(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query reassignment rates: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]domainstats.ReassignmentGroup, 0)
+	for rows.Next() {
+		var (
+			gTeam, gWeek int
+			team         sql.NullString
+			weekStart    sql.NullTime
+			group        domainstats.ReassignmentGroup
+		)
+		if err := rows.Scan(&gTeam, &gWeek, &team, &weekStart, &group.PullRequests, &group.Reassigned, &group.Reassignments); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		group.Dimension = dimension(gTeam, gWeek, 1)
+		group.Team = domain.TeamName(team.String)
+		if weekStart.Valid {
+			group.Week = weekStart.Time.UTC()
+		}
+		result = append(result, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return result, nil
+}
+
+func weekExpr(col string) string {
+	return "date_trunc('week', " + col + " AT TIME ZONE 'UTC')"
+}
+
+func windowConditions(timeCol, teamCol string, f domainstats.Filter) sq.And {
+	cond := sq.And{}
+	if f.From != nil {
+		cond = append(cond, sq.GtOrEq{timeCol: f.From.UTC()})
+	}
+	if f.To != nil {
+		cond = append(cond, sq.Lt{timeCol: f.To.UTC()})
+	}
+	if f.Team != "" {
+		cond = append(cond, sq.Eq{teamCol: string(f.Team)})
+	}
+	if len(cond) == 0 {
+		cond = append(cond, sq.Expr("TRUE"))
+	}
+	return cond
+}
+
+// dimension переводит флаги GROUPING() (0 — колонка участвует в группировке) в разрез
+func dimension(team, week, reviewer int) domainstats.Dimension {
+	switch {
+	case team == 0:
+		return domainstats.DimensionTeam
+	case week == 0:
+		return domainstats.DimensionWeek
+	case reviewer == 0:
+		return domainstats.DimensionReviewer
+	default:
+		return domainstats.DimensionTotal
+	}
+}
+
+func seconds(v sql.NullFloat64) time.Duration {
+	if !v.Valid {
+		return 0
+	}
+	return time.Duration(v.Float64 * float64(time.Second))
+}
@@ -8,6 +8,7 @@ import (
 	sq "github.com/Masterminds/squirrel"
 
 	"github.com/mashhkensss/PR-service/internal/domain"
+	domainstats "github.com/mashhkensss/PR-service/internal/domain/stats"
 	"github.com/mashhkensss/PR-service/internal/persistence/postgres"
 )
 
@@ -23,28 +24,52 @@ func New(db *sql.DB) *Repository {
 	}
 }
 
-func (r *Repository) AssignmentsPerUser(ctx context.Context) (map[domain.UserID]int, error) {
-	query, _, err := r.sql.Select("reviewer_id", "COUNT(*)").
-		From("pull_request_reviewers").
-		GroupBy("reviewer_id").
-		ToSql()
+// AssignmentsPerUser считает назначения по ревьюверам с разбивкой OPEN/MERGED. Активные
+// пользователи без назначений тоже попадают в выборку (с нулями)
+func (r *Repository) AssignmentsPerUser(ctx context.Context, f domainstats.Filter) ([]domainstats.ReviewerLoad, error) {
+	joinCond, joinArgs, err := assignmentConditions("rv.reviewer_id = u.user_id", f).ToSql()
 	if err != nil {
 		return nil, err
 	}
-	rows, err := postgres.ExecutorFromContext(ctx, r.db).QueryContext(ctx, query)
+	builder := r.sql.Select(
+		"u.user_id",
+		"u.team_name",
+		"u.is_active",
+		"COUNT(pr.pull_request_id) FILTER (WHERE pr.status = 'OPEN')",
+		"COUNT(pr.pull_request_id) FILTER (WHERE pr.status = 'MERGED')",
+	).From("users u").
+		LeftJoin("(pull_request_reviewers rv JOIN pull_requests pr ON pr.pull_request_id = rv.pull_request_id) ON "+joinCond, joinArgs...).
+		GroupBy("u.user_id", "u.team_name", "u.is_active").
+		Having("u.is_active OR COUNT(pr.pull_request_id) > 0").
+		OrderBy("COUNT(pr.pull_request_id) DESC", "u.user_id ASC")
+	if f.Team != "" {
+		builder = builder.Where("u.team_name = ?", f.Team)
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := postgres.ExecutorFromContext(ctx, r.db).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("count assignments per user: %w", err)
 	}
 	defer rows.Close()
 
-	result := make(map[domain.UserID]int)
+	result := make([]domainstats.ReviewerLoad, 0)
 	for rows.Next() {
-		var id string
-		var count int
-		if err := rows.Scan(&id, &count); err != nil {
+		var (
+			id       string
+			team     string
+			isActive bool
+			load     domainstats.ReviewerLoad
+		)
+		if err := rows.Scan(&id, &team, &isActive, &load.Open, &load.Merged); err != nil {
 			return nil, fmt.Errorf("scan row: %w", err)
 		}
-		result[domain.UserID(id)] = count
+		load.UserID = domain.UserID(id)
+		load.TeamName = domain.TeamName(team)
+		load.IsActive = isActive
+		result = append(result, load)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("rows error: %w", err)
@@ -52,15 +77,22 @@ func (r *Repository) AssignmentsPerUser(ctx context.Context) (map[domain.UserID]
 	return result, nil
 }
 
-func (r *Repository) AssignmentsPerPullRequest(ctx context.Context) (map[domain.PullRequestID]int, error) {
-	query, _, err := r.sql.Select("pull_request_id", "COUNT(*)").
-		From("pull_request_reviewers").
-		GroupBy("pull_request_id").
-		ToSql()
+// AssignmentsPerPullRequest считает назначения по PR с теми же фильтрами, что и AssignmentsPerUser,
+// поэтому суммы по обеим выборкам совпадают
+func (r *Repository) AssignmentsPerPullRequest(ctx context.Context, f domainstats.Filter) (map[domain.PullRequestID]int, error) {
+	builder := r.sql.Select("rv.pull_request_id", "COUNT(*)").
+		From("pull_request_reviewers rv").
+		Join("pull_requests pr ON pr.pull_request_id = rv.pull_request_id").
+		Where(assignmentConditions("", f)).
+		GroupBy("rv.pull_request_id")
+	if f.Team != "" {
+		builder = builder.Join("users u ON u.user_id = rv.reviewer_id").Where("u.team_name = ?", f.Team)
+	}
+	query, args, err := builder.ToSql()
 	if err != nil {
 		return nil, err
 	}
-	rows, err := postgres.ExecutorFromContext(ctx, r.db).QueryContext(ctx, query)
+	rows, err := postgres.ExecutorFromContext(ctx, r.db).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("count assignments per pull request: %w", err)
 	}
@@ -80,3 +112,23 @@ func (r *Repository) AssignmentsPerPullRequest(ctx context.Context) (map[domain.
 	}
 	return result, nil
 }
+
+func assignmentConditions(base string, f domainstats.Filter) sq.And {
+	cond := sq.And{}
+	if base != "" {
+		cond = append(cond, sq.Expr(base))
+	}
+	if f.From != nil {
+		cond = append(cond, sq.GtOrEq{"rv.assigned_at": f.From.UTC()})
+	}
+	if f.To != nil {
+		cond = append(cond, sq.Lt{"rv.assigned_at": f.To.UTC()})
+	}
+	if f.Status != "" {
+		cond = append(cond, sq.Eq{"pr.status": string(f.Status)})
+	}
+	if len(cond) == 0 {
+		cond = append(cond, sq.Expr("TRUE"))
+	}
+	return cond
+}
@@ -3,10 +3,12 @@ package statsrepo
 import (
 	"context"
 	"testing"
+	"time"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
 
 	"github.com/mashhkensss/PR-service/internal/domain"
+	domainstats "github.com/mashhkensss/PR-service/internal/domain/stats"
 )
 
 func TestAssignmentsPerUser(t *testing.T) {
@@ -17,21 +19,44 @@ func TestAssignmentsPerUser(t *testing.T) {
 	defer db.Close()
 
 	repo := New(db)
-	rows := sqlmock.NewRows([]string{"reviewer_id", "count"}).
-		AddRow("u1", 2).
-		AddRow("u2", 1)
-	mock.ExpectQuery(`SELECT reviewer_id`).
+	rows := sqlmock.NewRows([]string{"user_id", "team_name", "is_active", "open", "merged"}).
+		AddRow("u1", "backend", true, 1, 1).
+		AddRow("u2", "backend", true, 0, 0)
+	mock.ExpectQuery(`SELECT u.user_id, u.team_name, u.is_active`).
 		WillReturnRows(rows)
 
-	stats, err := repo.AssignmentsPerUser(context.Background())
+	stats, err := repo.AssignmentsPerUser(context.Background(), domainstats.Filter{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if stats[domain.UserID("u1")] != 2 || stats[domain.UserID("u2")] != 1 {
+	if len(stats) != 2 || stats[0].Total() != 2 || stats[1].Total() != 0 || !stats[1].IsActive {
 		t.Fatalf("unexpected stats %+v", stats)
 	}
 }
 
+func TestAssignmentsPerUser_Filters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	mock.ExpectQuery(`LEFT JOIN .*rv.assigned_at >= \$1 AND rv.assigned_at < \$2 AND pr.status = \$3.*WHERE u.team_name = \$4`).
+		WithArgs(from, to, "MERGED", domain.TeamName("backend")).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "team_name", "is_active", "open", "merged"}))
+
+	f := domainstats.Filter{From: &from, To: &to, Team: "backend", Status: domain.PullRequestStatusMerged}
+	if _, err := repo.AssignmentsPerUser(context.Background(), f); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
 func TestAssignmentsPerPullRequest(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -42,10 +67,10 @@ func TestAssignmentsPerPullRequest(t *testing.T) {
 	repo := New(db)
 	rows := sqlmock.NewRows([]string{"pull_request_id", "count"}).
 		AddRow("pr-1", 2)
-	mock.ExpectQuery(`SELECT pull_request_id`).
+	mock.ExpectQuery(`SELECT rv.pull_request_id`).
 		WillReturnRows(rows)
 
-	stats, err := repo.AssignmentsPerPullRequest(context.Background())
+	stats, err := repo.AssignmentsPerPullRequest(context.Background(), domainstats.Filter{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -53,3 +78,60 @@ func TestAssignmentsPerPullRequest(t *testing.T) {
 		t.Fatalf("unexpected stats %+v", stats)
 	}
 }
+
+func TestCreateToMergeTimes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	week := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"gt", "gw", "gr", "team_name", "week", "reviewer", "count", "p50", "p90", "p99"}).
+		AddRow(0, 1, 1, "backend", nil, nil, 2, 3600.0, 7200.0, 7200.0).
+		AddRow(1, 0, 1, nil, week, nil, 2, 3600.0, 7200.0, 7200.0).
+		AddRow(1, 1, 1, nil, nil, nil, 2, 3600.0, 7200.0, 7200.0)
+	mock.ExpectQuery(`percentile_cont\(0.5\) WITHIN GROUP \(ORDER BY EXTRACT\(EPOCH FROM pr.merged_at - pr.created_at\)\).*GROUPING SETS`).
+		WithArgs("MERGED", "backend").
+		WillReturnRows(rows)
+
+	groups, err := repo.CreateToMergeTimes(context.Background(), domainstats.Filter{Team: "backend"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(groups) != 3 {
+		t.Fatalf("unexpected groups %+v", groups)
+	}
+	if groups[0].Dimension != domainstats.DimensionTeam || groups[0].Team != "backend" || groups[0].P90 != 2*time.Hour {
+		t.Fatalf("unexpected team group %+v", groups[0])
+	}
+	if groups[1].Dimension != domainstats.DimensionWeek || !groups[1].Week.Equal(week) {
+		t.Fatalf("unexpected week group %+v", groups[1])
+	}
+	if groups[2].Dimension != domainstats.DimensionTotal {
+		t.Fatalf("unexpected total group %+v", groups[2])
+	}
+}
+
+func TestReassignmentRates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	rows := sqlmock.NewRows([]string{"gt", "gw", "team_name", "week", "prs", "reassigned", "reassignments"}).
+		AddRow(1, 1, nil, nil, 4, 1, 3)
+	mock.ExpectQuery(`FROM pull_request_reassignments`).
+		WillReturnRows(rows)
+
+	groups, err := repo.ReassignmentRates(context.Background(), domainstats.Filter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(groups) != 1 || groups[0].Dimension != domainstats.DimensionTotal || groups[0].Rate() != 0.25 {
+		t.Fatalf("unexpected groups %+v", groups)
+	}
+}
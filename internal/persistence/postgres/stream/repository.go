@@ -0,0 +1,155 @@
+package streamrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/persistence/postgres"
+	"github.com/mashhkensss/PR-service/internal/stream"
+)
+
+// visibleHorizon — самая старая незавершённая транзакция: всё, что записано раньше неё, уже зафиксировано
+const visibleHorizon = "pg_snapshot_xmin(pg_current_snapshot())"
+
+type Repository struct {
+	db  *sql.DB
+	sql sq.StatementBuilderType
+}
+
+func New(db *sql.DB) *Repository {
+	return &Repository{
+		db:  db,
+		sql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+func (r *Repository) Append(ctx context.Context, entries ...stream.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	builder := r.sql.Insert("assignment_events").
+		Columns("kind", "pull_request_id", "reviewer_id", "occurred_at")
+	for _, e := range entries {
+		builder = builder.Values(string(e.Kind), e.PullRequestID, e.ReviewerID, e.OccurredAt.UTC())
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("append assignment events: %w", err)
+	}
+	return nil
+}
+
+// After фильтрует по текущей команде ревьювера: поток команды показывает назначения её участников.
+// Записи транзакций не старше самой старой незавершённой не выдаются: она ещё может
+// зафиксировать записи, которые встанут перед ними
+func (r *Repository) After(ctx context.Context, filter stream.Filter, after stream.Position, limit int) ([]stream.Entry, error) {
+	builder := r.sql.Select("e.id", "e.xid::text::bigint", "e.kind", "e.pull_request_id", "e.reviewer_id", "COALESCE(u.team_name, '')", "e.occurred_at").
+		From("assignment_events e").
+		LeftJoin("users u ON u.user_id = e.reviewer_id").
+		Where("(e.xid, e.id) > (CAST(? AS text)::xid8, ?)", strconv.FormatInt(after.XID, 10), after.ID).
+		Where("e.xid < "+visibleHorizon).
+		OrderBy("e.xid", "e.id").
+		Limit(uint64(limit))
+	if filter.ReviewerID != "" {
+		builder = builder.Where("e.reviewer_id = ?", filter.ReviewerID)
+	}
+	if filter.TeamName != "" {
+		builder = builder.Where("u.team_name = ?", filter.TeamName)
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := postgres.ExecutorFromContext(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list assignment events: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []stream.Entry
+	for rows.Next() {
+		var (
+			e        stream.Entry
+			kind     string
+			prID     string
+			reviewer string
+			teamName string
+		)
+		if err := rows.Scan(&e.ID, &e.XID, &kind, &prID, &reviewer, &teamName, &e.OccurredAt); err != nil {
+			return nil, fmt.Errorf("scan assignment event: %w", err)
+		}
+		e.Kind = stream.Kind(kind)
+		e.PullRequestID = domain.PullRequestID(prID)
+		e.ReviewerID = domain.UserID(reviewer)
+		e.TeamName = domain.TeamName(teamName)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *Repository) Head(ctx context.Context) (stream.Position, error) {
+	query, args, err := r.sql.Select("xid::text::bigint", "id").
+		From("assignment_events").
+		Where("xid < "+visibleHorizon).
+		OrderBy("xid DESC", "id DESC").
+		Limit(1).
+		ToSql()
+	if err != nil {
+		return stream.Position{}, err
+	}
+	return r.position(ctx, query, args, "assignment stream head")
+}
+
+func (r *Repository) Locate(ctx context.Context, id int64) (stream.Position, error) {
+	query, args, err := r.sql.Select("xid::text::bigint", "id").
+		From("assignment_events").
+		Where(sq.Eq{"id": id}).
+		ToSql()
+	if err != nil {
+		return stream.Position{}, err
+	}
+	return r.position(ctx, query, args, "locate assignment event")
+}
+
+func (r *Repository) position(ctx context.Context, query string, args []any, op string) (stream.Position, error) {
+	var pos stream.Position
+	err := postgres.ExecutorFromContext(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&pos.XID, &pos.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return stream.Position{}, nil
+	}
+	if err != nil {
+		return stream.Position{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return pos, nil
+}
+
+// Purge удаляет до limit записей за раз, чтобы не держать долгую блокировку
+func (r *Repository) Purge(ctx context.Context, before time.Time, limit int) (int64, error) {
+	batch := r.sql.Select("id").
+		From("assignment_events").
+		Where("recorded_at <= ?", before.UTC()).
+		Limit(uint64(limit))
+	query, args, err := r.sql.Delete("assignment_events").
+		Where(sq.Expr("id IN (?)", batch)).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+	res, err := postgres.ExecutorFromContext(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("purge assignment events: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+var _ stream.Storage = (*Repository)(nil)
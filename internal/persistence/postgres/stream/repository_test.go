@@ -0,0 +1,90 @@
+package streamrepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/mashhkensss/PR-service/internal/stream"
+)
+
+func TestAppendEntries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	at := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectExec(`INSERT INTO assignment_events \(kind,pull_request_id,reviewer_id,occurred_at\) VALUES \(\$1,\$2,\$3,\$4\),\(\$5,\$6,\$7,\$8\)`).
+		WithArgs("UNASSIGNED", "pr-1", "u1", at, "ASSIGNED", "pr-1", "u2", at).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err = New(db).Append(context.Background(),
+		stream.Entry{Kind: stream.KindUnassigned, PullRequestID: "pr-1", ReviewerID: "u1", OccurredAt: at},
+		stream.Entry{Kind: stream.KindAssigned, PullRequestID: "pr-1", ReviewerID: "u2", OccurredAt: at},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestAfterFiltersByTeam(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	at := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT e.id, e.xid::text::bigint, e.kind, e.pull_request_id, e.reviewer_id, COALESCE\(u.team_name, ''\), e.occurred_at FROM assignment_events e LEFT JOIN users u ON u.user_id = e.reviewer_id WHERE \(e.xid, e.id\) > \(CAST\(\$1 AS text\)::xid8, \$2\) AND e.xid < pg_snapshot_xmin\(pg_current_snapshot\(\)\) AND u.team_name = \$3 ORDER BY e.xid, e.id LIMIT 50`).
+		WithArgs("700", int64(10), "backend").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "xid", "kind", "pull_request_id", "reviewer_id", "team_name", "occurred_at"}).
+			AddRow(int64(9), int64(701), "ASSIGNED", "pr-1", "u2", "backend", at))
+
+	entries, err := New(db).After(context.Background(), stream.Filter{TeamName: "backend"}, stream.Position{XID: 700, ID: 10}, 50)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Position() != (stream.Position{XID: 701, ID: 9}) || entries[0].Kind != stream.KindAssigned || entries[0].TeamName != "backend" {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+}
+
+func TestHeadOfEmptyLog(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT xid::text::bigint, id FROM assignment_events WHERE xid < pg_snapshot_xmin\(pg_current_snapshot\(\)\) ORDER BY xid DESC, id DESC LIMIT 1`).
+		WillReturnRows(sqlmock.NewRows([]string{"xid", "id"}))
+
+	pos, err := New(db).Head(context.Background())
+	if err != nil || pos != (stream.Position{}) {
+		t.Fatalf("expected zero position, got %+v err=%v", pos, err)
+	}
+}
+
+func TestLocateEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT xid::text::bigint, id FROM assignment_events WHERE id = \$1`).
+		WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"xid", "id"}).AddRow(int64(900), int64(42)))
+
+	pos, err := New(db).Locate(context.Background(), 42)
+	if err != nil || pos != (stream.Position{XID: 900, ID: 42}) {
+		t.Fatalf("unexpected position %+v err=%v", pos, err)
+	}
+}
@@ -3,10 +3,12 @@ package teamrepo
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgconn"
 
 	"github.com/mashhkensss/PR-service/internal/domain"
 	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
@@ -45,20 +47,126 @@ func (r *Repository) SaveTeam(ctx context.Context, aggregate domainteam.Team) er
 		return domain.ErrTeamExists
 	}
 
-	for _, member := range aggregate.Members() {
-		userQuery, userArgs, err := r.sql.Insert("users").
-			Columns("user_id", "username", "team_name", "is_active", "updated_at").
-			Values(member.UserID(), member.Username(), aggregate.TeamName(), member.IsActive(), time.Now().UTC()).
-			Suffix("ON CONFLICT (user_id) DO UPDATE SET username = EXCLUDED.username, team_name = EXCLUDED.team_name, is_active = EXCLUDED.is_active, updated_at = EXCLUDED.updated_at").
-			ToSql()
+	members := aggregate.Members()
+	if err := r.bumpFormerTeams(ctx, exec, aggregate.TeamName(), members); err != nil {
+		return err
+	}
 
-		if err != nil {
+	for _, member := range members {
+		if err := r.upsertMember(ctx, exec, aggregate.TeamName(), member); err != nil {
 			return err
 		}
+	}
+	return nil
+}
+
+// SaveMember добавляет участника в команду или обновляет его. Версия команды сверяется
+// с expected (AnyVersion — без проверки) и растёт вместе с версией прежней команды участника
+func (r *Repository) SaveMember(ctx context.Context, member domainuser.User, expected domain.Version) error {
+	exec := postgres.ExecutorFromContext(ctx, r.db)
+	if err := r.bumpTeam(ctx, exec, member.TeamName(), expected); err != nil {
+		return err
+	}
+	if err := r.bumpFormerTeams(ctx, exec, member.TeamName(), []domainuser.User{member}); err != nil {
+		return err
+	}
+	return r.upsertMember(ctx, exec, member.TeamName(), member)
+}
+
+// RemoveMember удаляет участника команды. На участника с PR или ревью ссылается история,
+// такого удалить нельзя — domain.ErrMemberHasHistory
+func (r *Repository) RemoveMember(ctx context.Context, name domain.TeamName, id domain.UserID, expected domain.Version) error {
+	exec := postgres.ExecutorFromContext(ctx, r.db)
+	if err := r.bumpTeam(ctx, exec, name, expected); err != nil {
+		return err
+	}
+	query, args, err := r.sql.Delete("users").
+		Where("user_id = ?", id).
+		Where("team_name = ?", name).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	res, err := exec.ExecContext(ctx, query, args...)
+	if isForeignKeyViolation(err) {
+		return fmt.Errorf("%w: %s", domain.ErrMemberHasHistory, id)
+	}
+	if err != nil {
+		return fmt.Errorf("delete user %s: %w", id, err)
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return fmt.Errorf("%w: %s", domain.ErrUserNotFound, id)
+	}
+	return nil
+}
+
+func (r *Repository) upsertMember(ctx context.Context, exec postgres.DBTX, name domain.TeamName, member domainuser.User) error {
+	query, args, err := r.sql.Insert("users").
+		Columns("user_id", "username", "team_name", "is_active", "updated_at").
+		Values(member.UserID(), member.Username(), name, member.IsActive(), time.Now().UTC()).
+		Suffix("ON CONFLICT (user_id) DO UPDATE SET username = EXCLUDED.username, team_name = EXCLUDED.team_name, is_active = EXCLUDED.is_active, updated_at = EXCLUDED.updated_at, version = users.version + 1").
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := exec.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("upsert user %s: %w", member.UserID(), err)
+	}
+	return nil
+}
 
-		if _, err := exec.ExecContext(ctx, userQuery, userArgs...); err != nil {
-			return fmt.Errorf("upsert user %s: %w", member.UserID(), err)
+// bumpTeam увеличивает версию команды и блокирует её строку до конца транзакции,
+// поэтому параллельные изменения состава выполняются по очереди
+func (r *Repository) bumpTeam(ctx context.Context, exec postgres.DBTX, name domain.TeamName, expected domain.Version) error {
+	builder := r.sql.Update("teams").
+		Set("version", sq.Expr("version + 1")).
+		Set("updated_at", time.Now().UTC()).
+		Where("team_name = ?", name)
+	if expected != domain.AnyVersion {
+		builder = builder.Where("version = ?", expected)
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return err
+	}
+	res, err := exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("bump team version: %w", err)
+	}
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		if expected != domain.AnyVersion {
+			return domain.ErrVersionMismatch
 		}
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// bumpFormerTeams увеличивает версию команд, из которых переходят участники: их состав меняется
+// вместе с новой командой, и закешированный клиентом ETag должен устареть
+func (r *Repository) bumpFormerTeams(ctx context.Context, exec postgres.DBTX, name domain.TeamName, members []domainuser.User) error {
+	if len(members) == 0 {
+		return nil
+	}
+	ids := make([]domain.UserID, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.UserID())
+	}
+	// подзапрос строится с ?-плейсхолдерами: нумерацию $n проставит внешний запрос
+	former := sq.Select("team_name").
+		From("users").
+		Where(sq.Eq{"user_id": ids}).
+		Where("team_name <> ?", name)
+	query, args, err := r.sql.Update("teams").
+		Set("version", sq.Expr("version + 1")).
+		Set("updated_at", time.Now().UTC()).
+		Where(sq.Expr("team_name IN (?)", former)).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := exec.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("bump former teams: %w", err)
 	}
 	return nil
 }
@@ -66,7 +174,7 @@ func (r *Repository) SaveTeam(ctx context.Context, aggregate domainteam.Team) er
 func (r *Repository) GetTeam(ctx context.Context, name domain.TeamName) (domainteam.Team, error) {
 	exec := postgres.ExecutorFromContext(ctx, r.db)
 
-	query, args, err := r.sql.Select("t.team_name", "u.user_id", "u.username", "u.is_active").
+	query, args, err := r.sql.Select("t.team_name", "t.version", "u.user_id", "u.username", "u.is_active", "u.version").
 		From("teams t").
 		LeftJoin("users u ON u.team_name = t.team_name").
 		Where("t.team_name = ?", name).
@@ -85,16 +193,20 @@ func (r *Repository) GetTeam(ctx context.Context, name domain.TeamName) (domaint
 
 	members := make([]domainuser.User, 0)
 	found := false
-	var teamName string
+	var (
+		teamName    string
+		teamVersion int64
+	)
 
 	for rows.Next() {
 		var (
-			teamVal string
-			userID  sql.NullString
-			nameVal sql.NullString
-			active  sql.NullBool
+			teamVal     string
+			userID      sql.NullString
+			nameVal     sql.NullString
+			active      sql.NullBool
+			userVersion sql.NullInt64
 		)
-		if err := rows.Scan(&teamVal, &userID, &nameVal, &active); err != nil {
+		if err := rows.Scan(&teamVal, &teamVersion, &userID, &nameVal, &active, &userVersion); err != nil {
 			return domainteam.Team{}, fmt.Errorf("scan team row: %w", err)
 		}
 		found = true
@@ -107,7 +219,7 @@ func (r *Repository) GetTeam(ctx context.Context, name domain.TeamName) (domaint
 			return domainteam.Team{}, fmt.Errorf("build user: %w", err)
 		}
 
-		members = append(members, member)
+		members = append(members, member.WithVersion(domain.Version(userVersion.Int64)))
 	}
 	if err := rows.Err(); err != nil {
 		return domainteam.Team{}, fmt.Errorf("rows error: %w", err)
@@ -117,5 +229,42 @@ func (r *Repository) GetTeam(ctx context.Context, name domain.TeamName) (domaint
 		return domainteam.Team{}, sql.ErrNoRows
 	}
 
-	return domainteam.New(domain.TeamName(teamName), members)
+	t, err := domainteam.New(domain.TeamName(teamName), members)
+	if err != nil {
+		return domainteam.Team{}, err
+	}
+	return t.WithVersion(domain.Version(teamVersion)), nil
+}
+
+func (r *Repository) ListTeamNames(ctx context.Context) ([]domain.TeamName, error) {
+	query, args, err := r.sql.Select("team_name").
+		From("teams").
+		OrderBy("team_name").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := postgres.ExecutorFromContext(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query teams: %w", err)
+	}
+	defer rows.Close()
+
+	names := make([]domain.TeamName, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan team name: %w", err)
+		}
+		names = append(names, domain.TeamName(name))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return names, nil
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
 }
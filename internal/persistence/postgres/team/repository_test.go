@@ -6,9 +6,11 @@ import (
 	"testing"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
 
 	"github.com/mashhkensss/PR-service/internal/domain"
 	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
+	domainuser "github.com/mashhkensss/PR-service/internal/domain/user"
 )
 
 func TestSaveTeamDuplicate(t *testing.T) {
@@ -42,9 +44,9 @@ func TestGetTeamBuildsMembers(t *testing.T) {
 
 	repo := New(db)
 
-	rows := sqlmock.NewRows([]string{"team_name", "user_id", "username", "is_active"}).
-		AddRow("backend", "u1", "Alice", true).
-		AddRow("backend", "u2", "Bob", false)
+	rows := sqlmock.NewRows([]string{"team_name", "version", "user_id", "username", "is_active", "version"}).
+		AddRow("backend", 4, "u1", "Alice", true, 2).
+		AddRow("backend", 4, "u2", "Bob", false, 1)
 	mock.ExpectQuery(`SELECT t\.team_name`).
 		WithArgs("backend").
 		WillReturnRows(rows)
@@ -53,7 +55,118 @@ func TestGetTeamBuildsMembers(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if got.TeamName() != "backend" || len(got.Members()) != 2 {
+	if got.TeamName() != "backend" || len(got.Members()) != 2 || got.Version() != 4 {
 		t.Fatalf("unexpected team %+v", got)
 	}
+	if alice, _ := got.Member("u1"); alice.Version() != 2 {
+		t.Fatalf("member version not loaded: %d", alice.Version())
+	}
+}
+
+func TestSaveTeamBumpsFormerTeams(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	alice, _ := domainuser.New("u1", "Alice", "backend", true)
+	team, _ := domainteam.New("backend", []domainuser.User{alice})
+
+	mock.ExpectExec(`INSERT INTO teams`).
+		WithArgs(team.TeamName(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// участник, перешедший из другой команды, меняет и её представление
+	mock.ExpectExec(`UPDATE teams SET version = version \+ 1, updated_at = \$1 WHERE team_name IN \(SELECT team_name FROM users WHERE user_id IN \(\$2\) AND team_name <> \$3\)`).
+		WithArgs(sqlmock.AnyArg(), alice.UserID(), team.TeamName()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO users .* version = users.version \+ 1`).
+		WithArgs(alice.UserID(), alice.Username(), team.TeamName(), true, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := New(db).SaveTeam(context.Background(), team); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestListTeamNames(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	mock.ExpectQuery(`SELECT team_name FROM teams ORDER BY team_name`).
+		WillReturnRows(sqlmock.NewRows([]string{"team_name"}).AddRow("backend").AddRow("mobile"))
+
+	names, err := repo.ListTeamNames(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(names) != 2 || names[0] != "backend" || names[1] != "mobile" {
+		t.Fatalf("unexpected names %v", names)
+	}
+}
+
+func TestSaveMemberChecksTeamVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+	member, _ := domainuser.New("u4", "Dave", "backend", true)
+
+	mock.ExpectExec(`UPDATE teams SET version = version \+ 1, updated_at = \$1 WHERE team_name = \$2 AND version = \$3`).
+		WithArgs(sqlmock.AnyArg(), "backend", domain.Version(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE teams SET version = version \+ 1, updated_at = \$1 WHERE team_name IN`).
+		WithArgs(sqlmock.AnyArg(), "u4", "backend").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO users`).
+		WithArgs("u4", "Dave", "backend", true, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := repo.SaveMember(context.Background(), member, 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mock.ExpectExec(`UPDATE teams`).
+		WithArgs(sqlmock.AnyArg(), "backend", domain.Version(3)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := repo.SaveMember(context.Background(), member, 3); !errors.Is(err, domain.ErrVersionMismatch) {
+		t.Fatalf("expected ErrVersionMismatch, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestRemoveMemberWithHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := New(db)
+
+	mock.ExpectExec(`UPDATE teams SET version = version \+ 1, updated_at = \$1 WHERE team_name = \$2$`).
+		WithArgs(sqlmock.AnyArg(), "backend").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM users WHERE user_id = \$1 AND team_name = \$2`).
+		WithArgs("u2", "backend").
+		WillReturnError(&pgconn.PgError{Code: "23503"})
+
+	if err := repo.RemoveMember(context.Background(), "backend", "u2", domain.AnyVersion); !errors.Is(err, domain.ErrMemberHasHistory) {
+		t.Fatalf("expected ErrMemberHasHistory, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
 }
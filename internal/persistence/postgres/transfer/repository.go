@@ -0,0 +1,350 @@
+package transferrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
+	"github.com/mashhkensss/PR-service/internal/domain/transfer"
+	domainuser "github.com/mashhkensss/PR-service/internal/domain/user"
+	"github.com/mashhkensss/PR-service/internal/persistence/postgres"
+)
+
+type Repository struct {
+	db  *sql.DB
+	sql sq.StatementBuilderType
+}
+
+func New(db *sql.DB) *Repository {
+	return &Repository{
+		db:  db,
+		sql: sq.StatementBuilder.PlaceholderFormat(sq.Dollar),
+	}
+}
+
+// ExportTeams читает команды с участниками одним запросом и отдаёт их fn по одной, не держа всё в памяти
+func (r *Repository) ExportTeams(ctx context.Context, fn func(domainteam.Team) error) error {
+	query, args, err := r.sql.Select("t.team_name", "u.user_id", "u.username", "u.is_active").
+		From("teams t").
+		LeftJoin("users u ON u.team_name = t.team_name").
+		OrderBy("t.team_name", "u.username", "u.user_id").
+		ToSql()
+	if err != nil {
+		return err
+	}
+	rows, err := postgres.ExecutorFromContext(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("query teams: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		current string
+		members []domainuser.User
+		started bool
+	)
+	flush := func() error {
+		t, err := domainteam.New(domain.TeamName(current), members)
+		if err != nil {
+			return fmt.Errorf("build team %s: %w", current, err)
+		}
+		return fn(t)
+	}
+	for rows.Next() {
+		var (
+			teamName string
+			userID   sql.NullString
+			username sql.NullString
+			active   sql.NullBool
+		)
+		if err := rows.Scan(&teamName, &userID, &username, &active); err != nil {
+			return fmt.Errorf("scan team row: %w", err)
+		}
+		if started && teamName != current {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if !started || teamName != current {
+			current, members, started = teamName, nil, true
+		}
+		if !userID.Valid {
+			continue
+		}
+		member, err := domainuser.New(domain.UserID(userID.String), username.String, domain.TeamName(teamName), active.Bool)
+		if err != nil {
+			return fmt.Errorf("build user: %w", err)
+		}
+		members = append(members, member)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+	if started {
+		return flush()
+	}
+	return nil
+}
+
+// ExportPullRequests отдаёт PR с назначениями в порядке создания
+func (r *Repository) ExportPullRequests(ctx context.Context, fn func(transfer.PullRequest) error) error {
+	query, args, err := r.sql.Select(
+		"pr.pull_request_id", "pr.pull_request_name", "pr.author_id", "pr.status", "pr.created_at", "pr.merged_at",
+		"rv.reviewer_id", "rv.assigned_at", "rv.reviewed_at", "rv.verdict", "rv.verdict_at",
+	).From("pull_requests pr").
+		LeftJoin("pull_request_reviewers rv ON rv.pull_request_id = pr.pull_request_id").
+		OrderBy("pr.created_at", "pr.pull_request_id", "rv.slot").
+		ToSql()
+	if err != nil {
+		return err
+	}
+	rows, err := postgres.ExecutorFromContext(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("query pull requests: %w", err)
+	}
+	defer rows.Close()
+
+	type header struct {
+		id       string
+		name     string
+		author   string
+		status   string
+		created  time.Time
+		mergedAt *time.Time
+	}
+	var (
+		current     header
+		assignments []transfer.Assignment
+		started     bool
+	)
+	flush := func() error {
+		pr, err := transfer.NewPullRequest(domain.PullRequestID(current.id), current.name, domain.UserID(current.author),
+			domain.PullRequestStatus(current.status), current.created, current.mergedAt, assignments)
+		if err != nil {
+			return fmt.Errorf("build pull request %s: %w", current.id, err)
+		}
+		return fn(pr)
+	}
+	for rows.Next() {
+		var (
+			h          header
+			merged     sql.NullTime
+			reviewerID sql.NullString
+			assignedAt sql.NullTime
+			reviewedAt sql.NullTime
+			verdict    sql.NullString
+			verdictAt  sql.NullTime
+		)
+		if err := rows.Scan(&h.id, &h.name, &h.author, &h.status, &h.created, &merged,
+			&reviewerID, &assignedAt, &reviewedAt, &verdict, &verdictAt); err != nil {
+			return fmt.Errorf("scan pull request row: %w", err)
+		}
+		if started && h.id != current.id {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if !started || h.id != current.id {
+			h.mergedAt = timePtr(merged)
+			current, assignments, started = h, nil, true
+		}
+		if !reviewerID.Valid {
+			continue
+		}
+		assignments = append(assignments, transfer.Assignment{
+			ReviewerID: domain.UserID(reviewerID.String),
+			AssignedAt: assignedAt.Time,
+			ReviewedAt: timePtr(reviewedAt),
+			Verdict:    domain.ReviewVerdict(verdict.String),
+			VerdictAt:  timePtr(verdictAt),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows error: %w", err)
+	}
+	if started {
+		return flush()
+	}
+	return nil
+}
+
+func (r *Repository) TeamExists(ctx context.Context, name domain.TeamName) (bool, error) {
+	return r.exists(ctx, sq.Select("1").From("teams").Where("team_name = ?", name))
+}
+
+func (r *Repository) PullRequestExists(ctx context.Context, id domain.PullRequestID) (bool, error) {
+	return r.exists(ctx, sq.Select("1").From("pull_requests").Where("pull_request_id = ?", id))
+}
+
+// exists принимает подзапрос с ?-плейсхолдерами: нумерацию $n проставит внешний запрос
+func (r *Repository) exists(ctx context.Context, inner sq.SelectBuilder) (bool, error) {
+	query, args, err := r.sql.Select().Column(sq.Expr("EXISTS(?)", inner)).ToSql()
+	if err != nil {
+		return false, err
+	}
+	var found bool
+	if err := postgres.ExecutorFromContext(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&found); err != nil {
+		return false, fmt.Errorf("check existence: %w", err)
+	}
+	return found, nil
+}
+
+// ExistingUsers возвращает те из ids, что уже есть в базе, в любой команде
+func (r *Repository) ExistingUsers(ctx context.Context, ids []domain.UserID) ([]domain.UserID, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	query, args, err := r.sql.Select("user_id").
+		From("users").
+		Where(sq.Eq{"user_id": ids}).
+		OrderBy("user_id").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := postgres.ExecutorFromContext(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query users: %w", err)
+	}
+	defer rows.Close()
+
+	found := make([]domain.UserID, 0, len(ids))
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan user id: %w", err)
+		}
+		found = append(found, domain.UserID(id))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return found, nil
+}
+
+// UpsertTeam создаёт команду или увеличивает версию существующей и переносит в неё участников.
+// Участники, которых нет в записи, остаются в команде
+func (r *Repository) UpsertTeam(ctx context.Context, aggregate domainteam.Team) error {
+	exec := postgres.ExecutorFromContext(ctx, r.db)
+	now := time.Now().UTC()
+	query, args, err := r.sql.Insert("teams").
+		Columns("team_name", "updated_at").
+		Values(aggregate.TeamName(), now).
+		Suffix("ON CONFLICT (team_name) DO UPDATE SET updated_at = EXCLUDED.updated_at, version = teams.version + 1").
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := exec.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("upsert team: %w", err)
+	}
+
+	members := aggregate.Members()
+	if len(members) == 0 {
+		return nil
+	}
+	ids := make([]domain.UserID, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.UserID())
+	}
+	// как и в teamrepo.SaveTeam, команды, из которых уходят участники, тоже меняют версию
+	former := sq.Select("team_name").
+		From("users").
+		Where(sq.Eq{"user_id": ids}).
+		Where("team_name <> ?", aggregate.TeamName())
+	bumpQuery, bumpArgs, err := r.sql.Update("teams").
+		Set("version", sq.Expr("version + 1")).
+		Set("updated_at", now).
+		Where(sq.Expr("team_name IN (?)", former)).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := exec.ExecContext(ctx, bumpQuery, bumpArgs...); err != nil {
+		return fmt.Errorf("bump former teams: %w", err)
+	}
+
+	for _, member := range members {
+		userQuery, userArgs, err := r.sql.Insert("users").
+			Columns("user_id", "username", "team_name", "is_active", "updated_at").
+			Values(member.UserID(), member.Username(), aggregate.TeamName(), member.IsActive(), now).
+			Suffix("ON CONFLICT (user_id) DO UPDATE SET username = EXCLUDED.username, team_name = EXCLUDED.team_name, is_active = EXCLUDED.is_active, updated_at = EXCLUDED.updated_at, version = users.version + 1").
+			ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err := exec.ExecContext(ctx, userQuery, userArgs...); err != nil {
+			return fmt.Errorf("upsert user %s: %w", member.UserID(), err)
+		}
+	}
+	return nil
+}
+
+// UpsertPullRequest записывает PR как есть, с его временем создания и merge, и целиком заменяет назначения
+func (r *Repository) UpsertPullRequest(ctx context.Context, record transfer.PullRequest) error {
+	exec := postgres.ExecutorFromContext(ctx, r.db)
+	pr := record.PullRequest
+	query, args, err := r.sql.Insert("pull_requests").
+		Columns("pull_request_id", "pull_request_name", "author_id", "status", "created_at", "merged_at", "updated_at").
+		Values(pr.PullRequestID(), pr.PullRequestName(), pr.AuthorID(), pr.Status(), pr.CreatedAt(), nullTime(pr.MergedAt()), time.Now().UTC()).
+		Suffix("ON CONFLICT (pull_request_id) DO UPDATE SET pull_request_name = EXCLUDED.pull_request_name, author_id = EXCLUDED.author_id, " +
+			"status = EXCLUDED.status, created_at = EXCLUDED.created_at, merged_at = EXCLUDED.merged_at, updated_at = EXCLUDED.updated_at, " +
+			"version = pull_requests.version + 1").
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := exec.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("upsert pull request: %w", err)
+	}
+
+	delQuery, delArgs, err := r.sql.Delete("pull_request_reviewers").
+		Where("pull_request_id = ?", pr.PullRequestID()).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := exec.ExecContext(ctx, delQuery, delArgs...); err != nil {
+		return fmt.Errorf("delete reviewers: %w", err)
+	}
+	if len(record.Assignments) == 0 {
+		return nil
+	}
+	insert := r.sql.Insert("pull_request_reviewers").
+		Columns("pull_request_id", "reviewer_id", "slot", "assigned_at", "reviewed_at", "verdict", "verdict_at")
+	for i, a := range record.Assignments {
+		var verdict sql.NullString
+		if a.Verdict != "" {
+			verdict = sql.NullString{String: string(a.Verdict), Valid: true}
+		}
+		insert = insert.Values(pr.PullRequestID(), a.ReviewerID, i+1, a.AssignedAt.UTC(), nullTime(a.ReviewedAt), verdict, nullTime(a.VerdictAt))
+	}
+	insQuery, insArgs, err := insert.ToSql()
+	if err != nil {
+		return err
+	}
+	if _, err := exec.ExecContext(ctx, insQuery, insArgs...); err != nil {
+		return fmt.Errorf("insert reviewers: %w", err)
+	}
+	return nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	ts := t.Time.UTC()
+	return &ts
+}
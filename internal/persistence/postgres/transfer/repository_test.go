@@ -0,0 +1,126 @@
+package transferrepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
+	"github.com/mashhkensss/PR-service/internal/domain/transfer"
+)
+
+func TestExportTeamsGroupsMembers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"team_name", "user_id", "username", "is_active"}).
+		AddRow("backend", "u1", "Alice", true).
+		AddRow("backend", "u2", "Bob", false).
+		AddRow("empty", nil, nil, nil).
+		AddRow("frontend", "u3", "Carol", true)
+	mock.ExpectQuery(`SELECT t\.team_name, u\.user_id, u\.username, u\.is_active FROM teams t LEFT JOIN users u`).
+		WillReturnRows(rows)
+
+	var got []domainteam.Team
+	err = New(db).ExportTeams(context.Background(), func(team domainteam.Team) error {
+		got = append(got, team)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 3 || len(got[0].Members()) != 2 || len(got[1].Members()) != 0 || got[2].TeamName() != "frontend" {
+		t.Fatalf("unexpected teams %+v", got)
+	}
+}
+
+func TestExportPullRequestsCollectsAssignments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	created := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	merged := created.Add(time.Hour)
+	rows := sqlmock.NewRows([]string{"pull_request_id", "pull_request_name", "author_id", "status", "created_at", "merged_at",
+		"reviewer_id", "assigned_at", "reviewed_at", "verdict", "verdict_at"}).
+		AddRow("pr-1", "Add search", "u1", "MERGED", created, merged, "u2", created, merged, "APPROVED", merged).
+		AddRow("pr-1", "Add search", "u1", "MERGED", created, merged, "u3", created, nil, nil, nil).
+		AddRow("pr-2", "Fix login", "u2", "OPEN", created, nil, nil, nil, nil, nil, nil)
+	mock.ExpectQuery(`SELECT pr\.pull_request_id, .* FROM pull_requests pr LEFT JOIN pull_request_reviewers rv .* ORDER BY pr\.created_at, pr\.pull_request_id, rv\.slot`).
+		WillReturnRows(rows)
+
+	var got []transfer.PullRequest
+	err = New(db).ExportPullRequests(context.Background(), func(pr transfer.PullRequest) error {
+		got = append(got, pr)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || len(got[0].Assignments) != 2 || got[0].Assignments[0].Verdict != domain.ReviewVerdictApproved {
+		t.Fatalf("unexpected pull requests %+v", got)
+	}
+	if got[0].PullRequest.Status() != domain.PullRequestStatusMerged || got[1].PullRequest.Status() != domain.PullRequestStatusOpen || len(got[1].Assignments) != 0 {
+		t.Fatalf("unexpected statuses %+v", got)
+	}
+}
+
+func TestTeamExists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM teams WHERE team_name = \$1\)`).
+		WithArgs(domain.TeamName("backend")).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := New(db).TeamExists(context.Background(), "backend")
+	if err != nil || !exists {
+		t.Fatalf("expected existing team, got %v %v", exists, err)
+	}
+}
+
+func TestUpsertPullRequestReplacesAssignments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	created := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	record, err := transfer.NewPullRequest("pr-1", "Add search", "u1", domain.PullRequestStatusOpen, created, nil, []transfer.Assignment{
+		{ReviewerID: "u2", Verdict: domain.ReviewVerdictCommented},
+		{ReviewerID: "u3"},
+	})
+	if err != nil {
+		t.Fatalf("build record: %v", err)
+	}
+
+	mock.ExpectExec(`INSERT INTO pull_requests .* ON CONFLICT \(pull_request_id\) DO UPDATE SET .*version = pull_requests\.version \+ 1`).
+		WithArgs(domain.PullRequestID("pr-1"), "Add search", domain.UserID("u1"), domain.PullRequestStatusOpen, created, nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM pull_request_reviewers WHERE pull_request_id = \$1`).
+		WithArgs(domain.PullRequestID("pr-1")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO pull_request_reviewers \(pull_request_id,reviewer_id,slot,assigned_at,reviewed_at,verdict,verdict_at\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\),\(\$8,`).
+		WithArgs(domain.PullRequestID("pr-1"), domain.UserID("u2"), 1, created, nil, "COMMENTED", nil,
+			domain.PullRequestID("pr-1"), domain.UserID("u3"), 2, created, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	if err := New(db).UpsertPullRequest(context.Background(), record); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
@@ -3,10 +3,23 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/mashhkensss/PR-service/internal/service"
+	"github.com/mashhkensss/PR-service/internal/tracing"
 )
 
 type txKey struct{}
 
+// savepointKey holds the nesting depth; savepoint names are derived from it.
+type savepointKey struct{}
+
 // DBTX represents the minimal subset of *sql.DB / *sql.Tx needed for queries.
 type DBTX interface {
 	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
@@ -14,34 +27,154 @@ type DBTX interface {
 	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
 }
 
+// RetryPolicy controls how transactions aborted by a serialization failure or a deadlock are retried.
+type RetryPolicy struct {
+	// MaxAttempts includes the first attempt; 1 disables retries.
+	MaxAttempts int
+	// BaseDelay doubles on every retry up to MaxDelay; the actual pause is jittered in [delay/2, delay).
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy is used by NewTxManager.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 20 * time.Millisecond, MaxDelay: 500 * time.Millisecond}
+
 // TxManager implements service.TxRunner.
 type TxManager struct {
-	db *sql.DB
+	db    *sql.DB
+	retry RetryPolicy
 }
 
 // NewTxManager constructs TxManager for the given DB.
 func NewTxManager(db *sql.DB) *TxManager {
-	return &TxManager{db: db}
+	return &TxManager{db: db, retry: DefaultRetryPolicy}
+}
+
+// WithRetry replaces the retry policy.
+func (m *TxManager) WithRetry(p RetryPolicy) *TxManager {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 1
+	}
+	if p.MaxDelay < p.BaseDelay {
+		p.MaxDelay = p.BaseDelay
+	}
+	m.retry = p
+	return m
 }
 
-// WithinTx starts a database transaction and injects it into context.
+// WithinTx starts a database transaction with default options and injects it into context.
 func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
-	tx, err := m.db.BeginTx(ctx, nil)
+	return m.WithinTxOptions(ctx, service.TxOptions{}, fn)
+}
+
+// WithinTxOptions runs fn in a transaction and retries the whole transaction on SQLSTATE 40001/40P01.
+// A call made inside an existing transaction joins it under a savepoint: options are ignored, retries are
+// left to the outer call, and a failed call is rolled back to the savepoint so the outer transaction stays usable.
+func (m *TxManager) WithinTxOptions(ctx context.Context, opts service.TxOptions, fn func(ctx context.Context) error) error {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok && tx != nil {
+		return withinSavepoint(ctx, tx, fn)
+	}
+	for attempt := 1; ; attempt++ {
+		err := m.runTx(ctx, opts, attempt, fn)
+		if err == nil || !IsRetryable(err) || attempt >= m.retry.MaxAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(m.backoff(attempt)):
+		}
+	}
+}
+
+func (m *TxManager) runTx(ctx context.Context, opts service.TxOptions, attempt int, fn func(ctx context.Context) error) (err error) {
+	ctx, span := tracing.Start(ctx, "db.Tx",
+		attribute.Int("db.tx.attempt", attempt),
+		attribute.String("db.tx.isolation", isolationLevel(opts.Isolation).String()),
+		attribute.Bool("db.tx.read_only", opts.ReadOnly),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{Isolation: isolationLevel(opts.Isolation), ReadOnly: opts.ReadOnly})
 	if err != nil {
 		return err
 	}
 	ctx = context.WithValue(ctx, txKey{}, tx)
+	ctx, hooks := service.WithCommitHooks(ctx)
 	if err := fn(ctx); err != nil {
 		_ = tx.Rollback()
 		return err
 	}
-	return tx.Commit()
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	hooks.Run()
+	return nil
+}
+
+// withinSavepoint: after an error Postgres rejects every statement until a rollback, so without the savepoint
+// the outer caller could not even record the failure (e.g. release an idempotency key) in the same transaction.
+func withinSavepoint(ctx context.Context, tx *sql.Tx, fn func(ctx context.Context) error) error {
+	depth, _ := ctx.Value(savepointKey{}).(int)
+	depth++
+	name := "tx_nested_" + strconv.Itoa(depth)
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+	hooks, _ := service.CommitHooksFromContext(ctx)
+	mark := hooks.Mark()
+	if err := fn(context.WithValue(ctx, savepointKey{}, depth)); err != nil {
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+		hooks.Discard(mark)
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+func (m *TxManager) backoff(attempt int) time.Duration {
+	delay := m.retry.BaseDelay << (attempt - 1)
+	if delay > m.retry.MaxDelay || delay <= 0 {
+		delay = m.retry.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + rand.N(delay-half)
+}
+
+func isolationLevel(i service.Isolation) sql.IsolationLevel {
+	switch i {
+	case service.IsolationReadCommitted:
+		return sql.LevelReadCommitted
+	case service.IsolationRepeatableRead:
+		return sql.LevelRepeatableRead
+	case service.IsolationSerializable:
+		return sql.LevelSerializable
+	default:
+		return sql.LevelDefault
+	}
 }
 
-// ExecutorFromContext returns the current transaction or base DB.
+// IsRetryable reports whether err aborted the transaction because of a serialization failure or a deadlock,
+// so running the same transaction again may succeed.
+func IsRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
+
+var _ service.TxRunner = (*TxManager)(nil)
+
+// ExecutorFromContext returns the current transaction or base DB, wrapped so every call is traced.
 func ExecutorFromContext(ctx context.Context, db *sql.DB) DBTX {
 	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok && tx != nil {
-		return tx
+		return tracedExecutor{next: tx}
 	}
-	return db
+	return tracedExecutor{next: db}
 }
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/mashhkensss/PR-service/internal/service"
+)
+
+func TestWithinTxOptions_RetriesSerializationFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	m := NewTxManager(db).WithRetry(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectCommit().WillReturnError(&pgconn.PgError{Code: "40P01"})
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	calls := 0
+	err = m.WithinTxOptions(context.Background(), service.TxOptions{Isolation: service.IsolationSerializable}, func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			return fmt.Errorf("update reviewers: %w", &pgconn.PgError{Code: "40001"})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestWithinTxOptions_GivesUpAndKeepsOtherErrors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	m := NewTxManager(db).WithRetry(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+	conflict := &pgconn.PgError{Code: "40001"}
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	if err := m.WithinTx(context.Background(), func(ctx context.Context) error { return conflict }); !errors.Is(err, conflict) {
+		t.Fatalf("expected serialization failure after last attempt, got %v", err)
+	}
+
+	unique := &pgconn.PgError{Code: "23505"}
+	calls := 0
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	err = m.WithinTx(context.Background(), func(ctx context.Context) error {
+		calls++
+		return unique
+	})
+	if !errors.Is(err, unique) || calls != 1 {
+		t.Fatalf("non-retryable error must not be retried, got %v after %d calls", err, calls)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestWithinTxOptions_NestedCallJoinsOuterTxUnderSavepoint(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	m := NewTxManager(db)
+	failed := errors.New("duplicate")
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT tx_nested_1`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`RELEASE SAVEPOINT tx_nested_1`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SAVEPOINT tx_nested_1`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT tx_nested_1`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	var committed []string
+	err = m.WithinTx(context.Background(), func(ctx context.Context) error {
+		err := m.WithinTxOptions(ctx, service.ReadOnlySnapshot, func(ctx context.Context) error {
+			service.AfterCommit(ctx, func() { committed = append(committed, "kept") })
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		err = m.WithinTx(ctx, func(ctx context.Context) error {
+			service.AfterCommit(ctx, func() { committed = append(committed, "rolled back") })
+			return failed
+		})
+		if !errors.Is(err, failed) {
+			t.Errorf("nested error = %v, want %v", err, failed)
+		}
+		if len(committed) != 0 {
+			t.Errorf("hooks ran before commit: %v", committed)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(committed) != 1 || committed[0] != "kept" {
+		t.Fatalf("after commit ran %v, want only the released savepoint's hook", committed)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestBackoffIsJitteredAndCapped(t *testing.T) {
+	m := NewTxManager(nil).WithRetry(RetryPolicy{MaxAttempts: 5, BaseDelay: 10 * time.Millisecond, MaxDelay: 40 * time.Millisecond})
+	for attempt, max := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 4: 40 * time.Millisecond} {
+		for i := 0; i < 20; i++ {
+			if d := m.backoff(attempt); d < max/2 || d >= max {
+				t.Fatalf("attempt %d: backoff %v outside [%v, %v)", attempt, d, max/2, max)
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@ package userrepo
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -25,35 +26,66 @@ func New(db *sql.DB) *Repository {
 	}
 }
 
-func (r *Repository) SetUserActivity(ctx context.Context, userID domain.UserID, active bool) (domainuser.User, error) {
-	query, args, err := r.sql.Update("users").
+// SetUserActivity меняет активность, если версия пользователя совпадает с expected (AnyVersion — без проверки),
+// и увеличивает версию его команды: активность участников входит в её представление
+func (r *Repository) SetUserActivity(ctx context.Context, userID domain.UserID, active bool, expected domain.Version) (domainuser.User, error) {
+	builder := r.sql.Update("users").
 		Set("is_active", active).
 		Set("updated_at", time.Now().UTC()).
-		Where("user_id = ?", userID).
-		Suffix("RETURNING user_id, username, team_name, is_active").
+		Set("version", sq.Expr("version + 1")).
+		Where("user_id = ?", userID)
+	if expected != domain.AnyVersion {
+		builder = builder.Where("version = ?", expected)
+	}
+	query, args, err := builder.
+		Suffix("RETURNING user_id, username, team_name, is_active, version").
 		ToSql()
-	
 	if err != nil {
 		return domainuser.User{}, err
 	}
-	row := postgres.ExecutorFromContext(ctx, r.db).QueryRowContext(ctx, query, args...)
+	exec := postgres.ExecutorFromContext(ctx, r.db)
+	row := exec.QueryRowContext(ctx, query, args...)
 
 	var (
 		id       string
 		username string
 		teamName string
 		isActive bool
+		version  int64
 	)
 
-	if err := row.Scan(&id, &username, &teamName, &isActive); err != nil {
+	err = row.Scan(&id, &username, &teamName, &isActive, &version)
+	if errors.Is(err, sql.ErrNoRows) && expected != domain.AnyVersion {
+		// строки нет либо версия разошлась: различаем, чтобы не отвечать 412 на несуществующего пользователя
+		if _, getErr := r.GetUser(ctx, userID); getErr == nil {
+			return domainuser.User{}, domain.ErrVersionMismatch
+		}
+	}
+	if err != nil {
 		return domainuser.User{}, fmt.Errorf("update user activity: %w", err)
 	}
 
-	return domainuser.New(domain.UserID(id), username, domain.TeamName(teamName), isActive)
+	teamQuery, teamArgs, err := r.sql.Update("teams").
+		Set("version", sq.Expr("version + 1")).
+		Set("updated_at", time.Now().UTC()).
+		Where("team_name = ?", teamName).
+		ToSql()
+	if err != nil {
+		return domainuser.User{}, err
+	}
+	if _, err := exec.ExecContext(ctx, teamQuery, teamArgs...); err != nil {
+		return domainuser.User{}, fmt.Errorf("bump team version: %w", err)
+	}
+
+	u, err := domainuser.New(domain.UserID(id), username, domain.TeamName(teamName), isActive)
+	if err != nil {
+		return domainuser.User{}, err
+	}
+	return u.WithVersion(domain.Version(version)), nil
 }
 
 func (r *Repository) GetUser(ctx context.Context, userID domain.UserID) (domainuser.User, error) {
-	query, args, err := r.sql.Select("user_id", "username", "team_name", "is_active").
+	query, args, err := r.sql.Select("user_id", "username", "team_name", "is_active", "version").
 		From("users").
 		Where("user_id = ?", userID).
 		ToSql()
@@ -69,10 +101,15 @@ func (r *Repository) GetUser(ctx context.Context, userID domain.UserID) (domainu
 		username string
 		teamName string
 		isActive bool
+		version  int64
 	)
-	if err := row.Scan(&id, &username, &teamName, &isActive); err != nil {
+	if err := row.Scan(&id, &username, &teamName, &isActive, &version); err != nil {
 		return domainuser.User{}, fmt.Errorf("get user: %w", err)
 	}
 
-	return domainuser.New(domain.UserID(id), username, domain.TeamName(teamName), isActive)
+	u, err := domainuser.New(domain.UserID(id), username, domain.TeamName(teamName), isActive)
+	if err != nil {
+		return domainuser.User{}, err
+	}
+	return u.WithVersion(domain.Version(version)), nil
 }
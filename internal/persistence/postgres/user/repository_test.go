@@ -3,6 +3,7 @@ package userrepo
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
 
 	sqlmock "github.com/DATA-DOG/go-sqlmock"
@@ -18,19 +19,45 @@ func TestSetUserActivity(t *testing.T) {
 	defer db.Close()
 
 	repo := New(db)
-	rows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active"}).
-		AddRow("u1", "Alice", "backend", false)
-	mock.ExpectQuery(`UPDATE users SET`).
+	rows := sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "version"}).
+		AddRow("u1", "Alice", "backend", false, 3)
+	mock.ExpectQuery(`UPDATE users SET is_active = \$1, updated_at = \$2, version = version \+ 1 WHERE user_id = \$3 RETURNING`).
 		WithArgs(false, sqlmock.AnyArg(), "u1").
 		WillReturnRows(rows)
+	mock.ExpectExec(`UPDATE teams SET version = version \+ 1`).
+		WithArgs(sqlmock.AnyArg(), "backend").
+		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	user, err := repo.SetUserActivity(context.Background(), "u1", false)
+	user, err := repo.SetUserActivity(context.Background(), "u1", false, domain.AnyVersion)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if user.UserID() != domain.UserID("u1") || user.IsActive() {
+	if user.UserID() != domain.UserID("u1") || user.IsActive() || user.Version() != 3 {
 		t.Fatalf("unexpected user %+v", user)
 	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations: %v", err)
+	}
+}
+
+func TestSetUserActivityVersionMismatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(`UPDATE users SET .* WHERE user_id = \$3 AND version = \$4`).
+		WithArgs(true, sqlmock.AnyArg(), "u1", domain.Version(2)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`SELECT user_id`).
+		WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "team_name", "is_active", "version"}).
+			AddRow("u1", "Alice", "backend", false, 3))
+
+	if _, err := New(db).SetUserActivity(context.Background(), "u1", true, 2); !errors.Is(err, domain.ErrVersionMismatch) {
+		t.Fatalf("expected ErrVersionMismatch, got %v", err)
+	}
 }
 
 func TestGetUserNotFound(t *testing.T) {
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens     int
+	interval   time.Duration
+	lastRefill time.Time
+}
+
+// Memory держит бакеты в памяти процесса: подходит для одного экземпляра,
+// при нескольких репликах каждая считает лимит сама
+type Memory struct {
+	cleanupEvery time.Duration
+	buckets      map[string]*bucket
+	mu           sync.Mutex
+	nextCleanup  time.Time
+}
+
+// NewMemory раз в cleanupEvery удаляет бакеты, которые всё равно пополнились бы целиком
+func NewMemory(cleanupEvery time.Duration) *Memory {
+	if cleanupEvery <= 0 {
+		cleanupEvery = time.Minute
+	}
+	return &Memory{
+		cleanupEvery: cleanupEvery,
+		buckets:      make(map[string]*bucket),
+		nextCleanup:  time.Now().Add(cleanupEvery),
+	}
+}
+
+func (m *Memory) Take(_ context.Context, key string, limit int, interval time.Duration, now time.Time) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.After(m.nextCleanup) {
+		m.cleanup(now)
+	}
+
+	b := m.buckets[key]
+	if b == nil {
+		b = &bucket{
+			tokens:     limit,
+			interval:   interval,
+			lastRefill: now,
+		}
+		m.buckets[key] = b
+	}
+
+	if since := now.Sub(b.lastRefill); since >= interval {
+		refills := int(since / interval)
+		b.tokens += refills * limit
+		if b.tokens > limit {
+			b.tokens = limit
+		}
+		b.lastRefill = now
+	}
+
+	reset := interval - now.Sub(b.lastRefill)
+	if reset <= 0 {
+		reset = interval
+	}
+	if b.tokens <= 0 {
+		return Result{Reset: reset}, nil
+	}
+	b.tokens--
+	return Result{Allowed: true, Remaining: b.tokens, Reset: reset}, nil
+}
+
+func (m *Memory) cleanup(now time.Time) {
+	for key, b := range m.buckets {
+		if now.Sub(b.lastRefill) >= b.interval {
+			delete(m.buckets, key)
+		}
+	}
+	m.nextCleanup = now.Add(m.cleanupEvery)
+}
+
+var _ Storage = (*Memory)(nil)
@@ -0,0 +1,19 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Result — состояние бакета после попытки взять токен
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// Reset — через сколько бакет пополнится
+	Reset time.Duration
+}
+
+// Storage хранит бакеты. Take атомарно пополняет бакет, если прошёл interval, и списывает один токен
+type Storage interface {
+	Take(ctx context.Context, key string, limit int, interval time.Duration, now time.Time) (Result, error)
+}
@@ -0,0 +1,114 @@
+package apikeyservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/apikey"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+)
+
+// touchInterval — с какой точностью храним last_used_at
+const touchInterval = time.Minute
+
+type Repository interface {
+	CreateAPIKey(ctx context.Context, k apikey.Key, hash []byte) error
+	GetAPIKey(ctx context.Context, id string) (apikey.Key, []byte, error)
+	ListAPIKeys(ctx context.Context) ([]apikey.Key, error)
+	RevokeAPIKey(ctx context.Context, id string, at time.Time) error
+	TouchAPIKey(ctx context.Context, id string, at, since time.Time) error
+}
+
+type CreateParams struct {
+	Name      string
+	Role      requester.Role
+	Scopes    []requester.Scope
+	ExpiresAt *time.Time
+}
+
+type Service interface {
+	// Create выпускает ключ и возвращает его открытое значение; повторно получить его нельзя
+	Create(ctx context.Context, actor requester.Requester, p CreateParams, now time.Time) (apikey.Key, string, error)
+	List(ctx context.Context, actor requester.Requester) ([]apikey.Key, error)
+	Revoke(ctx context.Context, actor requester.Requester, id string, now time.Time) (apikey.Key, error)
+	// Authenticate находит активный ключ по предъявленному значению
+	Authenticate(ctx context.Context, plain string, now time.Time) (apikey.Key, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+func New(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) Create(ctx context.Context, actor requester.Requester, p CreateParams, now time.Time) (apikey.Key, string, error) {
+	if !actor.CanManageAPIKeys() {
+		return apikey.Key{}, "", domain.ErrPermissionDenied
+	}
+	secret, err := apikey.Generate()
+	if err != nil {
+		return apikey.Key{}, "", err
+	}
+	k, err := apikey.New(secret.ID, p.Name, p.Role, p.Scopes, now, p.ExpiresAt)
+	if err != nil {
+		return apikey.Key{}, "", err
+	}
+	if err := s.repo.CreateAPIKey(ctx, k, secret.Hash); err != nil {
+		return apikey.Key{}, "", fmt.Errorf("create api key: %w", err)
+	}
+	return k, secret.Plain, nil
+}
+
+func (s *service) List(ctx context.Context, actor requester.Requester) ([]apikey.Key, error) {
+	if !actor.CanManageAPIKeys() {
+		return nil, domain.ErrPermissionDenied
+	}
+	keys, err := s.repo.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *service) Revoke(ctx context.Context, actor requester.Requester, id string, now time.Time) (apikey.Key, error) {
+	if !actor.CanManageAPIKeys() {
+		return apikey.Key{}, domain.ErrPermissionDenied
+	}
+	if err := s.repo.RevokeAPIKey(ctx, id, now); err != nil {
+		return apikey.Key{}, fmt.Errorf("revoke api key: %w", err)
+	}
+	k, _, err := s.repo.GetAPIKey(ctx, id)
+	if err != nil {
+		return apikey.Key{}, fmt.Errorf("load api key: %w", err)
+	}
+	return k, nil
+}
+
+func (s *service) Authenticate(ctx context.Context, plain string, now time.Time) (apikey.Key, error) {
+	id, ok := apikey.Parse(plain)
+	if !ok {
+		return apikey.Key{}, domain.ErrAPIKeyRejected
+	}
+	k, hash, err := s.repo.GetAPIKey(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apikey.Key{}, domain.ErrAPIKeyRejected
+	}
+	if err != nil {
+		return apikey.Key{}, fmt.Errorf("load api key: %w", err)
+	}
+	if !apikey.Matches(plain, hash) || !k.Active(now) {
+		return apikey.Key{}, domain.ErrAPIKeyRejected
+	}
+	if err := s.repo.TouchAPIKey(ctx, id, now, now.Add(-touchInterval)); err != nil {
+		return apikey.Key{}, fmt.Errorf("touch api key: %w", err)
+	}
+	return k, nil
+}
+
+var _ Service = (*service)(nil)
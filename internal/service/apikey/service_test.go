@@ -0,0 +1,126 @@
+package apikeyservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/apikey"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+)
+
+type testKeyRepo struct {
+	keys    map[string]apikey.Key
+	hashes  map[string][]byte
+	touched int
+}
+
+func newTestKeyRepo() *testKeyRepo {
+	return &testKeyRepo{keys: map[string]apikey.Key{}, hashes: map[string][]byte{}}
+}
+
+func (r *testKeyRepo) CreateAPIKey(ctx context.Context, k apikey.Key, hash []byte) error {
+	r.keys[k.ID()] = k
+	r.hashes[k.ID()] = hash
+	return nil
+}
+
+func (r *testKeyRepo) GetAPIKey(ctx context.Context, id string) (apikey.Key, []byte, error) {
+	k, ok := r.keys[id]
+	if !ok {
+		return apikey.Key{}, nil, sql.ErrNoRows
+	}
+	return k, r.hashes[id], nil
+}
+
+func (r *testKeyRepo) ListAPIKeys(ctx context.Context) ([]apikey.Key, error) {
+	keys := make([]apikey.Key, 0, len(r.keys))
+	for _, k := range r.keys {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+func (r *testKeyRepo) RevokeAPIKey(ctx context.Context, id string, at time.Time) error {
+	k, ok := r.keys[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	r.keys[id] = k.WithUsage(k.LastUsedAt(), &at)
+	return nil
+}
+
+func (r *testKeyRepo) TouchAPIKey(ctx context.Context, id string, at, since time.Time) error {
+	r.touched++
+	return nil
+}
+
+func TestService_CreateAndAuthenticate(t *testing.T) {
+	repo := newTestKeyRepo()
+	s := New(repo)
+	admin := requester.New("root", requester.RoleAdmin)
+	now := time.Now()
+
+	k, plain, err := s.Create(context.Background(), admin, CreateParams{
+		Name:   "ci-bot",
+		Role:   requester.RoleAdmin,
+		Scopes: []requester.Scope{requester.ScopePullRequestsWrite},
+	}, now)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if string(repo.hashes[k.ID()]) == plain {
+		t.Fatalf("plain key must not be stored")
+	}
+
+	got, err := s.Authenticate(context.Background(), plain, now)
+	if err != nil || got.ID() != k.ID() {
+		t.Fatalf("authenticate: %v %+v", err, got)
+	}
+	if repo.touched != 1 {
+		t.Fatalf("expected last_used_at to be updated")
+	}
+	if _, err := s.Authenticate(context.Background(), plain+"x", now); !errors.Is(err, domain.ErrAPIKeyRejected) {
+		t.Fatalf("expected tampered key to be rejected, got %v", err)
+	}
+
+	if _, err := s.Revoke(context.Background(), admin, k.ID(), now); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, err := s.Authenticate(context.Background(), plain, now); !errors.Is(err, domain.ErrAPIKeyRejected) {
+		t.Fatalf("expected revoked key to be rejected, got %v", err)
+	}
+}
+
+func TestService_ExpiredKeyRejected(t *testing.T) {
+	s := New(newTestKeyRepo())
+	now := time.Now()
+	expires := now.Add(time.Hour)
+	_, plain, err := s.Create(context.Background(), requester.System(), CreateParams{
+		Name:      "nightly",
+		Role:      requester.RoleUser,
+		Scopes:    []requester.Scope{requester.ScopeTeamsRead},
+		ExpiresAt: &expires,
+	}, now)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := s.Authenticate(context.Background(), plain, expires.Add(time.Second)); !errors.Is(err, domain.ErrAPIKeyRejected) {
+		t.Fatalf("expected expired key to be rejected, got %v", err)
+	}
+}
+
+func TestService_ManageRequiresAdmin(t *testing.T) {
+	s := New(newTestKeyRepo())
+	lead := requester.New("lead", requester.RoleTeamLead)
+	params := CreateParams{Name: "bot", Role: requester.RoleUser, Scopes: []requester.Scope{requester.ScopeTeamsRead}}
+	if _, _, err := s.Create(context.Background(), lead, params, time.Now()); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Fatalf("expected ErrPermissionDenied, got %v", err)
+	}
+	if _, err := s.List(context.Background(), lead); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Fatalf("expected ErrPermissionDenied, got %v", err)
+	}
+}
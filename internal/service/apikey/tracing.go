@@ -0,0 +1,51 @@
+package apikeyservice
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/mashhkensss/PR-service/internal/domain/apikey"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/tracing"
+)
+
+type tracedService struct {
+	next Service
+}
+
+// WithTracing оборачивает каждый метод сервиса в спан
+func WithTracing(next Service) Service {
+	return tracedService{next: next}
+}
+
+func (t tracedService) Create(ctx context.Context, actor requester.Requester, p CreateParams, now time.Time) (apikey.Key, string, error) {
+	ctx, span := tracing.Start(ctx, "apikey.Create", attribute.String("name", p.Name))
+	k, plain, err := t.next.Create(ctx, actor, p, now)
+	tracing.End(span, err)
+	return k, plain, err
+}
+
+func (t tracedService) List(ctx context.Context, actor requester.Requester) ([]apikey.Key, error) {
+	ctx, span := tracing.Start(ctx, "apikey.List")
+	res, err := t.next.List(ctx, actor)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) Revoke(ctx context.Context, actor requester.Requester, id string, now time.Time) (apikey.Key, error) {
+	ctx, span := tracing.Start(ctx, "apikey.Revoke", attribute.String("key_id", id))
+	res, err := t.next.Revoke(ctx, actor, id, now)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) Authenticate(ctx context.Context, plain string, now time.Time) (apikey.Key, error) {
+	ctx, span := tracing.Start(ctx, "apikey.Authenticate")
+	res, err := t.next.Authenticate(ctx, plain, now)
+	tracing.End(span, err)
+	return res, err
+}
+
+var _ Service = tracedService{}
@@ -0,0 +1,29 @@
+package assignment
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	domainuser "github.com/mashhkensss/PR-service/internal/domain/user"
+	"github.com/mashhkensss/PR-service/internal/tracing"
+)
+
+type tracedStrategy struct {
+	next Strategy
+}
+
+// WithTracing оборачивает Pick в спан
+func WithTracing(next Strategy) Strategy {
+	return tracedStrategy{next: next}
+}
+
+func (t tracedStrategy) Pick(ctx context.Context, candidates []domainuser.User, limit int) ([]domainuser.User, error) {
+	ctx, span := tracing.Start(ctx, "assignment.Pick",
+		attribute.Int("candidates", len(candidates)),
+		attribute.Int("limit", limit),
+	)
+	res, err := t.next.Pick(ctx, candidates, limit)
+	tracing.End(span, err)
+	return res, err
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+
+	"github.com/mashhkensss/PR-service/internal/domain/event"
+)
+
+// EventStore сохраняет доменные события в outbox. Вызывается внутри транзакции,
+// изменившей агрегат, поэтому событие фиксируется тогда и только тогда, когда фиксируется изменение
+type EventStore interface {
+	Append(ctx context.Context, events ...event.Event) error
+}
+
+// AppendEvents сохраняет события, если хранилище настроено
+func AppendEvents(ctx context.Context, store EventStore, events []event.Event) error {
+	if store == nil || len(events) == 0 {
+		return nil
+	}
+	return store.Append(ctx, events...)
+}
+
+// EventStores раздаёт события нескольким хранилищам в одной транзакции; nil-элементы пропускаются
+type EventStores []EventStore
+
+func (s EventStores) Append(ctx context.Context, events ...event.Event) error {
+	for _, store := range s {
+		if err := AppendEvents(ctx, store, events); err != nil {
+			return err
+		}
+	}
+	return nil
+}
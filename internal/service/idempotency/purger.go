@@ -0,0 +1,51 @@
+package idempotencyservice
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Purger периодически удаляет истёкшие ключи идемпотентности. Удаление идёт пачками,
+// чтобы не держать долгую блокировку на таблице
+type Purger struct {
+	svc      Service
+	interval time.Duration
+	batch    int
+	logger   *slog.Logger
+}
+
+func NewPurger(svc Service, interval time.Duration, batch int, logger *slog.Logger) *Purger {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Purger{svc: svc, interval: interval, batch: batch, logger: logger}
+}
+
+// Run блокируется до отмены ctx
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			p.tick(ctx)
+		}
+	}
+}
+
+func (p *Purger) tick(ctx context.Context) {
+	n, err := p.svc.Purge(ctx, time.Now().UTC(), p.batch)
+	if p.logger == nil {
+		return
+	}
+	if err != nil && ctx.Err() == nil {
+		p.logger.Error("idempotency purge failed", "error", err, "purged", n)
+		return
+	}
+	if n > 0 {
+		p.logger.Info("idempotency keys purged", "purged", n)
+	}
+}
@@ -0,0 +1,88 @@
+package idempotencyservice
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/idempotency"
+)
+
+type Repository interface {
+	ListRecords(ctx context.Context, subject, value string) ([]idempotency.Record, error)
+	DeleteRecords(ctx context.Context, subject, value string) (int64, error)
+	PurgeExpired(ctx context.Context, now time.Time, limit int) (int64, error)
+}
+
+type Service interface {
+	// Inspect возвращает записи ключа субъекта по всем эндпоинтам
+	Inspect(ctx context.Context, actor requester.Requester, subject, key string) ([]idempotency.Record, error)
+	// Delete снимает ключ, например чтобы клиент мог повторить запрос, не дожидаясь TTL
+	Delete(ctx context.Context, actor requester.Requester, subject, key string) (int64, error)
+	// Purge удаляет истёкшие записи пачками по batch, пока они не закончатся
+	Purge(ctx context.Context, now time.Time, batch int) (int64, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+func New(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+func (s *service) Inspect(ctx context.Context, actor requester.Requester, subject, key string) ([]idempotency.Record, error) {
+	if !actor.CanManageIdempotency() {
+		return nil, domain.ErrPermissionDenied
+	}
+	if key == "" {
+		return nil, domain.ErrInvalidIdentifier
+	}
+	records, err := s.repo.ListRecords(ctx, subject, key)
+	if err != nil {
+		return nil, fmt.Errorf("list idempotency records: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return records, nil
+}
+
+func (s *service) Delete(ctx context.Context, actor requester.Requester, subject, key string) (int64, error) {
+	if !actor.CanManageIdempotency() {
+		return 0, domain.ErrPermissionDenied
+	}
+	if key == "" {
+		return 0, domain.ErrInvalidIdentifier
+	}
+	n, err := s.repo.DeleteRecords(ctx, subject, key)
+	if err != nil {
+		return 0, fmt.Errorf("delete idempotency records: %w", err)
+	}
+	if n == 0 {
+		return 0, sql.ErrNoRows
+	}
+	return n, nil
+}
+
+func (s *service) Purge(ctx context.Context, now time.Time, batch int) (int64, error) {
+	if batch <= 0 {
+		batch = 1000
+	}
+	var total int64
+	for {
+		n, err := s.repo.PurgeExpired(ctx, now, batch)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("purge idempotency records: %w", err)
+		}
+		if n < int64(batch) || ctx.Err() != nil {
+			return total, nil
+		}
+	}
+}
+
+var _ Service = (*service)(nil)
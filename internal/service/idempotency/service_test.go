@@ -0,0 +1,77 @@
+package idempotencyservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/idempotency"
+)
+
+type testRepo struct {
+	expired int64
+	batches []int64
+	records []idempotency.Record
+}
+
+func (r *testRepo) ListRecords(ctx context.Context, subject, value string) ([]idempotency.Record, error) {
+	var res []idempotency.Record
+	for _, rec := range r.records {
+		if rec.Key.Subject == subject && rec.Key.Value == value {
+			res = append(res, rec)
+		}
+	}
+	return res, nil
+}
+
+func (r *testRepo) DeleteRecords(ctx context.Context, subject, value string) (int64, error) {
+	recs, _ := r.ListRecords(ctx, subject, value)
+	return int64(len(recs)), nil
+}
+
+func (r *testRepo) PurgeExpired(ctx context.Context, now time.Time, limit int) (int64, error) {
+	n := min(r.expired, int64(limit))
+	r.expired -= n
+	r.batches = append(r.batches, n)
+	return n, nil
+}
+
+func TestService_PurgeRunsBatchesUntilDrained(t *testing.T) {
+	repo := &testRepo{expired: 25}
+	n, err := New(repo).Purge(context.Background(), time.Now(), 10)
+	if err != nil || n != 25 {
+		t.Fatalf("expected 25 purged, got %d err=%v", n, err)
+	}
+	if len(repo.batches) != 3 {
+		t.Fatalf("expected 3 batches, got %v", repo.batches)
+	}
+}
+
+func TestService_InspectAndDelete(t *testing.T) {
+	repo := &testRepo{records: []idempotency.Record{
+		{Key: idempotency.Key{Subject: "u1", Method: "POST", Path: "/team/add", Value: "k"}, Status: 201},
+	}}
+	svc := New(repo)
+	admin := requester.New("admin", requester.RoleAdmin)
+
+	if _, err := svc.Inspect(context.Background(), requester.New("u1", requester.RoleUser), "u1", "k"); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Fatalf("expected ErrPermissionDenied, got %v", err)
+	}
+	records, err := svc.Inspect(context.Background(), admin, "u1", "k")
+	if err != nil || len(records) != 1 {
+		t.Fatalf("unexpected records %+v err=%v", records, err)
+	}
+	if _, err := svc.Inspect(context.Background(), admin, "u2", "k"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("key of another subject must not be found, got %v", err)
+	}
+	if _, err := svc.Delete(context.Background(), admin, "u1", ""); !errors.Is(err, domain.ErrInvalidIdentifier) {
+		t.Fatalf("expected ErrInvalidIdentifier, got %v", err)
+	}
+	if n, err := svc.Delete(context.Background(), admin, "u1", "k"); err != nil || n != 1 {
+		t.Fatalf("expected 1 deleted, got %d err=%v", n, err)
+	}
+}
@@ -0,0 +1,44 @@
+package idempotencyservice
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/idempotency"
+	"github.com/mashhkensss/PR-service/internal/tracing"
+)
+
+type tracedService struct {
+	next Service
+}
+
+// WithTracing оборачивает каждый метод сервиса в спан
+func WithTracing(next Service) Service {
+	return tracedService{next: next}
+}
+
+func (t tracedService) Inspect(ctx context.Context, actor requester.Requester, subject, key string) ([]idempotency.Record, error) {
+	ctx, span := tracing.Start(ctx, "idempotency.Inspect", attribute.String("subject", subject))
+	res, err := t.next.Inspect(ctx, actor, subject, key)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) Delete(ctx context.Context, actor requester.Requester, subject, key string) (int64, error) {
+	ctx, span := tracing.Start(ctx, "idempotency.Delete", attribute.String("subject", subject))
+	res, err := t.next.Delete(ctx, actor, subject, key)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) Purge(ctx context.Context, now time.Time, batch int) (int64, error) {
+	ctx, span := tracing.Start(ctx, "idempotency.Purge", attribute.Int("batch", batch))
+	res, err := t.next.Purge(ctx, now, batch)
+	tracing.End(span, err)
+	return res, err
+}
+
+var _ Service = tracedService{}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/mashhkensss/PR-service/internal/domain"
 	"github.com/mashhkensss/PR-service/internal/domain/pullrequest"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
 	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
 	"github.com/mashhkensss/PR-service/internal/domain/user"
 	"github.com/mashhkensss/PR-service/internal/service"
@@ -26,20 +27,46 @@ type PullRequestRepository interface {
 	CreatePullRequest(ctx context.Context, pr pullrequest.PullRequest) error
 	GetPullRequestForUpdate(ctx context.Context, id domain.PullRequestID) (pullrequest.PullRequest, error)
 	UpdatePullRequest(ctx context.Context, pr pullrequest.PullRequest) error
+	MarkReviewed(ctx context.Context, id domain.PullRequestID, reviewerID domain.UserID, verdict domain.ReviewVerdict, at time.Time) error
+	RecordReassignment(ctx context.Context, id domain.PullRequestID, oldReviewer, newReviewer domain.UserID, at time.Time) error
 }
 
+// Observer получает доменные события для метрик. Внутри внешней транзакции (атомарный batch)
+// события приходят только после её фиксации
+type Observer interface {
+	PullRequestCreated(reviewers, maxReviewers int)
+	PullRequestMerged()
+	ReviewerReassigned()
+}
+
+type noopObserver struct{}
+
+func (noopObserver) PullRequestCreated(int, int) {}
+func (noopObserver) PullRequestMerged()          {}
+func (noopObserver) ReviewerReassigned()         {}
+
 type Service interface {
-	Create(ctx context.Context, pr pullrequest.PullRequest) (pullrequest.PullRequest, error)
-	Merge(ctx context.Context, id domain.PullRequestID, mergedAt time.Time) (pullrequest.PullRequest, error)
-	Reassign(ctx context.Context, prID domain.PullRequestID, oldReviewer domain.UserID) (pullrequest.PullRequest, domain.UserID, error)
+	Create(ctx context.Context, actor requester.Requester, pr pullrequest.PullRequest) (pullrequest.PullRequest, error)
+	// Merge, Reassign и SubmitReview проверяют версию PR под блокировкой строки; AnyVersion отключает проверку
+	Merge(ctx context.Context, actor requester.Requester, id domain.PullRequestID, mergedAt time.Time, expected domain.Version) (pullrequest.PullRequest, error)
+	Reassign(ctx context.Context, actor requester.Requester, prID domain.PullRequestID, oldReviewer domain.UserID, expected domain.Version) (pullrequest.PullRequest, domain.UserID, error)
+	// AddReviewer вызывается только эскалацией SLA, поэтому без субъекта
+	AddReviewer(ctx context.Context, prID domain.PullRequestID) (pullrequest.PullRequest, domain.UserID, error)
+	SubmitReview(ctx context.Context, actor requester.Requester, prID domain.PullRequestID, reviewer domain.UserID, verdict domain.ReviewVerdict, reviewedAt time.Time, expected domain.Version) (pullrequest.PullRequest, error)
 }
 
+// reviewerWrite — для операций, выбирающих ревьюверов по составу команды: параллельные назначения
+// на пересекающиеся команды должны упасть конфликтом сериализации и повториться, а не разойтись
+var reviewerWrite = service.TxOptions{Isolation: service.IsolationSerializable}
+
 type svc struct {
 	teams    TeamRepository
 	users    UserRepository
 	prs      PullRequestRepository
 	tx       service.TxRunner
 	assigner assignment.Strategy
+	observer Observer
+	events   service.EventStore
 }
 
 func New(
@@ -48,6 +75,8 @@ func New(
 	prs PullRequestRepository,
 	tx service.TxRunner,
 	assigner assignment.Strategy,
+	observer Observer,
+	events service.EventStore,
 ) Service {
 	if assigner == nil {
 		assigner = assignment.NewStrategy(nil)
@@ -58,15 +87,30 @@ func New(
 		prs:      prs,
 		tx:       tx,
 		assigner: assigner,
+		observer: observer,
+		events:   events,
+	}
+}
+
+func (s *svc) observe() Observer {
+	if s.observer == nil {
+		return noopObserver{}
 	}
+	return s.observer
 }
 
-func (s *svc) Create(ctx context.Context, pr pullrequest.PullRequest) (pullrequest.PullRequest, error) {
+func (s *svc) Create(ctx context.Context, actor requester.Requester, pr pullrequest.PullRequest) (pullrequest.PullRequest, error) {
 	if s.assigner == nil {
 		return pullrequest.PullRequest{}, fmt.Errorf("assignment strategy is not configured")
 	}
+	if !actor.CanCreatePullRequest(pr.AuthorID()) {
+		return pullrequest.PullRequest{}, domain.ErrPermissionDenied
+	}
 
-	err := service.ExecInTx(ctx, s.tx, func(ctx context.Context) error {
+	var created pullrequest.PullRequest
+	err := service.ExecInTxWith(ctx, s.tx, reviewerWrite, func(ctx context.Context) error {
+		// транзакция может повториться: каждая попытка начинает с исходного PR, чтобы не копить события прошлых попыток
+		attempt := pr.WithVersion(pr.Version())
 		author, err := s.users.GetUser(ctx, pr.AuthorID())
 
 		if err != nil {
@@ -95,20 +139,33 @@ func (s *svc) Create(ctx context.Context, pr pullrequest.PullRequest) (pullreque
 			reviewerIDs = append(reviewerIDs, candidate.UserID())
 		}
 
-		if err := pr.AssignReviewers(reviewerIDs); err != nil {
+		if err := attempt.AssignReviewers(reviewerIDs); err != nil {
 			return fmt.Errorf("assign reviewers: %w", err)
 		}
 
-		return s.prs.CreatePullRequest(ctx, pr)
+		if err := s.prs.CreatePullRequest(ctx, attempt); err != nil {
+			return err
+		}
+
+		if err := s.appendEvents(ctx, attempt); err != nil {
+			return err
+		}
+
+		created = attempt
+		return nil
 	})
 	if err != nil {
 		return pullrequest.PullRequest{}, err
 	}
 
-	return pr, nil
+	service.AfterCommit(ctx, func() {
+		s.observe().PullRequestCreated(len(created.AssignedReviewers()), pullrequest.MaxReviewersPerPullRequest)
+	})
+	return created, nil
 }
 
-func (s *svc) Merge(ctx context.Context, id domain.PullRequestID, mergedAt time.Time) (pullrequest.PullRequest, error) {
+func (s *svc) Merge(ctx context.Context, actor requester.Requester, id domain.PullRequestID, mergedAt time.Time, expected domain.Version) (pullrequest.PullRequest, error) {
+	merged := false
 	pr, err := service.RunInTx(ctx, s.tx, func(ctx context.Context) (pullrequest.PullRequest, error) {
 		existing, err := s.prs.GetPullRequestForUpdate(ctx, id)
 
@@ -116,6 +173,14 @@ func (s *svc) Merge(ctx context.Context, id domain.PullRequestID, mergedAt time.
 			return pullrequest.PullRequest{}, err
 		}
 
+		if err := s.authorizeManage(ctx, actor, existing); err != nil {
+			return pullrequest.PullRequest{}, err
+		}
+
+		if err := expected.Check(existing.Version()); err != nil {
+			return pullrequest.PullRequest{}, err
+		}
+
 		if !existing.Merge(mergedAt) {
 			return existing, nil
 		}
@@ -124,16 +189,24 @@ func (s *svc) Merge(ctx context.Context, id domain.PullRequestID, mergedAt time.
 			return pullrequest.PullRequest{}, err
 		}
 
-		return existing, nil
+		if err := s.appendEvents(ctx, existing); err != nil {
+			return pullrequest.PullRequest{}, err
+		}
+
+		merged = true
+		return existing.WithVersion(existing.Version().Next()), nil
 	})
 	if err != nil {
 		return pullrequest.PullRequest{}, fmt.Errorf("merge pull request: %w", err)
 	}
 
+	if merged {
+		service.AfterCommit(ctx, s.observe().PullRequestMerged)
+	}
 	return pr, nil
 }
 
-func (s *svc) Reassign(ctx context.Context, prID domain.PullRequestID, oldReviewer domain.UserID) (pullrequest.PullRequest, domain.UserID, error) {
+func (s *svc) Reassign(ctx context.Context, actor requester.Requester, prID domain.PullRequestID, oldReviewer domain.UserID, expected domain.Version) (pullrequest.PullRequest, domain.UserID, error) {
 	if s.assigner == nil {
 		return pullrequest.PullRequest{}, "", fmt.Errorf("assignment strategy is not configured")
 	}
@@ -143,13 +216,21 @@ func (s *svc) Reassign(ctx context.Context, prID domain.PullRequestID, oldReview
 		newR domain.UserID
 	}
 
-	out, err := service.RunInTx(ctx, s.tx, func(ctx context.Context) (result, error) {
+	out, err := service.RunInTxWith(ctx, s.tx, reviewerWrite, func(ctx context.Context) (result, error) {
 		pr, err := s.prs.GetPullRequestForUpdate(ctx, prID)
 
 		if err != nil {
 			return result{}, err
 		}
 
+		if err := s.authorizeManage(ctx, actor, pr); err != nil {
+			return result{}, err
+		}
+
+		if err := expected.Check(pr.Version()); err != nil {
+			return result{}, err
+		}
+
 		if pr.Status() == domain.PullRequestStatusMerged {
 			return result{}, domain.ErrPullRequestAlreadyMerged
 		}
@@ -187,7 +268,82 @@ func (s *svc) Reassign(ctx context.Context, prID domain.PullRequestID, oldReview
 			return result{}, err
 		}
 
-		return result{pr: pr, newR: newReviewer}, nil
+		if err := s.prs.RecordReassignment(ctx, prID, oldReviewer, newReviewer, time.Now().UTC()); err != nil {
+			return result{}, err
+		}
+
+		if err := s.appendEvents(ctx, pr); err != nil {
+			return result{}, err
+		}
+
+		return result{pr: pr.WithVersion(pr.Version().Next()), newR: newReviewer}, nil
+	})
+	if err != nil {
+		return pullrequest.PullRequest{}, "", err
+	}
+	service.AfterCommit(ctx, s.observe().ReviewerReassigned)
+	return out.pr, out.newR, nil
+}
+
+// AddReviewer добирает ещё одного ревьювера из команды автора, если на PR есть свободный слот
+func (s *svc) AddReviewer(ctx context.Context, prID domain.PullRequestID) (pullrequest.PullRequest, domain.UserID, error) {
+	if s.assigner == nil {
+		return pullrequest.PullRequest{}, "", fmt.Errorf("assignment strategy is not configured")
+	}
+
+	type result struct {
+		pr   pullrequest.PullRequest
+		newR domain.UserID
+	}
+
+	out, err := service.RunInTxWith(ctx, s.tx, reviewerWrite, func(ctx context.Context) (result, error) {
+		pr, err := s.prs.GetPullRequestForUpdate(ctx, prID)
+		if err != nil {
+			return result{}, err
+		}
+
+		if pr.Status() == domain.PullRequestStatusMerged {
+			return result{}, domain.ErrPullRequestAlreadyMerged
+		}
+
+		if len(pr.AssignedReviewers()) >= pullrequest.MaxReviewersPerPullRequest {
+			return result{}, domain.ErrReviewerLimitExceeded
+		}
+
+		author, err := s.users.GetUser(ctx, pr.AuthorID())
+		if err != nil {
+			return result{}, fmt.Errorf("load author: %w", err)
+		}
+
+		authorTeam, err := s.teams.GetTeam(ctx, author.TeamName())
+		if err != nil {
+			return result{}, fmt.Errorf("load author team: %w", err)
+		}
+
+		candidates := filterCandidates(pr, authorTeam.ActiveMembers(author.UserID()))
+		selected, err := s.assigner.Pick(ctx, candidates, 1)
+		if err != nil {
+			return result{}, fmt.Errorf("pick reviewer: %w", err)
+		}
+
+		if len(selected) == 0 {
+			return result{}, domain.ErrNoActiveCandidate
+		}
+
+		newReviewer := selected[0].UserID()
+		if err := pr.AppendReviewer(newReviewer); err != nil {
+			return result{}, err
+		}
+
+		if err := s.prs.UpdatePullRequest(ctx, pr); err != nil {
+			return result{}, err
+		}
+
+		if err := s.appendEvents(ctx, pr); err != nil {
+			return result{}, err
+		}
+
+		return result{pr: pr.WithVersion(pr.Version().Next()), newR: newReviewer}, nil
 	})
 	if err != nil {
 		return pullrequest.PullRequest{}, "", err
@@ -195,6 +351,71 @@ func (s *svc) Reassign(ctx context.Context, prID domain.PullRequestID, oldReview
 	return out.pr, out.newR, nil
 }
 
+// SubmitReview сохраняет вердикт ревьювера; первая реакция фиксируется отдельно и используется для SLA
+func (s *svc) SubmitReview(ctx context.Context, actor requester.Requester, prID domain.PullRequestID, reviewer domain.UserID, verdict domain.ReviewVerdict, reviewedAt time.Time, expected domain.Version) (pullrequest.PullRequest, error) {
+	if err := domain.ValidateReviewVerdict(verdict); err != nil {
+		return pullrequest.PullRequest{}, err
+	}
+	if !actor.CanReview(reviewer) {
+		return pullrequest.PullRequest{}, domain.ErrPermissionDenied
+	}
+	if reviewedAt.IsZero() {
+		reviewedAt = time.Now().UTC()
+	}
+
+	return service.RunInTx(ctx, s.tx, func(ctx context.Context) (pullrequest.PullRequest, error) {
+		pr, err := s.prs.GetPullRequestForUpdate(ctx, prID)
+		if err != nil {
+			return pullrequest.PullRequest{}, err
+		}
+
+		if err := expected.Check(pr.Version()); err != nil {
+			return pullrequest.PullRequest{}, err
+		}
+
+		if pr.Status() == domain.PullRequestStatusMerged {
+			return pullrequest.PullRequest{}, domain.ErrPullRequestAlreadyMerged
+		}
+
+		if !slices.Contains(pr.AssignedReviewers(), reviewer) {
+			return pullrequest.PullRequest{}, domain.ErrReviewerNotAssigned
+		}
+
+		if err := s.prs.MarkReviewed(ctx, prID, reviewer, verdict, reviewedAt); err != nil {
+			return pullrequest.PullRequest{}, err
+		}
+
+		return pr.WithVersion(pr.Version().Next()), nil
+	})
+}
+
+// appendEvents пишет накопленные агрегатом события в outbox текущей транзакции
+func (s *svc) appendEvents(ctx context.Context, pr pullrequest.PullRequest) error {
+	if err := service.AppendEvents(ctx, s.events, pr.Events()); err != nil {
+		return fmt.Errorf("append events: %w", err)
+	}
+	return nil
+}
+
+// authorizeManage пропускает администратора и автора без запросов, иначе решает по команде автора
+func (s *svc) authorizeManage(ctx context.Context, actor requester.Requester, pr pullrequest.PullRequest) error {
+	if actor.CanManagePullRequest(pr, domainteam.Team{}) {
+		return nil
+	}
+	author, err := s.users.GetUser(ctx, pr.AuthorID())
+	if err != nil {
+		return fmt.Errorf("load author: %w", err)
+	}
+	authorTeam, err := s.teams.GetTeam(ctx, author.TeamName())
+	if err != nil {
+		return fmt.Errorf("load author team: %w", err)
+	}
+	if !actor.CanManagePullRequest(pr, authorTeam) {
+		return domain.ErrPermissionDenied
+	}
+	return nil
+}
+
 func filterCandidates(pr pullrequest.PullRequest, candidates []user.User) []user.User {
 	if len(candidates) == 0 {
 		return candidates
@@ -7,9 +7,12 @@ import (
 	"time"
 
 	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/event"
 	"github.com/mashhkensss/PR-service/internal/domain/pullrequest"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
 	"github.com/mashhkensss/PR-service/internal/domain/team"
 	"github.com/mashhkensss/PR-service/internal/domain/user"
+	"github.com/mashhkensss/PR-service/internal/service"
 )
 
 type testTeamRepo struct {
@@ -55,6 +58,8 @@ type testPRRepo struct {
 	getFn    func(ctx context.Context, id domain.PullRequestID) (pullrequest.PullRequest, error)
 	updateFn func(ctx context.Context, pr pullrequest.PullRequest) error
 	listFn   func(ctx context.Context, reviewer domain.UserID) ([]pullrequest.PullRequest, error)
+	markFn   func(ctx context.Context, id domain.PullRequestID, reviewer domain.UserID, verdict domain.ReviewVerdict, at time.Time) error
+	recordFn func(ctx context.Context, id domain.PullRequestID, oldReviewer, newReviewer domain.UserID, at time.Time) error
 }
 
 func (r testPRRepo) CreatePullRequest(ctx context.Context, pr pullrequest.PullRequest) error {
@@ -82,6 +87,20 @@ func (r testPRRepo) UpdatePullRequest(ctx context.Context, pr pullrequest.PullRe
 	return nil
 }
 
+func (r testPRRepo) MarkReviewed(ctx context.Context, id domain.PullRequestID, reviewer domain.UserID, verdict domain.ReviewVerdict, at time.Time) error {
+	if r.markFn != nil {
+		return r.markFn(ctx, id, reviewer, verdict, at)
+	}
+	return nil
+}
+
+func (r testPRRepo) RecordReassignment(ctx context.Context, id domain.PullRequestID, oldReviewer, newReviewer domain.UserID, at time.Time) error {
+	if r.recordFn != nil {
+		return r.recordFn(ctx, id, oldReviewer, newReviewer, at)
+	}
+	return nil
+}
+
 func (r testPRRepo) ListPullRequestsByReviewer(ctx context.Context, reviewer domain.UserID) ([]pullrequest.PullRequest, error) {
 	if r.listFn != nil {
 		return r.listFn(ctx, reviewer)
@@ -109,6 +128,18 @@ func makeUser(t *testing.T, id domain.UserID, teamName domain.TeamName, active b
 	return u
 }
 
+type countingObserver struct {
+	created, emptySlots, merged, reassigned int
+}
+
+func (o *countingObserver) PullRequestCreated(reviewers, maxReviewers int) {
+	o.created++
+	o.emptySlots += maxReviewers - reviewers
+}
+
+func (o *countingObserver) PullRequestMerged()  { o.merged++ }
+func (o *countingObserver) ReviewerReassigned() { o.reassigned++ }
+
 func TestService_CreateAssignsReviewers(t *testing.T) {
 	author := makeUser(t, "author", "backend", true)
 	reviewer1 := makeUser(t, "rev1", "backend", true)
@@ -147,7 +178,7 @@ func TestService_CreateAssignsReviewers(t *testing.T) {
 	}
 
 	pr, _ := pullrequest.New("pr-1", "Feature", "author", time.Now())
-	result, err := service.Create(context.Background(), pr)
+	result, err := service.Create(context.Background(), requester.System(), pr)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -172,12 +203,14 @@ func TestService_Merge(t *testing.T) {
 		},
 	}
 	stored = pr
+	observer := &countingObserver{}
 
 	s := &svc{
-		prs: prRepo,
+		prs:      prRepo,
+		observer: observer,
 	}
 
-	result, err := s.Merge(context.Background(), "pr-1", time.Now())
+	result, err := s.Merge(context.Background(), requester.System(), "pr-1", time.Now(), domain.AnyVersion)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -187,6 +220,39 @@ func TestService_Merge(t *testing.T) {
 	if stored.Status() != domain.PullRequestStatusMerged {
 		t.Fatalf("stored PR should be merged")
 	}
+
+	if _, err := s.Merge(context.Background(), requester.System(), "pr-1", time.Now(), domain.AnyVersion); err != nil {
+		t.Fatalf("repeated merge must be idempotent: %v", err)
+	}
+	if observer.merged != 1 {
+		t.Fatalf("repeated merge must not be counted, got %d", observer.merged)
+	}
+}
+
+func TestService_MergeChecksVersion(t *testing.T) {
+	pr, _ := pullrequest.New("pr-1", "Feature", "author", time.Now())
+	pr = pr.WithVersion(3)
+	updates := 0
+	s := &svc{
+		prs: testPRRepo{
+			getFn: func(context.Context, domain.PullRequestID) (pullrequest.PullRequest, error) { return pr, nil },
+			updateFn: func(context.Context, pullrequest.PullRequest) error {
+				updates++
+				return nil
+			},
+		},
+	}
+
+	if _, err := s.Merge(context.Background(), requester.System(), "pr-1", time.Now(), 2); !errors.Is(err, domain.ErrVersionMismatch) || updates != 0 {
+		t.Fatalf("expected ErrVersionMismatch without a write, got %v after %d updates", err, updates)
+	}
+	merged, err := s.Merge(context.Background(), requester.System(), "pr-1", time.Now(), 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if merged.Version() != 4 {
+		t.Fatalf("merged PR must carry the bumped version, got %d", merged.Version())
+	}
 }
 
 func TestService_Reassign(t *testing.T) {
@@ -196,6 +262,7 @@ func TestService_Reassign(t *testing.T) {
 
 	oldReviewer := makeUser(t, "rev1", "backend", true)
 	newReviewer := makeUser(t, "rev3", "backend", true)
+	var recorded []domain.UserID
 
 	s := &svc{
 		prs: testPRRepo{
@@ -206,6 +273,10 @@ func TestService_Reassign(t *testing.T) {
 				stored = pr
 				return nil
 			},
+			recordFn: func(ctx context.Context, id domain.PullRequestID, oldReviewer, newReviewer domain.UserID, at time.Time) error {
+				recorded = append(recorded, oldReviewer, newReviewer)
+				return nil
+			},
 		},
 		users: testUserRepo{
 			getFn: func(ctx context.Context, userID domain.UserID) (user.User, error) {
@@ -224,7 +295,7 @@ func TestService_Reassign(t *testing.T) {
 		},
 	}
 
-	updated, replacement, err := s.Reassign(context.Background(), "pr-1", "rev1")
+	updated, replacement, err := s.Reassign(context.Background(), requester.System(), "pr-1", "rev1", domain.AnyVersion)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -234,6 +305,9 @@ func TestService_Reassign(t *testing.T) {
 	if updated.AssignedReviewers()[0] != "rev3" {
 		t.Fatalf("expected reviewer replaced in PR")
 	}
+	if len(recorded) != 2 || recorded[0] != "rev1" || recorded[1] != "rev3" {
+		t.Fatalf("expected reassignment to be recorded, got %v", recorded)
+	}
 }
 
 func TestService_Reassign_NoCandidates(t *testing.T) {
@@ -266,7 +340,7 @@ func TestService_Reassign_NoCandidates(t *testing.T) {
 			},
 		},
 	}
-	if _, _, err := s.Reassign(context.Background(), "pr-1", "rev1"); !errors.Is(err, domain.ErrNoActiveCandidate) {
+	if _, _, err := s.Reassign(context.Background(), requester.System(), "pr-1", "rev1", domain.AnyVersion); !errors.Is(err, domain.ErrNoActiveCandidate) {
 		t.Fatalf("expected ErrNoActiveCandidate, got %v", err)
 	}
 }
@@ -284,7 +358,238 @@ func TestService_Reassign_MergedPR(t *testing.T) {
 		},
 		assigner: testStrategy{},
 	}
-	if _, _, err := s.Reassign(context.Background(), "pr-1", "rev1"); !errors.Is(err, domain.ErrPullRequestAlreadyMerged) {
+	if _, _, err := s.Reassign(context.Background(), requester.System(), "pr-1", "rev1", domain.AnyVersion); !errors.Is(err, domain.ErrPullRequestAlreadyMerged) {
 		t.Fatalf("expected ErrPullRequestAlreadyMerged, got %v", err)
 	}
 }
+
+func TestService_AddReviewer(t *testing.T) {
+	pr, _ := pullrequest.New("pr-1", "Feature", "author", time.Now())
+	_ = pr.AssignReviewers([]domain.UserID{"rev1"})
+	stored := pr
+
+	author := makeUser(t, "author", "backend", true)
+	rev1 := makeUser(t, "rev1", "backend", true)
+	rev2 := makeUser(t, "rev2", "backend", true)
+
+	s := &svc{
+		prs: testPRRepo{
+			getFn: func(ctx context.Context, id domain.PullRequestID) (pullrequest.PullRequest, error) {
+				return stored, nil
+			},
+			updateFn: func(ctx context.Context, pr pullrequest.PullRequest) error {
+				stored = pr
+				return nil
+			},
+		},
+		users: testUserRepo{
+			getFn: func(ctx context.Context, userID domain.UserID) (user.User, error) {
+				return author, nil
+			},
+		},
+		teams: testTeamRepo{
+			getFn: func(ctx context.Context, name domain.TeamName) (team.Team, error) {
+				return team.New("backend", []user.User{author, rev1, rev2})
+			},
+		},
+		assigner: testStrategy{
+			pickFn: func(ctx context.Context, candidates []user.User, limit int) ([]user.User, error) {
+				if len(candidates) != 1 || candidates[0].UserID() != "rev2" {
+					t.Fatalf("expected only rev2 as candidate, got %v", candidates)
+				}
+				return candidates, nil
+			},
+		},
+	}
+
+	updated, added, err := s.AddReviewer(context.Background(), "pr-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if added != "rev2" || len(updated.AssignedReviewers()) != 2 {
+		t.Fatalf("expected rev2 appended, got %s %v", added, updated.AssignedReviewers())
+	}
+	if _, _, err := s.AddReviewer(context.Background(), "pr-1"); !errors.Is(err, domain.ErrReviewerLimitExceeded) {
+		t.Fatalf("expected ErrReviewerLimitExceeded, got %v", err)
+	}
+}
+
+func TestService_SubmitReview(t *testing.T) {
+	pr, _ := pullrequest.New("pr-1", "Feature", "author", time.Now())
+	_ = pr.AssignReviewers([]domain.UserID{"rev1"})
+
+	var marked domain.UserID
+	s := &svc{
+		prs: testPRRepo{
+			getFn: func(ctx context.Context, id domain.PullRequestID) (pullrequest.PullRequest, error) {
+				return pr, nil
+			},
+			markFn: func(ctx context.Context, id domain.PullRequestID, reviewer domain.UserID, verdict domain.ReviewVerdict, at time.Time) error {
+				marked = reviewer
+				return nil
+			},
+		},
+	}
+
+	if _, err := s.SubmitReview(context.Background(), requester.New("rev1", requester.RoleUser), "pr-1", "rev1", domain.ReviewVerdictApproved, time.Now(), domain.AnyVersion); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if marked != "rev1" {
+		t.Fatalf("expected rev1 marked as reviewed, got %q", marked)
+	}
+	if _, err := s.SubmitReview(context.Background(), requester.System(), "pr-1", "stranger", domain.ReviewVerdictCommented, time.Now(), domain.AnyVersion); !errors.Is(err, domain.ErrReviewerNotAssigned) {
+		t.Fatalf("expected ErrReviewerNotAssigned, got %v", err)
+	}
+}
+
+func TestService_SubmitReviewPermissions(t *testing.T) {
+	pr, _ := pullrequest.New("pr-1", "Feature", "author", time.Now())
+	_ = pr.AssignReviewers([]domain.UserID{"rev1"})
+	s := &svc{
+		prs: testPRRepo{
+			getFn: func(ctx context.Context, id domain.PullRequestID) (pullrequest.PullRequest, error) {
+				return pr, nil
+			},
+		},
+	}
+
+	if _, err := s.SubmitReview(context.Background(), requester.New("rev2", requester.RoleUser), "pr-1", "rev1", domain.ReviewVerdictApproved, time.Now(), domain.AnyVersion); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Fatalf("expected ErrPermissionDenied for review on behalf of another user, got %v", err)
+	}
+	if _, err := s.SubmitReview(context.Background(), requester.New("rev1", requester.RoleUser), "pr-1", "rev1", "LGTM", time.Now(), domain.AnyVersion); !errors.Is(err, domain.ErrInvalidReviewVerdict) {
+		t.Fatalf("expected ErrInvalidReviewVerdict, got %v", err)
+	}
+}
+
+func TestService_MergePermissions(t *testing.T) {
+	author := makeUser(t, "author", "backend", true)
+	lead := makeUser(t, "lead", "backend", true)
+	otherLead := makeUser(t, "other-lead", "frontend", true)
+	backend, _ := team.New("backend", []user.User{author, lead})
+
+	s := &svc{
+		teams: testTeamRepo{
+			getFn: func(ctx context.Context, name domain.TeamName) (team.Team, error) {
+				return backend, nil
+			},
+		},
+		users: testUserRepo{
+			getFn: func(ctx context.Context, userID domain.UserID) (user.User, error) {
+				return author, nil
+			},
+		},
+		prs: testPRRepo{
+			getFn: func(ctx context.Context, id domain.PullRequestID) (pullrequest.PullRequest, error) {
+				pr, _ := pullrequest.New("pr-1", "Feature", "author", time.Now())
+				return pr, nil
+			},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		actor requester.Requester
+		ok    bool
+	}{
+		{"author", requester.New("author", requester.RoleUser), true},
+		{"lead of author team", requester.New(lead.UserID(), requester.RoleTeamLead), true},
+		{"lead of other team", requester.New(otherLead.UserID(), requester.RoleTeamLead), false},
+		{"teammate", requester.New("rev1", requester.RoleUser), false},
+		{"author with read-only token", requester.New("author", requester.RoleUser, requester.ScopeTeamsRead), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Merge(context.Background(), tt.actor, "pr-1", time.Now(), domain.AnyVersion)
+			if tt.ok && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, domain.ErrPermissionDenied) {
+				t.Fatalf("expected ErrPermissionDenied, got %v", err)
+			}
+		})
+	}
+}
+
+func TestService_CreateOnlyForSelf(t *testing.T) {
+	s := &svc{assigner: testStrategy{}}
+	pr, _ := pullrequest.New("pr-1", "Feature", "author", time.Now())
+	if _, err := s.Create(context.Background(), requester.New("someone", requester.RoleUser), pr); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Fatalf("expected ErrPermissionDenied, got %v", err)
+	}
+}
+
+type recordingEvents struct {
+	events []event.Event
+}
+
+func (r *recordingEvents) Append(_ context.Context, events ...event.Event) error {
+	r.events = append(r.events, events...)
+	return nil
+}
+
+// retryingTx выполняет fn дважды, как TxManager после конфликта сериализации
+type retryingTx struct{}
+
+func (retryingTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return retryingTx{}.WithinTxOptions(ctx, service.TxOptions{}, fn)
+}
+
+func (retryingTx) WithinTxOptions(ctx context.Context, _ service.TxOptions, fn func(ctx context.Context) error) error {
+	_ = fn(ctx)
+	return fn(ctx)
+}
+
+func TestService_CreateAppendsEventsOncePerCommit(t *testing.T) {
+	author := makeUser(t, "author", "backend", true)
+	reviewer1 := makeUser(t, "rev1", "backend", true)
+	reviewer2 := makeUser(t, "rev2", "backend", true)
+	teamAggregate, _ := team.New("backend", []user.User{author, reviewer1, reviewer2})
+
+	events := &recordingEvents{}
+	attempts := 0
+	s := &svc{
+		teams: testTeamRepo{getFn: func(context.Context, domain.TeamName) (team.Team, error) { return teamAggregate, nil }},
+		users: testUserRepo{getFn: func(context.Context, domain.UserID) (user.User, error) { return author, nil }},
+		prs: testPRRepo{createFn: func(context.Context, pullrequest.PullRequest) error {
+			attempts++
+			events.events = nil // первая попытка откатилась вместе с событиями
+			return nil
+		}},
+		tx: retryingTx{},
+		assigner: testStrategy{pickFn: func(_ context.Context, candidates []user.User, _ int) ([]user.User, error) {
+			// повтор выбирает другого ревьювера
+			return candidates[attempts%len(candidates) : attempts%len(candidates)+1], nil
+		}},
+		events: events,
+	}
+
+	pr, _ := pullrequest.New("pr-1", "Feature", "author", time.Now())
+	result, err := s.Create(context.Background(), requester.System(), pr)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events.events) != 2 || events.events[0].Type != event.TypePullRequestCreated {
+		t.Fatalf("expected created and one assigned event, got %+v", events.events)
+	}
+	assigned, _ := events.events[1].Payload.(event.ReviewerAssigned)
+	if reviewers := result.AssignedReviewers(); len(reviewers) != 1 || assigned.ReviewerID != reviewers[0] {
+		t.Fatalf("event must describe the committed reviewer, got %+v for %v", assigned, reviewers)
+	}
+}
+
+func TestService_MergeAppendsMergedEvent(t *testing.T) {
+	pr, _ := pullrequest.New("pr-1", "Feature", "author", time.Now())
+	pr.ClearEvents() // как после загрузки из хранилища
+	events := &recordingEvents{}
+	s := &svc{
+		prs:    testPRRepo{getFn: func(context.Context, domain.PullRequestID) (pullrequest.PullRequest, error) { return pr, nil }},
+		events: events,
+	}
+
+	if _, err := s.Merge(context.Background(), requester.System(), "pr-1", time.Now(), domain.AnyVersion); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events.events) != 1 || events.events[0].Type != event.TypePullRequestMerged {
+		t.Fatalf("expected a single merged event, got %+v", events.events)
+	}
+}
@@ -0,0 +1,62 @@
+package pullrequestservice
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/pullrequest"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/tracing"
+)
+
+type tracedService struct {
+	next Service
+}
+
+// WithTracing оборачивает каждый метод сервиса в спан
+func WithTracing(next Service) Service {
+	return tracedService{next: next}
+}
+
+func (t tracedService) Create(ctx context.Context, actor requester.Requester, pr pullrequest.PullRequest) (pullrequest.PullRequest, error) {
+	ctx, span := tracing.Start(ctx, "pullrequest.Create", attribute.String("pull_request_id", string(pr.PullRequestID())))
+	res, err := t.next.Create(ctx, actor, pr)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) Merge(ctx context.Context, actor requester.Requester, id domain.PullRequestID, mergedAt time.Time, expected domain.Version) (pullrequest.PullRequest, error) {
+	ctx, span := tracing.Start(ctx, "pullrequest.Merge", attribute.String("pull_request_id", string(id)))
+	res, err := t.next.Merge(ctx, actor, id, mergedAt, expected)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) Reassign(ctx context.Context, actor requester.Requester, prID domain.PullRequestID, oldReviewer domain.UserID, expected domain.Version) (pullrequest.PullRequest, domain.UserID, error) {
+	ctx, span := tracing.Start(ctx, "pullrequest.Reassign", attribute.String("pull_request_id", string(prID)))
+	res, newReviewer, err := t.next.Reassign(ctx, actor, prID, oldReviewer, expected)
+	tracing.End(span, err)
+	return res, newReviewer, err
+}
+
+func (t tracedService) AddReviewer(ctx context.Context, prID domain.PullRequestID) (pullrequest.PullRequest, domain.UserID, error) {
+	ctx, span := tracing.Start(ctx, "pullrequest.AddReviewer", attribute.String("pull_request_id", string(prID)))
+	res, newReviewer, err := t.next.AddReviewer(ctx, prID)
+	tracing.End(span, err)
+	return res, newReviewer, err
+}
+
+func (t tracedService) SubmitReview(ctx context.Context, actor requester.Requester, prID domain.PullRequestID, reviewer domain.UserID, verdict domain.ReviewVerdict, reviewedAt time.Time, expected domain.Version) (pullrequest.PullRequest, error) {
+	ctx, span := tracing.Start(ctx, "pullrequest.SubmitReview",
+		attribute.String("pull_request_id", string(prID)),
+		attribute.String("verdict", string(verdict)),
+	)
+	res, err := t.next.SubmitReview(ctx, actor, prID, reviewer, verdict, reviewedAt, expected)
+	tracing.End(span, err)
+	return res, err
+}
+
+var _ Service = tracedService{}
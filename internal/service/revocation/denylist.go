@@ -0,0 +1,138 @@
+package revocationservice
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/auth"
+	"github.com/mashhkensss/PR-service/internal/domain/revocation"
+)
+
+const (
+	// refreshOverlap — на сколько назад от последнего прочитанного отзыва перечитывается таблица.
+	// revoked_at ставят часы экземпляров, а транзакции фиксируются не по порядку, поэтому отзыв
+	// может появиться с отметкой раньше уже прочитанных. Повторное чтение безвредно: add идемпотентен
+	refreshOverlap = time.Minute
+	// expiryGrace держит запись после exp токена с запасом на допустимое расхождение часов верификатора
+	expiryGrace = 5 * time.Minute
+	// purgeInterval — как часто истёкшие отзывы удаляются из таблицы
+	purgeInterval = 10 * time.Minute
+)
+
+// Denylist держит отзывы в памяти: проверка токена — пара обращений к map под RLock,
+// без похода в базу. Обновление дочитывает только новые отзывы и сливает их с известными,
+// а отзывы по jti уже истёкших токенов выбрасывает
+type Denylist struct {
+	repo   Repository
+	logger *slog.Logger
+
+	mu       sync.RWMutex
+	tokens   map[string]revocation.Revocation
+	subjects map[string]revocation.Revocation
+	// seen — самый поздний revoked_at из прочитанных в базе
+	seen time.Time
+}
+
+func NewDenylist(repo Repository, logger *slog.Logger) *Denylist {
+	return &Denylist{
+		repo:     repo,
+		logger:   logger,
+		tokens:   make(map[string]revocation.Revocation),
+		subjects: make(map[string]revocation.Revocation),
+	}
+}
+
+// Revoked реализует auth.Denylist
+func (d *Denylist) Revoked(c auth.Claims) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if c.ID != "" {
+		if _, ok := d.tokens[c.ID]; ok {
+			return true
+		}
+	}
+	rev, ok := d.subjects[c.Subject]
+	return ok && rev.Covers(c.ID, c.Subject, c.IssuedAt)
+}
+
+// Refresh дочитывает отзывы, сделанные после уже известных, и забывает истёкшие
+func (d *Denylist) Refresh(ctx context.Context) error {
+	d.mu.RLock()
+	since := d.seen
+	d.mu.RUnlock()
+	if !since.IsZero() {
+		since = since.Add(-refreshOverlap)
+	}
+
+	revs, err := d.repo.ListRevocations(ctx, since)
+	if err != nil {
+		return err
+	}
+	for _, r := range revs {
+		d.add(r)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, r := range revs {
+		if r.RevokedAt().After(d.seen) {
+			d.seen = r.RevokedAt()
+		}
+	}
+	d.prune(time.Now().Add(-expiryGrace))
+	return nil
+}
+
+// Run периодически обновляет denylist, чтобы отзывы, сделанные через другие экземпляры, вступали в силу,
+// и раз в purgeInterval удаляет из базы отзывы истёкших токенов
+func (d *Denylist) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	purge := time.NewTicker(purgeInterval)
+	defer purge.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.Refresh(ctx); err != nil && ctx.Err() == nil && d.logger != nil {
+				d.logger.Error("revocation refresh failed", "error", err)
+			}
+		case <-purge.C:
+			if _, err := d.repo.PurgeExpired(ctx, time.Now().Add(-expiryGrace)); err != nil && ctx.Err() == nil && d.logger != nil {
+				d.logger.Error("revocation purge failed", "error", err)
+			}
+		}
+	}
+}
+
+func (d *Denylist) add(r revocation.Revocation) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch r.Kind() {
+	case revocation.KindToken:
+		if prev, ok := d.tokens[r.Value()]; !ok || r.ExpiresAt().After(prev.ExpiresAt()) {
+			d.tokens[r.Value()] = r
+		}
+	case revocation.KindSubject:
+		if prev, ok := d.subjects[r.Value()]; !ok || r.RevokedAt().After(prev.RevokedAt()) {
+			d.subjects[r.Value()] = r
+		}
+	}
+}
+
+// prune удаляет отзывы токенов, истёкших до before. Вызывается под d.mu
+func (d *Denylist) prune(before time.Time) {
+	for jti, r := range d.tokens {
+		if r.Expired(before) {
+			delete(d.tokens, jti)
+		}
+	}
+}
+
+var _ auth.Denylist = (*Denylist)(nil)
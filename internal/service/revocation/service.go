@@ -0,0 +1,67 @@
+package revocationservice
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/revocation"
+)
+
+type Repository interface {
+	SaveRevocation(ctx context.Context, r revocation.Revocation) (revocation.Revocation, error)
+	// ListRevocations возвращает отзывы, сделанные позже since; нулевой since — все
+	ListRevocations(ctx context.Context, since time.Time) ([]revocation.Revocation, error)
+	PurgeExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Service interface {
+	// Revoke сохраняет отзыв и сразу применяет его в этом экземпляре; остальные подхватят
+	// его при следующем обновлении Denylist. expiresAt — exp отзываемого по jti токена, после
+	// которого запись удаляется; нулевой — срок неизвестен
+	Revoke(ctx context.Context, actor requester.Requester, kind revocation.Kind, value string, expiresAt, now time.Time) (revocation.Revocation, error)
+	List(ctx context.Context, actor requester.Requester) ([]revocation.Revocation, error)
+}
+
+type service struct {
+	repo     Repository
+	denylist *Denylist
+}
+
+func New(repo Repository, denylist *Denylist) Service {
+	return &service{repo: repo, denylist: denylist}
+}
+
+func (s *service) Revoke(ctx context.Context, actor requester.Requester, kind revocation.Kind, value string, expiresAt, now time.Time) (revocation.Revocation, error) {
+	if !actor.CanRevokeTokens() {
+		return revocation.Revocation{}, domain.ErrPermissionDenied
+	}
+	rev, err := revocation.New(kind, value, now)
+	if err != nil {
+		return revocation.Revocation{}, err
+	}
+	if rev, err = rev.WithExpiry(expiresAt); err != nil {
+		return revocation.Revocation{}, err
+	}
+	saved, err := s.repo.SaveRevocation(ctx, rev)
+	if err != nil {
+		return revocation.Revocation{}, fmt.Errorf("save revocation: %w", err)
+	}
+	s.denylist.add(saved)
+	return saved, nil
+}
+
+func (s *service) List(ctx context.Context, actor requester.Requester) ([]revocation.Revocation, error) {
+	if !actor.CanRevokeTokens() {
+		return nil, domain.ErrPermissionDenied
+	}
+	revs, err := s.repo.ListRevocations(ctx, time.Time{})
+	if err != nil {
+		return nil, fmt.Errorf("list revocations: %w", err)
+	}
+	return revs, nil
+}
+
+var _ Service = (*service)(nil)
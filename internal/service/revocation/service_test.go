@@ -0,0 +1,144 @@
+package revocationservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/auth"
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/revocation"
+)
+
+type testRevocationRepo struct {
+	saved []revocation.Revocation
+	since []time.Time
+}
+
+func (r *testRevocationRepo) SaveRevocation(ctx context.Context, rev revocation.Revocation) (revocation.Revocation, error) {
+	r.saved = append(r.saved, rev)
+	return rev, nil
+}
+
+func (r *testRevocationRepo) ListRevocations(ctx context.Context, since time.Time) ([]revocation.Revocation, error) {
+	r.since = append(r.since, since)
+	var out []revocation.Revocation
+	for _, rev := range r.saved {
+		if rev.RevokedAt().After(since) {
+			out = append(out, rev)
+		}
+	}
+	return out, nil
+}
+
+func (r *testRevocationRepo) PurgeExpired(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func TestService_RevokeAppliesImmediately(t *testing.T) {
+	repo := &testRevocationRepo{}
+	denylist := NewDenylist(repo, nil)
+	svc := New(repo, denylist)
+	now := time.Unix(1_700_000_000, 0)
+
+	before := auth.Claims{Subject: "u1", ID: "t1", IssuedAt: now.Add(-time.Minute).Unix()}
+	after := auth.Claims{Subject: "u1", ID: "t2", IssuedAt: now.Add(time.Minute).Unix()}
+	other := auth.Claims{Subject: "u2", ID: "t3", IssuedAt: now.Unix()}
+
+	if _, err := svc.Revoke(context.Background(), requester.System(), revocation.KindSubject, "u1", time.Time{}, now); err != nil {
+		t.Fatalf("revoke subject: %v", err)
+	}
+	if !denylist.Revoked(before) || denylist.Revoked(after) || denylist.Revoked(other) {
+		t.Fatalf("subject revocation must cover only tokens issued before it")
+	}
+	if _, err := svc.Revoke(context.Background(), requester.System(), revocation.KindToken, "t3", time.Time{}, now); err != nil {
+		t.Fatalf("revoke jti: %v", err)
+	}
+	if !denylist.Revoked(other) {
+		t.Fatalf("jti revocation must apply without refresh")
+	}
+}
+
+func TestService_RevokeRequiresAdmin(t *testing.T) {
+	repo := &testRevocationRepo{}
+	svc := New(repo, NewDenylist(repo, nil))
+	user := requester.New("u1", requester.RoleUser)
+	if _, err := svc.Revoke(context.Background(), user, revocation.KindSubject, "u2", time.Time{}, time.Now()); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Fatalf("expected ErrPermissionDenied, got %v", err)
+	}
+	if _, err := svc.List(context.Background(), user); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Fatalf("expected ErrPermissionDenied, got %v", err)
+	}
+	if len(repo.saved) != 0 {
+		t.Fatalf("denied revocation must not be stored")
+	}
+}
+
+func TestDenylist_RefreshPicksUpOtherInstances(t *testing.T) {
+	repo := &testRevocationRepo{}
+	local := NewDenylist(repo, nil)
+	remote := New(repo, NewDenylist(repo, nil))
+
+	if _, err := remote.Revoke(context.Background(), requester.System(), revocation.KindToken, "t1", time.Time{}, time.Now()); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if local.Revoked(auth.Claims{ID: "t1"}) {
+		t.Fatalf("local instance must not see revocation before refresh")
+	}
+	if err := local.Refresh(context.Background()); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if !local.Revoked(auth.Claims{ID: "t1"}) {
+		t.Fatalf("refresh must load revocations from storage")
+	}
+}
+
+func TestDenylist_RefreshIsIncremental(t *testing.T) {
+	now := time.Now()
+	repo := &testRevocationRepo{}
+	remote := New(repo, NewDenylist(repo, nil))
+	local := NewDenylist(repo, nil)
+
+	if _, err := remote.Revoke(context.Background(), requester.System(), revocation.KindSubject, "u1", time.Time{}, now); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	for range 2 {
+		if err := local.Refresh(context.Background()); err != nil {
+			t.Fatalf("refresh: %v", err)
+		}
+	}
+	if !repo.since[0].IsZero() || !repo.since[1].Equal(now.UTC().Add(-refreshOverlap)) {
+		t.Fatalf("second refresh must read only past the last seen revocation, got %v", repo.since)
+	}
+}
+
+func TestDenylist_ForgetsExpiredTokens(t *testing.T) {
+	now := time.Now()
+	repo := &testRevocationRepo{}
+	svc := New(repo, NewDenylist(repo, nil))
+	local := NewDenylist(repo, nil)
+
+	if _, err := svc.Revoke(context.Background(), requester.System(), revocation.KindToken, "old", now.Add(-time.Hour), now); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, err := svc.Revoke(context.Background(), requester.System(), revocation.KindToken, "live", now.Add(time.Hour), now); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, err := svc.Revoke(context.Background(), requester.System(), revocation.KindToken, "unknown", time.Time{}, now); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if err := local.Refresh(context.Background()); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if local.Revoked(auth.Claims{ID: "old"}) {
+		t.Fatalf("revocation of an expired token must be dropped")
+	}
+	if !local.Revoked(auth.Claims{ID: "live"}) || !local.Revoked(auth.Claims{ID: "unknown"}) {
+		t.Fatalf("revocations of live tokens and tokens without exp must stay")
+	}
+	if _, err := svc.Revoke(context.Background(), requester.System(), revocation.KindSubject, "u1", now.Add(time.Hour), now); !errors.Is(err, domain.ErrInvalidRevocation) {
+		t.Fatalf("expected ErrInvalidRevocation for subject expiry, got %v", err)
+	}
+}
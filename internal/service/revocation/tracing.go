@@ -0,0 +1,37 @@
+package revocationservice
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/revocation"
+	"github.com/mashhkensss/PR-service/internal/tracing"
+)
+
+type tracedService struct {
+	next Service
+}
+
+// WithTracing оборачивает каждый метод сервиса в спан
+func WithTracing(next Service) Service {
+	return tracedService{next: next}
+}
+
+func (t tracedService) Revoke(ctx context.Context, actor requester.Requester, kind revocation.Kind, value string, expiresAt, now time.Time) (revocation.Revocation, error) {
+	ctx, span := tracing.Start(ctx, "revocation.Revoke", attribute.String("kind", string(kind)))
+	res, err := t.next.Revoke(ctx, actor, kind, value, expiresAt, now)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) List(ctx context.Context, actor requester.Requester) ([]revocation.Revocation, error) {
+	ctx, span := tracing.Start(ctx, "revocation.List")
+	res, err := t.next.List(ctx, actor)
+	tracing.End(span, err)
+	return res, err
+}
+
+var _ Service = tracedService{}
@@ -0,0 +1,85 @@
+package slaservice
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Scheduler периодически ищет нарушения SLA и эскалирует их
+type Scheduler struct {
+	svc      Service
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+func NewScheduler(svc Service, interval time.Duration, logger *slog.Logger) *Scheduler {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return &Scheduler{svc: svc, interval: interval, logger: logger}
+}
+
+// Run блокируется до отмены ctx
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	escalations, err := s.svc.EscalateBreaches(ctx, time.Now().UTC())
+	if err != nil && ctx.Err() == nil && s.logger != nil {
+		s.logger.Error("sla escalation failed", "error", err)
+	}
+	if s.logger == nil {
+		return
+	}
+	for _, e := range escalations {
+		fields := []any{
+			"pull_request_id", e.Breach.PullRequestID,
+			"reviewer_id", e.Breach.ReviewerID,
+			"team_name", e.Breach.TeamName,
+			"deadline", e.Breach.Deadline,
+			"action", e.Action,
+		}
+		if e.NewReviewerID != "" {
+			fields = append(fields, "new_reviewer_id", e.NewReviewerID)
+		}
+		if e.Err != nil {
+			s.logger.Error("sla escalation failed", append(fields, "error", e.Err)...)
+			continue
+		}
+		s.logger.Info("sla escalated", fields...)
+	}
+}
+
+// LogNotifier пишет событие о нарушении SLA в лог
+type LogNotifier struct {
+	logger *slog.Logger
+}
+
+func NewLogNotifier(logger *slog.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (n *LogNotifier) Notify(_ context.Context, e Escalation) error {
+	if n.logger == nil {
+		return nil
+	}
+	n.logger.Warn("review_sla_breached",
+		"pull_request_id", e.Breach.PullRequestID,
+		"reviewer_id", e.Breach.ReviewerID,
+		"team_name", e.Breach.TeamName,
+		"assigned_at", e.Breach.AssignedAt,
+		"deadline", e.Breach.Deadline,
+	)
+	return nil
+}
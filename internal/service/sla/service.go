@@ -0,0 +1,245 @@
+package slaservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/pullrequest"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/sla"
+	"github.com/mashhkensss/PR-service/internal/domain/team"
+	appservice "github.com/mashhkensss/PR-service/internal/service"
+)
+
+type Repository interface {
+	SavePolicy(ctx context.Context, p sla.Policy) error
+	GetPolicy(ctx context.Context, team domain.TeamName) (sla.Policy, error)
+	ListPendingReviews(ctx context.Context, team domain.TeamName) ([]sla.PendingReview, error)
+	ClaimEscalation(ctx context.Context, id domain.PullRequestID, reviewerID domain.UserID, at time.Time) (bool, error)
+}
+
+type TeamRepository interface {
+	GetTeam(ctx context.Context, name domain.TeamName) (team.Team, error)
+}
+
+type PullRequestService interface {
+	Reassign(ctx context.Context, actor requester.Requester, prID domain.PullRequestID, oldReviewer domain.UserID, expected domain.Version) (pullrequest.PullRequest, domain.UserID, error)
+	AddReviewer(ctx context.Context, prID domain.PullRequestID) (pullrequest.PullRequest, domain.UserID, error)
+}
+
+// Notifier получает эскалации, для которых политика (или fallback) требует события
+type Notifier interface {
+	Notify(ctx context.Context, e Escalation) error
+}
+
+type Breach struct {
+	PullRequestID domain.PullRequestID
+	ReviewerID    domain.UserID
+	TeamName      domain.TeamName
+	AssignedAt    time.Time
+	Deadline      time.Time
+	EscalatedAt   *time.Time
+	Escalation    sla.Escalation
+}
+
+// Escalation — результат обработки одного нарушения
+type Escalation struct {
+	Breach        Breach
+	Action        sla.Escalation
+	NewReviewerID domain.UserID
+	Err           error
+}
+
+type Service interface {
+	SetPolicy(ctx context.Context, actor requester.Requester, p sla.Policy) (sla.Policy, error)
+	GetPolicy(ctx context.Context, actor requester.Requester, team domain.TeamName) (sla.Policy, error)
+	ListBreaches(ctx context.Context, actor requester.Requester, team domain.TeamName, now time.Time) ([]Breach, error)
+	EscalateBreaches(ctx context.Context, now time.Time) ([]Escalation, error)
+}
+
+type service struct {
+	repo     Repository
+	teams    TeamRepository
+	prs      PullRequestService
+	tx       appservice.TxRunner
+	notifier Notifier
+	defaults sla.Policy
+}
+
+// escalationTx — захват и эскалация одной транзакцией; переназначение внутри неё
+// присоединяется к ней и требует той же изоляции
+var escalationTx = appservice.TxOptions{Isolation: appservice.IsolationSerializable}
+
+// New собирает сервис SLA; defaults применяется к командам без собственной политики
+// (пустая политика — такие команды не отслеживаются)
+func New(repo Repository, teams TeamRepository, prs PullRequestService, tx appservice.TxRunner, notifier Notifier, defaults sla.Policy) Service {
+	return &service{repo: repo, teams: teams, prs: prs, tx: tx, notifier: notifier, defaults: defaults}
+}
+
+func (s *service) SetPolicy(ctx context.Context, actor requester.Requester, p sla.Policy) (sla.Policy, error) {
+	if err := s.authorize(ctx, p.TeamName(), actor.CanManageSLA); err != nil {
+		return sla.Policy{}, err
+	}
+	if err := s.repo.SavePolicy(ctx, p); err != nil {
+		return sla.Policy{}, fmt.Errorf("save sla policy: %w", err)
+	}
+	return p, nil
+}
+
+func (s *service) GetPolicy(ctx context.Context, actor requester.Requester, team domain.TeamName) (sla.Policy, error) {
+	if err := s.authorize(ctx, team, actor.CanViewSLA); err != nil {
+		return sla.Policy{}, err
+	}
+	p, err := s.repo.GetPolicy(ctx, team)
+	if err == nil {
+		return p, nil
+	}
+	if errors.Is(err, sql.ErrNoRows) && !s.defaults.IsZero() {
+		return s.defaults.WithTeam(team), nil
+	}
+	return sla.Policy{}, fmt.Errorf("get sla policy: %w", err)
+}
+
+// ListBreaches без команды доступен только администратору, тимлид видит нарушения своей команды
+func (s *service) ListBreaches(ctx context.Context, actor requester.Requester, team domain.TeamName, now time.Time) ([]Breach, error) {
+	if err := s.authorize(ctx, team, actor.CanManageSLA); err != nil {
+		return nil, err
+	}
+	return s.listBreaches(ctx, team, now)
+}
+
+func (s *service) listBreaches(ctx context.Context, team domain.TeamName, now time.Time) ([]Breach, error) {
+	pending, err := s.repo.ListPendingReviews(ctx, team)
+	if err != nil {
+		return nil, fmt.Errorf("list pending reviews: %w", err)
+	}
+
+	breaches := make([]Breach, 0)
+	for _, p := range pending {
+		policy := p.Policy
+		if policy.IsZero() {
+			if s.defaults.IsZero() {
+				continue
+			}
+			policy = s.defaults.WithTeam(p.TeamName)
+		}
+		if !policy.Breached(p.AssignedAt, now) {
+			continue
+		}
+		breaches = append(breaches, Breach{
+			PullRequestID: p.PullRequestID,
+			ReviewerID:    p.ReviewerID,
+			TeamName:      p.TeamName,
+			AssignedAt:    p.AssignedAt,
+			Deadline:      policy.Deadline(p.AssignedAt),
+			EscalatedAt:   p.EscalatedAt,
+			Escalation:    policy.Escalation(),
+		})
+	}
+	return breaches, nil
+}
+
+// EscalateBreaches обрабатывает ещё не эскалированные нарушения. Каждое нарушение сначала
+// «захватывается» в БД, поэтому несколько реплик не эскалируют одно и то же дважды. Захват
+// фиксируется вместе с эскалацией: если она не удалась, нарушение повторится на следующем проходе
+func (s *service) EscalateBreaches(ctx context.Context, now time.Time) ([]Escalation, error) {
+	breaches, err := s.listBreaches(ctx, "", now)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Escalation, 0)
+	for _, b := range breaches {
+		if b.EscalatedAt != nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		var (
+			out     Escalation
+			claimed bool
+		)
+		err := appservice.ExecInTxWith(ctx, s.tx, escalationTx, func(ctx context.Context) error {
+			var err error
+			if claimed, err = s.repo.ClaimEscalation(ctx, b.PullRequestID, b.ReviewerID, now); err != nil || !claimed {
+				return err
+			}
+			escalatedAt := now.UTC()
+			b.EscalatedAt = &escalatedAt
+			out = s.escalate(ctx, b)
+			return out.Err
+		})
+		switch {
+		case claimed && err != nil:
+			out.Err = err
+		case err != nil:
+			out = Escalation{Breach: b, Action: b.Escalation, Err: err}
+		case !claimed:
+			continue
+		}
+		result = append(result, out)
+	}
+	return result, nil
+}
+
+func (s *service) escalate(ctx context.Context, b Breach) Escalation {
+	out := Escalation{Breach: b, Action: b.Escalation}
+
+	switch b.Escalation {
+	case sla.EscalationReassign:
+		_, newReviewer, err := s.prs.Reassign(ctx, requester.System(), b.PullRequestID, b.ReviewerID, domain.AnyVersion)
+		if err == nil {
+			out.NewReviewerID = newReviewer
+			return out
+		}
+		if !errors.Is(err, domain.ErrNoActiveCandidate) {
+			out.Err = err
+			return out
+		}
+	case sla.EscalationAddReviewer:
+		_, newReviewer, err := s.prs.AddReviewer(ctx, b.PullRequestID)
+		if err == nil {
+			out.NewReviewerID = newReviewer
+			return out
+		}
+		if !errors.Is(err, domain.ErrNoActiveCandidate) && !errors.Is(err, domain.ErrReviewerLimitExceeded) {
+			out.Err = err
+			return out
+		}
+	}
+
+	// EVENT, а также fallback, когда заменить/добавить ревьювера некем
+	out.Action = sla.EscalationEvent
+	if s.notifier != nil {
+		out.Err = s.notifier.Notify(ctx, out)
+	}
+	return out
+}
+
+// authorize грузит команду, только если право не следует из роли. Несуществующая команда даёт отказ
+func (s *service) authorize(ctx context.Context, name domain.TeamName, allowed func(team.Team) bool) error {
+	if allowed(team.Team{}) {
+		return nil
+	}
+	if name == "" {
+		return domain.ErrPermissionDenied
+	}
+	t, err := s.teams.GetTeam(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrPermissionDenied
+	}
+	if err != nil {
+		return fmt.Errorf("load team: %w", err)
+	}
+	if !allowed(t) {
+		return domain.ErrPermissionDenied
+	}
+	return nil
+}
+
+var _ Service = (*service)(nil)
@@ -0,0 +1,266 @@
+package slaservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/pullrequest"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/sla"
+	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
+	"github.com/mashhkensss/PR-service/internal/domain/user"
+	appservice "github.com/mashhkensss/PR-service/internal/service"
+)
+
+type testSLARepo struct {
+	policies map[domain.TeamName]sla.Policy
+	pending  []sla.PendingReview
+	claimed  map[domain.UserID]bool
+}
+
+func (r *testSLARepo) SavePolicy(ctx context.Context, p sla.Policy) error {
+	r.policies[p.TeamName()] = p
+	return nil
+}
+
+func (r *testSLARepo) GetPolicy(ctx context.Context, team domain.TeamName) (sla.Policy, error) {
+	p, ok := r.policies[team]
+	if !ok {
+		return sla.Policy{}, sql.ErrNoRows
+	}
+	return p, nil
+}
+
+func (r *testSLARepo) ListPendingReviews(ctx context.Context, team domain.TeamName) ([]sla.PendingReview, error) {
+	return r.pending, nil
+}
+
+func (r *testSLARepo) ClaimEscalation(ctx context.Context, id domain.PullRequestID, reviewerID domain.UserID, at time.Time) (bool, error) {
+	if r.claimed[reviewerID] {
+		return false, nil
+	}
+	r.claimed[reviewerID] = true
+	return true, nil
+}
+
+// rollbackTx откатывает захваты в testSLARepo, если fn вернула ошибку
+type rollbackTx struct {
+	repo *testSLARepo
+	opts appservice.TxOptions
+}
+
+func (tx *rollbackTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return tx.WithinTxOptions(ctx, appservice.TxOptions{}, fn)
+}
+
+func (tx *rollbackTx) WithinTxOptions(ctx context.Context, opts appservice.TxOptions, fn func(ctx context.Context) error) error {
+	tx.opts = opts
+	snapshot := make(map[domain.UserID]bool, len(tx.repo.claimed))
+	for k, v := range tx.repo.claimed {
+		snapshot[k] = v
+	}
+	if err := fn(ctx); err != nil {
+		tx.repo.claimed = snapshot
+		return err
+	}
+	return nil
+}
+
+type testPRService struct {
+	reassignFn func(ctx context.Context, id domain.PullRequestID, old domain.UserID) (pullrequest.PullRequest, domain.UserID, error)
+	addFn      func(ctx context.Context, id domain.PullRequestID) (pullrequest.PullRequest, domain.UserID, error)
+}
+
+func (s testPRService) Reassign(ctx context.Context, _ requester.Requester, id domain.PullRequestID, old domain.UserID, _ domain.Version) (pullrequest.PullRequest, domain.UserID, error) {
+	if s.reassignFn != nil {
+		return s.reassignFn(ctx, id, old)
+	}
+	return pullrequest.PullRequest{}, "", nil
+}
+
+func (s testPRService) AddReviewer(ctx context.Context, id domain.PullRequestID) (pullrequest.PullRequest, domain.UserID, error) {
+	if s.addFn != nil {
+		return s.addFn(ctx, id)
+	}
+	return pullrequest.PullRequest{}, "", nil
+}
+
+type testTeamRepo map[domain.TeamName]domainteam.Team
+
+func (r testTeamRepo) GetTeam(_ context.Context, name domain.TeamName) (domainteam.Team, error) {
+	if t, ok := r[name]; ok {
+		return t, nil
+	}
+	return domainteam.Team{}, sql.ErrNoRows
+}
+
+type recordingNotifier struct {
+	events []Escalation
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, e Escalation) error {
+	n.events = append(n.events, e)
+	return nil
+}
+
+func TestService_ListBreachesUsesDefaults(t *testing.T) {
+	now := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+	strict, _ := sla.New("backend", time.Hour, false, sla.EscalationReassign)
+	defaults, _ := sla.NewDefault(48*time.Hour, false, sla.EscalationEvent)
+	repo := &testSLARepo{
+		pending: []sla.PendingReview{
+			{PullRequestID: "pr-1", ReviewerID: "rev1", TeamName: "backend", AssignedAt: now.Add(-2 * time.Hour), Policy: strict},
+			{PullRequestID: "pr-2", ReviewerID: "rev2", TeamName: "mobile", AssignedAt: now.Add(-2 * time.Hour)},
+			{PullRequestID: "pr-3", ReviewerID: "rev3", TeamName: "mobile", AssignedAt: now.Add(-72 * time.Hour)},
+		},
+	}
+	s := &service{repo: repo, defaults: defaults}
+
+	breaches, err := s.ListBreaches(context.Background(), requester.System(), "", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(breaches) != 2 {
+		t.Fatalf("expected 2 breaches, got %+v", breaches)
+	}
+	if breaches[0].Escalation != sla.EscalationReassign || breaches[1].Escalation != sla.EscalationEvent {
+		t.Fatalf("unexpected escalations %+v", breaches)
+	}
+}
+
+func TestService_GetPolicyFallsBackToDefaults(t *testing.T) {
+	defaults, _ := sla.NewDefault(24*time.Hour, true, sla.EscalationEvent)
+	s := &service{repo: &testSLARepo{policies: map[domain.TeamName]sla.Policy{}}, defaults: defaults}
+	p, err := s.GetPolicy(context.Background(), requester.System(), "backend")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.TeamName() != "backend" || p.ReviewWithin() != 24*time.Hour {
+		t.Fatalf("unexpected policy %+v", p)
+	}
+}
+
+func TestService_EscalateBreaches(t *testing.T) {
+	now := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+	reassign, _ := sla.New("backend", time.Hour, false, sla.EscalationReassign)
+	addReviewer, _ := sla.New("mobile", time.Hour, false, sla.EscalationAddReviewer)
+	escalatedAt := now.Add(-time.Minute)
+	repo := &testSLARepo{
+		claimed: map[domain.UserID]bool{},
+		pending: []sla.PendingReview{
+			{PullRequestID: "pr-1", ReviewerID: "rev1", TeamName: "backend", AssignedAt: now.Add(-2 * time.Hour), Policy: reassign},
+			{PullRequestID: "pr-2", ReviewerID: "rev2", TeamName: "mobile", AssignedAt: now.Add(-2 * time.Hour), Policy: addReviewer},
+			{PullRequestID: "pr-3", ReviewerID: "rev3", TeamName: "backend", AssignedAt: now.Add(-2 * time.Hour), Policy: reassign, EscalatedAt: &escalatedAt},
+		},
+	}
+	notifier := &recordingNotifier{}
+	s := &service{
+		repo: repo,
+		prs: testPRService{
+			reassignFn: func(ctx context.Context, id domain.PullRequestID, old domain.UserID) (pullrequest.PullRequest, domain.UserID, error) {
+				return pullrequest.PullRequest{}, "rev9", nil
+			},
+			addFn: func(ctx context.Context, id domain.PullRequestID) (pullrequest.PullRequest, domain.UserID, error) {
+				return pullrequest.PullRequest{}, "", domain.ErrReviewerLimitExceeded
+			},
+		},
+		notifier: notifier,
+	}
+
+	escalations, err := s.EscalateBreaches(context.Background(), now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(escalations) != 2 {
+		t.Fatalf("already escalated breach must be skipped, got %+v", escalations)
+	}
+	if escalations[0].NewReviewerID != "rev9" || escalations[0].Action != sla.EscalationReassign {
+		t.Fatalf("unexpected reassign escalation %+v", escalations[0])
+	}
+	if escalations[1].Action != sla.EscalationEvent || len(notifier.events) != 1 {
+		t.Fatalf("full PR must fall back to event, got %+v", escalations[1])
+	}
+
+	again, err := s.EscalateBreaches(context.Background(), now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(again) != 0 {
+		t.Fatalf("claimed breaches must not be escalated twice, got %+v", again)
+	}
+}
+
+func TestService_EscalateBreachesReleasesClaimOnFailure(t *testing.T) {
+	now := time.Date(2025, 1, 8, 12, 0, 0, 0, time.UTC)
+	reassign, _ := sla.New("backend", time.Hour, false, sla.EscalationReassign)
+	repo := &testSLARepo{
+		claimed: map[domain.UserID]bool{},
+		pending: []sla.PendingReview{
+			{PullRequestID: "pr-1", ReviewerID: "rev1", TeamName: "backend", AssignedAt: now.Add(-2 * time.Hour), Policy: reassign},
+		},
+	}
+	tx := &rollbackTx{repo: repo}
+	failure := errors.New("connection reset")
+	attempts := 0
+	s := &service{
+		repo: repo,
+		tx:   tx,
+		prs: testPRService{
+			reassignFn: func(ctx context.Context, id domain.PullRequestID, old domain.UserID) (pullrequest.PullRequest, domain.UserID, error) {
+				attempts++
+				if attempts == 1 {
+					return pullrequest.PullRequest{}, "", failure
+				}
+				return pullrequest.PullRequest{}, "rev9", nil
+			},
+		},
+	}
+
+	escalations, err := s.EscalateBreaches(context.Background(), now)
+	if err != nil || len(escalations) != 1 || !errors.Is(escalations[0].Err, failure) {
+		t.Fatalf("expected failed escalation, got %+v, %v", escalations, err)
+	}
+	if repo.claimed["rev1"] {
+		t.Fatal("failed escalation must release the claim")
+	}
+	if tx.opts.Isolation != appservice.IsolationSerializable {
+		t.Fatalf("escalation must run serializable, got %+v", tx.opts)
+	}
+
+	escalations, err = s.EscalateBreaches(context.Background(), now)
+	if err != nil || len(escalations) != 1 || escalations[0].NewReviewerID != "rev9" || !repo.claimed["rev1"] {
+		t.Fatalf("breach must be retried on the next pass, got %+v, %v", escalations, err)
+	}
+}
+
+func TestService_SetPolicyPermissions(t *testing.T) {
+	lead, _ := user.New("lead", "Lena", "backend", true)
+	member, _ := user.New("u1", "Alice", "backend", true)
+	backend, _ := domainteam.New("backend", []user.User{lead, member})
+	s := &service{
+		repo:  &testSLARepo{policies: map[domain.TeamName]sla.Policy{}},
+		teams: testTeamRepo{"backend": backend},
+	}
+	backendPolicy, _ := sla.New("backend", time.Hour, false, sla.EscalationEvent)
+	mobilePolicy, _ := sla.New("mobile", time.Hour, false, sla.EscalationEvent)
+
+	if _, err := s.SetPolicy(context.Background(), requester.New("lead", requester.RoleTeamLead), backendPolicy); err != nil {
+		t.Fatalf("lead must manage own team policy: %v", err)
+	}
+	if _, err := s.SetPolicy(context.Background(), requester.New("lead", requester.RoleTeamLead), mobilePolicy); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Fatalf("expected ErrPermissionDenied for another team, got %v", err)
+	}
+	if _, err := s.SetPolicy(context.Background(), requester.New("u1", requester.RoleUser), backendPolicy); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Fatalf("expected ErrPermissionDenied for member, got %v", err)
+	}
+	if _, err := s.GetPolicy(context.Background(), requester.New("u1", requester.RoleUser), "backend"); err != nil {
+		t.Fatalf("member must see own team policy: %v", err)
+	}
+	if _, err := s.ListBreaches(context.Background(), requester.New("lead", requester.RoleTeamLead), "", time.Now()); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Fatalf("lead must not list breaches of all teams, got %v", err)
+	}
+}
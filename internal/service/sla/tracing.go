@@ -0,0 +1,53 @@
+package slaservice
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/sla"
+	"github.com/mashhkensss/PR-service/internal/tracing"
+)
+
+type tracedService struct {
+	next Service
+}
+
+// WithTracing оборачивает каждый метод сервиса в спан
+func WithTracing(next Service) Service {
+	return tracedService{next: next}
+}
+
+func (t tracedService) SetPolicy(ctx context.Context, actor requester.Requester, p sla.Policy) (sla.Policy, error) {
+	ctx, span := tracing.Start(ctx, "sla.SetPolicy", attribute.String("team_name", string(p.TeamName())))
+	res, err := t.next.SetPolicy(ctx, actor, p)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) GetPolicy(ctx context.Context, actor requester.Requester, team domain.TeamName) (sla.Policy, error) {
+	ctx, span := tracing.Start(ctx, "sla.GetPolicy", attribute.String("team_name", string(team)))
+	res, err := t.next.GetPolicy(ctx, actor, team)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) ListBreaches(ctx context.Context, actor requester.Requester, team domain.TeamName, now time.Time) ([]Breach, error) {
+	ctx, span := tracing.Start(ctx, "sla.ListBreaches")
+	res, err := t.next.ListBreaches(ctx, actor, team, now)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) EscalateBreaches(ctx context.Context, now time.Time) ([]Escalation, error) {
+	ctx, span := tracing.Start(ctx, "sla.EscalateBreaches")
+	res, err := t.next.EscalateBreaches(ctx, now)
+	span.SetAttributes(attribute.Int("escalations", len(res)))
+	tracing.End(span, err)
+	return res, err
+}
+
+var _ Service = tracedService{}
@@ -0,0 +1,148 @@
+package statsservice
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/stats"
+	appservice "github.com/mashhkensss/PR-service/internal/service"
+)
+
+type DurationStats struct {
+	TeamName   string  `json:"team_name,omitempty"`
+	Week       string  `json:"week,omitempty"`
+	ReviewerID string  `json:"reviewer_id,omitempty"`
+	Count      int     `json:"count"`
+	P50Seconds float64 `json:"p50_seconds"`
+	P90Seconds float64 `json:"p90_seconds"`
+	P99Seconds float64 `json:"p99_seconds"`
+}
+
+type DurationBreakdown struct {
+	Overall    DurationStats   `json:"overall"`
+	ByTeam     []DurationStats `json:"by_team"`
+	ByWeek     []DurationStats `json:"by_week"`
+	ByReviewer []DurationStats `json:"by_reviewer,omitempty"`
+}
+
+type ReassignmentStats struct {
+	TeamName               string  `json:"team_name,omitempty"`
+	Week                   string  `json:"week,omitempty"`
+	PullRequests           int     `json:"pull_requests"`
+	ReassignedPullRequests int     `json:"reassigned_pull_requests"`
+	Reassignments          int     `json:"reassignments"`
+	Rate                   float64 `json:"rate"`
+}
+
+type ReassignmentBreakdown struct {
+	Overall ReassignmentStats   `json:"overall"`
+	ByTeam  []ReassignmentStats `json:"by_team"`
+	ByWeek  []ReassignmentStats `json:"by_week"`
+}
+
+// CycleTimeReport — время от создания PR до merge, от назначения ревьювера до merge
+// (окно и недели по merged_at) и доля переназначений (окно и недели по created_at)
+type CycleTimeReport struct {
+	CreateToMerge     DurationBreakdown     `json:"create_to_merge"`
+	AssignmentToMerge DurationBreakdown     `json:"assignment_to_merge"`
+	Reassignment      ReassignmentBreakdown `json:"reassignment"`
+}
+
+func (s *service) GetCycleTime(ctx context.Context, actor requester.Requester, f stats.Filter) (CycleTimeReport, error) {
+	if !actor.CanViewStats() {
+		return CycleTimeReport{}, domain.ErrPermissionDenied
+	}
+	if err := f.Validate(); err != nil {
+		return CycleTimeReport{}, err
+	}
+	if f.Status != "" {
+		return CycleTimeReport{}, fmt.Errorf("%w: status is not supported for cycle time", domain.ErrInvalidStatsFilter)
+	}
+
+	return appservice.RunInTxWith(ctx, s.tx, appservice.ReadOnlySnapshot, func(ctx context.Context) (CycleTimeReport, error) {
+		return s.cycleTime(ctx, f)
+	})
+}
+
+func (s *service) cycleTime(ctx context.Context, f stats.Filter) (CycleTimeReport, error) {
+	createToMerge, err := s.repo.CreateToMergeTimes(ctx, f)
+	if err != nil {
+		return CycleTimeReport{}, fmt.Errorf("create to merge times: %w", err)
+	}
+	assignmentToMerge, err := s.repo.AssignmentToMergeTimes(ctx, f)
+	if err != nil {
+		return CycleTimeReport{}, fmt.Errorf("assignment to merge times: %w", err)
+	}
+	reassignments, err := s.repo.ReassignmentRates(ctx, f)
+	if err != nil {
+		return CycleTimeReport{}, fmt.Errorf("reassignment rates: %w", err)
+	}
+
+	return CycleTimeReport{
+		CreateToMerge:     durationBreakdown(createToMerge),
+		AssignmentToMerge: durationBreakdown(assignmentToMerge),
+		Reassignment:      reassignmentBreakdown(reassignments),
+	}, nil
+}
+
+func durationBreakdown(groups []stats.DurationGroup) DurationBreakdown {
+	out := DurationBreakdown{
+		ByTeam: make([]DurationStats, 0),
+		ByWeek: make([]DurationStats, 0),
+	}
+	for _, g := range groups {
+		item := DurationStats{
+			Count:      g.Count,
+			P50Seconds: g.P50.Seconds(),
+			P90Seconds: g.P90.Seconds(),
+			P99Seconds: g.P99.Seconds(),
+		}
+		switch g.Dimension {
+		case stats.DimensionTeam:
+			item.TeamName = string(g.Team)
+			out.ByTeam = append(out.ByTeam, item)
+		case stats.DimensionWeek:
+			item.Week = formatWeek(g.Week)
+			out.ByWeek = append(out.ByWeek, item)
+		case stats.DimensionReviewer:
+			item.ReviewerID = string(g.ReviewerID)
+			out.ByReviewer = append(out.ByReviewer, item)
+		default:
+			out.Overall = item
+		}
+	}
+	return out
+}
+
+func reassignmentBreakdown(groups []stats.ReassignmentGroup) ReassignmentBreakdown {
+	out := ReassignmentBreakdown{
+		ByTeam: make([]ReassignmentStats, 0),
+		ByWeek: make([]ReassignmentStats, 0),
+	}
+	for _, g := range groups {
+		item := ReassignmentStats{
+			PullRequests:           g.PullRequests,
+			ReassignedPullRequests: g.Reassigned,
+			Reassignments:          g.Reassignments,
+			Rate:                   g.Rate(),
+		}
+		switch g.Dimension {
+		case stats.DimensionTeam:
+			item.TeamName = string(g.Team)
+			out.ByTeam = append(out.ByTeam, item)
+		case stats.DimensionWeek:
+			item.Week = formatWeek(g.Week)
+			out.ByWeek = append(out.ByWeek, item)
+		default:
+			out.Overall = item
+		}
+	}
+	return out
+}
+
+func formatWeek(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
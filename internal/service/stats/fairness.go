@@ -0,0 +1,142 @@
+package statsservice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/stats"
+	appservice "github.com/mashhkensss/PR-service/internal/service"
+)
+
+type MemberLoad struct {
+	UserID      string  `json:"user_id"`
+	Assignments int     `json:"assignments"`
+	Share       float64 `json:"share"`
+	EvenShare   float64 `json:"even_share"`
+	// ShareVsEven — Share / EvenShare: 1 — ровно своя доля, 2 — вдвое больше
+	ShareVsEven float64 `json:"share_vs_even"`
+}
+
+type LoadExtreme struct {
+	UserIDs     []string `json:"user_ids"`
+	Assignments int      `json:"assignments"`
+}
+
+type TeamFairness struct {
+	TeamName               string       `json:"team_name"`
+	ActiveMembers          int          `json:"active_members"`
+	AssignmentsTotal       int          `json:"assignments_total"`
+	Mean                   float64      `json:"mean"`
+	CoefficientOfVariation float64      `json:"coefficient_of_variation"`
+	Gini                   float64      `json:"gini"`
+	MostLoaded             LoadExtreme  `json:"most_loaded"`
+	LeastLoaded            LoadExtreme  `json:"least_loaded"`
+	Members                []MemberLoad `json:"members"`
+}
+
+type FairnessReport struct {
+	Teams []TeamFairness `json:"teams"`
+}
+
+// GetFairness считает распределение назначений между активными участниками каждой команды.
+// Состав берётся из teamrepo, нагрузка — из statsrepo с тем же фильтром, что и у /stats/assignments
+func (s *service) GetFairness(ctx context.Context, actor requester.Requester, f stats.Filter) (FairnessReport, error) {
+	if !actor.CanViewStats() {
+		return FairnessReport{}, domain.ErrPermissionDenied
+	}
+	if err := f.Validate(); err != nil {
+		return FairnessReport{}, err
+	}
+	if s.teams == nil {
+		return FairnessReport{}, fmt.Errorf("team repository is not configured")
+	}
+	return appservice.RunInTxWith(ctx, s.tx, appservice.ReadOnlySnapshot, func(ctx context.Context) (FairnessReport, error) {
+		return s.fairness(ctx, f)
+	})
+}
+
+func (s *service) fairness(ctx context.Context, f stats.Filter) (FairnessReport, error) {
+	names := []domain.TeamName{f.Team}
+	if f.Team == "" {
+		var err error
+		if names, err = s.teams.ListTeamNames(ctx); err != nil {
+			return FairnessReport{}, fmt.Errorf("list teams: %w", err)
+		}
+	}
+
+	loads, err := s.repo.AssignmentsPerUser(ctx, f)
+	if err != nil {
+		return FairnessReport{}, fmt.Errorf("stats per user: %w", err)
+	}
+	byUser := make(map[domain.UserID]int, len(loads))
+	for _, l := range loads {
+		byUser[l.UserID] = l.Total()
+	}
+
+	report := FairnessReport{Teams: make([]TeamFairness, 0, len(names))}
+	for _, name := range names {
+		team, err := s.teams.GetTeam(ctx, name)
+		if err != nil {
+			return FairnessReport{}, fmt.Errorf("load team %s: %w", name, err)
+		}
+		ids := make([]string, 0)
+		values := make([]int, 0)
+		for _, m := range team.Members() {
+			if !m.IsActive() {
+				continue
+			}
+			ids = append(ids, string(m.UserID()))
+			values = append(values, byUser[m.UserID()])
+		}
+		report.Teams = append(report.Teams, teamFairness(name, ids, values))
+	}
+	return report, nil
+}
+
+func teamFairness(name domain.TeamName, ids []string, values []int) TeamFairness {
+	out := TeamFairness{
+		TeamName:               string(name),
+		ActiveMembers:          len(ids),
+		Mean:                   stats.Mean(values),
+		CoefficientOfVariation: stats.CoefficientOfVariation(values),
+		Gini:                   stats.Gini(values),
+		MostLoaded:             LoadExtreme{UserIDs: make([]string, 0)},
+		LeastLoaded:            LoadExtreme{UserIDs: make([]string, 0)},
+		Members:                make([]MemberLoad, 0, len(ids)),
+	}
+	for _, v := range values {
+		out.AssignmentsTotal += v
+	}
+	if len(ids) == 0 {
+		return out
+	}
+
+	evenShare := 1 / float64(len(ids))
+	out.MostLoaded.Assignments = values[0]
+	out.LeastLoaded.Assignments = values[0]
+	for i, id := range ids {
+		v := values[i]
+		member := MemberLoad{UserID: id, Assignments: v, EvenShare: evenShare}
+		if out.AssignmentsTotal > 0 {
+			member.Share = float64(v) / float64(out.AssignmentsTotal)
+			member.ShareVsEven = member.Share / evenShare
+		}
+		out.Members = append(out.Members, member)
+
+		switch {
+		case v > out.MostLoaded.Assignments:
+			out.MostLoaded = LoadExtreme{UserIDs: []string{id}, Assignments: v}
+		case v == out.MostLoaded.Assignments:
+			out.MostLoaded.UserIDs = append(out.MostLoaded.UserIDs, id)
+		}
+		switch {
+		case v < out.LeastLoaded.Assignments:
+			out.LeastLoaded = LoadExtreme{UserIDs: []string{id}, Assignments: v}
+		case v == out.LeastLoaded.Assignments:
+			out.LeastLoaded.UserIDs = append(out.LeastLoaded.UserIDs, id)
+		}
+	}
+	return out
+}
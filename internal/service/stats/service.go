@@ -5,44 +5,104 @@ import (
 	"fmt"
 
 	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/stats"
+	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
+	appservice "github.com/mashhkensss/PR-service/internal/service"
 )
 
 type Repository interface {
-	AssignmentsPerUser(ctx context.Context) (map[domain.UserID]int, error)
-	AssignmentsPerPullRequest(ctx context.Context) (map[domain.PullRequestID]int, error)
+	AssignmentsPerUser(ctx context.Context, f stats.Filter) ([]stats.ReviewerLoad, error)
+	AssignmentsPerPullRequest(ctx context.Context, f stats.Filter) (map[domain.PullRequestID]int, error)
+	CreateToMergeTimes(ctx context.Context, f stats.Filter) ([]stats.DurationGroup, error)
+	AssignmentToMergeTimes(ctx context.Context, f stats.Filter) ([]stats.DurationGroup, error)
+	ReassignmentRates(ctx context.Context, f stats.Filter) ([]stats.ReassignmentGroup, error)
+}
+
+type TeamRepository interface {
+	ListTeamNames(ctx context.Context) ([]domain.TeamName, error)
+	GetTeam(ctx context.Context, name domain.TeamName) (domainteam.Team, error)
+}
+
+// ReviewerStats — нагрузка одного ревьювера в пределах фильтра
+type ReviewerStats struct {
+	UserID   string `json:"user_id"`
+	TeamName string `json:"team_name"`
+	IsActive bool   `json:"is_active"`
+	Open     int    `json:"open"`
+	Merged   int    `json:"merged"`
+	Total    int    `json:"total"`
 }
 
 type AssignmentsStats struct {
-	ByUser        map[string]int `json:"by_user"`
-	ByPullRequest map[string]int `json:"by_pull_request"`
+	ByUser        map[string]int  `json:"by_user"`
+	ByPullRequest map[string]int  `json:"by_pull_request"`
+	Reviewers     []ReviewerStats `json:"reviewers"`
+}
+
+type Summary struct {
+	UsersCount                    int `json:"users_count"`
+	ActiveUsersWithoutAssignments int `json:"active_users_without_assignments"`
+	PullRequestsCount             int `json:"pull_requests_count"`
+	AssignmentsTotal              int `json:"assignments_total"`
+	OpenAssignments               int `json:"open_assignments"`
+	MergedAssignments             int `json:"merged_assignments"`
 }
 
 type Service interface {
-	GetAssignments(ctx context.Context) (AssignmentsStats, error)
+	GetAssignments(ctx context.Context, actor requester.Requester, f stats.Filter) (AssignmentsStats, error)
+	GetSummary(ctx context.Context, actor requester.Requester, f stats.Filter) (Summary, error)
+	GetCycleTime(ctx context.Context, actor requester.Requester, f stats.Filter) (CycleTimeReport, error)
+	GetFairness(ctx context.Context, actor requester.Requester, f stats.Filter) (FairnessReport, error)
 }
 
 type service struct {
-	repo Repository
+	repo  Repository
+	teams TeamRepository
+	tx    appservice.TxRunner
 }
 
-func New(repo Repository) Service {
-	return &service{repo: repo}
+// New принимает TxRunner, чтобы отчёт из нескольких запросов читался из одного снимка базы;
+// без него запросы идут по отдельности
+func New(repo Repository, teams TeamRepository, tx appservice.TxRunner) Service {
+	return &service{repo: repo, teams: teams, tx: tx}
 }
 
-func (s *service) GetAssignments(ctx context.Context) (AssignmentsStats, error) {
-	userStats, err := s.repo.AssignmentsPerUser(ctx)
+func (s *service) GetAssignments(ctx context.Context, actor requester.Requester, f stats.Filter) (AssignmentsStats, error) {
+	if !actor.CanViewStats() {
+		return AssignmentsStats{}, domain.ErrPermissionDenied
+	}
+	if err := f.Validate(); err != nil {
+		return AssignmentsStats{}, err
+	}
+	return appservice.RunInTxWith(ctx, s.tx, appservice.ReadOnlySnapshot, func(ctx context.Context) (AssignmentsStats, error) {
+		return s.assignments(ctx, f)
+	})
+}
+
+func (s *service) assignments(ctx context.Context, f stats.Filter) (AssignmentsStats, error) {
+	loads, err := s.repo.AssignmentsPerUser(ctx, f)
 	if err != nil {
 		return AssignmentsStats{}, fmt.Errorf("stats per user: %w", err)
 	}
 
-	prStats, err := s.repo.AssignmentsPerPullRequest(ctx)
+	prStats, err := s.repo.AssignmentsPerPullRequest(ctx, f)
 	if err != nil {
 		return AssignmentsStats{}, fmt.Errorf("stats per pull request: %w", err)
 	}
 
-	byUser := make(map[string]int, len(userStats))
-	for id, count := range userStats {
-		byUser[string(id)] = count
+	byUser := make(map[string]int, len(loads))
+	reviewers := make([]ReviewerStats, 0, len(loads))
+	for _, l := range loads {
+		byUser[string(l.UserID)] = l.Total()
+		reviewers = append(reviewers, ReviewerStats{
+			UserID:   string(l.UserID),
+			TeamName: string(l.TeamName),
+			IsActive: l.IsActive,
+			Open:     l.Open,
+			Merged:   l.Merged,
+			Total:    l.Total(),
+		})
 	}
 
 	byPR := make(map[string]int, len(prStats))
@@ -53,7 +113,31 @@ func (s *service) GetAssignments(ctx context.Context) (AssignmentsStats, error)
 	return AssignmentsStats{
 		ByUser:        byUser,
 		ByPullRequest: byPR,
+		Reviewers:     reviewers,
 	}, nil
 }
 
+func (s *service) GetSummary(ctx context.Context, actor requester.Requester, f stats.Filter) (Summary, error) {
+	if !actor.CanViewStats() {
+		return Summary{}, domain.ErrPermissionDenied
+	}
+	assignments, err := s.GetAssignments(ctx, actor, f)
+	if err != nil {
+		return Summary{}, err
+	}
+	summary := Summary{
+		UsersCount:        len(assignments.Reviewers),
+		PullRequestsCount: len(assignments.ByPullRequest),
+	}
+	for _, r := range assignments.Reviewers {
+		summary.AssignmentsTotal += r.Total
+		summary.OpenAssignments += r.Open
+		summary.MergedAssignments += r.Merged
+		if r.IsActive && r.Total == 0 {
+			summary.ActiveUsersWithoutAssignments++
+		}
+	}
+	return summary, nil
+}
+
 var _ Service = (*service)(nil)
@@ -4,60 +4,277 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/stats"
+	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
+	"github.com/mashhkensss/PR-service/internal/domain/user"
+	appservice "github.com/mashhkensss/PR-service/internal/service"
 )
 
 type testStatsRepo struct {
-	userFn func(ctx context.Context) (map[domain.UserID]int, error)
-	prFn   func(ctx context.Context) (map[domain.PullRequestID]int, error)
+	userFn func(ctx context.Context, f stats.Filter) ([]stats.ReviewerLoad, error)
+	prFn   func(ctx context.Context, f stats.Filter) (map[domain.PullRequestID]int, error)
+	cycle  []stats.DurationGroup
+	review []stats.DurationGroup
+	rates  []stats.ReassignmentGroup
 }
 
-func (r testStatsRepo) AssignmentsPerUser(ctx context.Context) (map[domain.UserID]int, error) {
+func (r testStatsRepo) AssignmentsPerUser(ctx context.Context, f stats.Filter) ([]stats.ReviewerLoad, error) {
 	if r.userFn != nil {
-		return r.userFn(ctx)
+		return r.userFn(ctx, f)
 	}
-	return map[domain.UserID]int{}, nil
+	return nil, nil
 }
 
-func (r testStatsRepo) AssignmentsPerPullRequest(ctx context.Context) (map[domain.PullRequestID]int, error) {
+func (r testStatsRepo) AssignmentsPerPullRequest(ctx context.Context, f stats.Filter) (map[domain.PullRequestID]int, error) {
 	if r.prFn != nil {
-		return r.prFn(ctx)
+		return r.prFn(ctx, f)
 	}
 	return map[domain.PullRequestID]int{}, nil
 }
 
+func (r testStatsRepo) CreateToMergeTimes(ctx context.Context, f stats.Filter) ([]stats.DurationGroup, error) {
+	return r.cycle, nil
+}
+
+func (r testStatsRepo) AssignmentToMergeTimes(ctx context.Context, f stats.Filter) ([]stats.DurationGroup, error) {
+	return r.review, nil
+}
+
+func (r testStatsRepo) ReassignmentRates(ctx context.Context, f stats.Filter) ([]stats.ReassignmentGroup, error) {
+	return r.rates, nil
+}
+
+type recordingTx struct {
+	opts []appservice.TxOptions
+}
+
+func (r *recordingTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.WithinTxOptions(ctx, appservice.TxOptions{}, fn)
+}
+
+func (r *recordingTx) WithinTxOptions(ctx context.Context, opts appservice.TxOptions, fn func(ctx context.Context) error) error {
+	r.opts = append(r.opts, opts)
+	return fn(ctx)
+}
+
+func TestService_ReportsReadOneSnapshot(t *testing.T) {
+	tx := &recordingTx{}
+	s := New(testStatsRepo{}, nil, tx)
+	if _, err := s.GetCycleTime(context.Background(), requester.System(), stats.Filter{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.GetSummary(context.Background(), requester.System(), stats.Filter{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tx.opts) != 2 || tx.opts[0] != appservice.ReadOnlySnapshot || tx.opts[1] != appservice.ReadOnlySnapshot {
+		t.Fatalf("each report must run in one read-only snapshot, got %+v", tx.opts)
+	}
+}
+
 func TestService_GetAssignments(t *testing.T) {
 	repo := testStatsRepo{
-		userFn: func(ctx context.Context) (map[domain.UserID]int, error) {
-			return map[domain.UserID]int{"u1": 2}, nil
+		userFn: func(ctx context.Context, f stats.Filter) ([]stats.ReviewerLoad, error) {
+			return []stats.ReviewerLoad{
+				{UserID: "u1", TeamName: "backend", IsActive: true, Open: 1, Merged: 1},
+				{UserID: "u2", TeamName: "backend", IsActive: true},
+			}, nil
 		},
-		prFn: func(ctx context.Context) (map[domain.PullRequestID]int, error) {
+		prFn: func(ctx context.Context, f stats.Filter) (map[domain.PullRequestID]int, error) {
 			return map[domain.PullRequestID]int{"pr-1": 1}, nil
 		},
 	}
 	s := &service{repo: repo}
-	stats, err := s.GetAssignments(context.Background())
+	result, err := s.GetAssignments(context.Background(), requester.System(), stats.Filter{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if stats.ByUser["u1"] != 2 {
-		t.Fatalf("unexpected user stats %v", stats.ByUser)
+	if result.ByUser["u1"] != 2 {
+		t.Fatalf("unexpected user stats %v", result.ByUser)
+	}
+	if count, ok := result.ByUser["u2"]; !ok || count != 0 {
+		t.Fatalf("zero-assignment user must be reported, got %v", result.ByUser)
+	}
+	if result.ByPullRequest["pr-1"] != 1 {
+		t.Fatalf("unexpected pr stats %v", result.ByPullRequest)
 	}
-	if stats.ByPullRequest["pr-1"] != 1 {
-		t.Fatalf("unexpected pr stats %v", stats.ByPullRequest)
+	if len(result.Reviewers) != 2 || result.Reviewers[0].Open != 1 || result.Reviewers[0].Merged != 1 {
+		t.Fatalf("unexpected reviewers %+v", result.Reviewers)
 	}
 }
 
 func TestService_GetAssignmentsError(t *testing.T) {
 	expected := errors.New("boom")
 	repo := testStatsRepo{
-		userFn: func(ctx context.Context) (map[domain.UserID]int, error) {
+		userFn: func(ctx context.Context, f stats.Filter) ([]stats.ReviewerLoad, error) {
 			return nil, expected
 		},
 	}
 	s := &service{repo: repo}
-	if _, err := s.GetAssignments(context.Background()); !errors.Is(err, expected) {
+	if _, err := s.GetAssignments(context.Background(), requester.System(), stats.Filter{}); !errors.Is(err, expected) {
 		t.Fatalf("expected error %v, got %v", expected, err)
 	}
 }
+
+func TestService_GetAssignmentsInvalidFilter(t *testing.T) {
+	from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(-time.Hour)
+	s := &service{repo: testStatsRepo{}}
+	_, err := s.GetAssignments(context.Background(), requester.System(), stats.Filter{From: &from, To: &to})
+	if !errors.Is(err, domain.ErrInvalidStatsFilter) {
+		t.Fatalf("expected ErrInvalidStatsFilter, got %v", err)
+	}
+}
+
+func TestService_GetSummary(t *testing.T) {
+	repo := testStatsRepo{
+		userFn: func(ctx context.Context, f stats.Filter) ([]stats.ReviewerLoad, error) {
+			return []stats.ReviewerLoad{
+				{UserID: "u1", IsActive: true, Open: 2, Merged: 1},
+				{UserID: "u2", IsActive: true},
+				{UserID: "u3", IsActive: false, Merged: 1},
+			}, nil
+		},
+		prFn: func(ctx context.Context, f stats.Filter) (map[domain.PullRequestID]int, error) {
+			return map[domain.PullRequestID]int{"pr-1": 2, "pr-2": 2}, nil
+		},
+	}
+	s := &service{repo: repo}
+	summary, err := s.GetSummary(context.Background(), requester.System(), stats.Filter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Summary{
+		UsersCount:                    3,
+		ActiveUsersWithoutAssignments: 1,
+		PullRequestsCount:             2,
+		AssignmentsTotal:              4,
+		OpenAssignments:               2,
+		MergedAssignments:             2,
+	}
+	if summary != want {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+}
+
+func TestService_GetCycleTime(t *testing.T) {
+	week := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	repo := testStatsRepo{
+		cycle: []stats.DurationGroup{
+			{Dimension: stats.DimensionTotal, Percentiles: stats.Percentiles{Count: 3, P50: time.Hour, P90: 2 * time.Hour, P99: 3 * time.Hour}},
+			{Dimension: stats.DimensionTeam, Team: "backend", Percentiles: stats.Percentiles{Count: 3, P50: time.Hour}},
+			{Dimension: stats.DimensionWeek, Week: week, Percentiles: stats.Percentiles{Count: 3, P50: time.Hour}},
+		},
+		review: []stats.DurationGroup{
+			{Dimension: stats.DimensionReviewer, ReviewerID: "u1", Percentiles: stats.Percentiles{Count: 1, P50: 30 * time.Minute}},
+		},
+		rates: []stats.ReassignmentGroup{
+			{Dimension: stats.DimensionTotal, PullRequests: 4, Reassigned: 1, Reassignments: 2},
+		},
+	}
+	s := &service{repo: repo}
+	report, err := s.GetCycleTime(context.Background(), requester.System(), stats.Filter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.CreateToMerge.Overall.P90Seconds != 7200 || report.CreateToMerge.Overall.Count != 3 {
+		t.Fatalf("unexpected overall %+v", report.CreateToMerge.Overall)
+	}
+	if len(report.CreateToMerge.ByTeam) != 1 || report.CreateToMerge.ByTeam[0].TeamName != "backend" {
+		t.Fatalf("unexpected by team %+v", report.CreateToMerge.ByTeam)
+	}
+	if len(report.CreateToMerge.ByWeek) != 1 || report.CreateToMerge.ByWeek[0].Week != "2025-01-06" {
+		t.Fatalf("unexpected by week %+v", report.CreateToMerge.ByWeek)
+	}
+	if len(report.AssignmentToMerge.ByReviewer) != 1 || report.AssignmentToMerge.ByReviewer[0].P50Seconds != 1800 {
+		t.Fatalf("unexpected by reviewer %+v", report.AssignmentToMerge.ByReviewer)
+	}
+	if report.Reassignment.Overall.Rate != 0.25 {
+		t.Fatalf("unexpected reassignment rate %+v", report.Reassignment.Overall)
+	}
+}
+
+func TestService_GetCycleTimeRejectsStatus(t *testing.T) {
+	s := &service{repo: testStatsRepo{}}
+	_, err := s.GetCycleTime(context.Background(), requester.System(), stats.Filter{Status: domain.PullRequestStatusOpen})
+	if !errors.Is(err, domain.ErrInvalidStatsFilter) {
+		t.Fatalf("expected ErrInvalidStatsFilter, got %v", err)
+	}
+}
+
+type testTeamRepo struct {
+	teams map[domain.TeamName]domainteam.Team
+}
+
+func (r testTeamRepo) ListTeamNames(ctx context.Context) ([]domain.TeamName, error) {
+	return []domain.TeamName{"backend"}, nil
+}
+
+func (r testTeamRepo) GetTeam(ctx context.Context, name domain.TeamName) (domainteam.Team, error) {
+	return r.teams[name], nil
+}
+
+func TestService_GetFairness(t *testing.T) {
+	members := make([]user.User, 0)
+	for _, m := range []struct {
+		id     domain.UserID
+		active bool
+	}{{"u1", true}, {"u2", true}, {"u3", true}, {"u4", false}} {
+		u, err := user.New(m.id, string(m.id), "backend", m.active)
+		if err != nil {
+			t.Fatalf("user: %v", err)
+		}
+		members = append(members, u)
+	}
+	backend, err := domainteam.New("backend", members)
+	if err != nil {
+		t.Fatalf("team: %v", err)
+	}
+	repo := testStatsRepo{
+		userFn: func(ctx context.Context, f stats.Filter) ([]stats.ReviewerLoad, error) {
+			return []stats.ReviewerLoad{
+				{UserID: "u1", Open: 3, Merged: 1},
+				{UserID: "u2", Open: 2},
+				{UserID: "u4", Merged: 5},
+			}, nil
+		},
+	}
+	s := &service{repo: repo, teams: testTeamRepo{teams: map[domain.TeamName]domainteam.Team{"backend": backend}}}
+
+	report, err := s.GetFairness(context.Background(), requester.System(), stats.Filter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Teams) != 1 {
+		t.Fatalf("unexpected teams %+v", report.Teams)
+	}
+	got := report.Teams[0]
+	if got.ActiveMembers != 3 || got.AssignmentsTotal != 6 {
+		t.Fatalf("inactive members must be excluded, got %+v", got)
+	}
+	if got.MostLoaded.Assignments != 4 || len(got.MostLoaded.UserIDs) != 1 || got.MostLoaded.UserIDs[0] != "u1" {
+		t.Fatalf("unexpected most loaded %+v", got.MostLoaded)
+	}
+	if got.LeastLoaded.Assignments != 0 || got.LeastLoaded.UserIDs[0] != "u3" {
+		t.Fatalf("unexpected least loaded %+v", got.LeastLoaded)
+	}
+	if got.Members[0].ShareVsEven != 2 {
+		t.Fatalf("u1 holds 4 of 6 with even share 1/3, got %+v", got.Members[0])
+	}
+	if got.Gini <= 0 || got.CoefficientOfVariation <= 0 {
+		t.Fatalf("uneven load must produce positive metrics, got %+v", got)
+	}
+}
+
+func TestService_StatsRequireScope(t *testing.T) {
+	s := &service{repo: testStatsRepo{}}
+	if _, err := s.GetSummary(context.Background(), requester.New("lead", requester.RoleTeamLead), stats.Filter{}); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Fatalf("team lead must not read global stats, got %v", err)
+	}
+	if _, err := s.GetSummary(context.Background(), requester.New("root", requester.RoleAdmin), stats.Filter{}); err != nil {
+		t.Fatalf("admin stats: %v", err)
+	}
+}
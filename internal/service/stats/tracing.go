@@ -0,0 +1,48 @@
+package statsservice
+
+import (
+	"context"
+
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/stats"
+	"github.com/mashhkensss/PR-service/internal/tracing"
+)
+
+type tracedService struct {
+	next Service
+}
+
+// WithTracing оборачивает каждый метод сервиса в спан
+func WithTracing(next Service) Service {
+	return tracedService{next: next}
+}
+
+func (t tracedService) GetAssignments(ctx context.Context, actor requester.Requester, f stats.Filter) (AssignmentsStats, error) {
+	ctx, span := tracing.Start(ctx, "stats.GetAssignments")
+	res, err := t.next.GetAssignments(ctx, actor, f)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) GetSummary(ctx context.Context, actor requester.Requester, f stats.Filter) (Summary, error) {
+	ctx, span := tracing.Start(ctx, "stats.GetSummary")
+	res, err := t.next.GetSummary(ctx, actor, f)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) GetCycleTime(ctx context.Context, actor requester.Requester, f stats.Filter) (CycleTimeReport, error) {
+	ctx, span := tracing.Start(ctx, "stats.GetCycleTime")
+	res, err := t.next.GetCycleTime(ctx, actor, f)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) GetFairness(ctx context.Context, actor requester.Requester, f stats.Filter) (FairnessReport, error) {
+	ctx, span := tracing.Start(ctx, "stats.GetFairness")
+	res, err := t.next.GetFairness(ctx, actor, f)
+	tracing.End(span, err)
+	return res, err
+}
+
+var _ Service = tracedService{}
@@ -0,0 +1,73 @@
+package streamservice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/team"
+	"github.com/mashhkensss/PR-service/internal/stream"
+)
+
+type TeamRepository interface {
+	GetTeam(ctx context.Context, name domain.TeamName) (team.Team, error)
+}
+
+type Service interface {
+	// Subscribe открывает поток назначений. Без команды пользователь получает свои назначения,
+	// администратор — все; с командой — назначения её участников (администратору и тимлиду команды).
+	// lastEventID > 0 продолжает поток после этой записи, иначе отдаются только новые записи
+	Subscribe(ctx context.Context, actor requester.Requester, teamName domain.TeamName, lastEventID int64) (*stream.Subscription, error)
+}
+
+type service struct {
+	log    stream.Storage
+	broker *stream.Broker
+	teams  TeamRepository
+	batch  int
+}
+
+func New(log stream.Storage, broker *stream.Broker, teams TeamRepository, batch int) Service {
+	return &service{log: log, broker: broker, teams: teams, batch: batch}
+}
+
+func (s *service) Subscribe(ctx context.Context, actor requester.Requester, teamName domain.TeamName, lastEventID int64) (*stream.Subscription, error) {
+	filter, err := s.filter(ctx, actor, teamName)
+	if err != nil {
+		return nil, err
+	}
+	var from stream.Position
+	if lastEventID > 0 {
+		// удалённая по сроку запись даёт нулевую позицию: клиент получит всё, что осталось в журнале
+		from, err = s.log.Locate(ctx, lastEventID)
+	} else {
+		from, err = s.log.Head(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load stream position: %w", err)
+	}
+	return s.broker.Subscribe(filter, from, s.batch), nil
+}
+
+func (s *service) filter(ctx context.Context, actor requester.Requester, teamName domain.TeamName) (stream.Filter, error) {
+	if teamName == "" {
+		if !actor.CanViewAssignments(actor.UserID(), team.Team{}) {
+			return stream.Filter{}, domain.ErrPermissionDenied
+		}
+		if actor.IsAdmin() {
+			return stream.Filter{}, nil
+		}
+		return stream.Filter{ReviewerID: actor.UserID()}, nil
+	}
+	t, err := s.teams.GetTeam(ctx, teamName)
+	if err != nil {
+		return stream.Filter{}, fmt.Errorf("load team: %w", err)
+	}
+	if !actor.CanViewAssignments("", t) {
+		return stream.Filter{}, domain.ErrPermissionDenied
+	}
+	return stream.Filter{TeamName: teamName}, nil
+}
+
+var _ Service = (*service)(nil)
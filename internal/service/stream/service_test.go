@@ -0,0 +1,121 @@
+package streamservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/team"
+	"github.com/mashhkensss/PR-service/internal/domain/user"
+	"github.com/mashhkensss/PR-service/internal/stream"
+)
+
+type testLog struct {
+	entries []stream.Entry
+}
+
+func (l *testLog) Append(context.Context, ...stream.Entry) error { return nil }
+
+func (l *testLog) After(_ context.Context, f stream.Filter, after stream.Position, limit int) ([]stream.Entry, error) {
+	var res []stream.Entry
+	for _, e := range l.entries {
+		if e.ID <= after.ID {
+			continue
+		}
+		if (f.ReviewerID != "" && e.ReviewerID != f.ReviewerID) || (f.TeamName != "" && e.TeamName != f.TeamName) {
+			continue
+		}
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+func (l *testLog) Head(context.Context) (stream.Position, error) {
+	n := int64(len(l.entries))
+	return stream.Position{XID: n, ID: n}, nil
+}
+
+func (l *testLog) Locate(_ context.Context, id int64) (stream.Position, error) {
+	return stream.Position{XID: id, ID: id}, nil
+}
+
+func (l *testLog) Purge(context.Context, time.Time, int) (int64, error) { return 0, nil }
+
+type testTeamRepo struct {
+	teams map[domain.TeamName]team.Team
+}
+
+func (r testTeamRepo) GetTeam(_ context.Context, name domain.TeamName) (team.Team, error) {
+	if t, ok := r.teams[name]; ok {
+		return t, nil
+	}
+	return team.Team{}, sql.ErrNoRows
+}
+
+func newTestService(t *testing.T) (*service, *testLog) {
+	t.Helper()
+	lead, _ := user.New("lead", "Lena", "backend", true)
+	member, _ := user.New("u1", "Alice", "backend", true)
+	backend, _ := team.New("backend", []user.User{lead, member})
+	log := &testLog{entries: []stream.Entry{
+		{ID: 1, Kind: stream.KindAssigned, ReviewerID: "u1", TeamName: "backend"},
+		{ID: 2, Kind: stream.KindAssigned, ReviewerID: "u2", TeamName: "frontend"},
+		{ID: 3, Kind: stream.KindMerged, ReviewerID: "u1", TeamName: "backend"},
+	}}
+	broker := stream.NewBroker(log, time.Hour, 0, nil)
+	return &service{log: log, broker: broker, teams: testTeamRepo{teams: map[domain.TeamName]team.Team{"backend": backend}}, batch: 10}, log
+}
+
+func TestSubscribeFiltersOwnAssignments(t *testing.T) {
+	s, _ := newTestService(t)
+	sub, err := s.Subscribe(context.Background(), requester.New("u2", requester.RoleUser), "", 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries, err := sub.Next(context.Background())
+	if err != nil || len(entries) != 1 || entries[0].ID != 2 {
+		t.Fatalf("user must see only own assignments, got %+v err=%v", entries, err)
+	}
+}
+
+func TestSubscribeWithoutLastEventIDStartsAtTheEnd(t *testing.T) {
+	s, _ := newTestService(t)
+	sub, err := s.Subscribe(context.Background(), requester.New("root", requester.RoleAdmin), "", 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub.Cursor() != 3 {
+		t.Fatalf("expected cursor at the last entry, got %d", sub.Cursor())
+	}
+}
+
+func TestSubscribeTeamPermissions(t *testing.T) {
+	s, _ := newTestService(t)
+	tests := []struct {
+		name  string
+		actor requester.Requester
+		team  domain.TeamName
+		err   error
+	}{
+		{"admin any team", requester.New("root", requester.RoleAdmin), "backend", nil},
+		{"lead of team", requester.New("lead", requester.RoleTeamLead), "backend", nil},
+		{"member of team", requester.New("u1", requester.RoleUser), "backend", domain.ErrPermissionDenied},
+		{"no scope", requester.New("u1", requester.RoleUser, requester.ScopeTeamsRead), "", domain.ErrPermissionDenied},
+		{"unknown team", requester.New("root", requester.RoleAdmin), "ghost", sql.ErrNoRows},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Subscribe(context.Background(), tt.actor, tt.team, 0)
+			if tt.err == nil && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package streamservice
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/stream"
+	"github.com/mashhkensss/PR-service/internal/tracing"
+)
+
+type tracedService struct {
+	next Service
+}
+
+// WithTracing оборачивает открытие подписки в спан; сам поток живёт дольше запроса и не трассируется
+func WithTracing(next Service) Service {
+	return tracedService{next: next}
+}
+
+func (t tracedService) Subscribe(ctx context.Context, actor requester.Requester, teamName domain.TeamName, lastEventID int64) (*stream.Subscription, error) {
+	ctx, span := tracing.Start(ctx, "stream.Subscribe", attribute.String("team_name", string(teamName)), attribute.Int64("last_event_id", lastEventID))
+	res, err := t.next.Subscribe(ctx, actor, teamName, lastEventID)
+	tracing.End(span, err)
+	return res, err
+}
@@ -2,37 +2,75 @@ package teamservice
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/event"
 	"github.com/mashhkensss/PR-service/internal/domain/requester"
 	"github.com/mashhkensss/PR-service/internal/domain/team"
+	"github.com/mashhkensss/PR-service/internal/domain/user"
 	"github.com/mashhkensss/PR-service/internal/service"
 )
 
 type Repository interface {
 	SaveTeam(ctx context.Context, t team.Team) error
 	GetTeam(ctx context.Context, name domain.TeamName) (team.Team, error)
+	SaveMember(ctx context.Context, member user.User, expected domain.Version) error
+	RemoveMember(ctx context.Context, name domain.TeamName, id domain.UserID, expected domain.Version) error
+}
+
+type UserRepository interface {
+	GetUser(ctx context.Context, id domain.UserID) (user.User, error)
 }
 
 type Service interface {
-	AddTeam(ctx context.Context, aggregate team.Team) (team.Team, error)
+	AddTeam(ctx context.Context, actor requester.Requester, aggregate team.Team) (team.Team, error)
 	GetTeam(ctx context.Context, name domain.TeamName) (team.Team, error)
 	GetTeamForUser(ctx context.Context, actor requester.Requester, name domain.TeamName) (team.Team, error)
+	// AddMember добавляет участника в команду или обновляет его. Перевести пользователя
+	// из другой команды можно, только если actor управляет и ею
+	AddMember(ctx context.Context, actor requester.Requester, member user.User, expected domain.Version) (team.Team, error)
+	// RemoveMember удаляет участника без PR и ревью; участника с историей остаётся деактивировать
+	RemoveMember(ctx context.Context, actor requester.Requester, name domain.TeamName, id domain.UserID, expected domain.Version) (team.Team, error)
 }
 
 type svc struct {
-	repo Repository
-	tx   service.TxRunner
+	repo   Repository
+	users  UserRepository
+	tx     service.TxRunner
+	events service.EventStore
 }
 
-func New(repo Repository, tx service.TxRunner) Service {
-	return &svc{repo: repo, tx: tx}
+// activityWrite — как в userservice: событие активности пишется по прочитанному состоянию,
+// и параллельное изменение той же строки должно упасть конфликтом, а не записать событие дважды
+var activityWrite = service.TxOptions{Isolation: service.IsolationRepeatableRead}
+
+func New(repo Repository, users UserRepository, tx service.TxRunner, events service.EventStore) Service {
+	return &svc{repo: repo, users: users, tx: tx, events: events}
 }
 
-func (s *svc) AddTeam(ctx context.Context, aggregate team.Team) (team.Team, error) {
-	err := service.ExecInTx(ctx, s.tx, func(ctx context.Context) error {
-		return s.repo.SaveTeam(ctx, aggregate)
+func (s *svc) AddTeam(ctx context.Context, actor requester.Requester, aggregate team.Team) (team.Team, error) {
+	if !actor.CanCreateTeam() {
+		return team.Team{}, domain.ErrPermissionDenied
+	}
+	err := service.ExecInTxWith(ctx, s.tx, activityWrite, func(ctx context.Context) error {
+		var events []event.Event
+		for _, member := range aggregate.Members() {
+			current, err := s.users.GetUser(ctx, member.UserID())
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("load user: %w", err)
+			}
+			events = append(events, current.WithActivity(member.IsActive()).Events()...)
+		}
+		if err := s.repo.SaveTeam(ctx, aggregate); err != nil {
+			return err
+		}
+		return appendEvents(ctx, s.events, events)
 	})
 	if err != nil {
 		return team.Team{}, fmt.Errorf("save team: %w", err)
@@ -61,4 +99,78 @@ func (s *svc) GetTeamForUser(ctx context.Context, actor requester.Requester, nam
 	return team.Team{}, domain.ErrTeamAccessDenied
 }
 
+func (s *svc) AddMember(ctx context.Context, actor requester.Requester, member user.User, expected domain.Version) (team.Team, error) {
+	name := member.TeamName()
+	return service.RunInTxWith(ctx, s.tx, activityWrite, func(ctx context.Context) (team.Team, error) {
+		if _, err := s.manageable(ctx, actor, name); err != nil {
+			return team.Team{}, err
+		}
+		var events []event.Event
+		current, err := s.users.GetUser(ctx, member.UserID())
+		if err == nil {
+			events = current.WithActivity(member.IsActive()).Events()
+		}
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+		case err != nil:
+			return team.Team{}, fmt.Errorf("load user: %w", err)
+		case current.TeamName() != name:
+			if _, err := s.manageable(ctx, actor, current.TeamName()); err != nil {
+				if errors.Is(err, domain.ErrPermissionDenied) {
+					return team.Team{}, fmt.Errorf("%w: %s", domain.ErrTeamMismatch, current.TeamName())
+				}
+				return team.Team{}, err
+			}
+		}
+		if err := s.repo.SaveMember(ctx, member, expected); err != nil {
+			return team.Team{}, fmt.Errorf("save member: %w", err)
+		}
+		if err := appendEvents(ctx, s.events, events); err != nil {
+			return team.Team{}, err
+		}
+		return s.GetTeam(ctx, name)
+	})
+}
+
+func (s *svc) RemoveMember(ctx context.Context, actor requester.Requester, name domain.TeamName, id domain.UserID, expected domain.Version) (team.Team, error) {
+	return service.RunInTx(ctx, s.tx, func(ctx context.Context) (team.Team, error) {
+		t, err := s.manageable(ctx, actor, name)
+		if err != nil {
+			return team.Team{}, err
+		}
+		if _, ok := t.Member(id); !ok {
+			return team.Team{}, fmt.Errorf("%w: %s", domain.ErrUserNotFound, id)
+		}
+		if err := s.repo.RemoveMember(ctx, name, id, expected); err != nil {
+			return team.Team{}, fmt.Errorf("remove member: %w", err)
+		}
+		return s.GetTeam(ctx, name)
+	})
+}
+
+// manageable загружает команду и проверяет право actor управлять ею. Тот, кто не управляет
+// любой командой, получает отказ и на несуществующую, чтобы не раскрывать список команд
+func (s *svc) manageable(ctx context.Context, actor requester.Requester, name domain.TeamName) (team.Team, error) {
+	t, err := s.repo.GetTeam(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) && !actor.CanManageTeam(team.Team{}) {
+		return team.Team{}, domain.ErrPermissionDenied
+	}
+	if err != nil {
+		return team.Team{}, fmt.Errorf("get team: %w", err)
+	}
+	if !actor.CanManageTeam(t) {
+		return team.Team{}, domain.ErrPermissionDenied
+	}
+	return t, nil
+}
+
+// appendEvents — upsert участника перезаписывает is_active, поэтому изменение активности
+// через команду уходит в outbox так же, как через SetIsActive
+func appendEvents(ctx context.Context, store service.EventStore, events []event.Event) error {
+	if err := service.AppendEvents(ctx, store, events); err != nil {
+		return fmt.Errorf("append events: %w", err)
+	}
+	return nil
+}
+
 var _ Service = (*svc)(nil)
@@ -2,16 +2,69 @@ package teamservice
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/event"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
 	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
+	domainuser "github.com/mashhkensss/PR-service/internal/domain/user"
+	"github.com/mashhkensss/PR-service/internal/service"
 )
 
 type testTeamRepo struct {
-	saveFn func(ctx context.Context, t domainteam.Team) error
-	getFn  func(ctx context.Context, name domain.TeamName) (domainteam.Team, error)
+	saveFn   func(ctx context.Context, t domainteam.Team) error
+	getFn    func(ctx context.Context, name domain.TeamName) (domainteam.Team, error)
+	memberFn func(ctx context.Context, member domainuser.User, expected domain.Version) error
+	removeFn func(ctx context.Context, name domain.TeamName, id domain.UserID, expected domain.Version) error
+}
+
+func (r testTeamRepo) SaveMember(ctx context.Context, member domainuser.User, expected domain.Version) error {
+	if r.memberFn != nil {
+		return r.memberFn(ctx, member, expected)
+	}
+	return nil
+}
+
+func (r testTeamRepo) RemoveMember(ctx context.Context, name domain.TeamName, id domain.UserID, expected domain.Version) error {
+	if r.removeFn != nil {
+		return r.removeFn(ctx, name, id, expected)
+	}
+	return nil
+}
+
+type testUserRepo map[domain.UserID]domainuser.User
+
+func (r testUserRepo) GetUser(_ context.Context, id domain.UserID) (domainuser.User, error) {
+	u, ok := r[id]
+	if !ok {
+		return domainuser.User{}, fmt.Errorf("get user: %w", sql.ErrNoRows)
+	}
+	return u, nil
+}
+
+// teams строит репозиторий с двумя командами: u1 — тимлид backend, u2 — его участник, u3 — участник frontend
+func teams(t *testing.T) testTeamRepo {
+	t.Helper()
+	lead, _ := domainuser.New("u1", "Alice", "backend", true)
+	dev, _ := domainuser.New("u2", "Bob", "backend", true)
+	other, _ := domainuser.New("u3", "Carol", "frontend", true)
+	backend, _ := domainteam.New("backend", []domainuser.User{lead, dev})
+	frontend, _ := domainteam.New("frontend", []domainuser.User{other})
+	return testTeamRepo{
+		getFn: func(_ context.Context, name domain.TeamName) (domainteam.Team, error) {
+			switch name {
+			case "backend":
+				return backend, nil
+			case "frontend":
+				return frontend, nil
+			}
+			return domainteam.Team{}, fmt.Errorf("get team: %w", sql.ErrNoRows)
+		},
+	}
 }
 
 func (r testTeamRepo) SaveTeam(ctx context.Context, team domainteam.Team) error {
@@ -33,6 +86,10 @@ type fakeTx struct {
 }
 
 func (f fakeTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return f.WithinTxOptions(ctx, service.TxOptions{}, fn)
+}
+
+func (f fakeTx) WithinTxOptions(ctx context.Context, _ service.TxOptions, fn func(ctx context.Context) error) error {
 	if f.err != nil {
 		return f.err
 	}
@@ -52,7 +109,7 @@ func TestService_AddTeam(t *testing.T) {
 		},
 		tx: fakeTx{},
 	}
-	got, err := s.AddTeam(context.Background(), expected)
+	got, err := s.AddTeam(context.Background(), requester.System(), expected)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -86,7 +143,156 @@ func TestService_AddTeamTxError(t *testing.T) {
 		tx:   fakeTx{err: unexpected},
 	}
 	team, _ := domainteam.New("backend", nil)
-	if _, err := s.AddTeam(context.Background(), team); !errors.Is(err, unexpected) {
+	if _, err := s.AddTeam(context.Background(), requester.System(), team); !errors.Is(err, unexpected) {
 		t.Fatalf("expected tx error, got %v", err)
 	}
 }
+
+func TestService_AddTeamRequiresAdmin(t *testing.T) {
+	s := &svc{
+		repo: testTeamRepo{
+			saveFn: func(context.Context, domainteam.Team) error {
+				t.Fatalf("team must not be saved")
+				return nil
+			},
+		},
+		tx: fakeTx{},
+	}
+	team, _ := domainteam.New("backend", nil)
+	for _, role := range []requester.Role{requester.RoleUser, requester.RoleTeamLead} {
+		if _, err := s.AddTeam(context.Background(), requester.New("u1", role), team); !errors.Is(err, domain.ErrPermissionDenied) {
+			t.Fatalf("%s: expected ErrPermissionDenied, got %v", role, err)
+		}
+	}
+}
+
+func TestService_AddMemberByLead(t *testing.T) {
+	repo := teams(t)
+	var saved domainuser.User
+	repo.memberFn = func(_ context.Context, member domainuser.User, expected domain.Version) error {
+		if expected != 3 {
+			t.Fatalf("expected version 3, got %d", expected)
+		}
+		saved = member
+		return nil
+	}
+	s := &svc{repo: repo, users: testUserRepo{}, tx: fakeTx{}}
+	member, _ := domainuser.New("u4", "Dave", "backend", true)
+	if _, err := s.AddMember(context.Background(), requester.New("u1", requester.RoleTeamLead), member, 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if saved.UserID() != "u4" {
+		t.Fatalf("member not saved: %+v", saved)
+	}
+}
+
+type recordingEvents struct {
+	events []event.Event
+}
+
+func (r *recordingEvents) Append(_ context.Context, events ...event.Event) error {
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func TestService_AddMemberAppendsActivityEvent(t *testing.T) {
+	dev, _ := domainuser.New("u2", "Bob", "backend", true)
+	events := &recordingEvents{}
+	s := &svc{repo: teams(t), users: testUserRepo{"u2": dev}, tx: fakeTx{}, events: events}
+	lead := requester.New("u1", requester.RoleTeamLead)
+
+	renamed, _ := domainuser.New("u2", "Robert", "backend", true)
+	if _, err := s.AddMember(context.Background(), lead, renamed, domain.AnyVersion); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events.events) != 0 {
+		t.Fatalf("unchanged activity must not produce events, got %+v", events.events)
+	}
+	inactive, _ := domainuser.New("u2", "Bob", "backend", false)
+	if _, err := s.AddMember(context.Background(), lead, inactive, domain.AnyVersion); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events.events) != 1 || events.events[0].Type != event.TypeUserActivityChanged {
+		t.Fatalf("expected UserActivityChanged, got %+v", events.events)
+	}
+}
+
+func TestService_AddTeamAppendsActivityEvents(t *testing.T) {
+	dev, _ := domainuser.New("u2", "Bob", "backend", true)
+	events := &recordingEvents{}
+	s := &svc{repo: testTeamRepo{}, users: testUserRepo{"u2": dev}, tx: fakeTx{}, events: events}
+
+	inactive, _ := domainuser.New("u2", "Bob", "backend", false)
+	newcomer, _ := domainuser.New("u4", "Dave", "backend", true)
+	team, _ := domainteam.New("backend", []domainuser.User{inactive, newcomer})
+	if _, err := s.AddTeam(context.Background(), requester.System(), team); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events.events) != 1 || events.events[0].Type != event.TypeUserActivityChanged {
+		t.Fatalf("expected one UserActivityChanged for u2, got %+v", events.events)
+	}
+}
+
+func TestService_AddMemberDenied(t *testing.T) {
+	repo := teams(t)
+	repo.memberFn = func(context.Context, domainuser.User, domain.Version) error {
+		t.Fatalf("member must not be saved")
+		return nil
+	}
+	s := &svc{repo: repo, users: testUserRepo{}, tx: fakeTx{}}
+	cases := map[string]struct {
+		actor requester.Requester
+		team  domain.TeamName
+	}{
+		"plain member":      {requester.New("u2", requester.RoleUser), "backend"},
+		"lead of another":   {requester.New("u1", requester.RoleTeamLead), "frontend"},
+		"lead without team": {requester.New("u1", requester.RoleTeamLead), "missing"},
+		"read-only scopes":  {requester.New("u1", requester.RoleTeamLead, requester.ScopeTeamsRead), "backend"},
+	}
+	for name, tc := range cases {
+		member, _ := domainuser.New("u4", "Dave", tc.team, true)
+		if _, err := s.AddMember(context.Background(), tc.actor, member, domain.AnyVersion); !errors.Is(err, domain.ErrPermissionDenied) {
+			t.Fatalf("%s: expected ErrPermissionDenied, got %v", name, err)
+		}
+	}
+}
+
+func TestService_AddMemberFromForeignTeam(t *testing.T) {
+	repo := teams(t)
+	repo.memberFn = func(context.Context, domainuser.User, domain.Version) error {
+		t.Fatalf("member must not be moved")
+		return nil
+	}
+	other, _ := domainuser.New("u3", "Carol", "frontend", true)
+	s := &svc{repo: repo, users: testUserRepo{"u3": other}, tx: fakeTx{}}
+	moved, _ := domainuser.New("u3", "Carol", "backend", true)
+	if _, err := s.AddMember(context.Background(), requester.New("u1", requester.RoleTeamLead), moved, domain.AnyVersion); !errors.Is(err, domain.ErrTeamMismatch) {
+		t.Fatalf("expected ErrTeamMismatch, got %v", err)
+	}
+}
+
+func TestService_RemoveMember(t *testing.T) {
+	repo := teams(t)
+	var removed domain.UserID
+	repo.removeFn = func(_ context.Context, name domain.TeamName, id domain.UserID, _ domain.Version) error {
+		if name != "backend" {
+			t.Fatalf("unexpected team %s", name)
+		}
+		removed = id
+		return nil
+	}
+	s := &svc{repo: repo, users: testUserRepo{}, tx: fakeTx{}}
+	lead := requester.New("u1", requester.RoleTeamLead)
+	if _, err := s.RemoveMember(context.Background(), lead, "backend", "u2", domain.AnyVersion); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if removed != "u2" {
+		t.Fatalf("expected u2 removed, got %q", removed)
+	}
+	if _, err := s.RemoveMember(context.Background(), lead, "backend", "u3", domain.AnyVersion); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("expected ErrUserNotFound for foreign user, got %v", err)
+	}
+	if _, err := s.RemoveMember(context.Background(), lead, "frontend", "u3", domain.AnyVersion); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Fatalf("expected ErrPermissionDenied, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package teamservice
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/team"
+	"github.com/mashhkensss/PR-service/internal/domain/user"
+	"github.com/mashhkensss/PR-service/internal/tracing"
+)
+
+type tracedService struct {
+	next Service
+}
+
+// WithTracing оборачивает каждый метод сервиса в спан
+func WithTracing(next Service) Service {
+	return tracedService{next: next}
+}
+
+func (t tracedService) AddTeam(ctx context.Context, actor requester.Requester, aggregate team.Team) (team.Team, error) {
+	ctx, span := tracing.Start(ctx, "team.AddTeam", attribute.String("team_name", string(aggregate.TeamName())))
+	res, err := t.next.AddTeam(ctx, actor, aggregate)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) GetTeam(ctx context.Context, name domain.TeamName) (team.Team, error) {
+	ctx, span := tracing.Start(ctx, "team.GetTeam", attribute.String("team_name", string(name)))
+	res, err := t.next.GetTeam(ctx, name)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) GetTeamForUser(ctx context.Context, actor requester.Requester, name domain.TeamName) (team.Team, error) {
+	ctx, span := tracing.Start(ctx, "team.GetTeamForUser", attribute.String("team_name", string(name)))
+	res, err := t.next.GetTeamForUser(ctx, actor, name)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) AddMember(ctx context.Context, actor requester.Requester, member user.User, expected domain.Version) (team.Team, error) {
+	ctx, span := tracing.Start(ctx, "team.AddMember",
+		attribute.String("team_name", string(member.TeamName())),
+		attribute.String("user_id", string(member.UserID())),
+	)
+	res, err := t.next.AddMember(ctx, actor, member, expected)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) RemoveMember(ctx context.Context, actor requester.Requester, name domain.TeamName, id domain.UserID, expected domain.Version) (team.Team, error) {
+	ctx, span := tracing.Start(ctx, "team.RemoveMember",
+		attribute.String("team_name", string(name)),
+		attribute.String("user_id", string(id)),
+	)
+	res, err := t.next.RemoveMember(ctx, actor, name, id, expected)
+	tracing.End(span, err)
+	return res, err
+}
+
+var _ Service = tracedService{}
@@ -0,0 +1,36 @@
+package tokenservice
+
+import (
+	"context"
+
+	"github.com/mashhkensss/PR-service/internal/auth"
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+)
+
+// Issuer подписывает токены; реализуется auth.Signer
+type Issuer interface {
+	Issue(req auth.TokenRequest) (string, auth.Claims, error)
+}
+
+type Service interface {
+	// Issue выпускает токен от имени любого субъекта. Некорректный запрос — auth.ErrInvalidTokenRequest
+	Issue(ctx context.Context, actor requester.Requester, req auth.TokenRequest) (string, auth.Claims, error)
+}
+
+type service struct {
+	issuer Issuer
+}
+
+func New(issuer Issuer) Service {
+	return &service{issuer: issuer}
+}
+
+func (s *service) Issue(_ context.Context, actor requester.Requester, req auth.TokenRequest) (string, auth.Claims, error) {
+	if !actor.CanIssueTokens() {
+		return "", auth.Claims{}, domain.ErrPermissionDenied
+	}
+	return s.issuer.Issue(req)
+}
+
+var _ Service = (*service)(nil)
@@ -0,0 +1,40 @@
+package tokenservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/auth"
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+)
+
+func TestService_IssueRequiresTokensScope(t *testing.T) {
+	signer := auth.NewSigner(auth.SignerOptions{MaxTTL: time.Hour}, auth.HMACKey("", []byte("secret"), auth.RoleAdmin, auth.RoleUser))
+	s := New(signer)
+	req := auth.TokenRequest{Subject: "u1", Role: auth.RoleUser, TTL: time.Minute}
+
+	cases := map[string]struct {
+		actor requester.Requester
+		ok    bool
+	}{
+		"admin":           {requester.New("root", requester.RoleAdmin), true},
+		"system":          {requester.System(), true},
+		"admin narrowed":  {requester.New("root", requester.RoleAdmin, requester.ScopeStatsRead), false},
+		"team lead":       {requester.New("lead", requester.RoleTeamLead), false},
+		"unauthenticated": {requester.Anonymous(), false},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			token, _, err := s.Issue(context.Background(), tc.actor, req)
+			if tc.ok && (err != nil || token == "") {
+				t.Fatalf("expected token, got %v", err)
+			}
+			if !tc.ok && !errors.Is(err, domain.ErrPermissionDenied) {
+				t.Fatalf("expected permission denied, got %v", err)
+			}
+		})
+	}
+}
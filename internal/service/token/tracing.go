@@ -0,0 +1,29 @@
+package tokenservice
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/mashhkensss/PR-service/internal/auth"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/tracing"
+)
+
+type tracedService struct {
+	next Service
+}
+
+// WithTracing оборачивает каждый метод сервиса в спан
+func WithTracing(next Service) Service {
+	return tracedService{next: next}
+}
+
+func (t tracedService) Issue(ctx context.Context, actor requester.Requester, req auth.TokenRequest) (string, auth.Claims, error) {
+	ctx, span := tracing.Start(ctx, "token.Issue", attribute.String("subject", req.Subject), attribute.String("role", req.Role))
+	token, claims, err := t.next.Issue(ctx, actor, req)
+	tracing.End(span, err)
+	return token, claims, err
+}
+
+var _ Service = tracedService{}
@@ -0,0 +1,221 @@
+package transferservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
+	"github.com/mashhkensss/PR-service/internal/domain/transfer"
+	appservice "github.com/mashhkensss/PR-service/internal/service"
+)
+
+type Repository interface {
+	ExportTeams(ctx context.Context, fn func(domainteam.Team) error) error
+	ExportPullRequests(ctx context.Context, fn func(transfer.PullRequest) error) error
+	TeamExists(ctx context.Context, name domain.TeamName) (bool, error)
+	PullRequestExists(ctx context.Context, id domain.PullRequestID) (bool, error)
+	ExistingUsers(ctx context.Context, ids []domain.UserID) ([]domain.UserID, error)
+	UpsertTeam(ctx context.Context, t domainteam.Team) error
+	UpsertPullRequest(ctx context.Context, pr transfer.PullRequest) error
+}
+
+// Sink получает записи экспорта по мере чтения: сначала все команды, затем все PR
+type Sink interface {
+	Team(t domainteam.Team) error
+	PullRequest(pr transfer.PullRequest) error
+}
+
+type Service interface {
+	// Export выгружает команды с участниками и PR с назначениями из одного снимка базы
+	Export(ctx context.Context, actor requester.Requester, sink Sink) error
+	// Import записывает данные в одной транзакции. Если хотя бы одна запись отклонена,
+	// не записывается ничего, а причины возвращаются в отчёте
+	Import(ctx context.Context, actor requester.Requester, data transfer.Dataset, mode transfer.Mode) (transfer.Report, error)
+}
+
+type service struct {
+	repo Repository
+	tx   appservice.TxRunner
+}
+
+func New(repo Repository, tx appservice.TxRunner) Service {
+	return &service{repo: repo, tx: tx}
+}
+
+// importTx — проверка существования и запись не должны разойтись с параллельными изменениями
+var importTx = appservice.TxOptions{Isolation: appservice.IsolationSerializable}
+
+// errRejected откатывает транзакцию импорта, в которой есть отклонённые записи
+var errRejected = errors.New("import rejected")
+
+func (s *service) Export(ctx context.Context, actor requester.Requester, sink Sink) error {
+	if !actor.CanExportData() {
+		return domain.ErrPermissionDenied
+	}
+	return appservice.ExecInTxWith(ctx, s.tx, appservice.ReadOnlySnapshot, func(ctx context.Context) error {
+		if err := s.repo.ExportTeams(ctx, sink.Team); err != nil {
+			return fmt.Errorf("export teams: %w", err)
+		}
+		if err := s.repo.ExportPullRequests(ctx, sink.PullRequest); err != nil {
+			return fmt.Errorf("export pull requests: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *service) Import(ctx context.Context, actor requester.Requester, data transfer.Dataset, mode transfer.Mode) (transfer.Report, error) {
+	if !actor.CanImportData() {
+		return transfer.Report{}, domain.ErrPermissionDenied
+	}
+	if !mode.Valid() {
+		return transfer.Report{}, fmt.Errorf("%w: %q", domain.ErrInvalidImportMode, mode)
+	}
+	var report transfer.Report
+	err := appservice.ExecInTxWith(ctx, s.tx, importTx, func(ctx context.Context) error {
+		report = transfer.Report{}
+		if err := s.importTeams(ctx, data.Teams, mode, &report); err != nil {
+			return err
+		}
+		if err := s.importPullRequests(ctx, data.PullRequests, mode, &report); err != nil {
+			return err
+		}
+		if len(report.Errors) > 0 {
+			return errRejected
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errRejected) {
+		return transfer.Report{}, err
+	}
+	return report, nil
+}
+
+func (s *service) importTeams(ctx context.Context, teams []domainteam.Team, mode transfer.Mode, report *transfer.Report) error {
+	for i, t := range teams {
+		exists, conflict, err := s.teamConflict(ctx, t)
+		if err != nil {
+			return err
+		}
+		if conflict != nil {
+			switch mode {
+			case transfer.ModeFail:
+				report.Errors = append(report.Errors, transfer.RecordError{Kind: transfer.KindTeam, Index: i, ID: string(t.TeamName()), Err: conflict})
+				continue
+			case transfer.ModeSkip:
+				report.Teams.Skipped++
+				continue
+			}
+		}
+		if err := s.repo.UpsertTeam(ctx, t); err != nil {
+			return fmt.Errorf("import team %s: %w", t.TeamName(), err)
+		}
+		if exists {
+			report.Teams.Updated++
+		} else {
+			report.Teams.Created++
+		}
+	}
+	return nil
+}
+
+// teamConflict — команда уже есть или кто-то из участников уже заведён (в том числе в другой команде)
+func (s *service) teamConflict(ctx context.Context, t domainteam.Team) (exists bool, conflict, err error) {
+	exists, err = s.repo.TeamExists(ctx, t.TeamName())
+	if err != nil {
+		return false, nil, fmt.Errorf("check team %s: %w", t.TeamName(), err)
+	}
+	if exists {
+		return true, domain.ErrTeamExists, nil
+	}
+	members := t.Members()
+	ids := make([]domain.UserID, 0, len(members))
+	for _, m := range members {
+		ids = append(ids, m.UserID())
+	}
+	existing, err := s.repo.ExistingUsers(ctx, ids)
+	if err != nil {
+		return false, nil, fmt.Errorf("check members of %s: %w", t.TeamName(), err)
+	}
+	if len(existing) > 0 {
+		return false, fmt.Errorf("%w: %s", domain.ErrUserExists, joinIDs(existing)), nil
+	}
+	return false, nil, nil
+}
+
+func (s *service) importPullRequests(ctx context.Context, prs []transfer.PullRequest, mode transfer.Mode, report *transfer.Report) error {
+	// ссылки на пользователей проверяются заранее: нарушение внешнего ключа прервало бы транзакцию
+	// на первой же записи, а отчёт должен перечислить все
+	refs := make([]domain.UserID, 0, len(prs))
+	for _, pr := range prs {
+		refs = append(refs, users(pr)...)
+	}
+	existing, err := s.repo.ExistingUsers(ctx, refs)
+	if err != nil {
+		return fmt.Errorf("check pull request users: %w", err)
+	}
+	known := make(map[domain.UserID]struct{}, len(existing))
+	for _, id := range existing {
+		known[id] = struct{}{}
+	}
+
+	for i, pr := range prs {
+		id := pr.PullRequest.PullRequestID()
+		var missing []domain.UserID
+		for _, u := range users(pr) {
+			if _, ok := known[u]; !ok {
+				missing = append(missing, u)
+			}
+		}
+		if len(missing) > 0 {
+			report.Errors = append(report.Errors, transfer.RecordError{
+				Kind: transfer.KindPullRequest, Index: i, ID: string(id),
+				Err: fmt.Errorf("%w: %s", domain.ErrUserNotFound, joinIDs(missing)),
+			})
+			continue
+		}
+		exists, err := s.repo.PullRequestExists(ctx, id)
+		if err != nil {
+			return fmt.Errorf("check pull request %s: %w", id, err)
+		}
+		if exists {
+			switch mode {
+			case transfer.ModeFail:
+				report.Errors = append(report.Errors, transfer.RecordError{Kind: transfer.KindPullRequest, Index: i, ID: string(id), Err: domain.ErrPullRequestExists})
+				continue
+			case transfer.ModeSkip:
+				report.PullRequests.Skipped++
+				continue
+			}
+		}
+		if err := s.repo.UpsertPullRequest(ctx, pr); err != nil {
+			return fmt.Errorf("import pull request %s: %w", id, err)
+		}
+		if exists {
+			report.PullRequests.Updated++
+		} else {
+			report.PullRequests.Created++
+		}
+	}
+	return nil
+}
+
+func users(pr transfer.PullRequest) []domain.UserID {
+	ids := make([]domain.UserID, 0, 1+len(pr.Assignments))
+	ids = append(ids, pr.PullRequest.AuthorID())
+	for _, a := range pr.Assignments {
+		ids = append(ids, a.ReviewerID)
+	}
+	return ids
+}
+
+func joinIDs(ids []domain.UserID) string {
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, string(id))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,197 @@
+package transferservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
+	"github.com/mashhkensss/PR-service/internal/domain/transfer"
+	domainuser "github.com/mashhkensss/PR-service/internal/domain/user"
+	appservice "github.com/mashhkensss/PR-service/internal/service"
+)
+
+type repoStub struct {
+	teams    map[domain.TeamName]bool
+	users    map[domain.UserID]bool
+	prs      map[domain.PullRequestID]bool
+	upserted []string
+}
+
+func newRepoStub() *repoStub {
+	return &repoStub{teams: map[domain.TeamName]bool{}, users: map[domain.UserID]bool{}, prs: map[domain.PullRequestID]bool{}}
+}
+
+func (r *repoStub) ExportTeams(_ context.Context, fn func(domainteam.Team) error) error {
+	t, _ := domainteam.New("backend", nil)
+	return fn(t)
+}
+
+func (r *repoStub) ExportPullRequests(_ context.Context, fn func(transfer.PullRequest) error) error {
+	pr, _ := transfer.NewPullRequest("pr-1", "Add search", "u1", domain.PullRequestStatusOpen, time.Now(), nil, nil)
+	return fn(pr)
+}
+
+func (r *repoStub) TeamExists(_ context.Context, name domain.TeamName) (bool, error) {
+	return r.teams[name], nil
+}
+
+func (r *repoStub) PullRequestExists(_ context.Context, id domain.PullRequestID) (bool, error) {
+	return r.prs[id], nil
+}
+
+func (r *repoStub) ExistingUsers(_ context.Context, ids []domain.UserID) ([]domain.UserID, error) {
+	var found []domain.UserID
+	for _, id := range ids {
+		if r.users[id] {
+			found = append(found, id)
+		}
+	}
+	return found, nil
+}
+
+func (r *repoStub) UpsertTeam(_ context.Context, t domainteam.Team) error {
+	r.teams[t.TeamName()] = true
+	for _, m := range t.Members() {
+		r.users[m.UserID()] = true
+	}
+	r.upserted = append(r.upserted, string(t.TeamName()))
+	return nil
+}
+
+func (r *repoStub) UpsertPullRequest(_ context.Context, pr transfer.PullRequest) error {
+	r.prs[pr.PullRequest.PullRequestID()] = true
+	r.upserted = append(r.upserted, string(pr.PullRequest.PullRequestID()))
+	return nil
+}
+
+type recordingTx struct {
+	opts appservice.TxOptions
+	err  error
+}
+
+func (r *recordingTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.WithinTxOptions(ctx, appservice.TxOptions{}, fn)
+}
+
+func (r *recordingTx) WithinTxOptions(ctx context.Context, opts appservice.TxOptions, fn func(ctx context.Context) error) error {
+	r.opts = opts
+	r.err = fn(ctx)
+	return r.err
+}
+
+type sinkStub struct{ records []string }
+
+func (s *sinkStub) Team(t domainteam.Team) error {
+	s.records = append(s.records, "team:"+string(t.TeamName()))
+	return nil
+}
+
+func (s *sinkStub) PullRequest(pr transfer.PullRequest) error {
+	s.records = append(s.records, "pr:"+string(pr.PullRequest.PullRequestID()))
+	return nil
+}
+
+var admin = requester.New("root", requester.RoleAdmin)
+
+func dataset(t *testing.T) transfer.Dataset {
+	t.Helper()
+	alice, _ := domainuser.New("u1", "Alice", "backend", true)
+	bob, _ := domainuser.New("u2", "Bob", "backend", true)
+	team, err := domainteam.New("backend", []domainuser.User{alice, bob})
+	if err != nil {
+		t.Fatalf("team: %v", err)
+	}
+	pr, err := transfer.NewPullRequest("pr-1", "Add search", "u1", domain.PullRequestStatusOpen, time.Now(), nil, []transfer.Assignment{{ReviewerID: "u2"}})
+	if err != nil {
+		t.Fatalf("pull request: %v", err)
+	}
+	return transfer.Dataset{Teams: []domainteam.Team{team}, PullRequests: []transfer.PullRequest{pr}}
+}
+
+func TestImport_CreatesInOneSerializableTx(t *testing.T) {
+	repo, tx := newRepoStub(), &recordingTx{}
+	report, err := New(repo, tx).Import(context.Background(), admin, dataset(t), transfer.ModeFail)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Teams.Created != 1 || report.PullRequests.Created != 1 || len(report.Errors) != 0 || tx.err != nil {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if tx.opts.Isolation != appservice.IsolationSerializable {
+		t.Fatalf("import must run serializable, got %+v", tx.opts)
+	}
+}
+
+func TestImport_FailModeReportsConflictsAndRollsBack(t *testing.T) {
+	repo, tx := newRepoStub(), &recordingTx{}
+	repo.users["u2"] = true
+	repo.prs["pr-1"] = true
+
+	report, err := New(repo, tx).Import(context.Background(), admin, dataset(t), transfer.ModeFail)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Errors) != 2 || !errors.Is(tx.err, errRejected) {
+		t.Fatalf("expected two rejected records and rollback, got %+v (tx %v)", report.Errors, tx.err)
+	}
+	if e := report.Errors[0]; e.Kind != transfer.KindTeam || !errors.Is(e.Err, domain.ErrUserExists) {
+		t.Fatalf("unexpected team error %+v", e)
+	}
+	// участник u1 не заведён, потому что команда отклонена
+	if e := report.Errors[1]; e.Kind != transfer.KindPullRequest || !errors.Is(e.Err, domain.ErrUserNotFound) {
+		t.Fatalf("unexpected pull request error %+v", e)
+	}
+}
+
+func TestImport_SkipAndUpsertModes(t *testing.T) {
+	for _, tc := range []struct {
+		mode    transfer.Mode
+		want    transfer.Counts
+		written int
+	}{
+		{transfer.ModeSkip, transfer.Counts{Skipped: 1}, 0},
+		{transfer.ModeUpsert, transfer.Counts{Updated: 1}, 2},
+	} {
+		repo := newRepoStub()
+		repo.teams["backend"], repo.users["u1"], repo.users["u2"], repo.prs["pr-1"] = true, true, true, true
+
+		report, err := New(repo, &recordingTx{}).Import(context.Background(), admin, dataset(t), tc.mode)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.mode, err)
+		}
+		if report.Teams != tc.want || report.PullRequests != tc.want || len(repo.upserted) != tc.written {
+			t.Fatalf("%s: unexpected report %+v, written %v", tc.mode, report, repo.upserted)
+		}
+	}
+}
+
+func TestImportExport_RequireAdmin(t *testing.T) {
+	svc := New(newRepoStub(), &recordingTx{})
+	lead := requester.New("u1", requester.RoleTeamLead)
+	if _, err := svc.Import(context.Background(), lead, transfer.Dataset{}, transfer.ModeFail); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+	if err := svc.Export(context.Background(), lead, &sinkStub{}); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+	if _, err := svc.Import(context.Background(), admin, transfer.Dataset{}, "merge"); !errors.Is(err, domain.ErrInvalidImportMode) {
+		t.Fatalf("expected invalid mode, got %v", err)
+	}
+}
+
+func TestExport_StreamsTeamsThenPullRequestsFromSnapshot(t *testing.T) {
+	tx, sink := &recordingTx{}, &sinkStub{}
+	if err := New(newRepoStub(), tx).Export(context.Background(), admin, sink); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sink.records) != 2 || sink.records[0] != "team:backend" || sink.records[1] != "pr:pr-1" {
+		t.Fatalf("unexpected records %v", sink.records)
+	}
+	if tx.opts != appservice.ReadOnlySnapshot {
+		t.Fatalf("export must read one snapshot, got %+v", tx.opts)
+	}
+}
@@ -0,0 +1,40 @@
+package transferservice
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/transfer"
+	"github.com/mashhkensss/PR-service/internal/tracing"
+)
+
+type tracedService struct {
+	next Service
+}
+
+// WithTracing оборачивает каждый метод сервиса в спан
+func WithTracing(next Service) Service {
+	return tracedService{next: next}
+}
+
+func (t tracedService) Export(ctx context.Context, actor requester.Requester, sink Sink) error {
+	ctx, span := tracing.Start(ctx, "transfer.Export")
+	err := t.next.Export(ctx, actor, sink)
+	tracing.End(span, err)
+	return err
+}
+
+func (t tracedService) Import(ctx context.Context, actor requester.Requester, data transfer.Dataset, mode transfer.Mode) (transfer.Report, error) {
+	ctx, span := tracing.Start(ctx, "transfer.Import",
+		attribute.String("mode", string(mode)),
+		attribute.Int("teams", len(data.Teams)),
+		attribute.Int("pull_requests", len(data.PullRequests)),
+	)
+	res, err := t.next.Import(ctx, actor, data, mode)
+	tracing.End(span, err)
+	return res, err
+}
+
+var _ Service = tracedService{}
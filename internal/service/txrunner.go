@@ -1,12 +1,42 @@
 package service
 
-import "context"
+import (
+	"context"
+	"sync"
+)
+
+// Isolation — уровень изоляции транзакции; IsolationDefault оставляет уровень базы (в Postgres — READ COMMITTED)
+type Isolation int
+
+const (
+	IsolationDefault Isolation = iota
+	IsolationReadCommitted
+	IsolationRepeatableRead
+	IsolationSerializable
+)
+
+type TxOptions struct {
+	Isolation Isolation
+	// ReadOnly — транзакция только читает; база отклонит любую запись
+	ReadOnly bool
+}
+
+// ReadOnlySnapshot — согласованный снимок для отчётов из нескольких запросов
+var ReadOnlySnapshot = TxOptions{Isolation: IsolationRepeatableRead, ReadOnly: true}
 
 type TxRunner interface {
 	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+	// WithinTxOptions выполняет fn в транзакции с заданными параметрами. При конфликте сериализации
+	// или взаимной блокировке fn может быть вызвана повторно, поэтому побочные эффекты вне базы
+	// выполняются после неё
+	WithinTxOptions(ctx context.Context, opts TxOptions, fn func(ctx context.Context) error) error
 }
 
 func RunInTx[T any](ctx context.Context, tx TxRunner, fn func(ctx context.Context) (T, error)) (T, error) {
+	return RunInTxWith(ctx, tx, TxOptions{}, fn)
+}
+
+func RunInTxWith[T any](ctx context.Context, tx TxRunner, opts TxOptions, fn func(ctx context.Context) (T, error)) (T, error) {
 	var zero T
 	if tx == nil {
 		return fn(ctx)
@@ -15,7 +45,7 @@ func RunInTx[T any](ctx context.Context, tx TxRunner, fn func(ctx context.Contex
 		result T
 		err    error
 	)
-	runErr := tx.WithinTx(ctx, func(ctx context.Context) error {
+	runErr := tx.WithinTxOptions(ctx, opts, func(ctx context.Context) error {
 		result, err = fn(ctx)
 		return err
 	})
@@ -26,8 +56,76 @@ func RunInTx[T any](ctx context.Context, tx TxRunner, fn func(ctx context.Contex
 }
 
 func ExecInTx(ctx context.Context, tx TxRunner, fn func(ctx context.Context) error) error {
+	return ExecInTxWith(ctx, tx, TxOptions{}, fn)
+}
+
+func ExecInTxWith(ctx context.Context, tx TxRunner, opts TxOptions, fn func(ctx context.Context) error) error {
 	if tx == nil {
 		return fn(ctx)
 	}
-	return tx.WithinTx(ctx, fn)
+	return tx.WithinTxOptions(ctx, opts, fn)
+}
+
+type commitHooksKey struct{}
+
+// CommitHooks — действия, отложенные до фиксации транзакции. Реализация TxRunner кладёт их в контекст
+// транзакции через WithCommitHooks и запускает Run после фиксации
+type CommitHooks struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+func WithCommitHooks(ctx context.Context) (context.Context, *CommitHooks) {
+	hooks := &CommitHooks{}
+	return context.WithValue(ctx, commitHooksKey{}, hooks), hooks
+}
+
+func CommitHooksFromContext(ctx context.Context) (*CommitHooks, bool) {
+	hooks, ok := ctx.Value(commitHooksKey{}).(*CommitHooks)
+	return hooks, ok
+}
+
+// AfterCommit откладывает fn до фиксации внешней транзакции: вложенная транзакция ещё может
+// откатиться вместе с ней. Вне транзакции fn выполняется сразу
+func AfterCommit(ctx context.Context, fn func()) {
+	hooks, ok := CommitHooksFromContext(ctx)
+	if !ok {
+		fn()
+		return
+	}
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	hooks.fns = append(hooks.fns, fn)
+}
+
+// Mark — число отложенных действий; Discard(mark) отменяет добавленные после него, когда
+// откатывается точка сохранения
+func (h *CommitHooks) Mark() int {
+	if h == nil {
+		return 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.fns)
+}
+
+func (h *CommitHooks) Discard(mark int) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if mark < len(h.fns) {
+		h.fns = h.fns[:mark]
+	}
+}
+
+func (h *CommitHooks) Run() {
+	h.mu.Lock()
+	fns := h.fns
+	h.fns = nil
+	h.mu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
 }
@@ -7,10 +7,18 @@ import (
 )
 
 type testTxRunner struct {
-	err error
+	err  error
+	opts *TxOptions
 }
 
 func (r testTxRunner) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.WithinTxOptions(ctx, TxOptions{}, fn)
+}
+
+func (r testTxRunner) WithinTxOptions(ctx context.Context, opts TxOptions, fn func(ctx context.Context) error) error {
+	if r.opts != nil {
+		*r.opts = opts
+	}
 	if r.err != nil {
 		return r.err
 	}
@@ -78,3 +86,37 @@ func TestExecInTx_Error(t *testing.T) {
 		t.Fatalf("expected %v, got %v", expected, err)
 	}
 }
+
+func TestRunInTxWith_PassesOptions(t *testing.T) {
+	var got TxOptions
+	tx := testTxRunner{opts: &got}
+	if _, err := RunInTxWith(context.Background(), tx, ReadOnlySnapshot, func(ctx context.Context) (int, error) {
+		return 1, nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != ReadOnlySnapshot {
+		t.Fatalf("expected read-only snapshot options, got %+v", got)
+	}
+}
+
+func TestAfterCommit_RunsImmediatelyOutsideTx(t *testing.T) {
+	ran := false
+	AfterCommit(context.Background(), func() { ran = true })
+	if !ran {
+		t.Fatal("expected hook to run without transaction")
+	}
+
+	ctx, hooks := WithCommitHooks(context.Background())
+	AfterCommit(ctx, func() { ran = false })
+	mark := hooks.Mark()
+	AfterCommit(ctx, func() { t.Fatal("discarded hook must not run") })
+	hooks.Discard(mark)
+	if !ran {
+		t.Fatal("hook ran before commit")
+	}
+	hooks.Run()
+	if ran {
+		t.Fatal("expected deferred hook to run on commit")
+	}
+}
@@ -2,15 +2,26 @@ package userservice
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/mashhkensss/PR-service/internal/domain"
 	"github.com/mashhkensss/PR-service/internal/domain/pullrequest"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/team"
 	"github.com/mashhkensss/PR-service/internal/domain/user"
+	appservice "github.com/mashhkensss/PR-service/internal/service"
 )
 
 type UserRepository interface {
-	SetUserActivity(ctx context.Context, userID domain.UserID, active bool) (user.User, error)
+	GetUser(ctx context.Context, userID domain.UserID) (user.User, error)
+	// SetUserActivity меняет активность при совпадении версии, expected == AnyVersion отключает проверку
+	SetUserActivity(ctx context.Context, userID domain.UserID, active bool, expected domain.Version) (user.User, error)
+}
+
+type TeamRepository interface {
+	GetTeam(ctx context.Context, name domain.TeamName) (team.Team, error)
 }
 
 type PullRequestRepository interface {
@@ -18,28 +29,58 @@ type PullRequestRepository interface {
 }
 
 type Service interface {
-	SetIsActive(ctx context.Context, userID domain.UserID, isActive bool) (user.User, error)
-	GetReviewAssignments(ctx context.Context, userID domain.UserID) ([]pullrequest.PullRequest, error)
+	SetIsActive(ctx context.Context, actor requester.Requester, userID domain.UserID, isActive bool, expected domain.Version) (user.User, error)
+	GetReviewAssignments(ctx context.Context, actor requester.Requester, userID domain.UserID) ([]pullrequest.PullRequest, error)
 }
 
 type service struct {
-	users UserRepository
-	prs   PullRequestRepository
+	users  UserRepository
+	teams  TeamRepository
+	prs    PullRequestRepository
+	tx     appservice.TxRunner
+	events appservice.EventStore
 }
 
-func New(users UserRepository, prs PullRequestRepository) Service {
-	return &service{users: users, prs: prs}
+// activityWrite — событие пишется по прочитанному состоянию, поэтому параллельное
+// переключение той же строки должно упасть конфликтом и повториться, а не записать событие дважды
+var activityWrite = appservice.TxOptions{Isolation: appservice.IsolationRepeatableRead}
+
+func New(users UserRepository, teams TeamRepository, prs PullRequestRepository, tx appservice.TxRunner, events appservice.EventStore) Service {
+	return &service{users: users, teams: teams, prs: prs, tx: tx, events: events}
 }
 
-func (s *service) SetIsActive(ctx context.Context, userID domain.UserID, isActive bool) (user.User, error) {
-	updated, err := s.users.SetUserActivity(ctx, userID, isActive)
+func (s *service) SetIsActive(ctx context.Context, actor requester.Requester, userID domain.UserID, isActive bool, expected domain.Version) (user.User, error) {
+	if err := s.authorize(ctx, userID, actor.CanManageTeam); err != nil {
+		return user.User{}, err
+	}
+
+	// пользователь, версия его команды и событие в outbox меняются вместе
+	updated, err := appservice.RunInTxWith(ctx, s.tx, activityWrite, func(ctx context.Context) (user.User, error) {
+		current, err := s.users.GetUser(ctx, userID)
+		if err != nil {
+			return user.User{}, err
+		}
+		updated, err := s.users.SetUserActivity(ctx, userID, isActive, expected)
+		if err != nil {
+			return user.User{}, err
+		}
+		if err := appservice.AppendEvents(ctx, s.events, current.WithActivity(isActive).Events()); err != nil {
+			return user.User{}, fmt.Errorf("append events: %w", err)
+		}
+		return updated, nil
+	})
 	if err != nil {
 		return user.User{}, fmt.Errorf("set user activity: %w", err)
 	}
 	return updated, nil
 }
 
-func (s *service) GetReviewAssignments(ctx context.Context, userID domain.UserID) ([]pullrequest.PullRequest, error) {
+func (s *service) GetReviewAssignments(ctx context.Context, actor requester.Requester, userID domain.UserID) ([]pullrequest.PullRequest, error) {
+	canView := func(t team.Team) bool { return actor.CanViewAssignments(userID, t) }
+	if err := s.authorize(ctx, userID, canView); err != nil {
+		return nil, err
+	}
+
 	prs, err := s.prs.ListPullRequestsByReviewer(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("list pull requests by reviewer: %w", err)
@@ -47,4 +88,28 @@ func (s *service) GetReviewAssignments(ctx context.Context, userID domain.UserID
 	return prs, nil
 }
 
+// authorize проверяет право на действие над пользователем. Команду грузим, только если право
+// не следует из роли или из того, что пользователь действует над собой. Несуществующий
+// пользователь даёт отказ, а не 404, чтобы не раскрывать, кто есть в системе
+func (s *service) authorize(ctx context.Context, userID domain.UserID, allowed func(team.Team) bool) error {
+	if allowed(team.Team{}) {
+		return nil
+	}
+	u, err := s.users.GetUser(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrPermissionDenied
+	}
+	if err != nil {
+		return fmt.Errorf("load user: %w", err)
+	}
+	t, err := s.teams.GetTeam(ctx, u.TeamName())
+	if err != nil {
+		return fmt.Errorf("load user team: %w", err)
+	}
+	if !allowed(t) {
+		return domain.ErrPermissionDenied
+	}
+	return nil
+}
+
 var _ Service = (*service)(nil)
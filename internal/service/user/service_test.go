@@ -2,23 +2,27 @@ package userservice
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"testing"
 	"time"
 
 	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/event"
 	"github.com/mashhkensss/PR-service/internal/domain/pullrequest"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
 	"github.com/mashhkensss/PR-service/internal/domain/user"
 )
 
 type testUserRepo struct {
-	setFn func(ctx context.Context, userID domain.UserID, active bool) (user.User, error)
+	setFn func(ctx context.Context, userID domain.UserID, active bool, expected domain.Version) (user.User, error)
 	getFn func(ctx context.Context, userID domain.UserID) (user.User, error)
 }
 
-func (r testUserRepo) SetUserActivity(ctx context.Context, userID domain.UserID, active bool) (user.User, error) {
+func (r testUserRepo) SetUserActivity(ctx context.Context, userID domain.UserID, active bool, expected domain.Version) (user.User, error) {
 	if r.setFn != nil {
-		return r.setFn(ctx, userID, active)
+		return r.setFn(ctx, userID, active, expected)
 	}
 	return user.User{}, nil
 }
@@ -30,6 +34,17 @@ func (r testUserRepo) GetUser(ctx context.Context, userID domain.UserID) (user.U
 	return user.User{}, nil
 }
 
+type testTeamRepo struct {
+	teams map[domain.TeamName]domainteam.Team
+}
+
+func (r testTeamRepo) GetTeam(_ context.Context, name domain.TeamName) (domainteam.Team, error) {
+	if t, ok := r.teams[name]; ok {
+		return t, nil
+	}
+	return domainteam.Team{}, sql.ErrNoRows
+}
+
 type testPRRepo struct {
 	listFn func(ctx context.Context, reviewer domain.UserID) ([]pullrequest.PullRequest, error)
 }
@@ -57,13 +72,13 @@ func TestService_SetIsActive(t *testing.T) {
 	u, _ := user.New("u1", "Alice", "backend", true)
 	s := &service{
 		users: testUserRepo{
-			setFn: func(ctx context.Context, userID domain.UserID, active bool) (user.User, error) {
+			setFn: func(ctx context.Context, userID domain.UserID, active bool, _ domain.Version) (user.User, error) {
 				return u.WithActivity(active), nil
 			},
 		},
 		prs: testPRRepo{},
 	}
-	updated, err := s.SetIsActive(context.Background(), "u1", false)
+	updated, err := s.SetIsActive(context.Background(), requester.System(), "u1", false, domain.AnyVersion)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -75,13 +90,13 @@ func TestService_SetIsActive(t *testing.T) {
 func TestService_SetIsActiveError(t *testing.T) {
 	s := &service{
 		users: testUserRepo{
-			setFn: func(ctx context.Context, userID domain.UserID, active bool) (user.User, error) {
+			setFn: func(ctx context.Context, userID domain.UserID, active bool, _ domain.Version) (user.User, error) {
 				return user.User{}, errors.New("boom")
 			},
 		},
 		prs: testPRRepo{},
 	}
-	if _, err := s.SetIsActive(context.Background(), "u1", true); err == nil {
+	if _, err := s.SetIsActive(context.Background(), requester.System(), "u1", true, domain.AnyVersion); err == nil {
 		t.Fatalf("expected error")
 	}
 }
@@ -96,7 +111,7 @@ func TestService_GetReviewAssignments(t *testing.T) {
 			},
 		},
 	}
-	res, err := s.GetReviewAssignments(context.Background(), "u1")
+	res, err := s.GetReviewAssignments(context.Background(), requester.System(), "u1")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -104,3 +119,112 @@ func TestService_GetReviewAssignments(t *testing.T) {
 		t.Fatalf("unexpected result %v", res)
 	}
 }
+
+func TestService_SetIsActivePermissions(t *testing.T) {
+	lead, _ := user.New("lead", "Lena", "backend", true)
+	member, _ := user.New("u1", "Alice", "backend", true)
+	outsider, _ := user.New("u2", "Bob", "frontend", true)
+	backend, _ := domainteam.New("backend", []user.User{lead, member})
+	frontend, _ := domainteam.New("frontend", []user.User{outsider})
+	users := map[domain.UserID]user.User{"lead": lead, "u1": member, "u2": outsider}
+
+	s := &service{
+		users: testUserRepo{
+			getFn: func(_ context.Context, id domain.UserID) (user.User, error) {
+				if u, ok := users[id]; ok {
+					return u, nil
+				}
+				return user.User{}, sql.ErrNoRows
+			},
+			setFn: func(_ context.Context, id domain.UserID, active bool, _ domain.Version) (user.User, error) {
+				return users[id].WithActivity(active), nil
+			},
+		},
+		teams: testTeamRepo{teams: map[domain.TeamName]domainteam.Team{"backend": backend, "frontend": frontend}},
+		prs:   testPRRepo{},
+	}
+
+	tests := []struct {
+		name   string
+		actor  requester.Requester
+		target domain.UserID
+		ok     bool
+	}{
+		{"admin", requester.New("root", requester.RoleAdmin), "u2", true},
+		{"lead of own team", requester.New("lead", requester.RoleTeamLead), "u1", true},
+		{"lead of other team", requester.New("lead", requester.RoleTeamLead), "u2", false},
+		{"lead without scope", requester.New("lead", requester.RoleTeamLead, requester.ScopeTeamsRead), "u1", false},
+		{"plain member", requester.New("u1", requester.RoleUser), "u1", false},
+		{"unknown user", requester.New("lead", requester.RoleTeamLead), "ghost", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.SetIsActive(context.Background(), tt.actor, tt.target, false, domain.AnyVersion)
+			if tt.ok && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.ok && !errors.Is(err, domain.ErrPermissionDenied) {
+				t.Fatalf("expected ErrPermissionDenied, got %v", err)
+			}
+		})
+	}
+}
+
+func TestService_GetReviewAssignmentsPermissions(t *testing.T) {
+	lead, _ := user.New("lead", "Lena", "backend", true)
+	member, _ := user.New("u1", "Alice", "backend", true)
+	backend, _ := domainteam.New("backend", []user.User{lead, member})
+	s := &service{
+		users: testUserRepo{
+			getFn: func(context.Context, domain.UserID) (user.User, error) { return member, nil },
+		},
+		teams: testTeamRepo{teams: map[domain.TeamName]domainteam.Team{"backend": backend}},
+		prs:   testPRRepo{},
+	}
+
+	if _, err := s.GetReviewAssignments(context.Background(), requester.New("u1", requester.RoleUser), "u1"); err != nil {
+		t.Fatalf("own assignments must be visible: %v", err)
+	}
+	if _, err := s.GetReviewAssignments(context.Background(), requester.New("lead", requester.RoleTeamLead), "u1"); err != nil {
+		t.Fatalf("lead must see team assignments: %v", err)
+	}
+	if _, err := s.GetReviewAssignments(context.Background(), requester.New("other", requester.RoleUser), "u1"); !errors.Is(err, domain.ErrPermissionDenied) {
+		t.Fatalf("expected ErrPermissionDenied, got %v", err)
+	}
+}
+
+type recordingEvents struct {
+	events []event.Event
+}
+
+func (r *recordingEvents) Append(_ context.Context, events ...event.Event) error {
+	r.events = append(r.events, events...)
+	return nil
+}
+
+func TestService_SetIsActiveAppendsEventOnChange(t *testing.T) {
+	u, _ := user.New("u1", "Alice", "backend", true)
+	events := &recordingEvents{}
+	s := &service{
+		users: testUserRepo{
+			getFn: func(context.Context, domain.UserID) (user.User, error) { return u, nil },
+			setFn: func(_ context.Context, _ domain.UserID, active bool, _ domain.Version) (user.User, error) {
+				return u.WithActivity(active), nil
+			},
+		},
+		events: events,
+	}
+
+	if _, err := s.SetIsActive(context.Background(), requester.System(), "u1", true, domain.AnyVersion); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events.events) != 0 {
+		t.Fatalf("unchanged activity must not produce events, got %+v", events.events)
+	}
+	if _, err := s.SetIsActive(context.Background(), requester.System(), "u1", false, domain.AnyVersion); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events.events) != 1 || events.events[0].Type != event.TypeUserActivityChanged {
+		t.Fatalf("expected UserActivityChanged, got %+v", events.events)
+	}
+}
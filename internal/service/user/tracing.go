@@ -0,0 +1,38 @@
+package userservice
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/pullrequest"
+	"github.com/mashhkensss/PR-service/internal/domain/requester"
+	"github.com/mashhkensss/PR-service/internal/domain/user"
+	"github.com/mashhkensss/PR-service/internal/tracing"
+)
+
+type tracedService struct {
+	next Service
+}
+
+// WithTracing оборачивает каждый метод сервиса в спан
+func WithTracing(next Service) Service {
+	return tracedService{next: next}
+}
+
+func (t tracedService) SetIsActive(ctx context.Context, actor requester.Requester, userID domain.UserID, isActive bool, expected domain.Version) (user.User, error) {
+	ctx, span := tracing.Start(ctx, "user.SetIsActive", attribute.String("user_id", string(userID)))
+	res, err := t.next.SetIsActive(ctx, actor, userID, isActive, expected)
+	tracing.End(span, err)
+	return res, err
+}
+
+func (t tracedService) GetReviewAssignments(ctx context.Context, actor requester.Requester, userID domain.UserID) ([]pullrequest.PullRequest, error) {
+	ctx, span := tracing.Start(ctx, "user.GetReviewAssignments", attribute.String("user_id", string(userID)))
+	res, err := t.next.GetReviewAssignments(ctx, actor, userID)
+	tracing.End(span, err)
+	return res, err
+}
+
+var _ Service = tracedService{}
@@ -0,0 +1,117 @@
+package stream
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Broker следит за журналом и будит подписчиков, когда в нём появляются записи. Сами записи
+// подписчик читает из журнала по своему фильтру и курсору, поэтому медленный клиент ничего
+// не теряет, а записи других реплик доходят так же, как свои
+type Broker struct {
+	store     Storage
+	interval  time.Duration
+	retention time.Duration
+	logger    *slog.Logger
+
+	mu      sync.Mutex
+	head    Position
+	changed chan struct{}
+}
+
+func NewBroker(store Storage, interval, retention time.Duration, logger *slog.Logger) *Broker {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return &Broker{store: store, interval: interval, retention: retention, logger: logger, changed: make(chan struct{})}
+}
+
+// Run блокируется до отмены ctx
+func (b *Broker) Run(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.tick(ctx)
+		}
+	}
+}
+
+func (b *Broker) tick(ctx context.Context) {
+	head, err := b.store.Head(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			b.logger.Error("stream poll failed", "error", err)
+		}
+		return
+	}
+	b.mu.Lock()
+	if head != b.head {
+		b.head = head
+		close(b.changed)
+		b.changed = make(chan struct{})
+	}
+	b.mu.Unlock()
+
+	if b.retention > 0 {
+		if _, err := b.store.Purge(ctx, time.Now().UTC().Add(-b.retention), 1000); err != nil && ctx.Err() == nil {
+			b.logger.Error("stream purge failed", "error", err)
+		}
+	}
+}
+
+// wait возвращает канал, который закроется при следующем изменении журнала
+func (b *Broker) wait() <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.changed
+}
+
+// Subscribe открывает подписку с записей после after
+func (b *Broker) Subscribe(filter Filter, after Position, batch int) *Subscription {
+	if batch <= 0 {
+		batch = 100
+	}
+	return &Subscription{broker: b, filter: filter, cursor: after, batch: batch}
+}
+
+type Subscription struct {
+	broker *Broker
+	filter Filter
+	cursor Position
+	batch  int
+}
+
+// Next блокируется до появления подходящих записей после курсора и сдвигает курсор.
+// Возвращает ошибку ctx, если записей не было до его отмены
+func (s *Subscription) Next(ctx context.Context) ([]Entry, error) {
+	for {
+		// канал берётся до чтения: запись, появившаяся между чтением и ожиданием, не потеряется
+		changed := s.broker.wait()
+		entries, err := s.broker.store.After(ctx, s.filter, s.cursor, s.batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 {
+			s.cursor = entries[len(entries)-1].Position()
+			return entries, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// Cursor — id последней выданной записи
+func (s *Subscription) Cursor() int64 { return s.cursor.ID }
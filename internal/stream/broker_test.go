@@ -0,0 +1,123 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+	"github.com/mashhkensss/PR-service/internal/domain/event"
+)
+
+type memoryStorage struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+func (s *memoryStorage) Append(_ context.Context, entries ...Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range entries {
+		e.ID = int64(len(s.entries) + 1)
+		e.XID = e.ID
+		s.entries = append(s.entries, e)
+	}
+	return nil
+}
+
+func (s *memoryStorage) After(_ context.Context, f Filter, after Position, limit int) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []Entry
+	for _, e := range s.entries {
+		if e.ID <= after.ID || (f.ReviewerID != "" && e.ReviewerID != f.ReviewerID) {
+			continue
+		}
+		res = append(res, e)
+		if len(res) == limit {
+			break
+		}
+	}
+	return res, nil
+}
+
+func (s *memoryStorage) Head(context.Context) (Position, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := int64(len(s.entries))
+	return Position{XID: n, ID: n}, nil
+}
+
+func (s *memoryStorage) Locate(_ context.Context, id int64) (Position, error) {
+	return Position{XID: id, ID: id}, nil
+}
+
+func (s *memoryStorage) Purge(context.Context, time.Time, int) (int64, error) { return 0, nil }
+
+func TestEntriesFromEvents(t *testing.T) {
+	at := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	entries := Entries([]event.Event{
+		event.ForPullRequest("pr-1", event.TypePullRequestCreated, at, event.PullRequestCreated{PullRequestID: "pr-1"}),
+		event.ForPullRequest("pr-1", event.TypeReviewerReplaced, at, event.ReviewerReplaced{PullRequestID: "pr-1", OldReviewerID: "u1", NewReviewerID: "u2"}),
+		event.ForPullRequest("pr-1", event.TypePullRequestMerged, at, event.PullRequestMerged{PullRequestID: "pr-1", Reviewers: []domain.UserID{"u2", "u3"}}),
+	})
+	want := []struct {
+		kind     Kind
+		reviewer domain.UserID
+	}{{KindUnassigned, "u1"}, {KindAssigned, "u2"}, {KindMerged, "u2"}, {KindMerged, "u3"}}
+	if len(entries) != len(want) {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	for i, w := range want {
+		if entries[i].Kind != w.kind || entries[i].ReviewerID != w.reviewer || entries[i].PullRequestID != "pr-1" {
+			t.Fatalf("entry %d: got %+v, want %v", i, entries[i], w)
+		}
+	}
+}
+
+func TestSubscriptionResumesAndWakesUp(t *testing.T) {
+	store := &memoryStorage{}
+	_ = store.Append(context.Background(),
+		Entry{Kind: KindAssigned, ReviewerID: "u1"},
+		Entry{Kind: KindAssigned, ReviewerID: "u2"},
+		Entry{Kind: KindMerged, ReviewerID: "u1"},
+	)
+	broker := NewBroker(store, time.Millisecond, 0, nil)
+
+	// возобновление после первой записи: вторая не подходит под фильтр
+	sub := broker.Subscribe(Filter{ReviewerID: "u1"}, Position{XID: 1, ID: 1}, 10)
+	entries, err := sub.Next(context.Background())
+	if err != nil || len(entries) != 1 || entries[0].ID != 3 {
+		t.Fatalf("unexpected resume %+v err=%v", entries, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go broker.Run(ctx)
+
+	got := make(chan []Entry, 1)
+	go func() {
+		entries, _ := sub.Next(ctx)
+		got <- entries
+	}()
+	_ = store.Append(context.Background(), Entry{Kind: KindAssigned, ReviewerID: "u1"})
+	select {
+	case entries := <-got:
+		if len(entries) != 1 || entries[0].ID != 4 || sub.Cursor() != 4 {
+			t.Fatalf("unexpected live entries %+v", entries)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("subscriber was not woken up")
+	}
+}
+
+func TestSubscriptionStopsOnContext(t *testing.T) {
+	sub := NewBroker(&memoryStorage{}, time.Hour, 0, nil).Subscribe(Filter{}, Position{}, 10)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := sub.Next(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline error, got %v", err)
+	}
+}
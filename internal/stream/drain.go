@@ -0,0 +1,28 @@
+package stream
+
+import "context"
+
+type drainKey struct{}
+
+// WithDrain кладёт в базовый контекст сервера канал, закрываемый в начале остановки.
+// Потоки не завершаются сами, поэтому без сигнала Shutdown ждал бы их до таймаута
+func WithDrain(ctx context.Context, drain <-chan struct{}) context.Context {
+	return context.WithValue(ctx, drainKey{}, drain)
+}
+
+// Draining возвращает контекст, отменяемый вместе с ctx или при начале остановки сервера
+func Draining(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	drain, _ := ctx.Value(drainKey{}).(<-chan struct{})
+	if drain == nil {
+		return ctx, cancel
+	}
+	go func() {
+		select {
+		case <-drain:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
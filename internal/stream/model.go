@@ -0,0 +1,62 @@
+package stream
+
+import (
+	"context"
+	"time"
+
+	"github.com/mashhkensss/PR-service/internal/domain"
+)
+
+// Kind — что произошло с назначением ревьювера
+type Kind string
+
+const (
+	KindAssigned   Kind = "ASSIGNED"
+	KindUnassigned Kind = "UNASSIGNED"
+	KindMerged     Kind = "MERGED"
+)
+
+// Entry — запись журнала назначений: одно изменение назначения одного ревьювера.
+// ID служит id события SSE
+type Entry struct {
+	ID int64
+	// XID — транзакция, сделавшая запись
+	XID           int64
+	Kind          Kind
+	PullRequestID domain.PullRequestID
+	ReviewerID    domain.UserID
+	// TeamName — текущая команда ревьювера, заполняется при чтении
+	TeamName   domain.TeamName
+	OccurredAt time.Time
+}
+
+func (e Entry) Position() Position {
+	return Position{XID: e.XID, ID: e.ID}
+}
+
+// Position — место записи в журнале. Журнал упорядочен по транзакции записи, затем по ID:
+// ID из последовательности выдаётся до фиксации, и транзакция с меньшим ID может
+// зафиксироваться позже, а курсор по одному ID пропустил бы её записи
+type Position struct {
+	XID int64
+	ID  int64
+}
+
+// Filter сужает поток до ревьювера или команды; пустой фильтр пропускает все назначения
+type Filter struct {
+	ReviewerID domain.UserID
+	TeamName   domain.TeamName
+}
+
+type Storage interface {
+	Append(ctx context.Context, entries ...Entry) error
+	// After возвращает до limit подходящих под фильтр записей после after в порядке журнала.
+	// Запись выдаётся, только когда зафиксированы все транзакции, чьи записи могут встать перед ней
+	After(ctx context.Context, filter Filter, after Position, limit int) ([]Entry, error)
+	// Head — позиция последней записи, которую уже можно выдать; нулевая для пустого журнала
+	Head(ctx context.Context) (Position, error)
+	// Locate — позиция записи id; нулевая, если запись уже удалена
+	Locate(ctx context.Context, id int64) (Position, error)
+	// Purge удаляет до limit записей, сделанных до before
+	Purge(ctx context.Context, before time.Time, limit int) (int64, error)
+}
@@ -0,0 +1,46 @@
+package stream
+
+import (
+	"context"
+
+	"github.com/mashhkensss/PR-service/internal/domain/event"
+)
+
+// Recorder ведёт журнал назначений по доменным событиям. Сервисы передают ему события
+// в своей транзакции, поэтому запись в журнале появляется вместе с изменением PR
+type Recorder struct {
+	store Storage
+}
+
+func NewRecorder(store Storage) *Recorder {
+	return &Recorder{store: store}
+}
+
+func (r *Recorder) Append(ctx context.Context, events ...event.Event) error {
+	entries := Entries(events)
+	if len(entries) == 0 {
+		return nil
+	}
+	return r.store.Append(ctx, entries...)
+}
+
+// Entries переводит события PR в записи журнала; события, не меняющие назначений, пропускаются
+func Entries(events []event.Event) []Entry {
+	var entries []Entry
+	for _, e := range events {
+		switch p := e.Payload.(type) {
+		case event.ReviewerAssigned:
+			entries = append(entries, Entry{Kind: KindAssigned, PullRequestID: p.PullRequestID, ReviewerID: p.ReviewerID, OccurredAt: e.OccurredAt})
+		case event.ReviewerReplaced:
+			entries = append(entries,
+				Entry{Kind: KindUnassigned, PullRequestID: p.PullRequestID, ReviewerID: p.OldReviewerID, OccurredAt: e.OccurredAt},
+				Entry{Kind: KindAssigned, PullRequestID: p.PullRequestID, ReviewerID: p.NewReviewerID, OccurredAt: e.OccurredAt},
+			)
+		case event.PullRequestMerged:
+			for _, reviewer := range p.Reviewers {
+				entries = append(entries, Entry{Kind: KindMerged, PullRequestID: p.PullRequestID, ReviewerID: reviewer, OccurredAt: e.OccurredAt})
+			}
+		}
+	}
+	return entries
+}
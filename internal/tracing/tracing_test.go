@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func installRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return recorder
+}
+
+func TestMiddleware_ContinuesTraceAndNamesSpanByRoute(t *testing.T) {
+	recorder := installRecorder(t)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	var innerTraceID string
+	r.Get("/team/{name}", func(w http.ResponseWriter, r *http.Request) {
+		innerTraceID = TraceID(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/team/backend", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if innerTraceID != traceID {
+		t.Fatalf("handler must see incoming trace id, got %q", innerTraceID)
+	}
+	if got := rr.Header().Get(TraceIDHeader); got != traceID {
+		t.Fatalf("expected %s header %q, got %q", TraceIDHeader, traceID, got)
+	}
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Name() != "GET /team/{name}" {
+		t.Fatalf("unexpected span name %q", spans[0].Name())
+	}
+	if spans[0].Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("span must be a child of the incoming span, got parent %s", spans[0].Parent().SpanID())
+	}
+}
+
+func TestEnd_RecordsError(t *testing.T) {
+	recorder := installRecorder(t)
+
+	_, span := Start(context.Background(), "op")
+	End(span, errors.New("boom"))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 || spans[0].Status().Code != codes.Error {
+		t.Fatalf("expected errored span, got %+v", spans)
+	}
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	if _, err := Setup(context.Background(), Options{Exporter: "zipkin"}); err == nil {
+		t.Fatalf("expected error for unknown exporter")
+	}
+}
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_reviewers_pending;
+DROP TABLE IF EXISTS team_review_sla;
+
+ALTER TABLE pull_request_reviewers
+    DROP COLUMN IF EXISTS sla_escalated_at,
+    DROP COLUMN IF EXISTS reviewed_at;
@@ -0,0 +1,13 @@
+ALTER TABLE pull_request_reviewers
+    ADD COLUMN IF NOT EXISTS reviewed_at      TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS sla_escalated_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS team_review_sla (
+    team_name             TEXT PRIMARY KEY REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE,
+    review_within_seconds BIGINT      NOT NULL CHECK (review_within_seconds > 0),
+    business_days_only    BOOLEAN     NOT NULL DEFAULT TRUE,
+    escalation            TEXT        NOT NULL CHECK (escalation IN ('REASSIGN','ADD_REVIEWER','EVENT')),
+    updated_at            TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reviewers_pending ON pull_request_reviewers(assigned_at) WHERE reviewed_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_pull_requests_merged;
+DROP TABLE IF EXISTS pull_request_reassignments;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS pull_request_reassignments (
+    id              BIGSERIAL PRIMARY KEY,
+    pull_request_id TEXT        NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+    old_reviewer_id TEXT        NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE,
+    new_reviewer_id TEXT        NOT NULL REFERENCES users(user_id) ON UPDATE CASCADE,
+    reassigned_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_reassignments_pull_request ON pull_request_reassignments(pull_request_id);
+
+CREATE INDEX IF NOT EXISTS idx_pull_requests_merged ON pull_requests(merged_at) WHERE merged_at IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE pull_request_reviewers
+    ADD COLUMN IF NOT EXISTS verdict    TEXT CHECK (verdict IN ('APPROVED','CHANGES_REQUESTED','COMMENTED')),
+    ADD COLUMN IF NOT EXISTS verdict_at TIMESTAMPTZ;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS token_revocations (
+    kind       TEXT        NOT NULL CHECK (kind IN ('jti','subject')),
+    value      TEXT        NOT NULL,
+    revoked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (kind, value)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE idempotency_keys
+    ADD COLUMN IF NOT EXISTS state TEXT NOT NULL DEFAULT 'completed' CHECK (state IN ('pending','completed'));
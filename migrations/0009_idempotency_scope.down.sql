@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_idempotency_subject_key;
+ALTER TABLE idempotency_keys
+    DROP COLUMN IF EXISTS idempotency_key,
+    DROP COLUMN IF EXISTS subject;
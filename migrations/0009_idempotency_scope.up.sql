@@ -0,0 +1,5 @@
+ALTER TABLE idempotency_keys
+    ADD COLUMN IF NOT EXISTS subject         TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS idempotency_key TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_subject_key ON idempotency_keys(subject, idempotency_key);
@@ -0,0 +1,6 @@
+ALTER TABLE pull_requests
+    DROP COLUMN IF EXISTS version;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS version;
+ALTER TABLE teams
+    DROP COLUMN IF EXISTS version;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS assignment_events (
+    id              BIGSERIAL PRIMARY KEY,
+    kind            TEXT        NOT NULL CHECK (kind IN ('ASSIGNED','UNASSIGNED','MERGED')),
+    pull_request_id TEXT        NOT NULL,
+    reviewer_id     TEXT        NOT NULL,
+    occurred_at     TIMESTAMPTZ NOT NULL,
+    recorded_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_assignment_events_reviewer ON assignment_events (reviewer_id, id);
+CREATE INDEX IF NOT EXISTS idx_assignment_events_recorded ON assignment_events (recorded_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_token_revocations_expires;
+DROP INDEX IF EXISTS idx_token_revocations_revoked;
+ALTER TABLE token_revocations DROP COLUMN IF EXISTS expires_at;
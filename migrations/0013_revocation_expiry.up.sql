@@ -0,0 +1,4 @@
+ALTER TABLE token_revocations ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_token_revocations_revoked ON token_revocations (revoked_at);
+CREATE INDEX IF NOT EXISTS idx_token_revocations_expires ON token_revocations (expires_at) WHERE kind = 'jti';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_assignment_events_reviewer;
+DROP INDEX IF EXISTS idx_assignment_events_position;
+ALTER TABLE assignment_events DROP COLUMN IF EXISTS xid;
+CREATE INDEX IF NOT EXISTS idx_assignment_events_reviewer ON assignment_events (reviewer_id, id);
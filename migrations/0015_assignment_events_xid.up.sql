@@ -0,0 +1,5 @@
+ALTER TABLE assignment_events ADD COLUMN IF NOT EXISTS xid xid8 NOT NULL DEFAULT pg_current_xact_id();
+
+DROP INDEX IF EXISTS idx_assignment_events_reviewer;
+CREATE INDEX IF NOT EXISTS idx_assignment_events_position ON assignment_events (xid, id);
+CREATE INDEX IF NOT EXISTS idx_assignment_events_reviewer ON assignment_events (reviewer_id, xid, id);
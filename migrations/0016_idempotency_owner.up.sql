@@ -0,0 +1 @@
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS owner_token TEXT NOT NULL DEFAULT '';
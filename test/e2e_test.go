@@ -2,34 +2,41 @@ package test
 
 import (
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	stdhttp "net/http"
 	"net/http/httptest"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/mashhkensss/PR-service/internal/auth"
 	domain "github.com/mashhkensss/PR-service/internal/domain"
 	domainpr "github.com/mashhkensss/PR-service/internal/domain/pullrequest"
+	domainrevocation "github.com/mashhkensss/PR-service/internal/domain/revocation"
+	domainstats "github.com/mashhkensss/PR-service/internal/domain/stats"
 	domainteam "github.com/mashhkensss/PR-service/internal/domain/team"
 	domainuser "github.com/mashhkensss/PR-service/internal/domain/user"
 	routerhttp "github.com/mashhkensss/PR-service/internal/http"
+	authhandler "github.com/mashhkensss/PR-service/internal/http/handlers/auth"
 	prhandler "github.com/mashhkensss/PR-service/internal/http/handlers/pullrequest"
+	revocationhandler "github.com/mashhkensss/PR-service/internal/http/handlers/revocation"
 	statshandler "github.com/mashhkensss/PR-service/internal/http/handlers/stats"
 	teamhandler "github.com/mashhkensss/PR-service/internal/http/handlers/team"
 	userhandler "github.com/mashhkensss/PR-service/internal/http/handlers/user"
 	"github.com/mashhkensss/PR-service/internal/http/middleware"
 	"github.com/mashhkensss/PR-service/internal/idempotency"
+	"github.com/mashhkensss/PR-service/internal/service"
 	"github.com/mashhkensss/PR-service/internal/service/assignment"
 	pullrequestservice "github.com/mashhkensss/PR-service/internal/service/pullrequest"
+	revocationservice "github.com/mashhkensss/PR-service/internal/service/revocation"
 	statsservice "github.com/mashhkensss/PR-service/internal/service/stats"
 	teamservice "github.com/mashhkensss/PR-service/internal/service/team"
+	tokenservice "github.com/mashhkensss/PR-service/internal/service/token"
 	userservice "github.com/mashhkensss/PR-service/internal/service/user"
 )
 
@@ -43,10 +50,10 @@ func TestEndToEndFlow(t *testing.T) {
 
 	txRunner := noopTx{}
 
-	teamSvc := teamservice.New(teamRepo, txRunner)
-	userSvc := userservice.New(userRepo, prRepo)
-	prSvc := pullrequestservice.New(teamRepo, userRepo, prRepo, txRunner, assignment.NewStrategy(nil))
-	statsSvc := statsservice.New(statsRepo)
+	teamSvc := teamservice.New(teamRepo, userRepo, txRunner, nil)
+	userSvc := userservice.New(userRepo, teamRepo, prRepo, txRunner, nil)
+	prSvc := pullrequestservice.New(teamRepo, userRepo, prRepo, txRunner, assignment.NewStrategy(nil), nil, nil)
+	statsSvc := statsservice.New(statsRepo, teamRepo, noopTx{})
 
 	teamHandler := teamhandler.New(teamSvc, logger.With("handler", "team"))
 	userHandler := userhandler.New(userSvc, logger.With("handler", "user"))
@@ -54,36 +61,58 @@ func TestEndToEndFlow(t *testing.T) {
 	statsHandler := statshandler.New(statsSvc, logger.With("handler", "stats"))
 
 	idStore := newMemoryStore()
-	auth := middleware.NewAuthorization([]byte("admin-secret"), []byte("user-secret"))
+	keys := auth.StaticKeys{
+		auth.HMACKey("", []byte("admin-secret"), auth.RoleAdmin, auth.RoleTeamLead),
+		auth.HMACKey("", []byte("user-secret"), auth.RoleUser),
+	}
+	revocationRepo := &inMemoryRevocationRepo{}
+	denylist := revocationservice.NewDenylist(revocationRepo, nil)
+	revocationSvc := revocationservice.New(revocationRepo, denylist)
+	verifier := auth.NewVerifier(auth.VerifierOptions{Denylist: denylist}, keys)
+	signer := auth.NewSigner(auth.SignerOptions{MaxTTL: time.Hour}, keys...)
+	authz := middleware.NewAuthorization(verifier, nil)
 	l := middleware.NewLogger(logger.With("component", "http"))
-	idempotencyMW := middleware.NewIdempotencyMiddleware(idStore, time.Minute, logger.With("component", "idempotency"))
+	idempotencyMW := middleware.NewIdempotencyMiddleware(idStore, middleware.IdempotencyConfig{TTL: time.Minute}, logger.With("component", "idempotency"), nil)
 	rateLimiter := middleware.NewRateLimiter(100, time.Second, false)
 	validator := middleware.NewValidatorMiddleware(middleware.NewTagValidator())
 
 	router := routerhttp.NewRouter(routerhttp.RouterConfig{
-		TeamHandler:  teamHandler,
-		UserHandler:  userHandler,
-		PRHandler:    prHandler,
-		StatsHandler: statsHandler,
-		Auth:         auth,
-		Logger:       l.Middleware,
-		RateLimiter:  rateLimiter.Middleware,
-		Idempotency:  idempotencyMW,
-		Validator:    validator,
+		TeamHandler:       teamHandler,
+		UserHandler:       userHandler,
+		PRHandler:         prHandler,
+		StatsHandler:      statsHandler,
+		TokenHandler:      authhandler.New(tokenservice.New(signer), logger.With("handler", "auth")),
+		RevocationHandler: revocationhandler.New(revocationSvc, logger.With("handler", "revocation")),
+		Auth:              authz,
+		Logger:            l.Middleware,
+		RateLimiter:       rateLimiter.Middleware,
+		Idempotency:       idempotencyMW,
+		Validator:         validator,
 	})
 
-	adminToken := makeToken("admin-secret", "admin", "admin")
+	adminToken, _, err := signer.Issue(auth.TokenRequest{Subject: "admin", Role: auth.RoleAdmin, TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("issue admin token: %v", err)
+	}
 
 	teamBody := `{"team_name":"backend","members":[{"user_id":"author","username":"Alice","is_active":true},{"user_id":"rev1","username":"Bob","is_active":true},{"user_id":"rev2","username":"Charlie","is_active":true}]}`
 	doRequest(t, router, stdhttp.MethodPost, "/team/add", adminToken, teamBody, stdhttp.StatusCreated)
 
+	authorToken := issueToken(t, router, adminToken, "author", auth.RoleUser)
+	userToken := issueToken(t, router, adminToken, "rev1", auth.RoleUser)
+	doRequest(t, router, stdhttp.MethodPost, "/auth/token", userToken, `{"subject":"rev1","role":"admin","ttl":"1h"}`, stdhttp.StatusForbidden)
+
 	createBody := `{"pull_request_id":"pr-1","pull_request_name":"Feature","author_id":"author"}`
-	doRequest(t, router, stdhttp.MethodPost, "/pullRequest/create", adminToken, createBody, stdhttp.StatusCreated)
+	doRequest(t, router, stdhttp.MethodPost, "/pullRequest/create", authorToken, createBody, stdhttp.StatusCreated)
+	doRequest(t, router, stdhttp.MethodPost, "/team/add", authorToken, teamBody, stdhttp.StatusForbidden)
+
+	reviewBody := `{"pull_request_id":"pr-1","reviewer_id":"rev1","verdict":"APPROVED"}`
+	doRequest(t, router, stdhttp.MethodPost, "/pullRequest/review", userToken, reviewBody, stdhttp.StatusOK)
 
 	mergeBody := `{"pull_request_id":"pr-1"}`
-	doRequest(t, router, stdhttp.MethodPost, "/pullRequest/merge", adminToken, mergeBody, stdhttp.StatusOK)
+	doRequest(t, router, stdhttp.MethodPost, "/pullRequest/merge", userToken, mergeBody, stdhttp.StatusForbidden)
+	doRequest(t, router, stdhttp.MethodPost, "/pullRequest/merge", authorToken, mergeBody, stdhttp.StatusOK)
 
-	userToken := makeToken("user-secret", "rev1", "user")
 	resp := doRequest(t, router, stdhttp.MethodGet, "/users/getReview?user_id=rev1", userToken, "", stdhttp.StatusOK)
 	var assignments struct {
 		PullRequests []struct {
@@ -97,7 +126,25 @@ func TestEndToEndFlow(t *testing.T) {
 		t.Fatalf("expected 1 assignment, got %v", assignments.PullRequests)
 	}
 
+	doRequest(t, router, stdhttp.MethodGet, "/stats/summary", userToken, "", stdhttp.StatusForbidden)
 	doRequest(t, router, stdhttp.MethodGet, "/stats/summary", adminToken, "", stdhttp.StatusOK)
+	doRequest(t, router, stdhttp.MethodGet, "/stats/cycle-time", adminToken, "", stdhttp.StatusOK)
+	doRequest(t, router, stdhttp.MethodGet, "/stats/fairness", adminToken, "", stdhttp.StatusOK)
+
+	// отзыв по jti действует сразу и только на этот токен
+	authorClaims, err := verifier.Verify(context.Background(), authorToken)
+	if err != nil {
+		t.Fatalf("verify author token: %v", err)
+	}
+	doRequest(t, router, stdhttp.MethodPost, "/auth/revoke", userToken, fmt.Sprintf(`{"jti":%q}`, authorClaims.ID), stdhttp.StatusForbidden)
+	doRequest(t, router, stdhttp.MethodPost, "/auth/revoke", adminToken, `{"jti":"x","subject":"rev1"}`, stdhttp.StatusBadRequest)
+	doRequest(t, router, stdhttp.MethodPost, "/auth/revoke", adminToken, fmt.Sprintf(`{"jti":%q}`, authorClaims.ID), stdhttp.StatusOK)
+	doRequest(t, router, stdhttp.MethodGet, "/team/get?team_name=backend", authorToken, "", stdhttp.StatusUnauthorized)
+	doRequest(t, router, stdhttp.MethodGet, "/team/get?team_name=backend", userToken, "", stdhttp.StatusOK)
+
+	doRequest(t, router, stdhttp.MethodPost, "/auth/revoke", adminToken, `{"subject":"rev1"}`, stdhttp.StatusOK)
+	doRequest(t, router, stdhttp.MethodGet, "/team/get?team_name=backend", userToken, "", stdhttp.StatusUnauthorized)
+	doRequest(t, router, stdhttp.MethodGet, "/auth/revocations", adminToken, "", stdhttp.StatusOK)
 }
 
 func doRequest(t *testing.T, handler stdhttp.Handler, method, path, token, body string, expected int) *stdhttp.Response {
@@ -122,14 +169,41 @@ func doRequest(t *testing.T, handler stdhttp.Handler, method, path, token, body
 	return resp
 }
 
-func makeToken(secret, sub, role string) string {
-	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
-	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"` + sub + `","role":"` + role + `","exp":` + fmt.Sprint(time.Now().Add(time.Hour).Unix()) + `}`))
-	data := header + "." + payload
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write([]byte(data))
-	signature := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
-	return data + "." + signature
+// issueToken выпускает токен через POST /auth/token, так что дальнейшие запросы проверяют round-trip
+func issueToken(t *testing.T, handler stdhttp.Handler, adminToken, subject, role string) string {
+	t.Helper()
+	body := fmt.Sprintf(`{"subject":%q,"role":%q,"ttl":"30m"}`, subject, role)
+	resp := doRequest(t, handler, stdhttp.MethodPost, "/auth/token", adminToken, body, stdhttp.StatusCreated)
+	var issued struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&issued); err != nil {
+		t.Fatalf("decode token: %v", err)
+	}
+	return issued.Token
+}
+
+type inMemoryRevocationRepo struct {
+	revocations []domainrevocation.Revocation
+}
+
+func (r *inMemoryRevocationRepo) SaveRevocation(ctx context.Context, rev domainrevocation.Revocation) (domainrevocation.Revocation, error) {
+	r.revocations = append(r.revocations, rev)
+	return rev, nil
+}
+
+func (r *inMemoryRevocationRepo) ListRevocations(ctx context.Context, since time.Time) ([]domainrevocation.Revocation, error) {
+	var out []domainrevocation.Revocation
+	for _, rev := range r.revocations {
+		if rev.RevokedAt().After(since) {
+			out = append(out, rev)
+		}
+	}
+	return out, nil
+}
+
+func (r *inMemoryRevocationRepo) PurgeExpired(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
 }
 
 type noopTx struct{}
@@ -138,6 +212,10 @@ func (noopTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error)
 	return fn(ctx)
 }
 
+func (noopTx) WithinTxOptions(ctx context.Context, _ service.TxOptions, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
 type inMemoryUserRepo struct {
 	users map[domain.UserID]domainuser.User
 }
@@ -150,19 +228,22 @@ func (r *inMemoryUserRepo) upsert(user domainuser.User) {
 	r.users[user.UserID()] = user
 }
 
-func (r *inMemoryUserRepo) SetUserActivity(ctx context.Context, id domain.UserID, active bool) (domainuser.User, error) {
+func (r *inMemoryUserRepo) SetUserActivity(ctx context.Context, id domain.UserID, active bool, expected domain.Version) (domainuser.User, error) {
 	u, ok := r.users[id]
 	if !ok {
 		return domainuser.User{}, fmt.Errorf("user not found")
 	}
-	r.users[id] = u.WithActivity(active)
+	if err := expected.Check(u.Version()); err != nil {
+		return domainuser.User{}, err
+	}
+	r.users[id] = u.WithActivity(active).WithVersion(u.Version().Next())
 	return r.users[id], nil
 }
 
 func (r *inMemoryUserRepo) GetUser(ctx context.Context, id domain.UserID) (domainuser.User, error) {
 	u, ok := r.users[id]
 	if !ok {
-		return domainuser.User{}, fmt.Errorf("user not found")
+		return domainuser.User{}, fmt.Errorf("get user: %w", sql.ErrNoRows)
 	}
 	return u, nil
 }
@@ -190,6 +271,15 @@ func (r *inMemoryTeamRepo) SaveTeam(ctx context.Context, t domainteam.Team) erro
 	return nil
 }
 
+func (r *inMemoryTeamRepo) ListTeamNames(ctx context.Context) ([]domain.TeamName, error) {
+	names := make([]domain.TeamName, 0, len(r.teams))
+	for name := range r.teams {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
 func (r *inMemoryTeamRepo) GetTeam(ctx context.Context, name domain.TeamName) (domainteam.Team, error) {
 	t, ok := r.teams[name]
 	if !ok {
@@ -198,12 +288,50 @@ func (r *inMemoryTeamRepo) GetTeam(ctx context.Context, name domain.TeamName) (d
 	return t, nil
 }
 
+func (r *inMemoryTeamRepo) SaveMember(ctx context.Context, member domainuser.User, expected domain.Version) error {
+	t, ok := r.teams[member.TeamName()]
+	if !ok {
+		return fmt.Errorf("team not found")
+	}
+	if err := expected.Check(t.Version()); err != nil {
+		return err
+	}
+	if err := t.UpsertMember(member); err != nil {
+		return err
+	}
+	r.teams[t.TeamName()] = t.WithVersion(t.Version().Next())
+	r.users.upsert(member)
+	return nil
+}
+
+func (r *inMemoryTeamRepo) RemoveMember(ctx context.Context, name domain.TeamName, id domain.UserID, expected domain.Version) error {
+	t, ok := r.teams[name]
+	if !ok {
+		return fmt.Errorf("team not found")
+	}
+	if err := expected.Check(t.Version()); err != nil {
+		return err
+	}
+	members := slices.DeleteFunc(t.Members(), func(u domainuser.User) bool { return u.UserID() == id })
+	updated, err := domainteam.New(name, members)
+	if err != nil {
+		return err
+	}
+	r.teams[name] = updated.WithVersion(t.Version().Next())
+	delete(r.users.users, id)
+	return nil
+}
+
 type inMemoryPRRepo struct {
-	prs map[domain.PullRequestID]domainpr.PullRequest
+	prs           map[domain.PullRequestID]domainpr.PullRequest
+	reassignments map[domain.PullRequestID]int
 }
 
 func newInMemoryPRRepo() *inMemoryPRRepo {
-	return &inMemoryPRRepo{prs: make(map[domain.PullRequestID]domainpr.PullRequest)}
+	return &inMemoryPRRepo{
+		prs:           make(map[domain.PullRequestID]domainpr.PullRequest),
+		reassignments: make(map[domain.PullRequestID]int),
+	}
 }
 
 func (r *inMemoryPRRepo) CreatePullRequest(ctx context.Context, pr domainpr.PullRequest) error {
@@ -215,7 +343,10 @@ func (r *inMemoryPRRepo) CreatePullRequest(ctx context.Context, pr domainpr.Pull
 }
 
 func (r *inMemoryPRRepo) UpdatePullRequest(ctx context.Context, pr domainpr.PullRequest) error {
-	r.prs[pr.PullRequestID()] = pr
+	if current := r.prs[pr.PullRequestID()]; current.Version() != pr.Version() {
+		return domain.ErrVersionMismatch
+	}
+	r.prs[pr.PullRequestID()] = pr.WithVersion(pr.Version().Next())
 	return nil
 }
 
@@ -231,6 +362,25 @@ func (r *inMemoryPRRepo) GetPullRequestForUpdate(ctx context.Context, id domain.
 	return r.GetPullRequest(ctx, id)
 }
 
+func (r *inMemoryPRRepo) MarkReviewed(ctx context.Context, id domain.PullRequestID, reviewer domain.UserID, verdict domain.ReviewVerdict, at time.Time) error {
+	pr, ok := r.prs[id]
+	if !ok {
+		return fmt.Errorf("not found")
+	}
+	for _, assigned := range pr.AssignedReviewers() {
+		if assigned == reviewer {
+			r.prs[id] = pr.WithVersion(pr.Version().Next())
+			return nil
+		}
+	}
+	return domain.ErrReviewerNotAssigned
+}
+
+func (r *inMemoryPRRepo) RecordReassignment(ctx context.Context, id domain.PullRequestID, oldReviewer, newReviewer domain.UserID, at time.Time) error {
+	r.reassignments[id]++
+	return nil
+}
+
 func (r *inMemoryPRRepo) ListPullRequestsByReviewer(ctx context.Context, reviewer domain.UserID) ([]domainpr.PullRequest, error) {
 	result := make([]domainpr.PullRequest, 0)
 	for _, pr := range r.prs {
@@ -252,46 +402,124 @@ func newInMemoryStatsRepo(prs *inMemoryPRRepo) *inMemoryStatsRepo {
 	return &inMemoryStatsRepo{prs: prs}
 }
 
-func (r *inMemoryStatsRepo) AssignmentsPerUser(ctx context.Context) (map[domain.UserID]int, error) {
-	result := make(map[domain.UserID]int)
+func (r *inMemoryStatsRepo) AssignmentsPerUser(ctx context.Context, f domainstats.Filter) ([]domainstats.ReviewerLoad, error) {
+	loads := make(map[domain.UserID]*domainstats.ReviewerLoad)
+	order := make([]domain.UserID, 0)
 	for _, pr := range r.prs.prs {
+		if f.Status != "" && pr.Status() != f.Status {
+			continue
+		}
 		for _, reviewer := range pr.AssignedReviewers() {
-			result[reviewer]++
+			load, ok := loads[reviewer]
+			if !ok {
+				load = &domainstats.ReviewerLoad{UserID: reviewer, IsActive: true}
+				loads[reviewer] = load
+				order = append(order, reviewer)
+			}
+			if pr.Status() == domain.PullRequestStatusMerged {
+				load.Merged++
+			} else {
+				load.Open++
+			}
 		}
 	}
+	result := make([]domainstats.ReviewerLoad, 0, len(order))
+	for _, id := range order {
+		result = append(result, *loads[id])
+	}
 	return result, nil
 }
 
-func (r *inMemoryStatsRepo) AssignmentsPerPullRequest(ctx context.Context) (map[domain.PullRequestID]int, error) {
+func (r *inMemoryStatsRepo) AssignmentsPerPullRequest(ctx context.Context, f domainstats.Filter) (map[domain.PullRequestID]int, error) {
 	result := make(map[domain.PullRequestID]int)
 	for id, pr := range r.prs.prs {
+		if f.Status != "" && pr.Status() != f.Status {
+			continue
+		}
 		result[id] = len(pr.AssignedReviewers())
 	}
 	return result, nil
 }
 
+func (r *inMemoryStatsRepo) CreateToMergeTimes(ctx context.Context, f domainstats.Filter) ([]domainstats.DurationGroup, error) {
+	return r.mergeTimes(func(pr domainpr.PullRequest) []time.Time {
+		return []time.Time{pr.CreatedAt()}
+	}), nil
+}
+
+func (r *inMemoryStatsRepo) AssignmentToMergeTimes(ctx context.Context, f domainstats.Filter) ([]domainstats.DurationGroup, error) {
+	return r.mergeTimes(func(pr domainpr.PullRequest) []time.Time {
+		starts := make([]time.Time, 0, len(pr.AssignedReviewers()))
+		for range pr.AssignedReviewers() {
+			starts = append(starts, pr.CreatedAt())
+		}
+		return starts
+	}), nil
+}
+
+// mergeTimes считает только общий разрез: в e2e важна форма ответа, а не перцентили
+func (r *inMemoryStatsRepo) mergeTimes(starts func(pr domainpr.PullRequest) []time.Time) []domainstats.DurationGroup {
+	total := domainstats.DurationGroup{Dimension: domainstats.DimensionTotal}
+	for _, pr := range r.prs.prs {
+		if pr.MergedAt() == nil {
+			continue
+		}
+		for _, start := range starts(pr) {
+			d := pr.MergedAt().Sub(start)
+			total.Count++
+			if d > total.P99 {
+				total.P50, total.P90, total.P99 = d, d, d
+			}
+		}
+	}
+	return []domainstats.DurationGroup{total}
+}
+
+func (r *inMemoryStatsRepo) ReassignmentRates(ctx context.Context, f domainstats.Filter) ([]domainstats.ReassignmentGroup, error) {
+	total := domainstats.ReassignmentGroup{Dimension: domainstats.DimensionTotal}
+	for id := range r.prs.prs {
+		total.PullRequests++
+		if n := r.prs.reassignments[id]; n > 0 {
+			total.Reassigned++
+			total.Reassignments += n
+		}
+	}
+	return []domainstats.ReassignmentGroup{total}, nil
+}
+
 type memoryStore struct {
-	data map[string]idempotency.StoredResponse
-	reqs map[string]idempotency.StoredRequest
+	data    map[idempotency.Key]idempotency.StoredResponse
+	reqs    map[idempotency.Key]idempotency.StoredRequest
+	pending map[idempotency.Key]bool
 }
 
 func newMemoryStore() *memoryStore {
 	return &memoryStore{
-		data: make(map[string]idempotency.StoredResponse),
-		reqs: make(map[string]idempotency.StoredRequest),
+		data:    make(map[idempotency.Key]idempotency.StoredResponse),
+		reqs:    make(map[idempotency.Key]idempotency.StoredRequest),
+		pending: make(map[idempotency.Key]bool),
 	}
 }
 
-func (m *memoryStore) Get(ctx context.Context, key string) (idempotency.StoredRequest, idempotency.StoredResponse, bool, error) {
-	req, ok := m.reqs[key]
-	if !ok {
-		return idempotency.StoredRequest{}, idempotency.StoredResponse{}, false, nil
+func (m *memoryStore) Reserve(ctx context.Context, key idempotency.Key, req idempotency.StoredRequest, lockTTL time.Duration) (idempotency.Reservation, error) {
+	if stored, ok := m.reqs[key]; ok {
+		return idempotency.Reservation{Pending: m.pending[key], Request: stored, Response: m.data[key]}, nil
 	}
-	return req, m.data[key], true, nil
+	m.reqs[key] = req
+	m.pending[key] = true
+	return idempotency.Reservation{Acquired: true}, nil
 }
 
-func (m *memoryStore) Save(ctx context.Context, key string, req idempotency.StoredRequest, ttl time.Duration, resp idempotency.StoredResponse) error {
-	m.reqs[key] = req
+func (m *memoryStore) Complete(ctx context.Context, key idempotency.Key, token string, resp idempotency.StoredResponse, ttl time.Duration) error {
 	m.data[key] = resp
+	delete(m.pending, key)
+	return nil
+}
+
+func (m *memoryStore) Release(ctx context.Context, key idempotency.Key, token string) error {
+	if m.pending[key] {
+		delete(m.reqs, key)
+		delete(m.pending, key)
+	}
 	return nil
 }